@@ -34,10 +34,11 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 	acmeAuth := cert.AcmeAuth
 
 	logrus.WithFields(logrus.Fields{
-		"certificate": cert.Name,
-		"domains":     cert.AcmeDomains,
-		"acme_type":   acmeType,
-		"acme_auth":   acmeAuth,
+		"certificate":    cert.Name,
+		"domains":        cert.AcmeDomains,
+		"acme_type":      acmeType,
+		"acme_auth":      acmeAuth,
+		"acme_directory": getDirectory(cert),
 	}).Info("acme: Generating acme certificate")
 
 	if cert.AcmeDomains == nil || len(cert.AcmeDomains) == 0 {
@@ -68,6 +69,8 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 			dnsSvc = &dns.Cloudflare{}
 		} else if acmeAuth == certificate.AcmeOracleCloud {
 			dnsSvc = &dns.Oracle{}
+		} else if acmeAuth == certificate.AcmeRfc2136 {
+			dnsSvc = &dns.Rfc2136{}
 		} else {
 			err = &errortypes.UnknownError{
 				errors.Wrapf(err,
@@ -121,11 +124,24 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 		}
 	}
 
+	client, err := newClient(cert, acctKey)
+	if err != nil {
+		return
+	}
+
 	acct := &acme.Account{}
 
-	client := &acme.Client{
-		DirectoryURL: AcmeDirectory,
-		Key:          acctKey,
+	if cert.AcmeEabKeyId != "" {
+		eabKey, e := cert.GetAcmeEabHmac()
+		if e != nil {
+			err = e
+			return
+		}
+
+		acct.ExternalAccountBinding = &acme.ExternalAccountBinding{
+			KID: cert.AcmeEabKeyId,
+			Key: eabKey,
+		}
 	}
 
 	_, err = client.Register(context.Background(), acct, acme.AcceptTOS)
@@ -358,7 +374,12 @@ func create(db *database.Database, cert *certificate.Certificate,
 	var csr []byte
 	var keyPem []byte
 
-	if settings.System.AcmeKeyAlgorithm == "ec" {
+	keyType := cert.AcmeKeyType
+	if keyType == "" {
+		keyType = settings.System.AcmeKeyAlgorithm
+	}
+
+	if keyType == certificate.AcmeKeyEc {
 		csr, keyPem, err = newEcCsr(cert.AcmeDomains)
 		if err != nil {
 			return
@@ -370,7 +391,7 @@ func create(db *database.Database, cert *certificate.Certificate,
 		}
 	}
 
-	derChain, _, err := client.CreateOrderCert(
+	derChain, certUrl, err := client.CreateOrderCert(
 		context.Background(),
 		order.FinalizeURL,
 		csr,
@@ -383,6 +404,10 @@ func create(db *database.Database, cert *certificate.Certificate,
 		return
 	}
 
+	if cert.AcmePreferredChain != "" {
+		derChain = preferredChain(client, cert, derChain, certUrl)
+	}
+
 	certPem := ""
 
 	for _, der := range derChain {
@@ -397,6 +422,8 @@ func create(db *database.Database, cert *certificate.Certificate,
 		certPem += strings.TrimSpace(string(pem.EncodeToMemory(certBlock)))
 	}
 
+	cert.PushHistory()
+
 	cert.Key = strings.TrimSpace(string(keyPem))
 	cert.Certificate = certPem
 	cert.AcmeHash = cert.Hash()
@@ -407,7 +434,7 @@ func create(db *database.Database, cert *certificate.Certificate,
 	}
 
 	err = cert.CommitFields(db, set.NewSet(
-		"key", "certificate", "acme_hash", "info"))
+		"key", "certificate", "acme_hash", "info", "history"))
 	if err != nil {
 		return
 	}
@@ -420,20 +447,76 @@ func create(db *database.Database, cert *certificate.Certificate,
 func Renew(db *database.Database, cert *certificate.Certificate) (
 	err error) {
 
+	err = renew(db, cert, false)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// renew generates the certificate when the configuration has changed or the
+// certificate is expiring, failed renewals are retried with an exponential
+// backoff unless forced
+func renew(db *database.Database, cert *certificate.Certificate,
+	force bool) (err error) {
+
 	if cert.Type != certificate.LetsEncrypt {
 		return
 	}
 
-	if cert.AcmeHash != cert.Hash() || (cert.Info != nil &&
-		!cert.Info.ExpiresOn.IsZero() &&
-		time.Until(cert.Info.ExpiresOn) < 168*time.Hour) {
+	now := time.Now()
+	renewOn := renewTime(cert.Info)
 
-		err = Generate(db, cert)
-		if err != nil {
-			return
+	if cert.AcmeHash == cert.Hash() &&
+		(renewOn.IsZero() || now.Before(renewOn)) {
+
+		if cert.Failing() {
+			cert.Lifecycle.Failures = 0
+			cert.Lifecycle.LastError = ""
+			cert.Lifecycle.NextAttempt = renewOn
+
+			err = cert.CommitFields(db, set.NewSet("lifecycle"))
+			if err != nil {
+				return
+			}
+
+			event.PublishDispatch(db, "certificate.change")
+		}
+
+		return
+	}
+
+	lifecycle := cert.GetLifecycle()
+
+	if !force && cert.Failing() && now.Before(lifecycle.NextAttempt) {
+		return
+	}
+
+	lifecycle.LastAttempt = now
+
+	err = Generate(db, cert)
+	if err != nil {
+		lifecycle.Failures += 1
+		lifecycle.LastError = err.Error()
+		lifecycle.NextAttempt = now.Add(backoff(lifecycle.Failures))
+	} else {
+		lifecycle.Failures = 0
+		lifecycle.LastError = ""
+		lifecycle.LastRenewed = now
+		lifecycle.NextAttempt = renewTime(cert.Info)
+	}
+
+	e := cert.CommitFields(db, set.NewSet("lifecycle"))
+	if e != nil {
+		if err == nil {
+			err = e
 		}
+		return
 	}
 
+	event.PublishDispatch(db, "certificate.change")
+
 	return
 }
 
@@ -442,7 +525,7 @@ func RenewBackground(cert *certificate.Certificate) {
 		db := database.GetDatabase()
 		defer db.Close()
 
-		err := Renew(db, cert)
+		err := renew(db, cert, true)
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
 				"certificate_id":   cert.Id.Hex(),
@@ -0,0 +1,257 @@
+package acme
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/pritunl/pritunl-cloud/certificate"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	testEabKid  = "kid-1"
+	testEabHmac = "zWNDZM6eQGHWpSRTPal5eIUYFTu7EajVIoguysqZ9wG44nMEtx3MUAsUDkMTQ12W"
+)
+
+type testJws struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+type testEabHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Url string `json:"url"`
+}
+
+type testAccount struct {
+	ExternalAccountBinding *testJws `json:"externalAccountBinding"`
+}
+
+type testDirectory struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	eab    *testJws
+}
+
+func (d *testDirectory) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", "nonce")
+
+	switch r.URL.Path {
+	case "/custom/directory":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   d.server.URL + "/custom/new-nonce",
+			"newAccount": d.server.URL + "/custom/new-account",
+			"newOrder":   d.server.URL + "/custom/new-order",
+			"revokeCert": d.server.URL + "/custom/revoke-cert",
+			"keyChange":  d.server.URL + "/custom/key-change",
+		})
+		break
+	case "/custom/new-nonce":
+		w.WriteHeader(200)
+		break
+	case "/custom/new-account":
+		req := &testJws{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(req.Payload)
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		acct := &testAccount{}
+		err = json.Unmarshal(payload, acct)
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		d.lock.Lock()
+		d.eab = acct.ExternalAccountBinding
+		d.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", d.server.URL+"/custom/account/1")
+		w.WriteHeader(201)
+		_, _ = w.Write([]byte(`{"status":"valid"}`))
+		break
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func newTestDirectory() (d *testDirectory) {
+	d = &testDirectory{}
+	d.server = httptest.NewTLSServer(http.HandlerFunc(d.handle))
+	return
+}
+
+func (d *testDirectory) rootCa() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: d.server.Certificate().Raw,
+	}))
+}
+
+func register(t *testing.T, cert *certificate.Certificate) (err error) {
+	acctKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newClient(cert, acctKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acct := &acme.Account{}
+	if cert.AcmeEabKeyId != "" {
+		eabKey, e := cert.GetAcmeEabHmac()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		acct.ExternalAccountBinding = &acme.ExternalAccountBinding{
+			KID: cert.AcmeEabKeyId,
+			Key: eabKey,
+		}
+	}
+
+	_, err = client.Register(context.Background(), acct, acme.AcceptTOS)
+	return
+}
+
+func TestDirectoryRootCa(t *testing.T) {
+	dir := newTestDirectory()
+	defer dir.server.Close()
+
+	cert := &certificate.Certificate{
+		AcmeDirectory: dir.server.URL + "/custom/directory",
+	}
+
+	err := register(t, cert)
+	if err == nil {
+		t.Fatal("expected untrusted directory certificate to fail")
+	}
+
+	cert.AcmeRootCa = dir.rootCa()
+
+	err = register(t, cert)
+	if err != nil {
+		t.Fatalf("register with directory root certificate: %v", err)
+	}
+}
+
+func TestDirectoryEab(t *testing.T) {
+	dir := newTestDirectory()
+	defer dir.server.Close()
+
+	cert := &certificate.Certificate{
+		AcmeDirectory: dir.server.URL + "/custom/directory",
+		AcmeRootCa:    dir.rootCa(),
+		AcmeEabKeyId:  testEabKid,
+		AcmeEabHmac:   testEabHmac,
+	}
+
+	err := register(t, cert)
+	if err != nil {
+		t.Fatalf("register with external account binding: %v", err)
+	}
+
+	dir.lock.Lock()
+	eab := dir.eab
+	dir.lock.Unlock()
+
+	if eab == nil {
+		t.Fatal("missing external account binding")
+	}
+
+	protected, err := base64.RawURLEncoding.DecodeString(eab.Protected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := &testEabHeader{}
+	err = json.Unmarshal(protected, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Alg != "HS256" {
+		t.Errorf("unexpected eab alg: %s", header.Alg)
+	}
+	if header.Kid != testEabKid {
+		t.Errorf("unexpected eab kid: %s", header.Kid)
+	}
+	if header.Url != dir.server.URL+"/custom/new-account" {
+		t.Errorf("unexpected eab url: %s", header.Url)
+	}
+
+	key, err := cert.GetAcmeEabHmac()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(eab.Protected + "." + eab.Payload))
+
+	sig, err := base64.RawURLEncoding.DecodeString(eab.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		t.Error("invalid eab signature")
+	}
+}
+
+// TestPebbleEab registers against a Pebble server started with
+// externalAccountBindingRequired, set PEBBLE_DIRECTORY, PEBBLE_ROOT_CA,
+// PEBBLE_EAB_KID and PEBBLE_EAB_HMAC to run
+func TestPebbleEab(t *testing.T) {
+	directory := os.Getenv("PEBBLE_DIRECTORY")
+	if directory == "" {
+		t.Skip("PEBBLE_DIRECTORY not set")
+	}
+
+	rootCa, err := os.ReadFile(os.Getenv("PEBBLE_ROOT_CA"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := &certificate.Certificate{
+		AcmeDirectory: directory,
+		AcmeRootCa:    string(rootCa),
+	}
+
+	err = register(t, cert)
+	if err == nil {
+		t.Fatal("expected register without external account binding to fail")
+	}
+
+	cert.AcmeEabKeyId = os.Getenv("PEBBLE_EAB_KID")
+	cert.AcmeEabHmac = os.Getenv("PEBBLE_EAB_HMAC")
+
+	err = register(t, cert)
+	if err != nil {
+		t.Fatalf("register with external account binding: %v", err)
+	}
+}
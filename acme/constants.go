@@ -1,6 +1,12 @@
 package acme
 
+import (
+	"time"
+)
+
 const (
+	RenewBefore = 168 * time.Hour
+
 	AcmeDirectory = "https://acme-v02.api.letsencrypt.org/directory"
 	AcmePath      = "/.well-known/acme-challenge/"
 )
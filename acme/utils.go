@@ -2,21 +2,27 @@ package acme
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"net"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/certificate"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/acme"
 )
 
@@ -40,6 +46,153 @@ func revoke(client *acme.Client, authzUrls []string) {
 	}
 }
 
+func getDirectory(cert *certificate.Certificate) string {
+	if cert.AcmeDirectory != "" {
+		return cert.AcmeDirectory
+	}
+	return AcmeDirectory
+}
+
+func newClient(cert *certificate.Certificate, acctKey crypto.Signer) (
+	client *acme.Client, err error) {
+
+	client = &acme.Client{
+		DirectoryURL: getDirectory(cert),
+		Key:          acctKey,
+	}
+
+	if cert.AcmeRootCa == "" && cert.Organization.IsZero() {
+		return
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cert.AcmeRootCa != "" {
+		rootCas, e := x509.SystemCertPool()
+		if e != nil {
+			rootCas = x509.NewCertPool()
+		}
+
+		if !rootCas.AppendCertsFromPEM([]byte(cert.AcmeRootCa)) {
+			err = &errortypes.ParseError{
+				errors.New("acme: Failed to parse directory root certificate"),
+			}
+			return
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    rootCas,
+		}
+	}
+
+	if !cert.Organization.IsZero() {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   utils.PublicControl,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	client.HTTPClient = &http.Client{
+		Transport: transport,
+	}
+
+	return
+}
+
+// renewTime returns the time a certificate is due for renewal after two
+// thirds of its lifetime, certificates without an issue time fall back to
+// renewing before expiration
+func renewTime(info *certificate.Info) time.Time {
+	if info == nil || info.ExpiresOn.IsZero() {
+		return time.Time{}
+	}
+
+	lifetime := info.ExpiresOn.Sub(info.IssuedOn)
+	if info.IssuedOn.IsZero() || lifetime <= 0 {
+		return info.ExpiresOn.Add(-RenewBefore)
+	}
+
+	return info.ExpiresOn.Add(-lifetime / 3)
+}
+
+// preferredChain returns the first chain offered by the certificate
+// authority with the top certificate issued by the preferred chain common
+// name, the default chain is returned when no chain matches
+func preferredChain(client *acme.Client, cert *certificate.Certificate,
+	derChain [][]byte, certUrl string) [][]byte {
+
+	if strings.EqualFold(chainIssuer(derChain), cert.AcmePreferredChain) {
+		return derChain
+	}
+
+	altUrls, err := client.ListCertAlternates(context.Background(), certUrl)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"certificate": cert.Name,
+			"error":       err,
+		}).Warn("acme: Failed to list alternate certificate chains")
+		return derChain
+	}
+
+	for _, altUrl := range altUrls {
+		altChain, e := client.FetchCert(context.Background(), altUrl, true)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"certificate": cert.Name,
+				"chain_url":   altUrl,
+				"error":       e,
+			}).Warn("acme: Failed to fetch alternate certificate chain")
+			continue
+		}
+
+		if strings.EqualFold(chainIssuer(altChain),
+			cert.AcmePreferredChain) {
+
+			return altChain
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"certificate":     cert.Name,
+		"preferred_chain": cert.AcmePreferredChain,
+	}).Warn("acme: Preferred certificate chain not available")
+
+	return derChain
+}
+
+func chainIssuer(derChain [][]byte) string {
+	if len(derChain) == 0 {
+		return ""
+	}
+
+	topCert, err := x509.ParseCertificate(derChain[len(derChain)-1])
+	if err != nil {
+		return ""
+	}
+
+	return topCert.Issuer.CommonName
+}
+
+// backoff returns the delay before the next renewal attempt, the delay
+// doubles with each consecutive failure up to the maximum
+func backoff(failures int) time.Duration {
+	delay := time.Duration(settings.Acme.RenewBackoff) * time.Second
+	maxDelay := time.Duration(settings.Acme.RenewBackoffMax) * time.Second
+
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
 func ParsePath(path string) string {
 	split := strings.SplitN(path, AcmePath, 2)
 	if len(split) == 2 {
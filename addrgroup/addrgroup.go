@@ -0,0 +1,106 @@
+package addrgroup
+
+import (
+	"net"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+type AddrGroup struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Comment      string             `bson:"comment" json:"comment"`
+	Organization primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
+	Addresses    []string           `bson:"addresses" json:"addresses"`
+}
+
+func (g *AddrGroup) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	addresses := []string{}
+	addressesSet := set.NewSet()
+
+	for _, address := range g.Addresses {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		if !strings.Contains(address, "/") {
+			if strings.Contains(address, ":") {
+				address += "/128"
+			} else {
+				address += "/32"
+			}
+		}
+
+		_, addressCidr, e := net.ParseCIDR(address)
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_address",
+				Message: "Invalid address group address",
+			}
+			return
+		}
+
+		address = addressCidr.String()
+		if addressesSet.Contains(address) {
+			continue
+		}
+		addressesSet.Add(address)
+
+		addresses = append(addresses, address)
+	}
+
+	g.Addresses = addresses
+
+	return
+}
+
+func (g *AddrGroup) Commit(db *database.Database) (err error) {
+	coll := db.AddrGroups()
+
+	err = coll.Commit(g.Id, g)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (g *AddrGroup) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.AddrGroups()
+
+	err = coll.CommitFields(g.Id, g, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (g *AddrGroup) Insert(db *database.Database) (err error) {
+	coll := db.AddrGroups()
+
+	if !g.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("addrgroup: Address group already exists"),
+		}
+		return
+	}
+
+	_, err = coll.InsertOne(db, g)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
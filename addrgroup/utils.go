@@ -0,0 +1,137 @@
+package addrgroup
+
+import (
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/mongo-go-driver/mongo/options"
+	"github.com/pritunl/pritunl-cloud/database"
+)
+
+func Get(db *database.Database, groupId primitive.ObjectID) (
+	group *AddrGroup, err error) {
+
+	coll := db.AddrGroups()
+	group = &AddrGroup{}
+
+	err = coll.FindOneId(groupId, group)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetOrg(db *database.Database, orgId, groupId primitive.ObjectID) (
+	group *AddrGroup, err error) {
+
+	coll := db.AddrGroups()
+	group = &AddrGroup{}
+
+	err = coll.FindOne(db, &bson.M{
+		"_id":          groupId,
+		"organization": orgId,
+	}).Decode(group)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAll(db *database.Database, query *bson.M) (
+	groups []*AddrGroup, err error) {
+
+	coll := db.AddrGroups()
+	groups = []*AddrGroup{}
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"name", 1},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		group := &AddrGroup{}
+		err = cursor.Decode(group)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		groups = append(groups, group)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetMulti(db *database.Database, groupIds []primitive.ObjectID) (
+	groups []*AddrGroup, err error) {
+
+	groups, err = GetAll(db, &bson.M{
+		"_id": &bson.M{
+			"$in": groupIds,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func Remove(db *database.Database, groupId primitive.ObjectID) (err error) {
+	coll := db.AddrGroups()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id": groupId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		switch err.(type) {
+		case *database.NotFoundError:
+			err = nil
+		default:
+			return
+		}
+	}
+
+	return
+}
+
+func RemoveOrg(db *database.Database, orgId, groupId primitive.ObjectID) (
+	err error) {
+
+	coll := db.AddrGroups()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id":          groupId,
+		"organization": orgId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		switch err.(type) {
+		case *database.NotFoundError:
+			err = nil
+		default:
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,188 @@
+package ahandlers
+
+import (
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/addrgroup"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/demo"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+type addrGroupData struct {
+	Id           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	Comment      string             `json:"comment"`
+	Organization primitive.ObjectID `json:"organization"`
+	Addresses    []string           `json:"addresses"`
+}
+
+func addrGroupPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &addrGroupData{}
+
+	groupId, ok := utils.ParseObjectId(c.Param("group_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	group, err := addrgroup.Get(db, groupId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	group.Name = data.Name
+	group.Comment = data.Comment
+	group.Organization = data.Organization
+	group.Addresses = data.Addresses
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"organization",
+		"addresses",
+	)
+
+	errData, err := group.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = group.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "addrgroup.change")
+
+	c.JSON(200, group)
+}
+
+func addrGroupPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &addrGroupData{
+		Name: "New Address Group",
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	group := &addrgroup.AddrGroup{
+		Name:         data.Name,
+		Comment:      data.Comment,
+		Organization: data.Organization,
+		Addresses:    data.Addresses,
+	}
+
+	errData, err := group.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = group.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "addrgroup.change")
+
+	c.JSON(200, group)
+}
+
+func addrGroupDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	groupId, ok := utils.ParseObjectId(c.Param("group_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := addrgroup.Remove(db, groupId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "addrgroup.change")
+
+	c.JSON(200, nil)
+}
+
+func addrGroupGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	groupId, ok := utils.ParseObjectId(c.Param("group_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	group, err := addrgroup.Get(db, groupId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, group)
+}
+
+func addrGroupsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	groups, err := addrgroup.GetAll(db, &bson.M{})
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, groups)
+}
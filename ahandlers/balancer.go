@@ -20,18 +20,38 @@ import (
 )
 
 type balancerData struct {
-	Id           primitive.ObjectID   `json:"id"`
-	Name         string               `json:"name"`
-	Comment      string               `json:"comment"`
-	State        bool                 `json:"state"`
-	Type         string               `json:"type"`
-	Organization primitive.ObjectID   `json:"organization"`
-	Datacenter   primitive.ObjectID   `json:"datacenter"`
-	Certificates []primitive.ObjectID `json:"certificates"`
-	WebSockets   bool                 `json:"websockets"`
-	Domains      []*balancer.Domain   `json:"domains"`
-	Backends     []*balancer.Backend  `json:"backends"`
-	CheckPath    string               `json:"check_path"`
+	Id                 primitive.ObjectID   `json:"id"`
+	Name               string               `json:"name"`
+	Comment            string               `json:"comment"`
+	State              bool                 `json:"state"`
+	Type               string               `json:"type"`
+	Organization       primitive.ObjectID   `json:"organization"`
+	Datacenter         primitive.ObjectID   `json:"datacenter"`
+	Certificates       []primitive.ObjectID `json:"certificates"`
+	WebSockets         bool                 `json:"websockets"`
+	Domains            []*balancer.Domain   `json:"domains"`
+	Backends           []*balancer.Backend  `json:"backends"`
+	CheckPath          string               `json:"check_path"`
+	CheckInterval      int                  `json:"check_interval"`
+	CheckTimeout       int                  `json:"check_timeout"`
+	CheckStatusCodes   []int                `json:"check_status_codes"`
+	CheckBody          string               `json:"check_body"`
+	HealthyThreshold   int                  `json:"healthy_threshold"`
+	UnhealthyThreshold int                  `json:"unhealthy_threshold"`
+	OutlierErrors      int                  `json:"outlier_errors"`
+	OutlierTime        int                  `json:"outlier_time"`
+	Affinity           string               `json:"affinity"`
+	DrainTimeout       int                  `json:"drain_timeout"`
+	AllowSources       []string             `json:"allow_sources"`
+	DenySources        []string             `json:"deny_sources"`
+	RateLimit          int                  `json:"rate_limit"`
+	RateBurst          int                  `json:"rate_burst"`
+	MaxBodyBytes       int64                `json:"max_body_bytes"`
+	MaxHeaderBytes     int                  `json:"max_header_bytes"`
+	AccessLog          string               `json:"access_log"`
+	CheckPort          int                  `json:"check_port"`
+	ListenPorts        []int                `json:"listen_ports"`
+	ProxyProtocol      string               `json:"proxy_protocol"`
 }
 
 type balancersData struct {
@@ -77,8 +97,30 @@ func balancerPut(c *gin.Context) {
 	balnc.Certificates = data.Certificates
 	balnc.WebSockets = data.WebSockets
 	balnc.Domains = data.Domains
+	prevBackends := balnc.Backends
 	balnc.Backends = data.Backends
+	balnc.UpdateDrain(prevBackends)
 	balnc.CheckPath = data.CheckPath
+	balnc.CheckInterval = data.CheckInterval
+	balnc.CheckTimeout = data.CheckTimeout
+	balnc.CheckStatusCodes = data.CheckStatusCodes
+	balnc.CheckBody = data.CheckBody
+	balnc.HealthyThreshold = data.HealthyThreshold
+	balnc.UnhealthyThreshold = data.UnhealthyThreshold
+	balnc.OutlierErrors = data.OutlierErrors
+	balnc.OutlierTime = data.OutlierTime
+	balnc.Affinity = data.Affinity
+	balnc.DrainTimeout = data.DrainTimeout
+	balnc.AllowSources = data.AllowSources
+	balnc.DenySources = data.DenySources
+	balnc.RateLimit = data.RateLimit
+	balnc.RateBurst = data.RateBurst
+	balnc.MaxBodyBytes = data.MaxBodyBytes
+	balnc.MaxHeaderBytes = data.MaxHeaderBytes
+	balnc.AccessLog = data.AccessLog
+	balnc.CheckPort = data.CheckPort
+	balnc.ListenPorts = data.ListenPorts
+	balnc.ProxyProtocol = data.ProxyProtocol
 
 	fields := set.NewSet(
 		"name",
@@ -92,6 +134,26 @@ func balancerPut(c *gin.Context) {
 		"domains",
 		"backends",
 		"check_path",
+		"check_interval",
+		"check_timeout",
+		"check_status_codes",
+		"check_body",
+		"healthy_threshold",
+		"unhealthy_threshold",
+		"outlier_errors",
+		"outlier_time",
+		"affinity",
+		"drain_timeout",
+		"allow_sources",
+		"deny_sources",
+		"rate_limit",
+		"rate_burst",
+		"max_body_bytes",
+		"max_header_bytes",
+		"access_log",
+		"check_port",
+		"listen_ports",
+		"proxy_protocol",
 	)
 
 	errData, err := balnc.Validate(db)
@@ -138,19 +200,41 @@ func balancerPost(c *gin.Context) {
 	}
 
 	balnc := &balancer.Balancer{
-		Name:         data.Name,
-		Comment:      data.Comment,
-		State:        data.State,
-		Type:         data.Type,
-		Organization: data.Organization,
-		Datacenter:   data.Datacenter,
-		Certificates: data.Certificates,
-		WebSockets:   data.WebSockets,
-		Domains:      data.Domains,
-		Backends:     data.Backends,
-		CheckPath:    data.CheckPath,
+		Name:               data.Name,
+		Comment:            data.Comment,
+		State:              data.State,
+		Type:               data.Type,
+		Organization:       data.Organization,
+		Datacenter:         data.Datacenter,
+		Certificates:       data.Certificates,
+		WebSockets:         data.WebSockets,
+		Domains:            data.Domains,
+		Backends:           data.Backends,
+		CheckPath:          data.CheckPath,
+		CheckInterval:      data.CheckInterval,
+		CheckTimeout:       data.CheckTimeout,
+		CheckStatusCodes:   data.CheckStatusCodes,
+		CheckBody:          data.CheckBody,
+		HealthyThreshold:   data.HealthyThreshold,
+		UnhealthyThreshold: data.UnhealthyThreshold,
+		OutlierErrors:      data.OutlierErrors,
+		OutlierTime:        data.OutlierTime,
+		Affinity:           data.Affinity,
+		DrainTimeout:       data.DrainTimeout,
+		AllowSources:       data.AllowSources,
+		DenySources:        data.DenySources,
+		RateLimit:          data.RateLimit,
+		RateBurst:          data.RateBurst,
+		MaxBodyBytes:       data.MaxBodyBytes,
+		MaxHeaderBytes:     data.MaxHeaderBytes,
+		AccessLog:          data.AccessLog,
+		CheckPort:          data.CheckPort,
+		ListenPorts:        data.ListenPorts,
+		ProxyProtocol:      data.ProxyProtocol,
 	}
 
+	balnc.UpdateDrain(nil)
+
 	errData, err := balnc.Validate(db)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
@@ -247,6 +331,30 @@ func balancerGet(c *gin.Context) {
 	c.JSON(200, balnc)
 }
 
+func balancerLogsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	balancerId, ok := utils.ParseObjectId(c.Param("balancer_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	balnc, err := balancer.Get(db, balancerId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	logs, err := balancer.GetAccessLogs(db, balnc.Id, 100)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, logs)
+}
+
 func balancersGet(c *gin.Context) {
 	db := c.MustGet("db").(*database.Database)
 
@@ -24,6 +24,8 @@ type datacenterData struct {
 	PrivateStorageClass string               `json:"private_storage_class"`
 	BackupStorage       primitive.ObjectID   `json:"backup_storage"`
 	BackupStorageClass  string               `json:"backup_storage_class"`
+	BackupEncryption    bool                 `json:"backup_encryption"`
+	BackupKey           string               `json:"backup_key"`
 }
 
 func datacenterPut(c *gin.Context) {
@@ -64,7 +66,11 @@ func datacenterPut(c *gin.Context) {
 	dc.PrivateStorageClass = data.PrivateStorageClass
 	dc.BackupStorage = data.BackupStorage
 	dc.BackupStorageClass = data.BackupStorageClass
+	dc.BackupEncryption = data.BackupEncryption
+	dc.SetBackupKey(data.BackupKey)
 
+	// Backup key is write only, an empty key keeps the stored key and a
+	// new key moves the stored key to the previous keys
 	fields := set.NewSet(
 		"name",
 		"comment",
@@ -75,6 +81,9 @@ func datacenterPut(c *gin.Context) {
 		"private_storage_class",
 		"backup_storage",
 		"backup_storage_class",
+		"backup_encryption",
+		"backup_key",
+		"backup_keys",
 	)
 
 	errData, err := dc.Validate(db)
@@ -96,6 +105,8 @@ func datacenterPut(c *gin.Context) {
 
 	event.PublishDispatch(db, "datacenter.change")
 
+	dc.Json()
+
 	c.JSON(200, dc)
 }
 
@@ -128,6 +139,8 @@ func datacenterPost(c *gin.Context) {
 		PrivateStorageClass: data.PrivateStorageClass,
 		BackupStorage:       data.BackupStorage,
 		BackupStorageClass:  data.BackupStorageClass,
+		BackupEncryption:    data.BackupEncryption,
+		BackupKey:           data.BackupKey,
 	}
 
 	errData, err := dc.Validate(db)
@@ -149,6 +162,8 @@ func datacenterPost(c *gin.Context) {
 
 	event.PublishDispatch(db, "datacenter.change")
 
+	dc.Json()
+
 	c.JSON(200, dc)
 }
 
@@ -191,6 +206,8 @@ func datacenterGet(c *gin.Context) {
 		return
 	}
 
+	dc.Json()
+
 	c.JSON(200, dc)
 }
 
@@ -203,5 +220,9 @@ func datacentersGet(c *gin.Context) {
 		return
 	}
 
+	for _, dc := range dcs {
+		dc.Json()
+	}
+
 	c.JSON(200, dcs)
 }
@@ -41,6 +41,9 @@ type diskData struct {
 	Size             int                `json:"size"`
 	NewSize          int                `json:"new_size"`
 	Backup           bool               `json:"backup"`
+	SnapshotSchedule string             `json:"snapshot_schedule"`
+	SnapshotRetain   int                `json:"snapshot_retain"`
+	SnapshotMaxAge   int                `json:"snapshot_max_age"`
 }
 
 type disksMultiData struct {
@@ -90,6 +93,9 @@ func diskPut(c *gin.Context) {
 		"delete_protection",
 		"index",
 		"backup",
+		"snapshot_schedule",
+		"snapshot_retain",
+		"snapshot_max_age",
 		"new_size",
 	)
 
@@ -101,6 +107,9 @@ func diskPut(c *gin.Context) {
 	dsk.DeleteProtection = dta.DeleteProtection
 	dsk.Index = dta.Index
 	dsk.Backup = dta.Backup
+	dsk.SnapshotSchedule = dta.SnapshotSchedule
+	dsk.SnapshotRetain = dta.SnapshotRetain
+	dsk.SnapshotMaxAge = dta.SnapshotMaxAge
 
 	if dsk.State == disk.Available && dta.State == disk.Snapshot {
 		dsk.State = disk.Snapshot
@@ -139,6 +148,17 @@ func diskPut(c *gin.Context) {
 			return
 		}
 
+		errData, err := data.ValidateRestore(db, dsk, img)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+
+		if errData != nil {
+			c.JSON(400, errData)
+			return
+		}
+
 		dsk.State = disk.Restore
 		dsk.RestoreImage = img.Id
 
@@ -238,6 +258,9 @@ func diskPost(c *gin.Context) {
 		Backing:          dta.Backing,
 		Size:             dta.Size,
 		Backup:           dta.Backup,
+		SnapshotSchedule: dta.SnapshotSchedule,
+		SnapshotRetain:   dta.SnapshotRetain,
+		SnapshotMaxAge:   dta.SnapshotMaxAge,
 	}
 
 	errData, err := dsk.Validate(db)
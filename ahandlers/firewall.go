@@ -23,7 +23,9 @@ type firewallData struct {
 	Comment      string             `json:"comment"`
 	Organization primitive.ObjectID `json:"organization"`
 	NetworkRoles []string           `json:"network_roles"`
+	LogOnly      bool               `json:"log_only"`
 	Ingress      []*firewall.Rule   `json:"ingress"`
+	Egress       []*firewall.Rule   `json:"egress"`
 }
 
 type firewallsData struct {
@@ -61,14 +63,18 @@ func firewallPut(c *gin.Context) {
 	fire.Comment = data.Comment
 	fire.Organization = data.Organization
 	fire.NetworkRoles = data.NetworkRoles
+	fire.LogOnly = data.LogOnly
 	fire.Ingress = data.Ingress
+	fire.Egress = data.Egress
 
 	fields := set.NewSet(
 		"name",
 		"comment",
 		"organization",
 		"network_roles",
+		"log_only",
 		"ingress",
+		"egress",
 	)
 
 	errData, err := fire.Validate(db)
@@ -114,7 +120,9 @@ func firewallPost(c *gin.Context) {
 		Comment:      data.Comment,
 		Organization: data.Organization,
 		NetworkRoles: data.NetworkRoles,
+		LogOnly:      data.LogOnly,
 		Ingress:      data.Ingress,
+		Egress:       data.Egress,
 	}
 
 	errData, err := fire.Validate(db)
@@ -203,6 +211,8 @@ func firewallGet(c *gin.Context) {
 		return
 	}
 
+	fire.Json()
+
 	c.JSON(200, fire)
 }
 
@@ -251,6 +261,10 @@ func firewallsGet(c *gin.Context) {
 		return
 	}
 
+	for _, fire := range firewalls {
+		fire.Json()
+	}
+
 	data := &firewallsData{
 		Firewalls: firewalls,
 		Count:     count,
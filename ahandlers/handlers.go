@@ -44,6 +44,14 @@ func Register(engine *gin.Engine) {
 	csrfGroup.DELETE("/alert", alertsDelete)
 	csrfGroup.DELETE("/alert/:alert_id", alertDelete)
 
+	csrfGroup.GET("/notification", notificationsGet)
+	csrfGroup.PUT("/notification/:notification_id", notificationPut)
+	csrfGroup.POST("/notification", notificationPost)
+	csrfGroup.POST("/notification/:notification_id/test",
+		notificationTestPost)
+	csrfGroup.DELETE("/notification", notificationsDelete)
+	csrfGroup.DELETE("/notification/:notification_id", notificationDelete)
+
 	engine.GET("/auth/state", authStateGet)
 	dbGroup.POST("/auth/session", authSessionPost)
 	dbGroup.POST("/auth/secondary", authSecondaryPost)
@@ -64,6 +72,7 @@ func Register(engine *gin.Engine) {
 
 	csrfGroup.GET("/balancer", balancersGet)
 	csrfGroup.GET("/balancer/:balancer_id", balancerGet)
+	csrfGroup.GET("/balancer/:balancer_id/log", balancerLogsGet)
 	csrfGroup.PUT("/balancer/:balancer_id", balancerPut)
 	csrfGroup.POST("/balancer", balancerPost)
 	csrfGroup.DELETE("/balancer", balancersDelete)
@@ -79,6 +88,8 @@ func Register(engine *gin.Engine) {
 	csrfGroup.GET("/certificate/:cert_id", certificateGet)
 	csrfGroup.PUT("/certificate/:cert_id", certificatePut)
 	csrfGroup.POST("/certificate", certificatePost)
+	csrfGroup.POST("/certificate/:cert_id/rollback",
+		certificateRollbackPost)
 	csrfGroup.DELETE("/certificate/:cert_id", certificateDelete)
 
 	engine.GET("/check", checkGet)
@@ -124,6 +135,12 @@ func Register(engine *gin.Engine) {
 	csrfGroup.DELETE("/firewall", firewallsDelete)
 	csrfGroup.DELETE("/firewall/:firewall_id", firewallDelete)
 
+	csrfGroup.GET("/addrgroup", addrGroupsGet)
+	csrfGroup.GET("/addrgroup/:group_id", addrGroupGet)
+	csrfGroup.PUT("/addrgroup/:group_id", addrGroupPut)
+	csrfGroup.POST("/addrgroup", addrGroupPost)
+	csrfGroup.DELETE("/addrgroup/:group_id", addrGroupDelete)
+
 	csrfGroup.GET("/image", imagesGet)
 	csrfGroup.GET("/image/:image_id", imageGet)
 	csrfGroup.PUT("/image/:image_id", imagePut)
@@ -135,6 +152,7 @@ func Register(engine *gin.Engine) {
 	csrfGroup.GET("/instance/:instance_id", instanceGet)
 	csrfGroup.GET("/instance/:instance_id/vnc", instanceVncGet)
 	csrfGroup.PUT("/instance/:instance_id", instancePut)
+	csrfGroup.POST("/instance/:instance_id/migrate", instanceMigratePost)
 	csrfGroup.POST("/instance", instancePost)
 	csrfGroup.DELETE("/instance", instancesDelete)
 	csrfGroup.DELETE("/instance/:instance_id", instanceDelete)
@@ -223,6 +241,12 @@ func Register(engine *gin.Engine) {
 	csrfGroup.DELETE("/vpc", vpcsDelete)
 	csrfGroup.DELETE("/vpc/:vpc_id", vpcDelete)
 
+	csrfGroup.GET("/peering", peeringsGet)
+	csrfGroup.GET("/peering/:peering_id", peeringGet)
+	csrfGroup.PUT("/peering/:peering_id", peeringPut)
+	csrfGroup.POST("/peering", peeringPost)
+	csrfGroup.DELETE("/peering/:peering_id", peeringDelete)
+
 	csrfGroup.GET("/zone", zonesGet)
 	csrfGroup.GET("/zone/:zone_id", zoneGet)
 	csrfGroup.PUT("/zone/:zone_id", zonePut)
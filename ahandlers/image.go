@@ -102,12 +102,17 @@ func imageDelete(c *gin.Context) {
 		return
 	}
 
-	err := data.DeleteImage(db, imageId)
+	errData, err := data.DeleteImage(db, imageId)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
 		return
 	}
 
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
 	event.PublishDispatch(db, "image.change")
 
 	c.JSON(200, nil)
@@ -127,12 +132,17 @@ func imagesDelete(c *gin.Context) {
 		return
 	}
 
-	err = data.DeleteImages(db, dta)
+	errData, err := data.DeleteImages(db, dta)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
 		return
 	}
 
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
 	event.PublishDispatch(db, "image.change")
 
 	c.JSON(200, nil)
@@ -75,6 +75,10 @@ type instanceData struct {
 	Count               int                `json:"count"`
 }
 
+type instanceMigrateData struct {
+	Node primitive.ObjectID `json:"node"`
+}
+
 type instanceMultiData struct {
 	Ids   []primitive.ObjectID `json:"ids"`
 	State string               `json:"state"`
@@ -724,3 +728,66 @@ func instanceVncGet(c *gin.Context) {
 		return
 	}
 }
+
+func instanceMigratePost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	dta := &instanceMigrateData{}
+
+	instanceId, ok := utils.ParseObjectId(c.Param("instance_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	inst, err := instance.Get(db, instanceId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	nde, err := node.Get(db, dta.Node)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	errData, err := inst.ValidateMigrate(db, nde)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	started, err := inst.Migrate(db, nde, false)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !started {
+		errData = &errortypes.ErrorData{
+			Error:   "instance_migrating",
+			Message: "Instance is already migrating",
+		}
+		c.JSON(400, errData)
+		return
+	}
+
+	event.PublishDispatch(db, "instance.change")
+
+	c.JSON(200, inst)
+}
@@ -70,6 +70,10 @@ type nodeData struct {
 	NetworkRoles            []string                `json:"network_roles"`
 	OracleUser              string                  `json:"oracle_user"`
 	OracleHostRoute         bool                    `json:"oracle_host_route"`
+	BackupPath              string                  `json:"backup_path"`
+	BackupBootTest          bool                    `json:"backup_boot_test"`
+	Maintenance             bool                    `json:"maintenance"`
+	Evacuate                bool                    `json:"evacuate"`
 }
 
 type nodesData struct {
@@ -157,6 +161,9 @@ func nodePut(c *gin.Context) {
 	nde.NetworkRoles = data.NetworkRoles
 	nde.OracleUser = data.OracleUser
 	nde.OracleHostRoute = data.OracleHostRoute
+	nde.BackupPath = data.BackupPath
+	nde.BackupBootTest = data.BackupBootTest
+	nde.Maintenance = data.Maintenance
 
 	fields := set.NewSet(
 		"name",
@@ -202,8 +209,18 @@ func nodePut(c *gin.Context) {
 		"network_roles",
 		"oracle_user",
 		"oracle_host_route",
+		"backup_path",
+		"backup_boot_test",
+		"maintenance",
+		"evacuate",
 	)
 
+	if data.Evacuate != nde.Evacuate {
+		nde.Evacuate = data.Evacuate
+		nde.Evacuation = nil
+		fields.Add("evacuation")
+	}
+
 	if !data.Zone.IsZero() && data.Zone != nde.Zone {
 		if !nde.Zone.IsZero() {
 			errData := &errortypes.ErrorData{
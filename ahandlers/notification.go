@@ -0,0 +1,322 @@
+package ahandlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/alert"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/demo"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/notification"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+type notificationData struct {
+	Id           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	Comment      string             `json:"comment"`
+	Organization primitive.ObjectID `json:"organization"`
+	Type         string             `json:"type"`
+	Url          string             `json:"url"`
+	Secret       string             `json:"secret"`
+	SmtpHost     string             `json:"smtp_host"`
+	SmtpPort     int                `json:"smtp_port"`
+	SmtpUsername string             `json:"smtp_username"`
+	SmtpPassword string             `json:"smtp_password"`
+	SmtpFrom     string             `json:"smtp_from"`
+	SmtpTo       []string           `json:"smtp_to"`
+}
+
+type notificationsData struct {
+	Notifications []*notification.Notification `json:"notifications"`
+	Count         int64                        `json:"count"`
+}
+
+func notificationPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &notificationData{}
+
+	notfId, ok := utils.ParseObjectId(c.Param("notification_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	notf, err := notification.Get(db, notfId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	notf.Name = data.Name
+	notf.Comment = data.Comment
+	notf.Organization = data.Organization
+	notf.Type = data.Type
+	notf.Url = data.Url
+	notf.Secret = data.Secret
+	notf.SmtpHost = data.SmtpHost
+	notf.SmtpPort = data.SmtpPort
+	notf.SmtpUsername = data.SmtpUsername
+	if data.SmtpPassword != "" {
+		notf.SmtpPassword = data.SmtpPassword
+	}
+	notf.SmtpFrom = data.SmtpFrom
+	notf.SmtpTo = data.SmtpTo
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"organization",
+		"type",
+		"url",
+		"secret",
+		"smtp_host",
+		"smtp_port",
+		"smtp_username",
+		"smtp_password",
+		"smtp_from",
+		"smtp_to",
+	)
+
+	errData, err := notf.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = notf.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "notification.change")
+
+	c.JSON(200, notf)
+}
+
+func notificationPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &notificationData{
+		Name: "New Notification",
+		Type: notification.Webhook,
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	notf := &notification.Notification{
+		Name:         data.Name,
+		Comment:      data.Comment,
+		Organization: data.Organization,
+		Type:         data.Type,
+		Url:          data.Url,
+		Secret:       data.Secret,
+		SmtpHost:     data.SmtpHost,
+		SmtpPort:     data.SmtpPort,
+		SmtpUsername: data.SmtpUsername,
+		SmtpPassword: data.SmtpPassword,
+		SmtpFrom:     data.SmtpFrom,
+		SmtpTo:       data.SmtpTo,
+	}
+
+	errData, err := notf.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = notf.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "notification.change")
+
+	c.JSON(200, notf)
+}
+
+func notificationTestPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	notfId, ok := utils.ParseObjectId(c.Param("notification_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	notf, err := notification.Get(db, notfId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = notf.SendTest()
+	if err != nil {
+		c.JSON(400, &errortypes.ErrorData{
+			Error:   "notification_test_failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, nil)
+}
+
+func notificationDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	notfId, ok := utils.ParseObjectId(c.Param("notification_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := notification.Remove(db, notfId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = alert.RemoveNotification(db, notfId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "notification.change")
+
+	c.JSON(200, nil)
+}
+
+func notificationsDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	dta := []primitive.ObjectID{}
+
+	err := c.Bind(&dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = notification.RemoveMulti(db, dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = alert.RemoveNotification(db, dta...)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "notification.change")
+
+	c.JSON(200, nil)
+}
+
+func notificationsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	query := bson.M{}
+
+	notfId, ok := utils.ParseObjectId(c.Query("id"))
+	if ok {
+		query["_id"] = notfId
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if name != "" {
+		query["$or"] = []*bson.M{
+			&bson.M{
+				"name": &bson.M{
+					"$regex":   fmt.Sprintf(".*%s.*", regexp.QuoteMeta(name)),
+					"$options": "i",
+				},
+			},
+		}
+	}
+
+	typ := strings.TrimSpace(c.Query("type"))
+	if typ != "" {
+		query["type"] = typ
+	}
+
+	organization, ok := utils.ParseObjectId(c.Query("organization"))
+	if ok {
+		query["organization"] = organization
+	}
+
+	notfs, count, err := notification.GetAllPaged(
+		db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	dta := &notificationsData{
+		Notifications: notfs,
+		Count:         count,
+	}
+
+	c.JSON(200, dta)
+}
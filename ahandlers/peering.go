@@ -0,0 +1,202 @@
+package ahandlers
+
+import (
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/demo"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/peering"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+type peeringData struct {
+	Id           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	Comment      string             `json:"comment"`
+	Vpc          primitive.ObjectID `json:"vpc"`
+	Accepted     bool               `json:"accepted"`
+	PeerVpc      primitive.ObjectID `json:"peer_vpc"`
+	PeerAccepted bool               `json:"peer_accepted"`
+}
+
+func peeringPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &peeringData{}
+
+	peeringId, ok := utils.ParseObjectId(c.Param("peering_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	peer, err := peering.Get(db, peeringId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	peer.Name = data.Name
+	peer.Comment = data.Comment
+	peer.Vpc = data.Vpc
+	peer.Accepted = data.Accepted
+	peer.PeerVpc = data.PeerVpc
+	peer.PeerAccepted = data.PeerAccepted
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"datacenter",
+		"organization",
+		"vpc",
+		"accepted",
+		"peer_organization",
+		"peer_vpc",
+		"peer_accepted",
+		"active",
+	)
+
+	errData, err := peer.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = peer.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "peering.change")
+
+	c.JSON(200, peer)
+}
+
+func peeringPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &peeringData{
+		Name:         "New Peering",
+		Accepted:     true,
+		PeerAccepted: true,
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	peer := &peering.Peering{
+		Name:         data.Name,
+		Comment:      data.Comment,
+		Vpc:          data.Vpc,
+		Accepted:     data.Accepted,
+		PeerVpc:      data.PeerVpc,
+		PeerAccepted: data.PeerAccepted,
+	}
+
+	errData, err := peer.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = peer.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "peering.change")
+
+	c.JSON(200, peer)
+}
+
+func peeringDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	peeringId, ok := utils.ParseObjectId(c.Param("peering_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := peering.Remove(db, peeringId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "peering.change")
+
+	c.JSON(200, nil)
+}
+
+func peeringGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	peeringId, ok := utils.ParseObjectId(c.Param("peering_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	peer, err := peering.Get(db, peeringId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, peer)
+}
+
+func peeringsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	peerings, err := peering.GetAll(db, &bson.M{})
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, peerings)
+}
@@ -26,6 +26,7 @@ type podData struct {
 	DeleteProtection bool               `json:"delete_protection"`
 	Zone             primitive.ObjectID `json:"zone"`
 	Roles            []string           `json:"roles"`
+	Plan             primitive.ObjectID `json:"plan"`
 	Spec             string             `json:"spec"`
 }
 
@@ -67,6 +68,7 @@ func podPut(c *gin.Context) {
 	pd.DeleteProtection = data.DeleteProtection
 	pd.Zone = data.Zone
 	pd.Roles = data.Roles
+	pd.Plan = data.Plan
 	pd.Spec = data.Spec
 
 	fields := set.NewSet(
@@ -78,6 +80,7 @@ func podPut(c *gin.Context) {
 		"delete_protection",
 		"zone",
 		"roles",
+		"plan",
 		"spec",
 	)
 
@@ -127,6 +130,7 @@ func podPost(c *gin.Context) {
 		DeleteProtection: data.DeleteProtection,
 		Zone:             data.Zone,
 		Roles:            data.Roles,
+		Plan:             data.Plan,
 		Spec:             data.Spec,
 	}
 
@@ -29,6 +29,7 @@ type vpcData struct {
 	Datacenter   primitive.ObjectID `json:"datacenter"`
 	Routes       []*vpc.Route       `json:"routes"`
 	Maps         []*vpc.Map         `json:"maps"`
+	DnsServer    bool               `json:"dns_server"`
 }
 
 type vpcsData struct {
@@ -68,6 +69,7 @@ func vpcPut(c *gin.Context) {
 	vc.Comment = data.Comment
 	vc.Routes = data.Routes
 	vc.Maps = data.Maps
+	vc.DnsServer = data.DnsServer
 	vc.Subnets = data.Subnets
 
 	fields := set.NewSet(
@@ -76,6 +78,7 @@ func vpcPut(c *gin.Context) {
 		"routes",
 		"maps",
 		"subnets",
+		"dns_server",
 	)
 
 	errData, err := vc.Validate(db)
@@ -141,6 +144,7 @@ func vpcPost(c *gin.Context) {
 		Datacenter:   data.Datacenter,
 		Routes:       data.Routes,
 		Maps:         data.Maps,
+		DnsServer:    data.DnsServer,
 	}
 
 	vc.InitVpc()
@@ -1,24 +1,30 @@
 package alert
 
 import (
+	"strings"
+
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/notification"
 	"github.com/pritunl/pritunl-cloud/utils"
 )
 
 type Alert struct {
-	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name         string             `bson:"name" json:"name"`
-	Organization primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
-	Roles        []string           `bson:"roles" json:"roles"`
-	Resource     string             `bson:"resource" json:"resource"`
-	Level        int                `bson:"level" json:"level"`
-	Frequency    int                `bson:"frequency" json:"frequency"`
-	Ignores      []string           `bson:"ignores" json:"ignores"`
-	ValueInt     int                `bson:"value_int" json:"value_int"`
-	ValueStr     string             `bson:"value_str" json:"value_str"`
+	Id            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name          string               `bson:"name" json:"name"`
+	Organization  primitive.ObjectID   `bson:"organization,omitempty" json:"organization"`
+	Roles         []string             `bson:"roles" json:"roles"`
+	Resource      string               `bson:"resource" json:"resource"`
+	Level         int                  `bson:"level" json:"level"`
+	Frequency     int                  `bson:"frequency" json:"frequency"`
+	Ignores       []string             `bson:"ignores" json:"ignores"`
+	ValueInt      int                  `bson:"value_int" json:"value_int"`
+	ValueStr      string               `bson:"value_str" json:"value_str"`
+	Notifications []primitive.ObjectID `bson:"notifications" json:"notifications"`
+	triggered     []*State
+	notfs         notifications
 }
 
 func (a *Alert) Validate(db *database.Database) (
@@ -31,6 +37,9 @@ func (a *Alert) Validate(db *database.Database) (
 		}
 	}
 
+	a.Name = strings.TrimSpace(
+		strings.NewReplacer("\r", "", "\n", "").Replace(a.Name))
+
 	if a.Roles == nil {
 		a.Roles = []string{}
 	}
@@ -55,7 +64,7 @@ func (a *Alert) Validate(db *database.Database) (
 		return
 	}
 
-	if a.Ignores != nil {
+	if a.Ignores == nil {
 		a.Ignores = []string{}
 	}
 
@@ -64,6 +73,142 @@ func (a *Alert) Validate(db *database.Database) (
 		a.ValueInt = 0
 		a.ValueStr = ""
 		break
+	case NodeLoad1, NodeLoad15:
+		if !a.Organization.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_resource_name_invalid",
+				Message: "Node alerts cannot be used in organizations",
+			}
+			return
+		}
+
+		if a.ValueInt == 0 {
+			a.ValueInt = 90
+		}
+		if a.ValueInt < 1 || a.ValueInt > 10000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Node load threshold must be between 1 and 10000",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case NodeCpuReserved, NodeMemoryReserved:
+		if !a.Organization.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_resource_name_invalid",
+				Message: "Node alerts cannot be used in organizations",
+			}
+			return
+		}
+
+		if a.ValueInt == 0 {
+			a.ValueInt = 90
+		}
+		if a.ValueInt < 1 || a.ValueInt > 1000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Node reservation must be between 1 and 1000",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case DiskBackupAge:
+		if a.ValueInt == 0 {
+			a.ValueInt = 48
+		}
+		if a.ValueInt < 1 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Disk backup age must be at least 1 hour",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case CertificateExpiry:
+		if a.ValueInt == 0 {
+			a.ValueInt = 14
+		}
+		if a.ValueInt < 1 || a.ValueInt > 365 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Certificate expiry must be between 1 and 365 days",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case CertificateRenewal:
+		if a.ValueInt == 0 {
+			a.ValueInt = 14
+		}
+		if a.ValueInt < 1 || a.ValueInt > 365 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Certificate expiry must be between 1 and 365 days",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case BalancerOffline:
+		if a.ValueInt == 0 {
+			a.ValueInt = 1
+		}
+		if a.ValueInt < 1 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Offline backend count must be at least 1",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case PoolFreeSpace:
+		if !a.Organization.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_resource_name_invalid",
+				Message: "Pool alerts cannot be used in organizations",
+			}
+			return
+		}
+
+		if a.ValueInt == 0 {
+			a.ValueInt = 10
+		}
+		if a.ValueInt < 1 || a.ValueInt > 99 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Pool free space must be between 1 and 99 percent",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case BackupVerify:
+		if !a.Organization.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_resource_name_invalid",
+				Message: "Backup alerts cannot be used in organizations",
+			}
+			return
+		}
+
+		if a.ValueInt == 0 {
+			a.ValueInt = 48
+		}
+		if a.ValueInt < 1 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Backup verify age must be at least 1 hour",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
 	default:
 		errData = &errortypes.ErrorData{
 			Error:   "alert_resource_name_invalid",
@@ -83,6 +228,36 @@ func (a *Alert) Validate(db *database.Database) (
 		return
 	}
 
+	if a.Notifications == nil {
+		a.Notifications = []primitive.ObjectID{}
+	}
+
+	if len(a.Notifications) > 0 {
+		notfs, e := notification.GetMulti(db, a.Notifications)
+		if e != nil {
+			err = e
+			return
+		}
+
+		notfIds := []primitive.ObjectID{}
+		for _, notf := range notfs {
+			if !a.Organization.IsZero() &&
+				notf.Organization != a.Organization {
+
+				continue
+			}
+			notfIds = append(notfIds, notf.Id)
+		}
+
+		if len(notfIds) != len(a.Notifications) {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_notification_invalid",
+				Message: "Alert notification channel is invalid",
+			}
+			return
+		}
+	}
+
 	return
 }
 
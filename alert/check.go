@@ -0,0 +1,442 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/alertevent"
+	"github.com/pritunl/pritunl-cloud/backup"
+	"github.com/pritunl/pritunl-cloud/balancer"
+	"github.com/pritunl/pritunl-cloud/certificate"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/notification"
+	"github.com/pritunl/pritunl-cloud/pool"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
+)
+
+func (a *Alert) ignored(resourceId primitive.ObjectID,
+	resourceName string) bool {
+
+	for _, ignore := range a.Ignores {
+		if ignore == resourceId.Hex() || ignore == resourceName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Alert) matchRoles(roles []string) bool {
+	for _, role := range roles {
+		for _, alrtRole := range a.Roles {
+			if role == alrtRole {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (a *Alert) query() (query bson.M) {
+	query = bson.M{}
+	if !a.Organization.IsZero() {
+		query["organization"] = a.Organization
+	}
+
+	return
+}
+
+func (a *Alert) send(resourceId primitive.ObjectID,
+	resourceName, message string) {
+
+	if a.ignored(resourceId, resourceName) {
+		return
+	}
+
+	a.triggered = append(a.triggered, &State{
+		Id:         fmt.Sprintf("%s-%s", a.Id.Hex(), resourceId.Hex()),
+		Alert:      a.Id,
+		Source:     resourceId,
+		SourceName: resourceName,
+		Message:    message,
+		State:      notification.Firing,
+	})
+
+	alertevent.New(
+		a.Roles,
+		resourceId,
+		a.Name,
+		resourceName,
+		a.Resource,
+		message,
+		a.Level,
+		time.Duration(a.Frequency)*time.Second,
+	)
+}
+
+func (a *Alert) checkInstances(db *database.Database) (err error) {
+	query := a.query()
+	query["network_roles"] = &bson.M{
+		"$in": a.Roles,
+	}
+	query["state"] = instance.Start
+
+	insts, err := instance.GetAll(db, &query)
+	if err != nil {
+		return
+	}
+
+	if len(insts) == 0 {
+		return
+	}
+
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	nodesOffline := set.NewSet()
+	for _, nde := range nodes {
+		if time.Since(nde.Timestamp) > 30*time.Second {
+			nodesOffline.Add(nde.Id)
+		}
+	}
+
+	for _, inst := range insts {
+		if inst.VmState == vm.Stopped || inst.VmState == vm.Failed {
+			a.send(inst.Id, inst.Name, "Instance is offline")
+		} else if nodesOffline.Contains(inst.Node) {
+			a.send(inst.Id, inst.Name, "Instance node is offline")
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkNodes(db *database.Database) (err error) {
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	threshold := float64(a.ValueInt)
+
+	for _, nde := range nodes {
+		if !a.matchRoles(nde.NetworkRoles) ||
+			time.Since(nde.Timestamp) > 30*time.Second {
+
+			continue
+		}
+
+		switch a.Resource {
+		case NodeLoad1:
+			if nde.Load1 > threshold {
+				a.send(nde.Id, nde.Name, fmt.Sprintf(
+					"Node load1 is %.0f%%", nde.Load1))
+			}
+			break
+		case NodeLoad15:
+			if nde.Load15 > threshold {
+				a.send(nde.Id, nde.Name, fmt.Sprintf(
+					"Node load15 is %.0f%%", nde.Load15))
+			}
+			break
+		case NodeCpuReserved:
+			if nde.CpuUnits == 0 {
+				continue
+			}
+
+			reserved := float64(nde.CpuUnitsRes) /
+				float64(nde.CpuUnits) * 100
+			if reserved > threshold {
+				a.send(nde.Id, nde.Name, fmt.Sprintf(
+					"Node CPU reservation is %.0f%%", reserved))
+			}
+			break
+		case NodeMemoryReserved:
+			if nde.MemoryUnits == 0 {
+				continue
+			}
+
+			reserved := nde.MemoryUnitsRes / nde.MemoryUnits * 100
+			if reserved > threshold {
+				a.send(nde.Id, nde.Name, fmt.Sprintf(
+					"Node memory reservation is %.0f%%", reserved))
+			}
+			break
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkDisks(db *database.Database) (err error) {
+	query := a.query()
+	query["network_roles"] = &bson.M{
+		"$in": a.Roles,
+	}
+
+	insts, err := instance.GetAll(db, &query)
+	if err != nil {
+		return
+	}
+
+	if len(insts) == 0 {
+		return
+	}
+
+	instIds := []primitive.ObjectID{}
+	for _, inst := range insts {
+		instIds = append(instIds, inst.Id)
+	}
+
+	disks, err := disk.GetAll(db, &bson.M{
+		"instance": &bson.M{
+			"$in": instIds,
+		},
+		"backup": true,
+	})
+	if err != nil {
+		return
+	}
+
+	maxAge := time.Duration(a.ValueInt) * time.Hour
+
+	for _, dsk := range disks {
+		if dsk.LastBackup.IsZero() {
+			if time.Since(dsk.Id.Timestamp()) > maxAge {
+				a.send(dsk.Id, dsk.Name, "Disk has never been backed up")
+			}
+			continue
+		}
+
+		age := time.Since(dsk.LastBackup)
+		if age > maxAge {
+			a.send(dsk.Id, dsk.Name, fmt.Sprintf(
+				"Disk last backup was %d hours ago", int(age.Hours())))
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkCertificates(db *database.Database) (err error) {
+	var certs []*certificate.Certificate
+	if a.Organization.IsZero() {
+		certs, err = certificate.GetAll(db)
+	} else {
+		certs, err = certificate.GetAllOrg(db, a.Organization)
+	}
+	if err != nil {
+		return
+	}
+
+	maxExpire := time.Duration(a.ValueInt) * 24 * time.Hour
+
+	for _, cert := range certs {
+		if cert.Info == nil || cert.Info.ExpiresOn.IsZero() {
+			continue
+		}
+
+		remaining := time.Until(cert.Info.ExpiresOn)
+		if remaining <= 0 {
+			a.send(cert.Id, cert.Name, "Certificate has expired")
+		} else if remaining < maxExpire {
+			a.send(cert.Id, cert.Name, fmt.Sprintf(
+				"Certificate expires in %d days",
+				int(math.Ceil(remaining.Hours()/24))))
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkCertificateRenewals(db *database.Database) (
+	err error) {
+
+	var certs []*certificate.Certificate
+	if a.Organization.IsZero() {
+		certs, err = certificate.GetAll(db)
+	} else {
+		certs, err = certificate.GetAllOrg(db, a.Organization)
+	}
+	if err != nil {
+		return
+	}
+
+	maxExpire := time.Duration(a.ValueInt) * 24 * time.Hour
+
+	for _, cert := range certs {
+		if cert.Type != certificate.LetsEncrypt || !cert.Failing() ||
+			cert.Info == nil || cert.Info.ExpiresOn.IsZero() {
+
+			continue
+		}
+
+		remaining := time.Until(cert.Info.ExpiresOn)
+		if remaining <= 0 {
+			a.send(cert.Id, cert.Name, fmt.Sprintf(
+				"Certificate has expired, renewal failed %d times",
+				cert.Lifecycle.Failures))
+		} else if remaining < maxExpire {
+			a.send(cert.Id, cert.Name, fmt.Sprintf(
+				"Certificate expires in %d days, renewal failed %d times",
+				int(math.Ceil(remaining.Hours()/24)),
+				cert.Lifecycle.Failures))
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkBalancers(db *database.Database) (err error) {
+	query := a.query()
+	query["state"] = true
+
+	balncs, err := balancer.GetAll(db, &query)
+	if err != nil {
+		return
+	}
+
+	for _, balnc := range balncs {
+		offline := set.NewSet()
+		for _, state := range balnc.States {
+			if state == nil || time.Since(state.Timestamp) > 1*time.Minute {
+				continue
+			}
+
+			for _, key := range state.Offline {
+				offline.Add(key)
+			}
+		}
+
+		if offline.Len() >= a.ValueInt {
+			a.send(balnc.Id, balnc.Name, fmt.Sprintf(
+				"Balancer has %d backends offline", offline.Len()))
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkPools(db *database.Database) (err error) {
+	pools, err := pool.GetAll(db, &bson.M{})
+	if err != nil {
+		return
+	}
+
+	threshold := float64(a.ValueInt)
+
+	for _, pl := range pools {
+		if pl.Size == 0 || time.Since(pl.Timestamp) > 10*time.Minute {
+			continue
+		}
+
+		free := pl.FreePercent()
+		if free < threshold {
+			a.send(pl.Id, pl.Name, fmt.Sprintf(
+				"Pool has %.0f%% free space remaining", free))
+		}
+	}
+
+	return
+}
+
+func (a *Alert) checkBackups(db *database.Database) (err error) {
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	maxAge := time.Duration(a.ValueInt) * time.Hour
+
+	for _, nde := range nodes {
+		if nde.BackupPath == "" || !a.matchRoles(nde.NetworkRoles) {
+			continue
+		}
+
+		result, e := backup.GetLatestResult(db, nde.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if result == nil || time.Since(result.Timestamp) > maxAge {
+			a.send(nde.Id, nde.Name, "Node backup has not been verified")
+		} else if result.State != backup.Passed {
+			a.send(nde.Id, nde.Name, "Node backup verification failed")
+		}
+	}
+
+	return
+}
+
+func (a *Alert) Check(db *database.Database) (err error) {
+	a.triggered = []*State{}
+
+	switch a.Resource {
+	case InstanceOffline:
+		err = a.checkInstances(db)
+		break
+	case NodeLoad1, NodeLoad15, NodeCpuReserved, NodeMemoryReserved:
+		err = a.checkNodes(db)
+		break
+	case DiskBackupAge:
+		err = a.checkDisks(db)
+		break
+	case CertificateExpiry:
+		err = a.checkCertificates(db)
+		break
+	case CertificateRenewal:
+		err = a.checkCertificateRenewals(db)
+		break
+	case BalancerOffline:
+		err = a.checkBalancers(db)
+		break
+	case PoolFreeSpace:
+		err = a.checkPools(db)
+		break
+	case BackupVerify:
+		err = a.checkBackups(db)
+		break
+	}
+	if err != nil {
+		return
+	}
+
+	err = a.notify(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func CheckAll(db *database.Database) (err error) {
+	alerts, err := GetAll(db)
+	if err != nil {
+		return
+	}
+
+	for _, alrt := range alerts {
+		e := alrt.Check(db)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"alert_id":       alrt.Id.Hex(),
+				"alert_resource": alrt.Resource,
+				"error":          e,
+			}).Error("alert: Failed to check alert")
+		}
+	}
+
+	return
+}
@@ -7,5 +7,15 @@ const (
 )
 
 const (
-	InstanceOffline = "instance_offline"
+	InstanceOffline    = "instance_offline"
+	NodeLoad1          = "node_load1"
+	NodeLoad15         = "node_load15"
+	NodeCpuReserved    = "node_cpu_reserved"
+	NodeMemoryReserved = "node_memory_reserved"
+	DiskBackupAge      = "disk_backup_age"
+	CertificateExpiry  = "certificate_expiry"
+	CertificateRenewal = "certificate_renewal"
+	BalancerOffline    = "balancer_offline"
+	PoolFreeSpace      = "pool_free_space"
+	BackupVerify       = "backup_verify"
 )
@@ -0,0 +1,215 @@
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/mongo-go-driver/mongo/options"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/notification"
+	"github.com/sirupsen/logrus"
+)
+
+type notifications map[primitive.ObjectID]*notification.Notification
+
+type State struct {
+	Id         string               `bson:"_id" json:"id"`
+	Alert      primitive.ObjectID   `bson:"alert" json:"alert"`
+	Source     primitive.ObjectID   `bson:"source" json:"source"`
+	SourceName string               `bson:"source_name" json:"source_name"`
+	Message    string               `bson:"message" json:"message"`
+	State      string               `bson:"state" json:"state"`
+	Timestamp  time.Time            `bson:"timestamp" json:"timestamp"`
+	LastSeen   time.Time            `bson:"last_seen" json:"last_seen"`
+	LastSent   time.Time            `bson:"last_sent" json:"last_sent"`
+	Pending    []primitive.ObjectID `bson:"pending" json:"pending"`
+}
+
+func (s *State) Commit(db *database.Database) (err error) {
+	coll := db.AlertsState()
+
+	opts := &options.UpdateOptions{}
+	opts.SetUpsert(true)
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id": s.Id,
+	}, &bson.M{
+		"$set": s,
+	}, opts)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func getStates(db *database.Database, alertId primitive.ObjectID) (
+	states map[string]*State, err error) {
+
+	coll := db.AlertsState()
+	states = map[string]*State{}
+
+	cursor, err := coll.Find(db, &bson.M{
+		"alert": alertId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		state := &State{}
+		err = cursor.Decode(state)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		states[state.Id] = state
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func (a *Alert) deliver(state *State) {
+	msg := &notification.Message{
+		Id: fmt.Sprintf("%s-%s-%d", state.Id, state.State,
+			state.LastSent.Unix()),
+		State:      state.State,
+		Alert:      a.Id,
+		AlertName:  a.Name,
+		Resource:   a.Resource,
+		Source:     state.Source,
+		SourceName: state.SourceName,
+		Level:      a.Level,
+		Message:    state.Message,
+		Timestamp:  state.LastSent,
+	}
+
+	pending := []primitive.ObjectID{}
+	for _, notfId := range state.Pending {
+		notf := a.notfs[notfId]
+		if notf == nil {
+			continue
+		}
+
+		err := notf.Send(msg)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"alert_id":        a.Id.Hex(),
+				"notification_id": notf.Id.Hex(),
+				"source_id":       state.Source.Hex(),
+				"state":           state.State,
+				"error":           err,
+			}).Error("alert: Failed to send alert notification")
+
+			pending = append(pending, notfId)
+			continue
+		}
+	}
+
+	state.Pending = pending
+}
+
+func (a *Alert) notify(db *database.Database) (err error) {
+	if len(a.Notifications) == 0 {
+		return
+	}
+
+	notfs, err := notification.GetMulti(db, a.Notifications)
+	if err != nil {
+		return
+	}
+
+	a.notfs = notifications{}
+	for _, notf := range notfs {
+		a.notfs[notf.Id] = notf
+	}
+
+	states, err := getStates(db, a.Id)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	frequency := time.Duration(a.Frequency) * time.Second
+	seen := set.NewSet()
+	commits := []*State{}
+
+	for _, state := range a.triggered {
+		if seen.Contains(state.Id) {
+			continue
+		}
+		seen.Add(state.Id)
+
+		cur := states[state.Id]
+		if cur == nil || cur.State == notification.Resolved {
+			state.Timestamp = now
+			state.LastSent = now
+			state.Pending = a.Notifications
+		} else if now.Sub(cur.LastSent) >= frequency {
+			state.Timestamp = cur.Timestamp
+			state.LastSent = now
+			state.Pending = a.Notifications
+		} else {
+			state.Timestamp = cur.Timestamp
+			state.LastSent = cur.LastSent
+			state.Pending = cur.Pending
+		}
+		state.LastSeen = now
+
+		commits = append(commits, state)
+	}
+
+	for _, state := range states {
+		if seen.Contains(state.Id) {
+			continue
+		}
+
+		if state.State == notification.Firing {
+			state.State = notification.Resolved
+			state.LastSent = now
+			state.Pending = a.Notifications
+		} else if len(state.Pending) == 0 {
+			continue
+		}
+
+		commits = append(commits, state)
+	}
+
+	// Deliver concurrently so a slow endpoint only delays its own states
+	waiter := sync.WaitGroup{}
+	for _, state := range commits {
+		if len(state.Pending) == 0 {
+			continue
+		}
+
+		waiter.Add(1)
+		go func(state *State) {
+			defer waiter.Done()
+			a.deliver(state)
+		}(state)
+	}
+	waiter.Wait()
+
+	for _, state := range commits {
+		err = state.Commit(db)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
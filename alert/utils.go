@@ -322,3 +322,53 @@ func RemoveMultiOrg(db *database.Database, orgId primitive.ObjectID,
 
 	return
 }
+
+// RemoveNotification removes deleted notifications from all alerts
+func RemoveNotification(db *database.Database,
+	notfIds ...primitive.ObjectID) (err error) {
+
+	coll := db.Alerts()
+
+	_, err = coll.UpdateMany(db, &bson.M{
+		"notifications": &bson.M{
+			"$in": notfIds,
+		},
+	}, &bson.M{
+		"$pull": &bson.M{
+			"notifications": &bson.M{
+				"$in": notfIds,
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func RemoveNotificationOrg(db *database.Database, orgId primitive.ObjectID,
+	notfIds ...primitive.ObjectID) (err error) {
+
+	coll := db.Alerts()
+
+	_, err = coll.UpdateMany(db, &bson.M{
+		"organization": orgId,
+		"notifications": &bson.M{
+			"$in": notfIds,
+		},
+	}, &bson.M{
+		"$pull": &bson.M{
+			"notifications": &bson.M{
+				"$in": notfIds,
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
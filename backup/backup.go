@@ -12,7 +12,6 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/bson"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
-	"github.com/pritunl/pritunl-cloud/config"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/disk"
 	"github.com/pritunl/pritunl-cloud/errortypes"
@@ -29,6 +28,8 @@ type Backup struct {
 	node        *node.Node
 	virtPath    string
 	errorCount  int
+	manifest    *Manifest
+	key         *key
 }
 
 func (b *Backup) backupDisk(db *database.Database,
@@ -80,6 +81,39 @@ func (b *Backup) backupDisk(db *database.Database,
 	return
 }
 
+func (b *Backup) exportDisk(db *database.Database,
+	dsk *disk.Disk, dest string) (err error) {
+
+	if b.key == nil {
+		err = b.backupDisk(db, dsk, dest)
+		return
+	}
+
+	cacheDir := b.node.GetCachePath()
+	err = utils.ExistsMkdir(cacheDir, 0755)
+	if err != nil {
+		return
+	}
+
+	tmpPath := path.Join(cacheDir,
+		fmt.Sprintf("backup-%s.qcow2", dsk.Id.Hex()))
+	defer utils.Remove(tmpPath)
+
+	err = b.backupDisk(db, dsk, tmpPath)
+	if err != nil {
+		return
+	}
+
+	_ = os.Remove(dest)
+
+	err = b.key.EncryptFile(tmpPath, dest)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
 func (b *Backup) backupDisks(db *database.Database) (err error) {
 	logrus.WithFields(logrus.Fields{
 		"node_id": b.node.Id.Hex(),
@@ -99,23 +133,57 @@ func (b *Backup) backupDisks(db *database.Database) (err error) {
 	diskFilenames := set.NewSet()
 	for _, dsk := range disks {
 		filename := fmt.Sprintf("%s.qcow2", dsk.Id.Hex())
+		if b.key != nil {
+			filename += EncryptedExt
+		}
 		diskFilenames.Add(filename)
 
 		destPath := path.Join(disksDir, filename)
 
-		err = b.backupDisk(db, dsk, destPath)
+		err = b.exportDisk(db, dsk, destPath)
 		if err != nil {
 			b.errorCount += 1
 			logrus.WithFields(logrus.Fields{
 				"disk_id": dsk.Id.Hex(),
 				"error":   err,
 			}).Error("qemu: Failed to backup disk")
-		} else {
+			continue
+		}
+
+		size, checksum, e := fileChecksum(destPath)
+		if e != nil {
+			b.errorCount += 1
 			logrus.WithFields(logrus.Fields{
-				"node_id": b.node.Id.Hex(),
 				"disk_id": dsk.Id.Hex(),
-			}).Info("backup: Disk exported")
+				"error":   e,
+			}).Error("backup: Failed to checksum disk")
+			continue
+		}
+
+		uefi := false
+		if !dsk.Instance.IsZero() {
+			inst, e := instance.Get(db, dsk.Instance)
+			if e == nil {
+				uefi = inst.Uefi
+			}
 		}
+
+		b.manifest.Disks = append(b.manifest.Disks, &ManifestDisk{
+			Id:        dsk.Id,
+			Instance:  dsk.Instance,
+			Index:     dsk.Index,
+			Uefi:      uefi,
+			Name:      dsk.Name,
+			File:      path.Join("disks", filename),
+			Size:      size,
+			Checksum:  checksum,
+			Timestamp: time.Now(),
+		})
+
+		logrus.WithFields(logrus.Fields{
+			"node_id": b.node.Id.Hex(),
+			"disk_id": dsk.Id.Hex(),
+		}).Info("backup: Disk exported")
 	}
 
 	exportedDisks, err := ioutil.ReadDir(disksDir)
@@ -193,19 +261,40 @@ func (b *Backup) backupBackingDisks(db *database.Database) (err error) {
 
 	backingFilenames := set.NewSet()
 	for _, item := range curBackingDisks {
-		filename := item.Name()
+		name := item.Name()
+		filename := name
+		if b.key != nil {
+			filename += EncryptedExt
+		}
 		backingFilenames.Add(filename)
 
-		backingPath := path.Join(curBackingDisksDir, filename)
+		backingPath := path.Join(curBackingDisksDir, name)
 		newBackingPath := path.Join(backingDisksDir, filename)
 
 		_ = os.Remove(newBackingPath)
 
-		err = utils.Exec("", "cp", backingPath, newBackingPath)
+		if b.key != nil {
+			err = b.key.EncryptFile(backingPath, newBackingPath)
+		} else {
+			err = utils.Exec("", "cp", backingPath, newBackingPath)
+		}
 		if err != nil {
 			return
 		}
 
+		size, checksum, e := fileChecksum(newBackingPath)
+		if e != nil {
+			err = e
+			return
+		}
+
+		b.manifest.Backing = append(b.manifest.Backing, &ManifestFile{
+			Name:     name,
+			File:     path.Join("backing", filename),
+			Size:     size,
+			Checksum: checksum,
+		})
+
 		logrus.WithFields(logrus.Fields{
 			"node_id":      b.node.Id.Hex(),
 			"backing_disk": filename,
@@ -355,21 +444,43 @@ func (b *Backup) Run() (err error) {
 	db := database.GetDatabase()
 	defer db.Close()
 
-	ndeId, err := primitive.ObjectIDFromHex(config.Config.NodeId)
+	nde, err := getNode(db)
+	if err != nil {
+		return
+	}
+
+	dcId, err := nde.GetDatacenter(db)
 	if err != nil {
-		err = &errortypes.ParseError{
-			errors.Wrap(err, "backup: Failed to parse ObjectId"),
-		}
 		return
 	}
 
-	nde, err := node.Get(db, ndeId)
+	k, err := getKey(db, dcId)
 	if err != nil {
 		return
 	}
 
 	b.node = nde
+	b.key = k
 	b.virtPath = nde.GetVirtPath()
+	b.manifest = &Manifest{
+		Id:         primitive.NewObjectID(),
+		Node:       nde.Id,
+		Datacenter: dcId,
+		Timestamp:  time.Now(),
+		Disks:      []*ManifestDisk{},
+		Backing:    []*ManifestFile{},
+	}
+
+	if k != nil {
+		b.manifest.Encrypted = true
+		b.manifest.KeyId = k.Id
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"node_id":     nde.Id.Hex(),
+		"destination": b.Destination,
+		"encrypted":   b.manifest.Encrypted,
+	}).Info("backup: Starting backup")
 
 	err = b.backupDisks(db)
 	if err != nil {
@@ -386,6 +497,11 @@ func (b *Backup) Run() (err error) {
 		return
 	}
 
+	err = b.manifest.Write(b.Destination)
+	if err != nil {
+		return
+	}
+
 	if b.errorCount > 0 {
 		err = &errortypes.ExecError{
 			errors.Wrap(err, "backup: Backup encountered errors"),
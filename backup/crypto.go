@@ -0,0 +1,344 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/datacenter"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+// Encrypted files are a magic header followed by a random salt and a
+// sequence of AES-256-GCM sealed chunks. Each file is sealed with a subkey
+// derived from the datacenter key and salt. Chunks are prefixed with their
+// sealed length and a final flag, the chunk counter and final flag are
+// bound into the nonce and additional data to prevent reordering or
+// truncation.
+const (
+	EncryptedExt = ".enc"
+	cryptoMagic  = "PCBACKUP1"
+	chunkSize    = 1024 * 1024
+	saltSize     = 32
+)
+
+type key struct {
+	Id   string
+	data []byte
+}
+
+func (k *key) fileCipher(salt []byte) (aead cipher.AEAD, err error) {
+	hash := hmac.New(sha256.New, k.data)
+	hash.Write(salt)
+
+	block, err := aes.NewCipher(hash.Sum(nil))
+	if err != nil {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "backup: Failed to create cipher"),
+		}
+		return
+	}
+
+	aead, err = cipher.NewGCM(block)
+	if err != nil {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "backup: Failed to create GCM cipher"),
+		}
+		return
+	}
+
+	return
+}
+
+func nonce(aead cipher.AEAD, counter uint64) []byte {
+	n := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(n[len(n)-8:], counter)
+	return n
+}
+
+func (k *key) EncryptFile(srcPth, dstPth string) (err error) {
+	src, err := os.Open(srcPth)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "backup: Failed to open source file"),
+		}
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPth, os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
+		0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to open destination file"),
+		}
+		return
+	}
+	defer dst.Close()
+
+	salt, err := utils.RandBytes(saltSize)
+	if err != nil {
+		return
+	}
+
+	aead, err := k.fileCipher(salt)
+	if err != nil {
+		return
+	}
+
+	_, err = dst.Write(append([]byte(cryptoMagic), salt...))
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to write header"),
+		}
+		return
+	}
+
+	buf := make([]byte, chunkSize)
+	next := make([]byte, chunkSize)
+	n, err := io.ReadFull(src, buf)
+	counter := uint64(0)
+
+	for {
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "backup: Failed to read source file"),
+			}
+			return
+		}
+
+		final := byte(0)
+		nextN := 0
+		if err == nil {
+			nextN, err = io.ReadFull(src, next)
+			if err == io.EOF {
+				final = 1
+			}
+		} else {
+			final = 1
+		}
+
+		sealed := aead.Seal(nil, nonce(aead, counter),
+			buf[:n], []byte{final})
+
+		header := make([]byte, 5)
+		binary.BigEndian.PutUint32(header, uint32(len(sealed)))
+		header[4] = final
+
+		_, e := dst.Write(append(header, sealed...))
+		if e != nil {
+			err = &errortypes.WriteError{
+				errors.Wrap(e, "backup: Failed to write chunk"),
+			}
+			return
+		}
+
+		if final == 1 {
+			break
+		}
+
+		buf, next = next, buf
+		n = nextN
+		counter += 1
+	}
+
+	err = dst.Sync()
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to sync destination file"),
+		}
+		return
+	}
+
+	return
+}
+
+func (k *key) DecryptFile(srcPth, dstPth string) (err error) {
+	src, err := os.Open(srcPth)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "backup: Failed to open source file"),
+		}
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPth, os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
+		0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to open destination file"),
+		}
+		return
+	}
+	defer dst.Close()
+
+	header := make([]byte, len(cryptoMagic)+saltSize)
+	_, err = io.ReadFull(src, header)
+	if err != nil || string(header[:len(cryptoMagic)]) != cryptoMagic {
+		err = &errortypes.ParseError{
+			errors.New("backup: Invalid encrypted file header"),
+		}
+		return
+	}
+
+	aead, err := k.fileCipher(header[len(cryptoMagic):])
+	if err != nil {
+		return
+	}
+
+	chunkHeader := make([]byte, 5)
+	buf := make([]byte, chunkSize+aead.Overhead())
+	counter := uint64(0)
+
+	for {
+		_, err = io.ReadFull(src, chunkHeader)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "backup: Encrypted file truncated"),
+			}
+			return
+		}
+
+		size := binary.BigEndian.Uint32(chunkHeader)
+		final := chunkHeader[4]
+		if int(size) > len(buf) {
+			err = &errortypes.ParseError{
+				errors.New("backup: Invalid encrypted chunk size"),
+			}
+			return
+		}
+
+		_, err = io.ReadFull(src, buf[:size])
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "backup: Encrypted file truncated"),
+			}
+			return
+		}
+
+		plain, e := aead.Open(buf[:0], nonce(aead, counter),
+			buf[:size], []byte{final})
+		if e != nil {
+			err = &errortypes.VerificationError{
+				errors.Wrap(e, "backup: Failed to decrypt chunk"),
+			}
+			return
+		}
+
+		_, err = dst.Write(plain)
+		if err != nil {
+			err = &errortypes.WriteError{
+				errors.Wrap(err, "backup: Failed to write chunk"),
+			}
+			return
+		}
+
+		if final == 1 {
+			break
+		}
+		counter += 1
+	}
+
+	err = dst.Sync()
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to sync destination file"),
+		}
+		return
+	}
+
+	return
+}
+
+func newKey(encoded string) (k *key, err error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) != 32 {
+		err = &errortypes.ParseError{
+			errors.New("backup: Invalid backup key"),
+		}
+		return
+	}
+
+	k = &key{
+		Id:   datacenter.BackupKeyId(encoded),
+		data: data,
+	}
+
+	return
+}
+
+func getKey(db *database.Database, dcId primitive.ObjectID) (
+	k *key, err error) {
+
+	if dcId.IsZero() {
+		return
+	}
+
+	dc, err := datacenter.Get(db, dcId)
+	if err != nil {
+		return
+	}
+
+	if !dc.BackupEncryption {
+		return
+	}
+
+	if dc.BackupKey == "" {
+		err = &errortypes.NotFoundError{
+			errors.New("backup: Datacenter missing backup key"),
+		}
+		return
+	}
+
+	k, err = newKey(dc.BackupKey)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func getManifestKey(db *database.Database, manifest *Manifest) (
+	k *key, err error) {
+
+	if !manifest.Encrypted {
+		return
+	}
+
+	dc, err := datacenter.Get(db, manifest.Datacenter)
+	if err != nil {
+		return
+	}
+
+	encoded := dc.GetBackupKey(manifest.KeyId)
+	if encoded == "" {
+		err = &errortypes.NotFoundError{
+			errors.New("backup: Datacenter missing backup key for manifest"),
+		}
+		return
+	}
+
+	k, err = newKey(encoded)
+	if err != nil {
+		return
+	}
+
+	if k.Id != manifest.KeyId {
+		err = &errortypes.VerificationError{
+			errors.New("backup: Datacenter backup key does not match manifest"),
+		}
+		return
+	}
+
+	return
+}
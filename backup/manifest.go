@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+const ManifestName = "manifest.json"
+
+type ManifestDisk struct {
+	Id        primitive.ObjectID `json:"id"`
+	Instance  primitive.ObjectID `json:"instance"`
+	Index     string             `json:"index"`
+	Uefi      bool               `json:"uefi"`
+	Name      string             `json:"name"`
+	File      string             `json:"file"`
+	Size      int64              `json:"size"`
+	Checksum  string             `json:"checksum"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+type ManifestFile struct {
+	Name     string `json:"name"`
+	File     string `json:"file"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+type Manifest struct {
+	Id         primitive.ObjectID `json:"id"`
+	Node       primitive.ObjectID `json:"node"`
+	Datacenter primitive.ObjectID `json:"datacenter"`
+	Encrypted  bool               `json:"encrypted"`
+	KeyId      string             `json:"key_id,omitempty"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Disks      []*ManifestDisk    `json:"disks"`
+	Backing    []*ManifestFile    `json:"backing"`
+}
+
+func (m *Manifest) Write(dest string) (err error) {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "backup: Failed to marshal manifest"),
+		}
+		return
+	}
+
+	pth := path.Join(dest, ManifestName)
+	tmpPth := pth + ".tmp"
+
+	err = ioutil.WriteFile(tmpPth, data, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to write manifest"),
+		}
+		return
+	}
+
+	err = os.Rename(tmpPth, pth)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to move manifest"),
+		}
+		return
+	}
+
+	return
+}
+
+func LoadManifest(dest string) (manifest *Manifest, err error) {
+	data, err := ioutil.ReadFile(path.Join(dest, ManifestName))
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "backup: Failed to read manifest"),
+		}
+		return
+	}
+
+	manifest = &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "backup: Failed to parse manifest"),
+		}
+		return
+	}
+
+	return
+}
+
+func fileChecksum(pth string) (size int64, checksum string, err error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "backup: Failed to open file"),
+		}
+		return
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err = io.Copy(hash, file)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "backup: Failed to read file"),
+		}
+		return
+	}
+
+	checksum = hex.EncodeToString(hash.Sum(nil))
+
+	return
+}
@@ -0,0 +1,255 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
+)
+
+type Restore struct {
+	Destination string
+	Disks       []primitive.ObjectID
+	node        *node.Node
+	key         *key
+	virtPath    string
+	errorCount  int
+}
+
+func (r *Restore) restoreFile(src, dest, checksum string) (err error) {
+	_, sum, err := fileChecksum(src)
+	if err != nil {
+		return
+	}
+
+	if sum != checksum {
+		err = &errortypes.VerificationError{
+			errors.Newf("backup: Checksum mismatch for '%s'", src),
+		}
+		return
+	}
+
+	tmpPath := dest + ".restore"
+	defer utils.Remove(tmpPath)
+
+	if r.key != nil {
+		err = r.key.DecryptFile(src, tmpPath)
+	} else {
+		err = utils.Exec("", "cp", src, tmpPath)
+	}
+	if err != nil {
+		return
+	}
+
+	err = os.Chmod(tmpPath, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to chmod restored file"),
+		}
+		return
+	}
+
+	err = os.Rename(tmpPath, dest)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "backup: Failed to move restored file"),
+		}
+		return
+	}
+
+	return
+}
+
+func (r *Restore) restoreBacking(manifest *Manifest) (err error) {
+	backingDir := path.Join(r.virtPath, "backing")
+	err = utils.ExistsMkdir(backingDir, 0755)
+	if err != nil {
+		return
+	}
+
+	for _, mf := range manifest.Backing {
+		dest := path.Join(backingDir, path.Base(mf.Name))
+
+		exists, e := utils.Exists(dest)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if exists {
+			continue
+		}
+
+		err = r.restoreFile(path.Join(r.Destination, mf.File),
+			dest, mf.Checksum)
+		if err != nil {
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"node_id":      r.node.Id.Hex(),
+			"backing_disk": mf.Name,
+		}).Info("backup: Backing disk restored")
+	}
+
+	return
+}
+
+func (r *Restore) restoreDisk(db *database.Database,
+	md *ManifestDisk) (err error) {
+
+	dsk, err := disk.Get(db, md.Id)
+	if err != nil {
+		return
+	}
+
+	if dsk.Node != r.node.Id {
+		err = &errortypes.VerificationError{
+			errors.New("backup: Disk is not attached to this node"),
+		}
+		return
+	}
+
+	if !dsk.Instance.IsZero() {
+		inst, e := instance.Get(db, dsk.Instance)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if inst.VmState == vm.Running || inst.VmState == vm.Starting {
+			err = &errortypes.VerificationError{
+				errors.New("backup: Instance must be stopped to restore"),
+			}
+			return
+		}
+	}
+
+	disksDir := path.Join(r.virtPath, "disks")
+	err = utils.ExistsMkdir(disksDir, 0755)
+	if err != nil {
+		return
+	}
+
+	dest := path.Join(disksDir, fmt.Sprintf("%s.qcow2", dsk.Id.Hex()))
+
+	err = r.restoreFile(path.Join(r.Destination, md.File),
+		dest, md.Checksum)
+	if err != nil {
+		return
+	}
+
+	dsk.BackupChain = []primitive.ObjectID{}
+	err = dsk.CommitFields(db, set.NewSet("backup_chain"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Restore) Run() (err error) {
+	db := database.GetDatabase()
+	defer db.Close()
+
+	nde, err := getNode(db)
+	if err != nil {
+		return
+	}
+
+	r.node = nde
+	r.virtPath = nde.GetVirtPath()
+
+	manifest, err := LoadManifest(r.Destination)
+	if err != nil {
+		return
+	}
+
+	if manifest.Node != nde.Id {
+		err = &errortypes.VerificationError{
+			errors.New("backup: Manifest was created on a different node"),
+		}
+		return
+	}
+
+	r.key, err = getManifestKey(db, manifest)
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"node_id":     nde.Id.Hex(),
+		"destination": r.Destination,
+		"manifest":    manifest.Timestamp,
+		"encrypted":   manifest.Encrypted,
+	}).Info("backup: Restoring backup")
+
+	err = r.restoreBacking(manifest)
+	if err != nil {
+		return
+	}
+
+	filter := len(r.Disks) > 0
+	diskIds := set.NewSet()
+	for _, dskId := range r.Disks {
+		diskIds.Add(dskId)
+	}
+
+	for _, md := range manifest.Disks {
+		if filter && !diskIds.Contains(md.Id) {
+			continue
+		}
+		diskIds.Remove(md.Id)
+
+		e := r.restoreDisk(db, md)
+		if e != nil {
+			r.errorCount += 1
+			logrus.WithFields(logrus.Fields{
+				"node_id": nde.Id.Hex(),
+				"disk_id": md.Id.Hex(),
+				"error":   e,
+			}).Error("backup: Failed to restore disk")
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"node_id": nde.Id.Hex(),
+			"disk_id": md.Id.Hex(),
+		}).Info("backup: Disk restored")
+	}
+
+	for dskIdInf := range diskIds.Iter() {
+		r.errorCount += 1
+		logrus.WithFields(logrus.Fields{
+			"node_id": nde.Id.Hex(),
+			"disk_id": dskIdInf.(primitive.ObjectID).Hex(),
+		}).Error("backup: Disk not found in manifest")
+	}
+
+	if r.errorCount > 0 {
+		err = &errortypes.ExecError{
+			errors.New("backup: Restore encountered errors"),
+		}
+		return
+	}
+
+	return
+}
+
+func NewRestore(dest string, disks []primitive.ObjectID) *Restore {
+	return &Restore{
+		Destination: dest,
+		Disks:       disks,
+	}
+}
@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/mongo-go-driver/mongo/options"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+const (
+	Passed = "passed"
+	Failed = "failed"
+)
+
+type DiskResult struct {
+	Id       primitive.ObjectID `bson:"id" json:"id"`
+	Name     string             `bson:"name" json:"name"`
+	File     string             `bson:"file" json:"file"`
+	Checksum bool               `bson:"checksum" json:"checksum"`
+	Check    bool               `bson:"check" json:"check"`
+	Boot     bool               `bson:"boot" json:"boot"`
+	Error    string             `bson:"error" json:"error"`
+}
+
+type Result struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Node        primitive.ObjectID `bson:"node" json:"node"`
+	Destination string             `bson:"destination" json:"destination"`
+	Manifest    time.Time          `bson:"manifest" json:"manifest"`
+	Timestamp   time.Time          `bson:"timestamp" json:"timestamp"`
+	State       string             `bson:"state" json:"state"`
+	Error       string             `bson:"error" json:"error"`
+	Disks       []*DiskResult      `bson:"disks" json:"disks"`
+}
+
+func (r *Result) Insert(db *database.Database) (err error) {
+	coll := db.BackupVerify()
+
+	if !r.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("backup: Result already exists"),
+		}
+		return
+	}
+
+	resp, err := coll.InsertOne(db, r)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	r.Id = resp.InsertedID.(primitive.ObjectID)
+
+	return
+}
+
+func GetLatestResult(db *database.Database, ndeId primitive.ObjectID) (
+	result *Result, err error) {
+
+	coll := db.BackupVerify()
+	result = &Result{}
+
+	err = coll.FindOne(
+		db,
+		&bson.M{
+			"node": ndeId,
+		},
+		&options.FindOneOptions{
+			Sort: &bson.D{
+				{"timestamp", -1},
+			},
+		},
+	).Decode(result)
+	if err != nil {
+		err = database.ParseError(err)
+		if _, ok := err.(*database.NotFoundError); ok {
+			result = nil
+			err = nil
+		}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,27 @@
+package backup
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/config"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/node"
+)
+
+func getNode(db *database.Database) (nde *node.Node, err error) {
+	ndeId, err := primitive.ObjectIDFromHex(config.Config.NodeId)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "backup: Failed to parse ObjectId"),
+		}
+		return
+	}
+
+	nde, err = node.Get(db, ndeId)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,313 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/features"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/paths"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const bootTimeout = 90 * time.Second
+
+type Verify struct {
+	Destination string
+	BootTest    bool
+	node        *node.Node
+	key         *key
+	result      *Result
+}
+
+func (v *Verify) bootTest(md *ManifestDisk, pth string) (err error) {
+	cacheDir := v.node.GetCachePath()
+	testId := primitive.NewObjectID().Hex()
+	overlayPath := path.Join(cacheDir,
+		fmt.Sprintf("verify-%s.qcow2", testId))
+	logPath := path.Join(cacheDir, fmt.Sprintf("verify-%s.log", testId))
+	varsPath := path.Join(cacheDir, fmt.Sprintf("verify-%s.fd", testId))
+	namespace := fmt.Sprintf("verify-%s", testId[16:])
+
+	err = utils.ExistsMkdir(cacheDir, 0755)
+	if err != nil {
+		return
+	}
+
+	defer utils.Remove(overlayPath)
+	defer utils.Remove(logPath)
+	defer utils.Remove(varsPath)
+
+	err = utils.Exec("", "qemu-img", "create",
+		"-f", "qcow2",
+		"-F", "qcow2",
+		"-b", pth,
+		overlayPath,
+	)
+	if err != nil {
+		return
+	}
+
+	qemuPath, err := features.GetQemuPath()
+	if err != nil {
+		return
+	}
+
+	args := []string{
+		"netns", "exec", namespace,
+		qemuPath,
+		"-machine", "accel=kvm:tcg",
+		"-m", "1024",
+		"-display", "none",
+		"-nic", "none",
+		"-no-reboot",
+		"-serial", "file:" + logPath,
+		"-drive", fmt.Sprintf("file=%s,if=virtio,format=qcow2", overlayPath),
+	}
+
+	if md.Uefi {
+		codePath, e := paths.FindOvmfCodePath(false)
+		if e != nil {
+			err = e
+			return
+		}
+
+		varsSource, e := paths.FindOvmfVarsPath(false)
+		if e != nil {
+			err = e
+			return
+		}
+
+		err = utils.Exec("", "cp", varsSource, varsPath)
+		if err != nil {
+			return
+		}
+
+		args = append(args,
+			"-drive", fmt.Sprintf(
+				"if=pflash,format=raw,readonly=on,file=%s", codePath),
+			"-drive", fmt.Sprintf(
+				"if=pflash,format=raw,file=%s", varsPath),
+		)
+	}
+
+	err = utils.Exec("", "ip", "netns", "add", namespace)
+	if err != nil {
+		return
+	}
+	defer utils.ExecCombinedOutput("", "ip", "netns", "del", namespace)
+
+	cmd := exec.Command("ip", args...)
+	err = cmd.Start()
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "backup: Failed to start boot test"),
+		}
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	start := time.Now()
+	for {
+		select {
+		case e := <-done:
+			err = &errortypes.ExecError{
+				errors.Newf("backup: Boot test exited early %v", e),
+			}
+			return
+		case <-time.After(2 * time.Second):
+		}
+
+		output, _ := ioutil.ReadFile(logPath)
+		if strings.Contains(string(output), "login:") {
+			break
+		}
+
+		if strings.Contains(string(output), "Kernel panic") {
+			err = &errortypes.ExecError{
+				errors.New("backup: Boot test kernel panic"),
+			}
+			break
+		}
+
+		if time.Since(start) > bootTimeout {
+			if len(output) == 0 {
+				err = &errortypes.ExecError{
+					errors.New("backup: Boot test produced no output"),
+				}
+			} else {
+				err = &errortypes.ExecError{
+					errors.New("backup: Boot test timed out before login"),
+				}
+			}
+			break
+		}
+	}
+
+	_ = cmd.Process.Kill()
+	<-done
+
+	return
+}
+
+func (v *Verify) verifyDisk(md *ManifestDisk) (dskResult *DiskResult) {
+	dskResult = &DiskResult{
+		Id:   md.Id,
+		Name: md.Name,
+		File: md.File,
+	}
+	pth := path.Join(v.Destination, md.File)
+
+	size, checksum, err := fileChecksum(pth)
+	if err != nil {
+		dskResult.Error = err.Error()
+		return
+	}
+
+	if size != md.Size {
+		dskResult.Error = fmt.Sprintf(
+			"Size mismatch %d != %d", size, md.Size)
+		return
+	}
+
+	if checksum != md.Checksum {
+		dskResult.Error = "Checksum mismatch"
+		return
+	}
+	dskResult.Checksum = true
+
+	if v.key != nil {
+		tmpPath := path.Join(v.node.GetCachePath(),
+			fmt.Sprintf("verify-%s.qcow2", md.Id.Hex()))
+		defer utils.Remove(tmpPath)
+
+		err = utils.ExistsMkdir(v.node.GetCachePath(), 0755)
+		if err != nil {
+			dskResult.Error = err.Error()
+			return
+		}
+
+		err = v.key.DecryptFile(pth, tmpPath)
+		if err != nil {
+			dskResult.Error = err.Error()
+			return
+		}
+		pth = tmpPath
+	}
+
+	output, err := utils.ExecCombinedOutput("",
+		"qemu-img", "check", "-f", "qcow2", pth)
+	if err != nil {
+		dskResult.Error = fmt.Sprintf(
+			"Image check failed: %s", strings.TrimSpace(output))
+		return
+	}
+	dskResult.Check = true
+
+	if v.BootTest && md.Index == "0" {
+		err = v.bootTest(md, pth)
+		if err != nil {
+			dskResult.Error = err.Error()
+			return
+		}
+		dskResult.Boot = true
+	}
+
+	return
+}
+
+func (v *Verify) Run() (err error) {
+	db := database.GetDatabase()
+	defer db.Close()
+
+	nde := node.Self
+	if nde == nil {
+		nde, err = getNode(db)
+		if err != nil {
+			return
+		}
+	}
+	v.node = nde
+
+	v.result = &Result{
+		Node:        nde.Id,
+		Destination: v.Destination,
+		Timestamp:   time.Now(),
+		State:       Passed,
+		Disks:       []*DiskResult{},
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"node_id":     nde.Id.Hex(),
+		"destination": v.Destination,
+		"boot_test":   v.BootTest,
+	}).Info("backup: Verifying backup")
+
+	manifest, err := LoadManifest(v.Destination)
+	if err == nil {
+		v.key, err = getManifestKey(db, manifest)
+	}
+	if err != nil {
+		v.result.State = Failed
+		v.result.Error = err.Error()
+	} else {
+		v.result.Manifest = manifest.Timestamp
+
+		for _, md := range manifest.Disks {
+			dskResult := v.verifyDisk(md)
+			v.result.Disks = append(v.result.Disks, dskResult)
+
+			if dskResult.Error != "" {
+				v.result.State = Failed
+
+				logrus.WithFields(logrus.Fields{
+					"node_id": nde.Id.Hex(),
+					"disk_id": md.Id.Hex(),
+					"error":   dskResult.Error,
+				}).Error("backup: Disk backup verification failed")
+			} else {
+				logrus.WithFields(logrus.Fields{
+					"node_id": nde.Id.Hex(),
+					"disk_id": md.Id.Hex(),
+				}).Info("backup: Disk backup verified")
+			}
+		}
+	}
+
+	err = v.result.Insert(db)
+	if err != nil {
+		return
+	}
+
+	event.PublishDispatch(db, "backup_verify.change")
+
+	if v.result.State != Passed {
+		err = &errortypes.VerificationError{
+			errors.New("backup: Backup verification failed"),
+		}
+		return
+	}
+
+	return
+}
+
+func NewVerify(dest string, bootTest bool) *Verify {
+	return &Verify{
+		Destination: dest,
+		BootTest:    bootTest,
+	}
+}
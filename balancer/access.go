@@ -0,0 +1,92 @@
+package balancer
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/mongo-go-driver/mongo/options"
+	"github.com/pritunl/pritunl-cloud/database"
+)
+
+type AccessLog struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Balancer  primitive.ObjectID `bson:"b" json:"balancer"`
+	Node      primitive.ObjectID `bson:"n" json:"node"`
+	Timestamp time.Time          `bson:"t" json:"timestamp"`
+	Client    string             `bson:"c" json:"client"`
+	Method    string             `bson:"m" json:"method"`
+	Host      string             `bson:"h" json:"host"`
+	Path      string             `bson:"p" json:"path"`
+	Status    int                `bson:"s" json:"status"`
+	Bytes     int64              `bson:"y" json:"bytes"`
+	Backend   string             `bson:"k" json:"backend"`
+	Latency   float64            `bson:"l" json:"latency"`
+}
+
+func InsertAccessLogs(db *database.Database, logs []*AccessLog) (
+	err error) {
+
+	if len(logs) == 0 {
+		return
+	}
+
+	coll := db.BalancerLogs()
+
+	docs := []interface{}{}
+	for _, lg := range logs {
+		docs = append(docs, lg)
+	}
+
+	_, err = coll.InsertMany(db, docs)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAccessLogs(db *database.Database, balncId primitive.ObjectID,
+	limit int64) (logs []*AccessLog, err error) {
+
+	coll := db.BalancerLogs()
+	logs = []*AccessLog{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"b": balncId,
+		},
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"t", -1},
+			},
+			Limit: &limit,
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		lg := &AccessLog{}
+		err = cursor.Decode(lg)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		logs = append(logs, lg)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
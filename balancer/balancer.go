@@ -1,6 +1,11 @@
 package balancer
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
@@ -10,46 +15,210 @@ import (
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/utils"
 )
 
+type Rule struct {
+	Path   string `bson:"path" json:"path"`
+	Method string `bson:"method" json:"method"`
+	Header string `bson:"header" json:"header"`
+	Value  string `bson:"value" json:"value"`
+	Group  string `bson:"group" json:"group"`
+}
+
 type Domain struct {
-	Domain string `bson:"domain" json:"domain"`
-	Host   string `bson:"host" json:"host"`
+	Domain string  `bson:"domain" json:"domain"`
+	Host   string  `bson:"host" json:"host"`
+	Rules  []*Rule `bson:"rules" json:"rules"`
 }
 
 type Backend struct {
-	Protocol string `bson:"protocol" json:"protocol"`
-	Hostname string `bson:"hostname" json:"hostname"`
-	Port     int    `bson:"port" json:"port"`
+	Protocol   string    `bson:"protocol" json:"protocol"`
+	Hostname   string    `bson:"hostname" json:"hostname"`
+	Port       int       `bson:"port" json:"port"`
+	Group      string    `bson:"group" json:"group"`
+	Weight     int       `bson:"weight" json:"weight"`
+	Drain      bool      `bson:"drain" json:"drain"`
+	DrainStart time.Time `bson:"drain_start" json:"drain_start"`
+}
+
+func (b *Backend) Key() string {
+	return fmt.Sprintf("%s:%d", b.Hostname, b.Port)
+}
+
+type BackendState struct {
+	Key              string           `bson:"key" json:"key"`
+	Connections      int              `bson:"connections" json:"connections"`
+	ConnectionsTotal int64            `bson:"connections_total" json:"connections_total"`
+	Errors           int64            `bson:"errors" json:"errors"`
+	Requests         int64            `bson:"requests" json:"requests"`
+	Latency          []int64          `bson:"latency" json:"latency"`
+	Statuses         map[string]int64 `bson:"statuses" json:"statuses"`
 }
 
 type State struct {
-	Timestamp   time.Time `bson:"timestamp" json:"timestamp"`
-	Requests    int       `bson:"requests" json:"requests"`
-	Retries     int       `bson:"retries" json:"retries"`
-	WebSockets  int       `bson:"websockets" json:"websockets"`
-	Online      []string  `bson:"online" json:"online"`
-	UnknownHigh []string  `bson:"unknown_high" json:"unknown_high"`
-	UnknownMid  []string  `bson:"unknown_mid" json:"unknown_mid"`
-	UnknownLow  []string  `bson:"unknown_low" json:"unknown_low"`
-	Offline     []string  `bson:"offline" json:"offline"`
+	Timestamp   time.Time       `bson:"timestamp" json:"timestamp"`
+	Requests    int             `bson:"requests" json:"requests"`
+	Retries     int             `bson:"retries" json:"retries"`
+	WebSockets  int             `bson:"websockets" json:"websockets"`
+	Online      []string        `bson:"online" json:"online"`
+	UnknownHigh []string        `bson:"unknown_high" json:"unknown_high"`
+	UnknownMid  []string        `bson:"unknown_mid" json:"unknown_mid"`
+	UnknownLow  []string        `bson:"unknown_low" json:"unknown_low"`
+	Offline     []string        `bson:"offline" json:"offline"`
+	Draining    []string        `bson:"draining" json:"draining"`
+	Backends    []*BackendState `bson:"backends" json:"backends"`
+	Rejected    int             `bson:"rejected" json:"rejected"`
 }
 
 type Balancer struct {
-	Id              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Name            string               `bson:"name" json:"name"`
-	Comment         string               `bson:"comment" json:"comment"`
-	Type            string               `bson:"type" json:"type"`
-	State           bool                 `bson:"state" json:"state"`
-	Organization    primitive.ObjectID   `bson:"organization,omitempty" json:"organization"`
-	Datacenter      primitive.ObjectID   `bson:"datacenter,omitempty" json:"datacenter"`
-	Certificates    []primitive.ObjectID `bson:"certificates" json:"certificates"`
-	ClientAuthority primitive.ObjectID   `bson:"client_authority" json:"client_authority"`
-	WebSockets      bool                 `bson:"websockets" json:"websockets"`
-	Domains         []*Domain            `bson:"domains" json:"domains"`
-	Backends        []*Backend           `bson:"backends" json:"backends"`
-	States          map[string]*State    `bson:"states" json:"states"`
-	CheckPath       string               `bson:"check_path" json:"check_path"`
+	Id                 primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name               string               `bson:"name" json:"name"`
+	Comment            string               `bson:"comment" json:"comment"`
+	Type               string               `bson:"type" json:"type"`
+	State              bool                 `bson:"state" json:"state"`
+	Organization       primitive.ObjectID   `bson:"organization,omitempty" json:"organization"`
+	Datacenter         primitive.ObjectID   `bson:"datacenter,omitempty" json:"datacenter"`
+	Certificates       []primitive.ObjectID `bson:"certificates" json:"certificates"`
+	ClientAuthority    primitive.ObjectID   `bson:"client_authority" json:"client_authority"`
+	WebSockets         bool                 `bson:"websockets" json:"websockets"`
+	Domains            []*Domain            `bson:"domains" json:"domains"`
+	Backends           []*Backend           `bson:"backends" json:"backends"`
+	States             map[string]*State    `bson:"states" json:"states"`
+	CheckPath          string               `bson:"check_path" json:"check_path"`
+	CheckInterval      int                  `bson:"check_interval" json:"check_interval"`
+	CheckTimeout       int                  `bson:"check_timeout" json:"check_timeout"`
+	CheckStatusCodes   []int                `bson:"check_status_codes" json:"check_status_codes"`
+	CheckBody          string               `bson:"check_body" json:"check_body"`
+	HealthyThreshold   int                  `bson:"healthy_threshold" json:"healthy_threshold"`
+	UnhealthyThreshold int                  `bson:"unhealthy_threshold" json:"unhealthy_threshold"`
+	OutlierErrors      int                  `bson:"outlier_errors" json:"outlier_errors"`
+	OutlierTime        int                  `bson:"outlier_time" json:"outlier_time"`
+	Affinity           string               `bson:"affinity" json:"affinity"`
+	DrainTimeout       int                  `bson:"drain_timeout" json:"drain_timeout"`
+	AllowSources       []string             `bson:"allow_sources" json:"allow_sources"`
+	DenySources        []string             `bson:"deny_sources" json:"deny_sources"`
+	RateLimit          int                  `bson:"rate_limit" json:"rate_limit"`
+	RateBurst          int                  `bson:"rate_burst" json:"rate_burst"`
+	MaxBodyBytes       int64                `bson:"max_body_bytes" json:"max_body_bytes"`
+	MaxHeaderBytes     int                  `bson:"max_header_bytes" json:"max_header_bytes"`
+	AccessLog          string               `bson:"access_log" json:"access_log"`
+	CheckPort          int                  `bson:"check_port" json:"check_port"`
+	ListenPorts        []int                `bson:"listen_ports" json:"listen_ports"`
+	ProxyProtocol      string               `bson:"proxy_protocol" json:"proxy_protocol"`
+}
+
+func (b *Balancer) IsStream() bool {
+	return b.Type == Tcp || b.Type == Udp
+}
+
+// UpdateDrain sets the drain start of backends that have started draining
+// and keeps the drain start of backends that were already draining.
+func (b *Balancer) UpdateDrain(prevBackends []*Backend) {
+	drainStarts := map[string]time.Time{}
+	for _, backend := range prevBackends {
+		if backend.Drain && !backend.DrainStart.IsZero() {
+			drainStarts[backend.Key()] = backend.DrainStart
+		}
+	}
+
+	for _, backend := range b.Backends {
+		if !backend.Drain {
+			backend.DrainStart = time.Time{}
+			continue
+		}
+
+		drainStart, ok := drainStarts[backend.Key()]
+		if ok {
+			backend.DrainStart = drainStart
+		} else {
+			backend.DrainStart = time.Now()
+		}
+	}
+}
+
+// reservedPorts returns the ports used by the node web servers, these
+// cannot be used as stream balancer listen ports
+func reservedPorts(db *database.Database) (ports set.Set, err error) {
+	ports = set.NewSet()
+	for _, port := range ReservedPorts {
+		ports.Add(port)
+	}
+
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	for _, nde := range nodes {
+		if nde.Port != 0 {
+			ports.Add(nde.Port)
+		}
+	}
+
+	return
+}
+
+// NodeAddresses returns the addresses of all nodes, stream balancer
+// backends cannot target services on the nodes
+func NodeAddresses(db *database.Database) (addrs set.Set, err error) {
+	addrs = set.NewSet()
+
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	for _, nde := range nodes {
+		for _, addr := range nde.PublicIps {
+			addrs.Add(addr)
+		}
+		for _, addr := range nde.PublicIps6 {
+			addrs.Add(addr)
+		}
+		for _, addr := range nde.PrivateIps {
+			addrs.Add(addr)
+		}
+	}
+
+	return
+}
+
+// BackendIpAllowed returns false for loopback, link local and node
+// addresses that stream balancer backends cannot target
+func BackendIpAllowed(ip net.IP, nodeAddrs set.Set) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+
+		return false
+	}
+
+	if nodeAddrs != nil && nodeAddrs.Contains(ip.String()) {
+		return false
+	}
+
+	return true
+}
+
+func backendHostAllowed(host string, nodeAddrs set.Set) bool {
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return BackendIpAllowed(ip, nodeAddrs)
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+
+	for _, ip := range ips {
+		if !BackendIpAllowed(ip, nodeAddrs) {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (b *Balancer) Validate(db *database.Database) (
@@ -75,7 +244,310 @@ func (b *Balancer) Validate(db *database.Database) (
 		b.States = map[string]*State{}
 	}
 
+	if b.ListenPorts == nil {
+		b.ListenPorts = []int{}
+	}
+
+	if b.AllowSources == nil {
+		b.AllowSources = []string{}
+	}
+
+	if b.DenySources == nil {
+		b.DenySources = []string{}
+	}
+
+	errData = b.validateHealth()
+	if errData != nil {
+		return
+	}
+
+	if b.DrainTimeout == 0 {
+		b.DrainTimeout = DefaultDrainTimeout
+	}
+
+	if b.DrainTimeout < 1 || b.DrainTimeout > 86400 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_drain_timeout_invalid",
+			Message: "Drain timeout must be between 1 and 86400",
+		}
+		return
+	}
+
+	switch b.Affinity {
+	case "":
+		break
+	case AffinityCookie:
+		if b.IsStream() {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_affinity_unsupported",
+				Message: "Cookie affinity only supported on HTTP balancers",
+			}
+			return
+		}
+		break
+	case AffinitySourceIp:
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_affinity_invalid",
+			Message: "Invalid balancer affinity",
+		}
+		return
+	}
+
 	for _, backend := range b.Backends {
+		if backend.Weight == 0 {
+			backend.Weight = 1
+		}
+
+		if backend.Weight < 1 || backend.Weight > 1000 {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_weight_invalid",
+				Message: "Balancer backend weight must be between 1 and 1000",
+			}
+			return
+		}
+	}
+
+	switch b.Type {
+	case Http:
+		errData, err = b.validateHttp(db)
+		break
+	case Tcp, Udp:
+		errData, err = b.validateStream(db)
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_type_invalid",
+			Message: "Invalid balancer type",
+		}
+		break
+	}
+
+	return
+}
+
+func (b *Balancer) validateHealth() (errData *errortypes.ErrorData) {
+	if b.CheckInterval == 0 {
+		b.CheckInterval = DefaultCheckInterval
+	}
+	if b.CheckTimeout == 0 {
+		b.CheckTimeout = DefaultCheckTimeout
+	}
+	if b.HealthyThreshold == 0 {
+		b.HealthyThreshold = DefaultHealthyThreshold
+	}
+	if b.UnhealthyThreshold == 0 {
+		b.UnhealthyThreshold = DefaultUnhealthyThreshold
+	}
+	if b.OutlierTime == 0 {
+		b.OutlierTime = DefaultOutlierTime
+	}
+	if b.CheckStatusCodes == nil {
+		b.CheckStatusCodes = []int{}
+	}
+
+	if b.CheckInterval < 1 || b.CheckInterval > 3600 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_check_interval_invalid",
+			Message: "Health check interval must be between 1 and 3600",
+		}
+		return
+	}
+
+	if b.CheckTimeout < 1 || b.CheckTimeout > 300 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_check_timeout_invalid",
+			Message: "Health check timeout must be between 1 and 300",
+		}
+		return
+	}
+
+	if b.HealthyThreshold < 1 || b.HealthyThreshold > 100 ||
+		b.UnhealthyThreshold < 1 || b.UnhealthyThreshold > 100 {
+
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_check_threshold_invalid",
+			Message: "Health check thresholds must be between 1 and 100",
+		}
+		return
+	}
+
+	if b.OutlierErrors < 0 || b.OutlierErrors > 1000 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_outlier_errors_invalid",
+			Message: "Outlier error count must be between 0 and 1000",
+		}
+		return
+	}
+
+	if b.OutlierTime < 1 || b.OutlierTime > 86400 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_outlier_time_invalid",
+			Message: "Outlier ejection time must be between 1 and 86400",
+		}
+		return
+	}
+
+	if b.IsStream() {
+		b.CheckStatusCodes = []int{}
+		b.CheckBody = ""
+		return
+	}
+
+	for _, code := range b.CheckStatusCodes {
+		if code < 100 || code > 599 {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_check_status_invalid",
+				Message: "Invalid health check status code",
+			}
+			return
+		}
+	}
+
+	if b.CheckBody != "" {
+		_, e := regexp.Compile(b.CheckBody)
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_check_body_invalid",
+				Message: "Invalid health check body regular expression",
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func validateSources(sources []string) (valid []string, ok bool) {
+	valid = []string{}
+	sourcesSet := set.NewSet()
+
+	for _, source := range sources {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			return
+		}
+
+		if !strings.Contains(source, "/") {
+			if strings.Contains(source, ":") {
+				source += "/128"
+			} else {
+				source += "/32"
+			}
+		}
+
+		_, sourceCidr, e := net.ParseCIDR(source)
+		if e != nil {
+			return
+		}
+
+		source = sourceCidr.String()
+		if sourcesSet.Contains(source) {
+			continue
+		}
+		sourcesSet.Add(source)
+
+		valid = append(valid, source)
+	}
+
+	ok = true
+	return
+}
+
+func (b *Balancer) validateLimits() (errData *errortypes.ErrorData) {
+	allowSources, ok := validateSources(b.AllowSources)
+	if !ok {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_allow_source_invalid",
+			Message: "Invalid balancer allowed source",
+		}
+		return
+	}
+	b.AllowSources = allowSources
+
+	denySources, ok := validateSources(b.DenySources)
+	if !ok {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_deny_source_invalid",
+			Message: "Invalid balancer denied source",
+		}
+		return
+	}
+	b.DenySources = denySources
+
+	if b.RateLimit < 0 || b.RateLimit > 100000 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_rate_limit_invalid",
+			Message: "Rate limit must be between 0 and 100000",
+		}
+		return
+	}
+
+	if b.RateLimit == 0 {
+		b.RateBurst = 0
+	} else if b.RateBurst < b.RateLimit {
+		b.RateBurst = b.RateLimit
+	}
+
+	if b.RateBurst > 1000000 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_rate_burst_invalid",
+			Message: "Rate burst must be less than 1000000",
+		}
+		return
+	}
+
+	if b.MaxBodyBytes < 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_max_body_bytes_invalid",
+			Message: "Invalid balancer max body size",
+		}
+		return
+	}
+
+	if b.MaxHeaderBytes != 0 && (b.MaxHeaderBytes < 1024 ||
+		b.MaxHeaderBytes > 4194304) {
+
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_max_header_bytes_invalid",
+			Message: "Max header size must be between 1024 and 4194304",
+		}
+		return
+	}
+
+	return
+}
+
+func (b *Balancer) validateHttp(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	b.ListenPorts = []int{}
+	b.ProxyProtocol = ""
+	b.CheckPort = 0
+
+	errData = b.validateLimits()
+	if errData != nil {
+		return
+	}
+
+	switch b.AccessLog {
+	case "", AccessLogDatabase, AccessLogLogger:
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_access_log_invalid",
+			Message: "Invalid balancer access log",
+		}
+		return
+	}
+
+	groups := set.NewSet()
+	for _, backend := range b.Backends {
+		backend.Group = utils.FilterStr(
+			strings.ToLower(strings.TrimSpace(backend.Group)), 64)
+		groups.Add(backend.Group)
+
 		if backend.Protocol != "http" && backend.Protocol != "https" {
 			errData = &errortypes.ErrorData{
 				Error:   "balancer_protocol_invalid",
@@ -101,6 +573,49 @@ func (b *Balancer) Validate(db *database.Database) (
 		}
 	}
 
+	for _, domain := range b.Domains {
+		if domain.Rules == nil {
+			domain.Rules = []*Rule{}
+		}
+
+		for _, rule := range domain.Rules {
+			rule.Path = strings.TrimSpace(rule.Path)
+			rule.Method = strings.ToUpper(strings.TrimSpace(rule.Method))
+			rule.Header = http.CanonicalHeaderKey(
+				strings.TrimSpace(rule.Header))
+			rule.Group = utils.FilterStr(
+				strings.ToLower(strings.TrimSpace(rule.Group)), 64)
+
+			if rule.Header == "" {
+				rule.Value = ""
+			}
+
+			if rule.Path == "" && rule.Method == "" && rule.Header == "" {
+				errData = &errortypes.ErrorData{
+					Error:   "balancer_rule_empty",
+					Message: "Balancer rule must match path, method or header",
+				}
+				return
+			}
+
+			if rule.Path != "" && !strings.HasPrefix(rule.Path, "/") {
+				errData = &errortypes.ErrorData{
+					Error:   "balancer_rule_path_invalid",
+					Message: "Balancer rule path must start with /",
+				}
+				return
+			}
+
+			if rule.Group == "" || !groups.Contains(rule.Group) {
+				errData = &errortypes.ErrorData{
+					Error:   "balancer_rule_group_invalid",
+					Message: "Balancer rule group does not match a backend",
+				}
+				return
+			}
+		}
+	}
+
 	if b.State {
 		if b.Organization.IsZero() {
 			errData = &errortypes.ErrorData{
@@ -175,6 +690,186 @@ func (b *Balancer) Validate(db *database.Database) (
 	return
 }
 
+func (b *Balancer) validateStream(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	b.Domains = []*Domain{}
+	b.Certificates = []primitive.ObjectID{}
+	b.WebSockets = false
+	b.CheckPath = ""
+	b.AllowSources = []string{}
+	b.DenySources = []string{}
+	b.RateLimit = 0
+	b.RateBurst = 0
+	b.MaxBodyBytes = 0
+	b.MaxHeaderBytes = 0
+	b.AccessLog = ""
+
+	switch b.ProxyProtocol {
+	case "":
+		break
+	case ProxyProtocolV1, ProxyProtocolV2:
+		if b.Type != Tcp {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_proxy_protocol_unsupported",
+				Message: "Proxy protocol only supported on TCP balancers",
+			}
+			return
+		}
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_proxy_protocol_invalid",
+			Message: "Invalid balancer proxy protocol",
+		}
+		return
+	}
+
+	if b.CheckPort < 0 || b.CheckPort > 65535 {
+		errData = &errortypes.ErrorData{
+			Error:   "balancer_check_port_invalid",
+			Message: "Invalid balancer health check port",
+		}
+		return
+	}
+
+	reserved := set.NewSet()
+	if len(b.ListenPorts) > 0 {
+		reserved, err = reservedPorts(db)
+		if err != nil {
+			return
+		}
+	}
+
+	listenPorts := set.NewSet()
+	for _, port := range b.ListenPorts {
+		if port < 1 || port > 65535 {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_listen_port_invalid",
+				Message: "Invalid balancer listen port",
+			}
+			return
+		}
+
+		if reserved.Contains(port) {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_listen_port_reserved",
+				Message: "Balancer listen port is reserved",
+			}
+			return
+		}
+
+		if listenPorts.Contains(port) {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_listen_port_duplicate",
+				Message: "Duplicate balancer listen port",
+			}
+			return
+		}
+		listenPorts.Add(port)
+	}
+
+	if len(b.ListenPorts) > 0 && !b.Datacenter.IsZero() {
+		coll := db.Balancers()
+		count, e := coll.CountDocuments(db, &bson.M{
+			"_id": &bson.M{
+				"$ne": b.Id,
+			},
+			"datacenter": b.Datacenter,
+			"type":       b.Type,
+			"listen_ports": &bson.M{
+				"$in": b.ListenPorts,
+			},
+		})
+		if e != nil {
+			err = database.ParseError(e)
+			return
+		}
+
+		if count > 0 {
+			errData = &errortypes.ErrorData{
+				Error: "listen_port_conflict",
+				Message: "Listen port conflicts with another " +
+					"load balancer in same datacenter",
+			}
+			return
+		}
+	}
+
+	nodeAddrs := set.NewSet()
+	if len(b.Backends) > 0 {
+		nodeAddrs, err = NodeAddresses(db)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, backend := range b.Backends {
+		backend.Protocol = b.Type
+		backend.Group = ""
+
+		if backend.Hostname == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_hostname_invalid",
+				Message: "Invalid balancer backend hostname",
+			}
+			return
+		}
+
+		if !backendHostAllowed(backend.Hostname, nodeAddrs) {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_hostname_invalid",
+				Message: "Balancer backend hostname not allowed",
+			}
+			return
+		}
+
+		if backend.Port < 0 || backend.Port > 65535 {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_port_invalid",
+				Message: "Invalid balancer backend port",
+			}
+			return
+		}
+	}
+
+	if b.State {
+		if b.Organization.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "organization_required",
+				Message: "Missing required organization",
+			}
+			return
+		}
+
+		if b.Datacenter.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "datacenter_required",
+				Message: "Missing required datacenter",
+			}
+			return
+		}
+
+		if len(b.ListenPorts) == 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "listen_port_required",
+				Message: "Missing required listen port",
+			}
+			return
+		}
+
+		if len(b.Backends) == 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "backend_required",
+				Message: "Missing required backend",
+			}
+			return
+		}
+	}
+
+	return
+}
+
 func (b *Balancer) Json() {
 	if b.States == nil || len(b.States) == 0 {
 		return
@@ -247,7 +942,8 @@ func (b *Balancer) CommitFields(db *database.Database, fields set.Set) (
 
 	coll := db.Balancers()
 
-	if b.State && (fields.Contains("state") || fields.Contains("domains")) {
+	if b.State && b.Type == Http &&
+		(fields.Contains("state") || fields.Contains("domains")) {
 		domains := []string{}
 		for _, domain := range b.Domains {
 			domains = append(domains, domain.Domain)
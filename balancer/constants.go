@@ -2,4 +2,29 @@ package balancer
 
 const (
 	Http = "http"
+	Tcp  = "tcp"
+	Udp  = "udp"
+
+	AffinityCookie   = "cookie"
+	AffinitySourceIp = "source_ip"
+
+	ProxyProtocolV1 = "v1"
+	ProxyProtocolV2 = "v2"
+
+	AccessLogDatabase = "database"
+	AccessLogLogger   = "logger"
+
+	DefaultCheckInterval      = 5
+	DefaultCheckTimeout       = 5
+	DefaultHealthyThreshold   = 1
+	DefaultUnhealthyThreshold = 1
+	DefaultOutlierTime        = 30
+	DefaultDrainTimeout       = 300
 )
+
+// ReservedPorts cannot be used as stream balancer listen ports
+var ReservedPorts = []int{22, 80, 443}
+
+// LatencyBuckets are the upper bounds in milliseconds of the backend
+// latency histogram, the last histogram count holds slower requests.
+var LatencyBuckets = []int{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
@@ -3,9 +3,11 @@ package certificate
 import (
 	"crypto/md5"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,20 +31,43 @@ type Info struct {
 }
 
 type Certificate struct {
-	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name         string             `bson:"name" json:"name"`
-	Comment      string             `bson:"comment" json:"comment"`
-	Organization primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
-	Type         string             `bson:"type" json:"type"`
-	Key          string             `bson:"key" json:"key"`
-	Certificate  string             `bson:"certificate" json:"certificate"`
-	Info         *Info              `bson:"info" json:"info"`
-	AcmeHash     string             `bson:"acme_hash" json:"-"`
-	AcmeAccount  string             `bson:"acme_account" json:"-"`
-	AcmeDomains  []string           `bson:"acme_domains" json:"acme_domains"`
-	AcmeType     string             `bson:"acme_type" json:"acme_type"`
-	AcmeAuth     string             `bson:"acme_auth" json:"acme_auth"`
-	AcmeSecret   primitive.ObjectID `bson:"acme_secret,omitempty" json:"acme_secret"`
+	Id                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name               string             `bson:"name" json:"name"`
+	Comment            string             `bson:"comment" json:"comment"`
+	Organization       primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
+	Type               string             `bson:"type" json:"type"`
+	Key                string             `bson:"key" json:"key"`
+	Certificate        string             `bson:"certificate" json:"certificate"`
+	Info               *Info              `bson:"info" json:"info"`
+	AcmeHash           string             `bson:"acme_hash" json:"-"`
+	AcmeAccount        string             `bson:"acme_account" json:"-"`
+	AcmeDomains        []string           `bson:"acme_domains" json:"acme_domains"`
+	AcmeType           string             `bson:"acme_type" json:"acme_type"`
+	AcmeAuth           string             `bson:"acme_auth" json:"acme_auth"`
+	AcmeSecret         primitive.ObjectID `bson:"acme_secret,omitempty" json:"acme_secret"`
+	AcmeDirectory      string             `bson:"acme_directory" json:"acme_directory"`
+	AcmeRootCa         string             `bson:"acme_root_ca" json:"acme_root_ca"`
+	AcmeEabKeyId       string             `bson:"acme_eab_key_id" json:"acme_eab_key_id"`
+	AcmeEabHmac        string             `bson:"acme_eab_hmac" json:"-"`
+	AcmePreferredChain string             `bson:"acme_preferred_chain" json:"acme_preferred_chain"`
+	AcmeKeyType        string             `bson:"acme_key_type" json:"acme_key_type"`
+	Lifecycle          *Lifecycle         `bson:"lifecycle" json:"lifecycle"`
+	History            []*Version         `bson:"history" json:"history"`
+}
+
+// GetAcmeEabHmac returns the decoded external account binding key, keys are
+// provided by the certificate authority encoded with base64url
+func (c *Certificate) GetAcmeEabHmac() (key []byte, err error) {
+	key, err = base64.RawURLEncoding.DecodeString(
+		strings.TrimRight(c.AcmeEabHmac, "="))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "certificate: Failed to decode EAB HMAC key"),
+		}
+		return
+	}
+
+	return
 }
 
 func (c *Certificate) Validate(db *database.Database) (
@@ -81,6 +107,8 @@ func (c *Certificate) Validate(db *database.Database) (
 			break
 		case AcmeOracleCloud:
 			break
+		case AcmeRfc2136:
+			break
 		default:
 			errData = &errortypes.ErrorData{
 				Error:   "acme_auth_invalid",
@@ -88,12 +116,88 @@ func (c *Certificate) Validate(db *database.Database) (
 			}
 			return
 		}
+
+		c.AcmeDirectory = strings.TrimSpace(c.AcmeDirectory)
+		if c.AcmeDirectory != "" {
+			directoryUrl, e := url.Parse(c.AcmeDirectory)
+			if e != nil || directoryUrl.Scheme != "https" ||
+				directoryUrl.Host == "" {
+
+				errData = &errortypes.ErrorData{
+					Error:   "acme_directory_invalid",
+					Message: "ACME directory must be a HTTPS URL",
+				}
+				return
+			}
+
+			if !c.Organization.IsZero() &&
+				!utils.PublicHost(directoryUrl.Hostname()) {
+
+				errData = &errortypes.ErrorData{
+					Error:   "acme_directory_invalid",
+					Message: "ACME directory must be a public host",
+				}
+				return
+			}
+		}
+
+		c.AcmeRootCa = strings.TrimSpace(c.AcmeRootCa)
+		if c.AcmeRootCa != "" {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(c.AcmeRootCa)) {
+				errData = &errortypes.ErrorData{
+					Error:   "acme_root_ca_invalid",
+					Message: "ACME directory root certificate invalid",
+				}
+				return
+			}
+		}
+
+		c.AcmeEabKeyId = strings.TrimSpace(c.AcmeEabKeyId)
+		c.AcmeEabHmac = strings.TrimSpace(c.AcmeEabHmac)
+		if (c.AcmeEabKeyId == "") != (c.AcmeEabHmac == "") {
+			errData = &errortypes.ErrorData{
+				Error:   "acme_eab_invalid",
+				Message: "ACME external account key ID and HMAC required",
+			}
+			return
+		}
+
+		if c.AcmeEabHmac != "" {
+			key, e := c.GetAcmeEabHmac()
+			if e != nil || len(key) == 0 {
+				errData = &errortypes.ErrorData{
+					Error:   "acme_eab_hmac_invalid",
+					Message: "ACME external account HMAC key invalid",
+				}
+				return
+			}
+		}
+
+		c.AcmePreferredChain = strings.TrimSpace(c.AcmePreferredChain)
+
+		switch c.AcmeKeyType {
+		case "", AcmeKeyRsa, AcmeKeyEc:
+			break
+		default:
+			errData = &errortypes.ErrorData{
+				Error:   "acme_key_type_invalid",
+				Message: "ACME key type invalid",
+			}
+			return
+		}
 	} else {
 		c.AcmeAccount = ""
 		c.AcmeDomains = []string{}
 		c.AcmeType = ""
 		c.AcmeAuth = ""
 		c.AcmeSecret = primitive.NilObjectID
+		c.AcmeDirectory = ""
+		c.AcmeRootCa = ""
+		c.AcmeEabKeyId = ""
+		c.AcmeEabHmac = ""
+		c.AcmePreferredChain = ""
+		c.AcmeKeyType = ""
 	}
 
 	if c.AcmeDomains == nil {
@@ -244,5 +348,14 @@ func (c *Certificate) Hash() string {
 			io.WriteString(hash, domain)
 		}
 	}
+	if c.AcmeDirectory != "" {
+		io.WriteString(hash, c.AcmeDirectory)
+	}
+	if c.AcmePreferredChain != "" {
+		io.WriteString(hash, c.AcmePreferredChain)
+	}
+	if c.AcmeKeyType != "" {
+		io.WriteString(hash, c.AcmeKeyType)
+	}
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
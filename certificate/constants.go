@@ -10,4 +10,10 @@ const (
 	AcmeAWS         = "acme_aws"
 	AcmeCloudflare  = "acme_cloudflare"
 	AcmeOracleCloud = "acme_oracle_cloud"
+	AcmeRfc2136     = "acme_rfc2136"
+
+	AcmeKeyRsa = "rsa"
+	AcmeKeyEc  = "ec"
+
+	HistoryMax = 5
 )
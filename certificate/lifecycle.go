@@ -0,0 +1,116 @@
+package certificate
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+// Lifecycle records the automatic renewal state of a certificate, failed
+// renewals are retried at the next attempt time
+type Lifecycle struct {
+	LastAttempt time.Time `bson:"last_attempt" json:"last_attempt"`
+	LastRenewed time.Time `bson:"last_renewed" json:"last_renewed"`
+	LastError   string    `bson:"last_error" json:"last_error"`
+	Failures    int       `bson:"failures" json:"failures"`
+	NextAttempt time.Time `bson:"next_attempt" json:"next_attempt"`
+}
+
+// Version is a previous certificate kept for rollback
+type Version struct {
+	Key         string    `bson:"key" json:"-"`
+	Certificate string    `bson:"certificate" json:"certificate"`
+	Info        *Info     `bson:"info" json:"info"`
+	Replaced    time.Time `bson:"replaced" json:"replaced"`
+}
+
+func (c *Certificate) GetLifecycle() *Lifecycle {
+	if c.Lifecycle == nil {
+		c.Lifecycle = &Lifecycle{}
+	}
+	return c.Lifecycle
+}
+
+// Failing returns true if the last renewal attempt failed
+func (c *Certificate) Failing() bool {
+	return c.Lifecycle != nil && c.Lifecycle.Failures > 0
+}
+
+// PushHistory stores the current certificate as a previous version before
+// it is replaced, only the newest versions up to HistoryMax are kept
+func (c *Certificate) PushHistory() {
+	if c.Certificate == "" {
+		return
+	}
+
+	if c.Info == nil || c.Info.Hash != c.Hash() {
+		_ = c.UpdateInfo()
+	}
+
+	versions := []*Version{
+		&Version{
+			Key:         c.Key,
+			Certificate: c.Certificate,
+			Info:        c.Info,
+			Replaced:    time.Now(),
+		},
+	}
+
+	for _, version := range c.History {
+		if len(versions) >= HistoryMax {
+			break
+		}
+		if version.Certificate == c.Certificate {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	c.History = versions
+}
+
+// Rollback replaces the certificate with the previous version matching the
+// info hash, the current certificate is moved to the history
+func (c *Certificate) Rollback(hash string) (
+	errData *errortypes.ErrorData, err error) {
+
+	var target *Version
+	for _, version := range c.History {
+		if version.Info != nil && version.Info.Hash == hash {
+			target = version
+			break
+		}
+	}
+
+	if target == nil {
+		errData = &errortypes.ErrorData{
+			Error:   "certificate_version_invalid",
+			Message: "Certificate version not found",
+		}
+		return
+	}
+
+	c.PushHistory()
+
+	versions := []*Version{}
+	for _, version := range c.History {
+		if version != target {
+			versions = append(versions, version)
+		}
+	}
+	c.History = versions
+
+	c.Key = target.Key
+	c.Certificate = target.Certificate
+
+	err = c.UpdateInfo()
+	if err != nil {
+		return
+	}
+
+	if c.Type == LetsEncrypt {
+		c.AcmeHash = c.Hash()
+	}
+
+	return
+}
@@ -162,8 +162,8 @@ type cloudConfigData struct {
 }
 
 func getUserData(db *database.Database, inst *instance.Instance,
-	virt *vm.VirtualMachine, initial bool, addr6, gateway6 net.IP) (
-	usrData string, err error) {
+	virt *vm.VirtualMachine, initial bool, addr6, gateway6 net.IP,
+	dnsServers []string) (usrData string, err error) {
 
 	authrs, err := authority.GetOrgRoles(db, inst.Organization,
 		inst.NetworkRoles)
@@ -206,16 +206,8 @@ func getUserData(db *database.Database, inst *instance.Instance,
 	if virt.CloudType == instance.BSD {
 		resolvConf := ""
 
-		if inst.IsIpv6Only() {
-			resolvConf += fmt.Sprintf("nameserver %s\n",
-				settings.Hypervisor.DnsServerPrimary6)
-			resolvConf += fmt.Sprintf("nameserver %s\n",
-				settings.Hypervisor.DnsServerSecondary6)
-		} else {
-			resolvConf += fmt.Sprintf("nameserver %s\n",
-				settings.Hypervisor.DnsServerPrimary)
-			resolvConf += fmt.Sprintf("nameserver %s\n",
-				settings.Hypervisor.DnsServerSecondary)
+		for _, dnsServer := range dnsServers {
+			resolvConf += fmt.Sprintf("nameserver %s\n", dnsServer)
 		}
 
 		writeFiles = append(writeFiles, &fileData{
@@ -355,7 +347,7 @@ func getUserData(db *database.Database, inst *instance.Instance,
 
 func getNetData(db *database.Database, inst *instance.Instance,
 	virt *vm.VirtualMachine) (netData string, addr6, gateway6 net.IP,
-	err error) {
+	dnsServers []string, err error) {
 
 	if len(virt.NetworkAdapters) == 0 {
 		err = &errortypes.NotFoundError{
@@ -415,6 +407,18 @@ func getNetData(db *database.Database, inst *instance.Instance,
 		dns2 = settings.Hypervisor.DnsServerSecondary
 	}
 
+	// Built-in dns server on the VPC gateway forwards unknown names to
+	// the primary dns server
+	if vc.DnsServer {
+		dns2 = dns1
+		if inst.IsIpv6Only() {
+			dns1 = gateway6.String()
+		} else {
+			dns1 = gatewayAddr.String()
+		}
+	}
+	dnsServers = []string{dns1, dns2}
+
 	data := netConfigData{
 		Mac:          adapter.MacAddress,
 		Address:      addr.String(),
@@ -480,12 +484,13 @@ func Write(db *database.Database, inst *instance.Instance,
 		return
 	}
 
-	netData, addr6, gateway6, err := getNetData(db, inst, virt)
+	netData, addr6, gateway6, dnsServers, err := getNetData(db, inst, virt)
 	if err != nil {
 		return
 	}
 
-	usrData, err := getUserData(db, inst, virt, initial, addr6, gateway6)
+	usrData, err := getUserData(db, inst, virt, initial, addr6, gateway6,
+		dnsServers)
 	if err != nil {
 		return
 	}
@@ -4,8 +4,10 @@ import (
 	"flag"
 
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/backup"
 	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
 )
 
 func Backup() (err error) {
@@ -27,3 +29,64 @@ func Backup() (err error) {
 
 	return
 }
+
+func BackupVerify() (err error) {
+	dest := ""
+	bootTest := false
+
+	for _, arg := range flag.Args()[1:] {
+		if arg == "--boot-test" {
+			bootTest = true
+		} else {
+			dest = arg
+		}
+	}
+
+	if dest == "" {
+		err = &errortypes.ParseError{
+			errors.New("cmd: Missing backup destination path"),
+		}
+		return
+	}
+
+	verify := backup.NewVerify(dest, bootTest)
+
+	err = verify.Run()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func BackupRestore() (err error) {
+	dest := flag.Arg(1)
+	disks := []primitive.ObjectID{}
+
+	if dest == "" {
+		err = &errortypes.ParseError{
+			errors.New("cmd: Missing backup destination path"),
+		}
+		return
+	}
+
+	for _, arg := range flag.Args()[2:] {
+		dskId, ok := utils.ParseObjectId(arg)
+		if !ok {
+			err = &errortypes.ParseError{
+				errors.Newf("cmd: Invalid disk ID '%s'", arg),
+			}
+			return
+		}
+		disks = append(disks, dskId)
+	}
+
+	restore := backup.NewRestore(dest, disks)
+
+	err = restore.Run()
+	if err != nil {
+		return
+	}
+
+	return
+}
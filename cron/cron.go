@@ -0,0 +1,194 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+type field struct {
+	min int
+	max int
+}
+
+var (
+	fields = []field{
+		{0, 59},
+		{0, 23},
+		{1, 31},
+		{1, 12},
+		{0, 7},
+	}
+	aliases = map[string]string{
+		"@hourly":   "0 * * * *",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@weekly":   "0 0 * * 0",
+		"@monthly":  "0 0 1 * *",
+		"@yearly":   "0 0 1 1 *",
+	}
+)
+
+type Schedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	anyDay   bool
+	anyWeek  bool
+}
+
+func parseField(spec string, fld field) (bits uint64, err error) {
+	for _, part := range strings.Split(spec, ",") {
+		step := 1
+		rng := part
+
+		if i := strings.Index(part, "/"); i != -1 {
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				err = &errortypes.ParseError{
+					errors.Newf("cron: Invalid step '%s'", part),
+				}
+				return
+			}
+		}
+
+		start := fld.min
+		end := fld.max
+
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				err = &errortypes.ParseError{
+					errors.Newf("cron: Invalid value '%s'", part),
+				}
+				return
+			}
+
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					err = &errortypes.ParseError{
+						errors.Newf("cron: Invalid value '%s'", part),
+					}
+					return
+				}
+			} else if step == 1 {
+				end = start
+			}
+		}
+
+		if start < fld.min || end > fld.max || start > end {
+			err = &errortypes.ParseError{
+				errors.Newf("cron: Value out of range '%s'", part),
+			}
+			return
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return
+}
+
+func Parse(spec string) (sched *Schedule, err error) {
+	spec = strings.TrimSpace(spec)
+	if alias, ok := aliases[spec]; ok {
+		spec = alias
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		err = &errortypes.ParseError{
+			errors.Newf("cron: Schedule must have 5 fields '%s'", spec),
+		}
+		return
+	}
+
+	vals := make([]uint64, 5)
+	for i, part := range parts {
+		vals[i], err = parseField(part, fields[i])
+		if err != nil {
+			return
+		}
+	}
+
+	// Sunday may be written as 0 or 7
+	if vals[4]&(1<<7) != 0 {
+		vals[4] = (vals[4] &^ (1 << 7)) | 1
+	}
+
+	sched = &Schedule{
+		minutes:  vals[0],
+		hours:    vals[1],
+		days:     vals[2],
+		months:   vals[3],
+		weekdays: vals[4],
+		anyDay:   strings.HasPrefix(parts[2], "*"),
+		anyWeek:  strings.HasPrefix(parts[4], "*"),
+	}
+
+	return
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	week := s.weekdays&(1<<uint(t.Weekday())) != 0
+
+	if s.anyDay || s.anyWeek {
+		return day && week
+	}
+	return day || week
+}
+
+func (s *Schedule) Match(t time.Time) bool {
+	t = t.UTC()
+
+	return s.minutes&(1<<uint(t.Minute())) != 0 &&
+		s.hours&(1<<uint(t.Hour())) != 0 &&
+		s.months&(1<<uint(t.Month())) != 0 &&
+		s.matchDay(t)
+}
+
+// Next returns the first matching minute after t in UTC or a zero time
+// if no match occurs within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1,
+				0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/image"
+	"github.com/pritunl/pritunl-cloud/settings"
+)
+
+func backupChainParent(db *database.Database, dsk *disk.Disk) (
+	parent primitive.ObjectID, full bool, err error) {
+
+	chainLen := len(dsk.BackupChain)
+	if chainLen == 0 || chainLen >= settings.System.DiskBackupChain {
+		full = true
+		return
+	}
+
+	imgs, err := image.GetAllNames(db, &bson.M{
+		"_id": &bson.M{
+			"$in": dsk.BackupChain,
+		},
+		"disk": dsk.Id,
+	})
+	if err != nil {
+		return
+	}
+
+	if len(imgs) != chainLen {
+		full = true
+		return
+	}
+
+	parent = dsk.BackupChain[chainLen-1]
+
+	return
+}
+
+func commitBackupChain(db *database.Database, dsk *disk.Disk,
+	imgId primitive.ObjectID, incremental bool) (err error) {
+
+	if incremental {
+		dsk.BackupChain = append(dsk.BackupChain, imgId)
+	} else {
+		dsk.BackupChain = []primitive.ObjectID{imgId}
+	}
+
+	err = dsk.CommitFields(db, set.NewSet("backup_chain"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func resetBackupChain(db *database.Database, dsk *disk.Disk) (err error) {
+	dsk.BackupChain = []primitive.ObjectID{}
+
+	err = dsk.CommitFields(db, set.NewSet("backup_chain"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func getRestoreChain(db *database.Database, dskId primitive.ObjectID,
+	img *image.Image) (chain []*image.Image, err error) {
+
+	chain = []*image.Image{img}
+	seen := set.NewSet(img.Id)
+
+	cur := img
+	for !cur.Parent.IsZero() {
+		if seen.Contains(cur.Parent) {
+			err = &errortypes.VerificationError{
+				errors.New("data: Restore chain loop"),
+			}
+			return
+		}
+		seen.Add(cur.Parent)
+
+		cur, err = image.Get(db, cur.Parent)
+		if err != nil {
+			if _, ok := err.(*database.NotFoundError); ok {
+				err = &errortypes.NotFoundError{
+					errors.New("data: Restore chain image missing"),
+				}
+			}
+			return
+		}
+
+		if cur.Disk != dskId {
+			err = &errortypes.VerificationError{
+				errors.New("data: Restore chain image invalid"),
+			}
+			return
+		}
+
+		chain = append([]*image.Image{cur}, chain...)
+	}
+
+	return
+}
+
+// ValidateRestore checks that every image in the incremental backup chain
+// of the restore image is available
+func ValidateRestore(db *database.Database, dsk *disk.Disk,
+	img *image.Image) (errData *errortypes.ErrorData, err error) {
+
+	_, err = getRestoreChain(db, dsk.Id, img)
+	if err != nil {
+		switch err.(type) {
+		case *errortypes.NotFoundError, *errortypes.VerificationError:
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_restore_chain",
+				Message: "Restore image backup chain is incomplete",
+			}
+			break
+		}
+		return
+	}
+
+	return
+}
+
+// sortDeleteImages orders images newest first so incremental backups are
+// removed before their parents
+func sortDeleteImages(imgIds []primitive.ObjectID) []primitive.ObjectID {
+	sorted := append([]primitive.ObjectID{}, imgIds...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) > 0
+	})
+	return sorted
+}
@@ -61,6 +61,87 @@ func getImage(db *database.Database, img *image.Image,
 		return
 	}
 
+	if !img.Parent.IsZero() {
+		err = getImageChain(db, img, pth)
+		if err != nil {
+			return
+		}
+		return
+	}
+
+	err = downloadImage(db, img, pth)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// getImageChain merges an incremental backup image with its parents into
+// a single image, the image alone only contains the changes from its parent
+func getImageChain(db *database.Database, img *image.Image,
+	pth string) (err error) {
+
+	chain, err := getRestoreChain(db, img.Disk, img)
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"image_id": img.Id.Hex(),
+		"chain":    len(chain),
+		"path":     pth,
+	}).Info("data: Merging incremental image chain")
+
+	tmpPth := paths.GetImageTempPath()
+	defer utils.Remove(tmpPth)
+
+	chainPaths := []string{}
+	for _, chainImg := range chain {
+		chainPath := paths.GetImageTempPath()
+		defer utils.Remove(chainPath)
+
+		err = downloadImage(db, chainImg, chainPath)
+		if err != nil {
+			return
+		}
+
+		if len(chainPaths) > 0 {
+			err = utils.Exec("", "qemu-img", "rebase", "-u",
+				"-f", "qcow2",
+				"-b", chainPaths[len(chainPaths)-1],
+				"-F", "qcow2",
+				chainPath,
+			)
+			if err != nil {
+				return
+			}
+		}
+
+		chainPaths = append(chainPaths, chainPath)
+	}
+
+	err = utils.Exec("", "qemu-img", "convert",
+		"-f", "qcow2",
+		"-O", "qcow2",
+		chainPaths[len(chainPaths)-1],
+		tmpPth,
+	)
+	if err != nil {
+		return
+	}
+
+	err = utils.Exec("", "mv", tmpPth, pth)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func downloadImage(db *database.Database, img *image.Image,
+	pth string) (err error) {
+
 	tmpPth := paths.GetImageTempPath()
 
 	store, err := storage.Get(db, img.Storage)
@@ -529,7 +610,7 @@ func writeImageLvm(db *database.Database, dsk *disk.Disk,
 }
 
 func DeleteImage(db *database.Database, imgId primitive.ObjectID) (
-	err error) {
+	errData *errortypes.ErrorData, err error) {
 
 	img, err := image.Get(db, imgId)
 	if err != nil {
@@ -540,6 +621,19 @@ func DeleteImage(db *database.Database, imgId primitive.ObjectID) (
 		return
 	}
 
+	hasChildren, err := image.HasChildren(db, img.Id)
+	if err != nil {
+		return
+	}
+
+	if hasChildren {
+		errData = &errortypes.ErrorData{
+			Error:   "image_has_children",
+			Message: "Image has newer incremental backups",
+		}
+		return
+	}
+
 	store, err := storage.Get(db, img.Storage)
 	if err != nil {
 		return
@@ -603,11 +697,11 @@ func WriteImage(db *database.Database, dsk *disk.Disk) (
 }
 
 func DeleteImages(db *database.Database, imgIds []primitive.ObjectID) (
-	err error) {
+	errData *errortypes.ErrorData, err error) {
 
-	for _, imgId := range imgIds {
-		err = DeleteImage(db, imgId)
-		if err != nil {
+	for _, imgId := range sortDeleteImages(imgIds) {
+		errData, err = DeleteImage(db, imgId)
+		if err != nil || errData != nil {
 			return
 		}
 	}
@@ -616,7 +710,7 @@ func DeleteImages(db *database.Database, imgIds []primitive.ObjectID) (
 }
 
 func DeleteImageOrg(db *database.Database, orgId, imgId primitive.ObjectID) (
-	err error) {
+	errData *errortypes.ErrorData, err error) {
 
 	img, err := image.GetOrg(db, orgId, imgId)
 	if err != nil {
@@ -627,6 +721,19 @@ func DeleteImageOrg(db *database.Database, orgId, imgId primitive.ObjectID) (
 		return
 	}
 
+	hasChildren, err := image.HasChildren(db, img.Id)
+	if err != nil {
+		return
+	}
+
+	if hasChildren {
+		errData = &errortypes.ErrorData{
+			Error:   "image_has_children",
+			Message: "Image has newer incremental backups",
+		}
+		return
+	}
+
 	store, err := storage.Get(db, img.Storage)
 	if err != nil {
 		return
@@ -658,11 +765,11 @@ func DeleteImageOrg(db *database.Database, orgId, imgId primitive.ObjectID) (
 }
 
 func DeleteImagesOrg(db *database.Database, orgId primitive.ObjectID,
-	imgIds []primitive.ObjectID) (err error) {
+	imgIds []primitive.ObjectID) (errData *errortypes.ErrorData, err error) {
 
-	for _, imgId := range imgIds {
-		err = DeleteImageOrg(db, orgId, imgId)
-		if err != nil {
+	for _, imgId := range sortDeleteImages(imgIds) {
+		errData, err = DeleteImageOrg(db, orgId, imgId)
+		if err != nil || errData != nil {
 			return
 		}
 	}
@@ -724,7 +831,8 @@ func CreateSnapshot(db *database.Database, dsk *disk.Disk,
 	tmpPath := path.Join(cacheDir,
 		fmt.Sprintf("snapshot-%s", imgId.Hex()))
 	img := &image.Image{
-		Id: imgId,
+		Id:   imgId,
+		Disk: dsk.Id,
 		Name: fmt.Sprintf("%s-%s", dsk.Name,
 			time.Now().Format("2006-01-02T15:04:05")),
 		Organization: dsk.Organization,
@@ -900,9 +1008,16 @@ func CreateBackup(db *database.Database, dsk *disk.Disk,
 
 	defer utils.Remove(tmpPath)
 
+	parent, full, err := backupChainParent(db, dsk)
+	if err != nil {
+		return
+	}
+
 	available := false
+	incremental := false
 	if virt != nil && virt.Running() {
-		err = qmp.BackupDisk(virt.Id, dsk, tmpPath)
+		incremental, err = qmp.BackupDiskIncremental(
+			virt.Id, dsk, tmpPath, full)
 		if err != nil {
 			if _, ok := err.(*qmp.DiskNotFound); ok {
 				err = nil
@@ -921,6 +1036,18 @@ func CreateBackup(db *database.Database, dsk *disk.Disk,
 		}
 	}
 
+	if incremental {
+		img.Parent = parent
+
+		// Changes are cleared from the dirty bitmap once the incremental
+		// job completes, a failed upload must restart the chain
+		defer func() {
+			if err != nil {
+				_ = resetBackupChain(db, dsk)
+			}
+		}()
+	}
+
 	err = utils.Chmod(tmpPath, 0600)
 	if err != nil {
 		return
@@ -985,6 +1112,11 @@ func CreateBackup(db *database.Database, dsk *disk.Disk,
 		return
 	}
 
+	err = commitBackupChain(db, dsk, img.Id, incremental)
+	if err != nil {
+		return
+	}
+
 	event.PublishDispatch(db, "image.change")
 
 	return
@@ -1034,17 +1166,68 @@ func RestoreBackup(db *database.Database, dsk *disk.Disk) (err error) {
 		return
 	}
 
+	chain, err := getRestoreChain(db, dsk.Id, img)
+	if err != nil {
+		return
+	}
+
 	imgId := primitive.NewObjectID()
 	tmpPath := path.Join(cacheDir,
 		fmt.Sprintf("restore-%s", imgId.Hex()))
-
 	defer utils.Remove(tmpPath)
-	err = client.FGetObject(context.Background(), store.Bucket,
-		img.Key, tmpPath, minio.GetObjectOptions{})
-	if err != nil {
-		err = &errortypes.ReadError{
-			errors.Wrap(err, "data: Failed to download restore image"),
+
+	chainPaths := []string{}
+	for _, chainImg := range chain {
+		chainPath := path.Join(cacheDir, fmt.Sprintf(
+			"restore-%s-%s", imgId.Hex(), chainImg.Id.Hex()))
+		defer utils.Remove(chainPath)
+
+		if chainImg.Storage != store.Id {
+			err = &errortypes.VerificationError{
+				errors.New("data: Restore chain storage mismatch"),
+			}
+			return
+		}
+
+		err = client.FGetObject(context.Background(), store.Bucket,
+			chainImg.Key, chainPath, minio.GetObjectOptions{})
+		if err != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "data: Failed to download restore image"),
+			}
+			return
 		}
+
+		if len(chainPaths) > 0 {
+			err = utils.Exec("", "qemu-img", "rebase", "-u",
+				"-f", "qcow2",
+				"-b", chainPaths[len(chainPaths)-1],
+				"-F", "qcow2",
+				chainPath,
+			)
+			if err != nil {
+				return
+			}
+		}
+
+		chainPaths = append(chainPaths, chainPath)
+	}
+
+	if len(chain) > 1 {
+		logrus.WithFields(logrus.Fields{
+			"disk_id":  dsk.Id.Hex(),
+			"image_id": img.Id.Hex(),
+			"chain":    len(chain),
+		}).Info("data: Merging incremental backup chain")
+	}
+
+	err = utils.Exec("", "qemu-img", "convert",
+		"-f", "qcow2",
+		"-O", "qcow2",
+		chainPaths[len(chainPaths)-1],
+		tmpPath,
+	)
+	if err != nil {
 		return
 	}
 
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/image"
+	"github.com/sirupsen/logrus"
+)
+
+func PruneSnapshots(db *database.Database, dsk *disk.Disk) (err error) {
+	if dsk.SnapshotRetain == 0 && dsk.SnapshotMaxAge == 0 {
+		return
+	}
+
+	imgs, err := image.GetDiskSnapshots(db, dsk.Id)
+	if err != nil {
+		return
+	}
+
+	maxAge := time.Duration(dsk.SnapshotMaxAge) * time.Hour
+	removed := false
+
+	for i, img := range imgs {
+		if i == 0 {
+			continue
+		}
+
+		if (dsk.SnapshotRetain == 0 || i < dsk.SnapshotRetain) &&
+			(maxAge == 0 || time.Since(img.Id.Timestamp()) < maxAge) {
+
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"disk_id":    dsk.Id.Hex(),
+			"image_id":   img.Id.Hex(),
+			"object_key": img.Key,
+		}).Info("data: Removing expired disk snapshot")
+
+		errData, e := DeleteImage(db, img.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if errData != nil {
+			continue
+		}
+
+		removed = true
+	}
+
+	if removed {
+		event.PublishDispatch(db, "image.change")
+	}
+
+	return
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/bson"
 	"github.com/pritunl/mongo-go-driver/mongo"
@@ -118,6 +119,21 @@ func (d *Database) AlertsEventLock() (coll *Collection) {
 	return
 }
 
+func (d *Database) AlertsState() (coll *Collection) {
+	coll = d.getCollection("alerts_state")
+	return
+}
+
+func (d *Database) Notifications() (coll *Collection) {
+	coll = d.getCollection("notifications")
+	return
+}
+
+func (d *Database) BackupVerify() (coll *Collection) {
+	coll = d.getCollection("backup_verify")
+	return
+}
+
 func (d *Database) Sessions() (coll *Collection) {
 	coll = d.getCollection("sessions")
 	return
@@ -203,6 +219,21 @@ func (d *Database) Balancers() (coll *Collection) {
 	return
 }
 
+func (d *Database) Plans() (coll *Collection) {
+	coll = d.getCollection("plans")
+	return
+}
+
+func (d *Database) PlansRun() (coll *Collection) {
+	coll = d.getCollection("plans_run")
+	return
+}
+
+func (d *Database) Pods() (coll *Collection) {
+	coll = d.getCollection("pods")
+	return
+}
+
 func (d *Database) Instances() (coll *Collection) {
 	coll = d.getCollection("instances")
 	return
@@ -248,6 +279,11 @@ func (d *Database) VpcsIp() (coll *Collection) {
 	return
 }
 
+func (d *Database) VpcPeerings() (coll *Collection) {
+	coll = d.getCollection("vpc_peerings")
+	return
+}
+
 func (d *Database) Authorities() (coll *Collection) {
 	coll = d.getCollection("authorities")
 	return
@@ -258,6 +294,11 @@ func (d *Database) Certificates() (coll *Collection) {
 	return
 }
 
+func (d *Database) AddrGroups() (coll *Collection) {
+	coll = d.getCollection("address_groups")
+	return
+}
+
 func (d *Database) Secrets() (coll *Collection) {
 	coll = d.getCollection("secrets")
 	return
@@ -283,6 +324,11 @@ func (d *Database) Logs() (coll *Collection) {
 	return
 }
 
+func (d *Database) BalancerLogs() (coll *Collection) {
+	coll = d.getCollection("balancer_logs")
+	return
+}
+
 func (d *Database) Audits() (coll *Collection) {
 	coll = d.getCollection("audits")
 	return
@@ -638,6 +684,65 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.AlertsState(),
+		Keys: &bson.D{
+			{"alert", 1},
+			{"state", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.AlertsState(),
+		Keys: &bson.D{
+			{"last_seen", 1},
+		},
+		Expire: 720 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.Notifications(),
+		Keys: &bson.D{
+			{"organization", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.BackupVerify(),
+		Keys: &bson.D{
+			{"node", 1},
+			{"timestamp", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.BackupVerify(),
+		Keys: &bson.D{
+			{"timestamp", 1},
+		},
+		Expire: 720 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Organizations(),
 		Keys: &bson.D{
@@ -702,6 +807,16 @@ func addIndexes() (err error) {
 	if err != nil {
 		return
 	}
+	index = &Index{
+		Collection: db.Images(),
+		Keys: &bson.D{
+			{"parent", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
 
 	index = &Index{
 		Collection: db.LvmLock(),
@@ -914,6 +1029,57 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.VpcPeerings(),
+		Keys: &bson.D{
+			{"vpc", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+	index = &Index{
+		Collection: db.VpcPeerings(),
+		Keys: &bson.D{
+			{"peer_vpc", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+	index = &Index{
+		Collection: db.VpcPeerings(),
+		Keys: &bson.D{
+			{"datacenter", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+	index = &Index{
+		Collection: db.VpcPeerings(),
+		Keys: &bson.D{
+			{"organization", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+	index = &Index{
+		Collection: db.VpcPeerings(),
+		Keys: &bson.D{
+			{"peer_organization", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Sessions(),
 		Keys: &bson.D{
@@ -968,6 +1134,18 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.AddrGroups(),
+		Keys: &bson.D{
+			{"organization", 1},
+			{"name", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Zones(),
 		Keys: &bson.D{
@@ -990,6 +1168,18 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.BalancerLogs(),
+		Keys: &bson.D{
+			{"b", 1},
+			{"t", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Authorities(),
 		Keys: &bson.D{
@@ -1043,6 +1233,26 @@ func addIndexes() (err error) {
 	if err != nil {
 		return
 	}
+	index = &Index{
+		Collection: db.Instances(),
+		Keys: &bson.D{
+			{"pod", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+	index = &Index{
+		Collection: db.Instances(),
+		Keys: &bson.D{
+			{"migrate_node", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
 	index = &Index{
 		Collection: db.Instances(),
 		Keys: &bson.D{
@@ -1064,6 +1274,17 @@ func addIndexes() (err error) {
 	if err != nil {
 		return
 	}
+	index = &Index{
+		Collection: db.Instances(),
+		Keys: &bson.D{
+			{"vpc", 1},
+			{"network_roles", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
 	index = &Index{
 		Collection: db.Instances(),
 		Keys: &bson.D{
@@ -1115,6 +1336,63 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.Pods(),
+		Keys: &bson.D{
+			{"organization", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.Plans(),
+		Keys: &bson.D{
+			{"organization", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.PlansRun(),
+		Keys: &bson.D{
+			{"plan", 1},
+			{"timestamp", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.PlansRun(),
+		Keys: &bson.D{
+			{"pod", 1},
+			{"timestamp", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.PlansRun(),
+		Keys: &bson.D{
+			{"state", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Tasks(),
 		Keys: &bson.D{
@@ -1177,6 +1455,7 @@ func addCollections() (err error) {
 	}
 	defer cursor.Close(db)
 
+	colls := set.NewSet()
 	for cursor.Next(db) {
 		item := &struct {
 			Name string `bson:"name"`
@@ -1187,9 +1466,7 @@ func addCollections() (err error) {
 			return
 		}
 
-		if item.Name == "events" {
-			return
-		}
+		colls.Add(item.Name)
 	}
 
 	err = cursor.Err()
@@ -1198,18 +1475,36 @@ func addCollections() (err error) {
 		return
 	}
 
-	err = db.database.RunCommand(
-		context.Background(),
-		bson.D{
-			{"create", "events"},
-			{"capped", true},
-			{"max", 1000},
-			{"size", 5242880},
-		},
-	).Err()
-	if err != nil {
-		err = ParseError(err)
-		return
+	if !colls.Contains("events") {
+		err = db.database.RunCommand(
+			context.Background(),
+			bson.D{
+				{"create", "events"},
+				{"capped", true},
+				{"max", 1000},
+				{"size", 5242880},
+			},
+		).Err()
+		if err != nil {
+			err = ParseError(err)
+			return
+		}
+	}
+
+	if !colls.Contains("balancer_logs") {
+		err = db.database.RunCommand(
+			context.Background(),
+			bson.D{
+				{"create", "balancer_logs"},
+				{"capped", true},
+				{"max", 200000},
+				{"size", 104857600},
+			},
+		).Err()
+		if err != nil {
+			err = ParseError(err)
+			return
+		}
 	}
 
 	return
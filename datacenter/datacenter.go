@@ -1,24 +1,85 @@
 package datacenter
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
 )
 
 type Datacenter struct {
-	Id                  primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Name                string               `bson:"name" json:"name"`
-	Comment             string               `bson:"comment" json:"comment"`
-	MatchOrganizations  bool                 `bson:"match_organizations" json:"match_organizations"`
-	Organizations       []primitive.ObjectID `bson:"organizations" json:"organizations"`
-	PublicStorages      []primitive.ObjectID `bson:"public_storages" json:"public_storages"`
-	PrivateStorage      primitive.ObjectID   `bson:"private_storage,omitempty" json:"private_storage"`
-	PrivateStorageClass string               `bson:"private_storage_class" json:"private_storage_class"`
-	BackupStorage       primitive.ObjectID   `bson:"backup_storage,omitempty" json:"backup_storage"`
-	BackupStorageClass  string               `bson:"backup_storage_class" json:"backup_storage_class"`
+	Id                   primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name                 string               `bson:"name" json:"name"`
+	Comment              string               `bson:"comment" json:"comment"`
+	MatchOrganizations   bool                 `bson:"match_organizations" json:"match_organizations"`
+	Organizations        []primitive.ObjectID `bson:"organizations" json:"organizations"`
+	PublicStorages       []primitive.ObjectID `bson:"public_storages" json:"public_storages"`
+	PrivateStorage       primitive.ObjectID   `bson:"private_storage,omitempty" json:"private_storage"`
+	PrivateStorageClass  string               `bson:"private_storage_class" json:"private_storage_class"`
+	BackupStorage        primitive.ObjectID   `bson:"backup_storage,omitempty" json:"backup_storage"`
+	BackupStorageClass   string               `bson:"backup_storage_class" json:"backup_storage_class"`
+	BackupEncryption     bool                 `bson:"backup_encryption" json:"backup_encryption"`
+	BackupKey            string               `bson:"backup_key" json:"-"`
+	BackupKeys           map[string]string    `bson:"backup_keys" json:"-"`
+	BackupKeyFingerprint string               `bson:"-" json:"backup_key_fingerprint"`
+}
+
+// BackupKeyId returns the id of a base64 encoded backup key, the id is
+// stored in backup manifests and shown as the key fingerprint
+func BackupKeyId(encoded string) string {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:8])
+}
+
+// Json sets the backup key fingerprint, the backup key is never sent to
+// clients
+func (d *Datacenter) Json() {
+	d.BackupKeyFingerprint = ""
+	if d.BackupKey != "" {
+		d.BackupKeyFingerprint = BackupKeyId(d.BackupKey)
+	}
+}
+
+// SetBackupKey replaces the backup key, the previous key is kept to
+// decrypt existing backups
+func (d *Datacenter) SetBackupKey(key string) {
+	key = strings.TrimSpace(key)
+	if key == "" || key == d.BackupKey {
+		return
+	}
+
+	if d.BackupKey != "" {
+		keyId := BackupKeyId(d.BackupKey)
+		if keyId != "" {
+			if d.BackupKeys == nil {
+				d.BackupKeys = map[string]string{}
+			}
+			d.BackupKeys[keyId] = d.BackupKey
+		}
+	}
+
+	d.BackupKey = key
+}
+
+// GetBackupKey returns the current or previous backup key with the id
+func (d *Datacenter) GetBackupKey(keyId string) string {
+	if d.BackupKey != "" && BackupKeyId(d.BackupKey) == keyId {
+		return d.BackupKey
+	}
+
+	return d.BackupKeys[keyId]
 }
 
 func (d *Datacenter) Validate(db *database.Database) (
@@ -32,6 +93,29 @@ func (d *Datacenter) Validate(db *database.Database) (
 		d.PublicStorages = []primitive.ObjectID{}
 	}
 
+	if d.BackupKeys == nil {
+		d.BackupKeys = map[string]string{}
+	}
+
+	d.BackupKey = strings.TrimSpace(d.BackupKey)
+	if d.BackupKey != "" {
+		key, e := base64.StdEncoding.DecodeString(d.BackupKey)
+		if e != nil || len(key) != 32 {
+			errData = &errortypes.ErrorData{
+				Error:   "backup_key_invalid",
+				Message: "Backup key must be a base64 encoded 256-bit key",
+			}
+			return
+		}
+	} else if d.BackupEncryption {
+		key, e := utils.RandBytes(32)
+		if e != nil {
+			err = e
+			return
+		}
+		d.BackupKey = base64.StdEncoding.EncodeToString(key)
+	}
+
 	return
 }
 
@@ -44,6 +44,18 @@ func Deploy(stat *state.State) (err error) {
 		return
 	}
 
+	migrations := NewMigrations(stat)
+	err = migrations.Deploy(db)
+	if err != nil {
+		return
+	}
+
+	evacuate := NewEvacuate(stat)
+	err = evacuate.Deploy(db)
+	if err != nil {
+		return
+	}
+
 	namespaces := NewNamespace(stat)
 	err = namespaces.Deploy()
 	if err != nil {
@@ -4,12 +4,15 @@ import (
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/constants"
 	"github.com/pritunl/pritunl-cloud/data"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/disk"
 	"github.com/pritunl/pritunl-cloud/event"
 	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/lvm"
+	"github.com/pritunl/pritunl-cloud/node"
 	"github.com/pritunl/pritunl-cloud/settings"
 	"github.com/pritunl/pritunl-cloud/state"
 	"github.com/pritunl/pritunl-cloud/utils"
@@ -308,7 +311,8 @@ func (d *Disks) restore(dsk *disk.Disk) {
 		}
 
 		dsk.State = disk.Available
-		err := dsk.CommitFields(db, set.NewSet("state"))
+		dsk.BackupChain = []primitive.ObjectID{}
+		err := dsk.CommitFields(db, set.NewSet("state", "backup_chain"))
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
 				"disk_id": dsk.Id.Hex(),
@@ -480,6 +484,15 @@ func (d *Disks) Deploy(db *database.Database) (err error) {
 		}
 	}
 
+	if !node.Self.Zone.IsZero() {
+		e := lvm.UpdatePoolsState(db, node.Self.Zone)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": e,
+			}).Error("deploy: Failed to update pools state")
+		}
+	}
+
 	return
 }
 
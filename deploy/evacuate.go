@@ -0,0 +1,249 @@
+package deploy
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/shape"
+	"github.com/pritunl/pritunl-cloud/state"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
+)
+
+// Evacuate moves the instances off a node in maintenance to other nodes
+// in the zone. Running instances are live migrated, stopped instances and
+// instances that failed to live migrate are moved with an offline
+// migration that stops the instance and copies the disks. Failed offline
+// migrations are retried after the evacuate retry interval in case capacity
+// has become available on another node.
+type Evacuate struct {
+	stat  *state.State
+	nodes map[primitive.ObjectID][]*node.Node
+}
+
+func (e *Evacuate) getNodes(db *database.Database,
+	inst *instance.Instance) (ndes []*node.Node, err error) {
+
+	ndes, ok := e.nodes[inst.Shape]
+	if ok {
+		return
+	}
+
+	roles := e.stat.Node().NetworkRoles
+	if !inst.Shape.IsZero() {
+		var shpe *shape.Shape
+		shpe, err = shape.Get(db, inst.Shape)
+		if err != nil {
+			if _, ok := err.(*database.NotFoundError); !ok {
+				return
+			}
+			err = nil
+		} else {
+			roles = shpe.Roles
+		}
+	}
+
+	shpe := &shape.Shape{
+		Zone:  inst.Zone,
+		Roles: roles,
+	}
+
+	ndes, err = shpe.FindNodes(db)
+	if err != nil {
+		return
+	}
+
+	e.nodes[inst.Shape] = ndes
+
+	return
+}
+
+func (e *Evacuate) migrate(db *database.Database,
+	inst *instance.Instance, offline bool) (started bool, err error) {
+
+	ndes, err := e.getNodes(db, inst)
+	if err != nil {
+		return
+	}
+
+	msg := "No node available for evacuation"
+	for _, nde := range ndes {
+		var errData *errortypes.ErrorData
+		if offline {
+			errData, err = inst.ValidateMigrateOffline(db, nde)
+		} else {
+			errData, err = inst.ValidateMigrate(db, nde)
+		}
+		if err != nil {
+			return
+		}
+
+		if errData != nil {
+			msg = errData.Message
+			continue
+		}
+
+		started, err = inst.Migrate(db, nde, offline)
+		if err != nil {
+			return
+		}
+
+		if started {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"node_id":     nde.Id.Hex(),
+				"offline":     offline,
+			}).Info("deploy: Evacuating instance to node")
+		}
+
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"instance_id": inst.Id.Hex(),
+		"message":     msg,
+	}).Warn("deploy: Failed to find node for instance evacuation")
+
+	err = instance.SetMigrateError(db, inst.Id, msg)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (e *Evacuate) Deploy(db *database.Database) (err error) {
+	nde := e.stat.Node()
+	if !nde.Maintenance || !nde.Evacuate {
+		return
+	}
+
+	evac := &node.Evacuation{
+		State: node.EvacuateActive,
+		Start: time.Now(),
+	}
+	if nde.Evacuation != nil && !nde.Evacuation.Start.IsZero() {
+		evac.Start = nde.Evacuation.Start
+		evac.Total = nde.Evacuation.Total
+	}
+
+	limit := settings.Hypervisor.EvacuateLimit
+	retry := time.Duration(settings.Hypervisor.EvacuateRetry) * time.Second
+	changed := false
+
+	for _, inst := range e.stat.Instances() {
+		if inst.State == instance.Destroy {
+			continue
+		}
+
+		evac.Remaining += 1
+
+		if inst.IsMigrating() {
+			evac.Migrating += 1
+			limit -= 1
+			continue
+		}
+
+		offline := false
+		if inst.MigrateState == instance.MigrateFailed &&
+			inst.MigrateTimestamp.After(evac.Start) {
+
+			if inst.MigrateOffline &&
+				time.Since(inst.MigrateTimestamp) < retry {
+
+				evac.Failed += 1
+				continue
+			}
+			offline = true
+		}
+
+		switch inst.State {
+		case instance.Start:
+			switch inst.VmState {
+			case vm.Running:
+				break
+			case vm.Stopped, vm.Failed:
+				offline = true
+				break
+			default:
+				continue
+			}
+			break
+		case instance.Stop:
+			offline = true
+			break
+		default:
+			continue
+		}
+
+		if limit <= 0 {
+			continue
+		}
+
+		started := false
+		started, err = e.migrate(db, inst, offline)
+		if err != nil {
+			return
+		}
+		changed = true
+
+		if started {
+			evac.Migrating += 1
+			limit -= 1
+		} else {
+			evac.Failed += 1
+		}
+	}
+
+	if evac.Remaining > evac.Total {
+		evac.Total = evac.Remaining
+	}
+
+	if evac.Remaining == 0 {
+		evac.State = node.EvacuateComplete
+	} else if evac.Migrating == 0 && evac.Failed >= evac.Remaining {
+		evac.State = node.EvacuateFailed
+	}
+
+	if changed {
+		event.PublishDispatch(db, "instance.change")
+	}
+
+	if evac.Equal(nde.Evacuation) {
+		return
+	}
+
+	if nde.Evacuation == nil || nde.Evacuation.State != evac.State {
+		logrus.WithFields(logrus.Fields{
+			"state":     evac.State,
+			"total":     evac.Total,
+			"remaining": evac.Remaining,
+			"failed":    evac.Failed,
+		}).Info("deploy: Node evacuation state changed")
+	}
+
+	evac.Timestamp = time.Now()
+	nde.Evacuation = evac
+
+	err = node.SetEvacuation(db, nde.Id, evac)
+	if err != nil {
+		return
+	}
+
+	event.PublishDispatch(db, "node.change")
+
+	return
+}
+
+func NewEvacuate(stat *state.State) *Evacuate {
+	return &Evacuate{
+		stat:  stat,
+		nodes: map[primitive.ObjectID][]*node.Node{},
+	}
+}
@@ -844,6 +844,10 @@ func (s *Instances) Deploy(db *database.Database) (err error) {
 		cpuUnits += inst.Processors
 		memoryUnits += float64(inst.Memory) / float64(1024)
 
+		if inst.IsMigrating() {
+			continue
+		}
+
 		if curVirt == nil {
 			if inst.State == instance.Start {
 				s.create(inst)
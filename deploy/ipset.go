@@ -14,8 +14,10 @@ func (t *Ipset) Deploy() (err error) {
 	namespaces := t.stat.Namespaces()
 	nodeFirewall := t.stat.NodeFirewall()
 	firewalls := t.stat.Firewalls()
+	firewallsEgress := t.stat.FirewallsEgress()
 
-	err = ipset.UpdateState(instaces, namespaces, nodeFirewall, firewalls)
+	err = ipset.UpdateState(instaces, namespaces, nodeFirewall, firewalls,
+		firewallsEgress)
 	if err != nil {
 		return
 	}
@@ -27,8 +29,10 @@ func (t *Ipset) Clean() (err error) {
 	instaces := t.stat.Instances()
 	nodeFirewall := t.stat.NodeFirewall()
 	firewalls := t.stat.Firewalls()
+	firewallsEgress := t.stat.FirewallsEgress()
 
-	err = ipset.UpdateNamesState(instaces, nodeFirewall, firewalls)
+	err = ipset.UpdateNamesState(instaces, nodeFirewall, firewalls,
+		firewallsEgress)
 	if err != nil {
 		return
 	}
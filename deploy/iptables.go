@@ -16,9 +16,11 @@ func (t *Iptables) Deploy() (err error) {
 	namespaces := t.stat.Namespaces()
 	nodeFirewall := t.stat.NodeFirewall()
 	firewalls := t.stat.Firewalls()
+	firewallsEgress := t.stat.FirewallsEgress()
+	logModes := t.stat.LogModes()
 
 	iptables.UpdateStateRecover(nodeSelf, vpcs, instaces, namespaces,
-		nodeFirewall, firewalls)
+		nodeFirewall, firewalls, firewallsEgress, logModes)
 
 	return
 }
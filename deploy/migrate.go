@@ -0,0 +1,1046 @@
+package deploy
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/paths"
+	"github.com/pritunl/pritunl-cloud/pool"
+	"github.com/pritunl/pritunl-cloud/qemu"
+	"github.com/pritunl/pritunl-cloud/qmp"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/state"
+	"github.com/pritunl/pritunl-cloud/systemd"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
+)
+
+const migrateLockTimeout = 6 * time.Hour
+
+// Migrations moves running instances between nodes. The source node
+// drives the migration and owns the instance until the memory transfer
+// completes, the destination node prepares an incoming virtual machine
+// and takes ownership once the instance node is changed. Each step is
+// guarded by a conditional update of the migrate state so the two nodes
+// never start the same instance. Offline migrations stop the instance
+// and copy the disks with qemu-nbd before the destination node starts
+// the instance.
+type Migrations struct {
+	stat *state.State
+}
+
+type migrateCloser interface {
+	Close()
+}
+
+// getMigrateAddr returns the node internal address, the migration stream
+// is not encrypted and must not be sent over public networks.
+func getMigrateAddr(nde *node.Node) string {
+	if nde.InternalInterfaces != nil && nde.PrivateIps != nil {
+		for _, iface := range nde.InternalInterfaces {
+			addr := nde.PrivateIps[iface]
+			if addr != "" {
+				return addr
+			}
+		}
+	}
+
+	return ""
+}
+
+func getMigrateAllowed(nde *node.Node) (allowed set.Set) {
+	allowed = set.NewSet()
+
+	for _, addr := range nde.PrivateIps {
+		allowed.Add(addr)
+	}
+
+	return
+}
+
+func getMigrateDisks(inst *instance.Instance) (
+	dskIds []primitive.ObjectID) {
+
+	dskIds = []primitive.ObjectID{}
+	for _, dsk := range inst.Virt.Disks {
+		dskIds = append(dskIds, dsk.Id)
+	}
+
+	return
+}
+
+// loadMigrateVirt loads the virtual machine of an instance that is not
+// yet owned by this node.
+func loadMigrateVirt(db *database.Database, inst *instance.Instance) (
+	err error) {
+
+	dsks, err := disk.GetInstance(db, inst.Id)
+	if err != nil {
+		return
+	}
+
+	poolsMap := map[primitive.ObjectID]*pool.Pool{}
+	if inst.DiskType == disk.Lvm {
+		pl, e := pool.Get(db, inst.DiskPool)
+		if e != nil {
+			err = e
+			return
+		}
+		poolsMap[pl.Id] = pl
+	}
+
+	virtDsks := []*disk.Disk{}
+	for _, dsk := range dsks {
+		if dsk.State != disk.Available {
+			continue
+		}
+		virtDsks = append(virtDsks, dsk)
+	}
+
+	inst.LoadVirt(poolsMap, virtDsks)
+
+	return
+}
+
+func (m *Migrations) fail(db *database.Database, inst *instance.Instance,
+	curState string, e error) {
+
+	logrus.WithFields(logrus.Fields{
+		"instance_id":   inst.Id.Hex(),
+		"migrate_state": curState,
+		"error":         e,
+	}).Error("deploy: Instance migration failed")
+
+	_, err := instance.SetMigrateFailed(db, inst.Id, curState, e.Error())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"instance_id": inst.Id.Hex(),
+			"error":       err,
+		}).Error("deploy: Failed to update instance migration")
+	}
+
+	event.PublishDispatch(db, "instance.change")
+}
+
+// touch refreshes the migrate timestamp while a long transfer is active
+// to prevent the migration from expiring.
+func (m *Migrations) touch(instId primitive.ObjectID,
+	curState string) (stop func()) {
+
+	done := make(chan bool)
+
+	go func() {
+		db := database.GetDatabase()
+		defer db.Close()
+
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_, _ = instance.UpdateMigrate(
+					db, instId, curState, bson.M{})
+			}
+		}
+	}()
+
+	stop = func() {
+		close(done)
+	}
+
+	return
+}
+
+func (m *Migrations) prepare(inst *instance.Instance) {
+	acquired, lockId := instancesLock.LockOpen(inst.Id.Hex())
+	if !acquired {
+		return
+	}
+
+	go func() {
+		defer func() {
+			instancesLock.Unlock(inst.Id.Hex(), lockId)
+		}()
+
+		db := database.GetDatabase()
+		defer db.Close()
+
+		migrateDisks := []*instance.MigrateDisk{}
+
+		if inst.MigrateOffline {
+			curVirt := m.stat.GetVirt(inst.Id)
+			if curVirt != nil && curVirt.State == vm.Running {
+				logrus.WithFields(logrus.Fields{
+					"instance_id": inst.Id.Hex(),
+				}).Info("deploy: Stopping instance for offline migration")
+
+				err := qemu.PowerOff(db, inst.Virt)
+				if err != nil {
+					m.fail(db, inst, instance.MigratePending, err)
+					return
+				}
+			}
+		}
+
+		if len(inst.Virt.Disks) > 0 {
+			var sizes map[primitive.ObjectID]int64
+			var err error
+			if inst.MigrateOffline {
+				sizes, err = qemu.GetOfflineDiskSizes(inst.Virt)
+			} else {
+				sizes, err = qmp.GetDiskSizes(inst.Id)
+			}
+			if err != nil {
+				m.fail(db, inst, instance.MigratePending, err)
+				return
+			}
+
+			for _, dsk := range inst.Virt.Disks {
+				size, ok := sizes[dsk.Id]
+				if !ok {
+					m.fail(db, inst, instance.MigratePending,
+						&errortypes.NotFoundError{
+							errors.Newf("deploy: Failed to find disk "+
+								"size for '%s'", dsk.Id.Hex()),
+						})
+					return
+				}
+
+				migrateDisks = append(migrateDisks, &instance.MigrateDisk{
+					Id:   dsk.Id,
+					Size: size,
+				})
+			}
+		}
+
+		_, err := instance.UpdateMigrate(db, inst.Id,
+			instance.MigratePending, bson.M{
+				"migrate_state": instance.MigratePrepared,
+				"migrate_disks": migrateDisks,
+			})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       err,
+			}).Error("deploy: Failed to prepare instance migration")
+			return
+		}
+
+		event.PublishDispatch(db, "instance.change")
+	}()
+}
+
+func (m *Migrations) sendMigrate(db *database.Database,
+	inst *instance.Instance) (err error) {
+
+	dskIds := getMigrateDisks(inst)
+	timeout := time.Duration(settings.Hypervisor.MigrateTimeout) *
+		time.Second
+
+	migrateRelay, err := newRelayUnix(paths.GetMigrateSockPath(inst.Id),
+		net.JoinHostPort(inst.MigrateAddress,
+			strconv.Itoa(inst.MigratePort)), inst.UnixId)
+	if err != nil {
+		return
+	}
+	defer migrateRelay.Close()
+
+	if len(dskIds) > 0 {
+		nbdRelay, e := newRelayUnix(paths.GetMigrateNbdSockPath(inst.Id),
+			net.JoinHostPort(inst.MigrateAddress,
+				strconv.Itoa(inst.MigrateNbdPort)), inst.UnixId)
+		if e != nil {
+			err = e
+			return
+		}
+		defer nbdRelay.Close()
+
+		err = qmp.MirrorStart(inst.Id,
+			paths.GetMigrateNbdSockPath(inst.Id), dskIds)
+		if err != nil {
+			return
+		}
+
+		err = qmp.MirrorWaitReady(inst.Id, dskIds, timeout)
+		if err != nil {
+			return
+		}
+	}
+
+	err = qmp.MigrateStart(inst.Id, paths.GetMigrateSockPath(inst.Id))
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	for {
+		info, e := qmp.MigrateStatus(inst.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		switch info.Status {
+		case qmp.MigratePreSwitch:
+			if len(dskIds) > 0 {
+				err = qmp.MirrorCancel(inst.Id, dskIds, false)
+				if err != nil {
+					return
+				}
+			}
+
+			err = qmp.MigrateContinue(inst.Id)
+			if err != nil {
+				return
+			}
+			break
+		case qmp.MigrateCompleted:
+			return
+		case qmp.MigrateFailed, qmp.MigrateCancelled:
+			err = &errortypes.ExecError{
+				errors.Newf("deploy: Migration %s %s",
+					info.Status, info.ErrorDesc),
+			}
+			return
+		}
+
+		if time.Since(start) > timeout {
+			err = &errortypes.TimeoutError{
+				errors.New("deploy: Migration timeout"),
+			}
+			return
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (m *Migrations) sendOffline(inst *instance.Instance) (err error) {
+
+	ports := map[primitive.ObjectID]int{}
+	for _, dsk := range inst.MigrateDisks {
+		ports[dsk.Id] = dsk.Port
+	}
+
+	if len(inst.Virt.Disks) > 0 {
+		err = utils.ExistsMkdir(paths.GetCacheDir(inst.Id), 0755)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, dsk := range inst.Virt.Disks {
+		port := ports[dsk.Id]
+		if port == 0 {
+			err = &errortypes.NotFoundError{
+				errors.Newf("deploy: Missing migrate disk port for '%s'",
+					dsk.Id.Hex()),
+			}
+			return
+		}
+
+		sockPath := paths.GetMigrateDiskSockPath(inst.Id, dsk.Id)
+
+		diskRelay, e := newRelayUnix(sockPath,
+			net.JoinHostPort(inst.MigrateAddress, strconv.Itoa(port)), 0)
+		if e != nil {
+			err = e
+			return
+		}
+
+		err = qemu.CopyOfflineDisk(dsk.Path, sockPath)
+		diskRelay.Close()
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (m *Migrations) release(db *database.Database,
+	inst *instance.Instance) (err error) {
+
+	err = qemu.ReleaseMigrated(db, inst.Virt)
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"instance_id": inst.Id.Hex(),
+		"node_id":     inst.MigrateNode.Hex(),
+	}).Info("deploy: Instance migrated to node")
+
+	return
+}
+
+func (m *Migrations) complete(db *database.Database,
+	inst *instance.Instance) (err error) {
+
+	nde, err := node.Get(db, inst.MigrateNode)
+	if err != nil {
+		return
+	}
+
+	updated, err := instance.CompleteMigrate(db, inst, nde)
+	if err != nil {
+		return
+	}
+
+	if !updated {
+		cur, e := instance.Get(db, inst.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if cur.Node != nde.Id {
+			err = &errortypes.ExecError{
+				errors.New("deploy: Instance migration state changed"),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (m *Migrations) send(inst *instance.Instance) {
+	acquired, lockId := instancesLock.LockOpenTimeout(
+		inst.Id.Hex(), migrateLockTimeout)
+	if !acquired {
+		return
+	}
+
+	go func() {
+		defer func() {
+			instancesLock.Unlock(inst.Id.Hex(), lockId)
+		}()
+
+		db := database.GetDatabase()
+		defer db.Close()
+
+		updated, err := instance.UpdateMigrate(db, inst.Id,
+			instance.MigrateReady, bson.M{
+				"migrate_state": instance.MigrateMigrating,
+			})
+		if err != nil || !updated {
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"instance_id": inst.Id.Hex(),
+			"node_id":     inst.MigrateNode.Hex(),
+		}).Info("deploy: Starting instance migration")
+
+		event.PublishDispatch(db, "instance.change")
+
+		stop := m.touch(inst.Id, instance.MigrateMigrating)
+		if inst.MigrateOffline {
+			err = m.sendOffline(inst)
+		} else {
+			err = m.sendMigrate(db, inst)
+		}
+		stop()
+		if err != nil {
+			if !inst.MigrateOffline {
+				_ = qmp.MigrateCancel(inst.Id)
+				_ = qmp.MirrorCancel(inst.Id, getMigrateDisks(inst), true)
+			}
+			m.fail(db, inst, instance.MigrateMigrating, err)
+			return
+		}
+
+		err = m.complete(db, inst)
+		if err != nil {
+			m.fail(db, inst, instance.MigrateMigrating, err)
+			return
+		}
+
+		event.PublishDispatch(db, "instance.change")
+
+		err = m.release(db, inst)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       err,
+			}).Error("deploy: Failed to release migrated instance")
+			return
+		}
+	}()
+}
+
+// recover handles a migration interrupted by a restart of the source
+// node. A completed transfer is committed, otherwise the migration is
+// cancelled and the instance continues on this node. An interrupted disk
+// copy of an offline migration cannot be resumed.
+func (m *Migrations) recover(inst *instance.Instance) {
+	acquired, lockId := instancesLock.LockOpen(inst.Id.Hex())
+	if !acquired {
+		return
+	}
+
+	go func() {
+		defer func() {
+			instancesLock.Unlock(inst.Id.Hex(), lockId)
+		}()
+
+		db := database.GetDatabase()
+		defer db.Close()
+
+		if inst.MigrateOffline {
+			m.fail(db, inst, instance.MigrateMigrating,
+				&errortypes.ExecError{
+					errors.New("deploy: Source node disk copy interrupted"),
+				})
+			return
+		}
+
+		info, err := qmp.MigrateStatus(inst.Id)
+		if err == nil && info.Status == qmp.MigrateCompleted {
+			err = m.complete(db, inst)
+			if err != nil {
+				m.fail(db, inst, instance.MigrateMigrating, err)
+				return
+			}
+
+			event.PublishDispatch(db, "instance.change")
+
+			err = m.release(db, inst)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"instance_id": inst.Id.Hex(),
+					"error":       err,
+				}).Error("deploy: Failed to release migrated instance")
+			}
+			return
+		}
+
+		_ = qmp.MigrateCancel(inst.Id)
+		_ = qmp.MirrorCancel(inst.Id, getMigrateDisks(inst), true)
+
+		m.fail(db, inst, instance.MigrateMigrating,
+			&errortypes.ExecError{
+				errors.New("deploy: Source node migration interrupted"),
+			})
+	}()
+}
+
+func (m *Migrations) receiveOnline(db *database.Database,
+	inst *instance.Instance, addr string, allowed set.Set) (
+	doc bson.M, closers []migrateCloser, err error) {
+
+	closers = []migrateCloser{}
+
+	err = qemu.PowerOnIncoming(db, inst, inst.Virt)
+	if err != nil {
+		return
+	}
+
+	dskIds := getMigrateDisks(inst)
+	nbdPort := 0
+
+	if len(dskIds) > 0 {
+		err = qmp.NbdStart(inst.Id,
+			paths.GetMigrateNbdSockPath(inst.Id), dskIds)
+		if err != nil {
+			return
+		}
+
+		nbdRelay, e := newRelayTcp(addr,
+			paths.GetMigrateNbdSockPath(inst.Id), allowed)
+		if e != nil {
+			err = e
+			return
+		}
+		closers = append(closers, nbdRelay)
+		nbdPort = nbdRelay.Port()
+	}
+
+	err = qmp.MigrateIncoming(inst.Id, paths.GetMigrateSockPath(inst.Id))
+	if err != nil {
+		return
+	}
+
+	migrateRelay, err := newRelayTcp(addr,
+		paths.GetMigrateSockPath(inst.Id), allowed)
+	if err != nil {
+		return
+	}
+	closers = append(closers, migrateRelay)
+
+	doc = bson.M{
+		"migrate_port":     migrateRelay.Port(),
+		"migrate_nbd_port": nbdPort,
+	}
+
+	return
+}
+
+func (m *Migrations) receiveOffline(inst *instance.Instance,
+	addr string, allowed set.Set) (
+	doc bson.M, closers []migrateCloser, err error) {
+
+	closers = []migrateCloser{}
+
+	sizes := map[primitive.ObjectID]int64{}
+	for _, dsk := range inst.MigrateDisks {
+		sizes[dsk.Id] = dsk.Size
+	}
+
+	exports, err := qemu.ExportOfflineDisks(inst, inst.Virt)
+	if err != nil {
+		return
+	}
+	for _, export := range exports {
+		closers = append(closers, export)
+	}
+
+	migrateDisks := []*instance.MigrateDisk{}
+	for _, export := range exports {
+		diskRelay, e := newRelayTcp(addr, export.Socket, allowed)
+		if e != nil {
+			err = e
+			return
+		}
+		closers = append(closers, diskRelay)
+
+		migrateDisks = append(migrateDisks, &instance.MigrateDisk{
+			Id:   export.Disk,
+			Size: sizes[export.Disk],
+			Port: diskRelay.Port(),
+		})
+	}
+
+	doc = bson.M{
+		"migrate_disks": migrateDisks,
+	}
+
+	return
+}
+
+func (m *Migrations) receivePrepare(db *database.Database,
+	inst *instance.Instance) (closers []migrateCloser, err error) {
+
+	closers = []migrateCloser{}
+
+	srcNde, err := node.Get(db, inst.Node)
+	if err != nil {
+		return
+	}
+
+	addr := getMigrateAddr(m.stat.Node())
+	if addr == "" {
+		err = &errortypes.NotFoundError{
+			errors.New("deploy: Failed to find node migration address"),
+		}
+		return
+	}
+	allowed := getMigrateAllowed(srcNde)
+
+	err = loadMigrateVirt(db, inst)
+	if err != nil {
+		return
+	}
+
+	var doc bson.M
+	var cls []migrateCloser
+	if inst.MigrateOffline {
+		doc, cls, err = m.receiveOffline(inst, addr, allowed)
+	} else {
+		doc, cls, err = m.receiveOnline(db, inst, addr, allowed)
+	}
+	closers = append(closers, cls...)
+	if err != nil {
+		return
+	}
+
+	doc["migrate_state"] = instance.MigrateReady
+	doc["migrate_address"] = addr
+
+	updated, err := instance.UpdateMigrate(db, inst.Id,
+		instance.MigratePrepared, doc)
+	if err != nil {
+		return
+	}
+
+	if !updated {
+		err = &errortypes.ExecError{
+			errors.New("deploy: Instance migration state changed"),
+		}
+		return
+	}
+
+	return
+}
+
+// receiveWait waits for the source node to finish the transfer. If the
+// source node is lost after the incoming virtual machine has completed
+// the destination node takes ownership.
+func (m *Migrations) receiveWait(db *database.Database,
+	inst *instance.Instance) (err error) {
+
+	completed := time.Time{}
+
+	for {
+		time.Sleep(1 * time.Second)
+
+		cur, e := instance.Get(db, inst.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		switch cur.MigrateState {
+		case instance.MigrateComplete:
+			return
+		case instance.MigrateFailed:
+			err = &errortypes.ExecError{
+				errors.Newf("deploy: Source node migration failed %s",
+					cur.MigrateError),
+			}
+			return
+		case instance.MigrateReady, instance.MigrateMigrating:
+			break
+		default:
+			err = &errortypes.ExecError{
+				errors.Newf("deploy: Unexpected migration state %s",
+					cur.MigrateState),
+			}
+			return
+		}
+
+		if inst.MigrateOffline {
+			if cur.IsMigrateExpired() {
+				err = &errortypes.TimeoutError{
+					errors.New("deploy: Migration timeout"),
+				}
+				return
+			}
+			continue
+		}
+
+		info, e := qmp.MigrateStatus(inst.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if info.Status == qmp.MigrateFailed {
+			err = &errortypes.ExecError{
+				errors.Newf("deploy: Incoming migration failed %s",
+					info.ErrorDesc),
+			}
+			return
+		}
+
+		if info.Status == qmp.MigrateCompleted {
+			if completed.IsZero() {
+				completed = time.Now()
+			} else if time.Since(completed) > 30*time.Second {
+				err = m.complete(db, cur)
+				if err != nil {
+					return
+				}
+				return
+			}
+		} else if cur.IsMigrateExpired() {
+			err = &errortypes.TimeoutError{
+				errors.New("deploy: Migration timeout"),
+			}
+			return
+		}
+	}
+}
+
+func (m *Migrations) finish(db *database.Database,
+	inst *instance.Instance) (err error) {
+
+	if inst.Virt == nil {
+		err = loadMigrateVirt(db, inst)
+		if err != nil {
+			return
+		}
+	}
+
+	if inst.MigrateOffline {
+		err = qemu.FinishOffline(inst.Virt)
+	} else {
+		err = qemu.FinishIncoming(db, inst, inst.Virt)
+	}
+	if err != nil {
+		return
+	}
+
+	err = instance.ClearMigrate(db, inst.Id, instance.MigrateComplete)
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"instance_id": inst.Id.Hex(),
+	}).Info("deploy: Instance migration complete")
+
+	event.PublishDispatch(db, "instance.change")
+
+	return
+}
+
+func (m *Migrations) abort(db *database.Database,
+	inst *instance.Instance) {
+
+	cur, err := instance.Get(db, inst.Id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"instance_id": inst.Id.Hex(),
+			"error":       err,
+		}).Error("deploy: Failed to get migrating instance")
+		return
+	}
+
+	if cur.Node == m.stat.Node().Id {
+		return
+	}
+
+	if inst.Virt == nil {
+		err = loadMigrateVirt(db, inst)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       err,
+			}).Error("deploy: Failed to load migrating instance")
+			return
+		}
+	}
+
+	if inst.MigrateOffline {
+		err = qemu.AbortOffline(inst.Virt)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       err,
+			}).Error("deploy: Failed to abort offline instance migration")
+			return
+		}
+	} else {
+		unitName := paths.GetUnitName(inst.Id)
+		unitState, _, e := systemd.GetState(unitName)
+		if e == nil && unitState != "" {
+			err = qemu.AbortIncoming(db, inst.Virt)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"instance_id": inst.Id.Hex(),
+					"error":       err,
+				}).Error("deploy: Failed to abort incoming instance")
+				return
+			}
+		}
+	}
+
+	err = instance.AbortMigrate(db, inst.Id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"instance_id": inst.Id.Hex(),
+			"error":       err,
+		}).Error("deploy: Failed to abort instance migration")
+		return
+	}
+
+	event.PublishDispatch(db, "instance.change")
+}
+
+func (m *Migrations) receive(inst *instance.Instance) {
+	acquired, lockId := instancesLock.LockOpenTimeout(
+		inst.Id.Hex(), migrateLockTimeout)
+	if !acquired {
+		return
+	}
+
+	go func() {
+		defer func() {
+			instancesLock.Unlock(inst.Id.Hex(), lockId)
+		}()
+
+		db := database.GetDatabase()
+		defer db.Close()
+
+		logrus.WithFields(logrus.Fields{
+			"instance_id": inst.Id.Hex(),
+			"node_id":     inst.Node.Hex(),
+		}).Info("deploy: Preparing incoming instance migration")
+
+		closers, err := m.receivePrepare(db, inst)
+		defer func() {
+			for _, clsr := range closers {
+				clsr.Close()
+			}
+		}()
+		if err != nil {
+			m.fail(db, inst, instance.MigratePrepared, err)
+			m.abort(db, inst)
+			return
+		}
+
+		event.PublishDispatch(db, "instance.change")
+
+		err = m.receiveWait(db, inst)
+		if err != nil {
+			cur, e := instance.Get(db, inst.Id)
+			if e == nil && cur.IsMigrating() &&
+				cur.MigrateState != instance.MigrateComplete {
+
+				m.fail(db, inst, cur.MigrateState, err)
+			}
+			m.abort(db, inst)
+			return
+		}
+
+		err = m.finish(db, inst)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       err,
+			}).Error("deploy: Failed to finish incoming instance migration")
+			return
+		}
+	}()
+}
+
+// resume handles migrations on the destination node that are not active
+// in this process such as after a restart.
+func (m *Migrations) resume(inst *instance.Instance) {
+	acquired, lockId := instancesLock.LockOpen(inst.Id.Hex())
+	if !acquired {
+		return
+	}
+
+	go func() {
+		defer func() {
+			instancesLock.Unlock(inst.Id.Hex(), lockId)
+		}()
+
+		db := database.GetDatabase()
+		defer db.Close()
+
+		switch inst.MigrateState {
+		case instance.MigrateComplete:
+			err := m.finish(db, inst)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"instance_id": inst.Id.Hex(),
+					"error":       err,
+				}).Error("deploy: Failed to finish incoming " +
+					"instance migration")
+			}
+			return
+		case instance.MigrateFailed:
+			m.abort(db, inst)
+			return
+		case instance.MigrateReady, instance.MigrateMigrating:
+			m.fail(db, inst, inst.MigrateState, &errortypes.ExecError{
+				errors.New("deploy: Destination node migration interrupted"),
+			})
+			m.abort(db, inst)
+			return
+		}
+	}()
+}
+
+func (m *Migrations) Deploy(db *database.Database) (err error) {
+	ndeId := m.stat.Node().Id
+
+	for _, inst := range m.stat.Instances() {
+		if inst.MigrateNode.IsZero() || inst.MigrateNode == ndeId ||
+			!inst.IsMigrating() {
+
+			continue
+		}
+
+		if inst.IsMigrateExpired() &&
+			inst.MigrateState != instance.MigrateMigrating {
+
+			m.fail(db, inst, inst.MigrateState, &errortypes.TimeoutError{
+				errors.New("deploy: Migration timeout"),
+			})
+			continue
+		}
+
+		curVirt := m.stat.GetVirt(inst.Id)
+
+		switch inst.MigrateState {
+		case instance.MigratePending:
+			if !inst.MigrateOffline &&
+				(curVirt == nil || curVirt.State != vm.Running) {
+
+				m.fail(db, inst, inst.MigrateState,
+					&errortypes.ExecError{
+						errors.New("deploy: Instance not running"),
+					})
+				continue
+			}
+
+			m.prepare(inst)
+			break
+		case instance.MigrateReady:
+			m.send(inst)
+			break
+		case instance.MigrateMigrating:
+			if !instancesLock.Locked(inst.Id.Hex()) {
+				m.recover(inst)
+			}
+			break
+		}
+	}
+
+	for _, inst := range m.stat.Migrations() {
+		if inst.MigrateState != instance.MigrateComplete &&
+			inst.Node == ndeId {
+
+			continue
+		}
+
+		switch inst.MigrateState {
+		case instance.MigratePending:
+			if inst.IsMigrateExpired() {
+				m.fail(db, inst, inst.MigrateState,
+					&errortypes.TimeoutError{
+						errors.New("deploy: Migration timeout"),
+					})
+			}
+			break
+		case instance.MigratePrepared:
+			m.receive(inst)
+			break
+		case instance.MigrateReady, instance.MigrateMigrating,
+			instance.MigrateComplete, instance.MigrateFailed:
+
+			if !instancesLock.Locked(inst.Id.Hex()) {
+				m.resume(inst)
+			}
+			break
+		}
+	}
+
+	return
+}
+
+func NewMigrations(stat *state.State) *Migrations {
+	return &Migrations{
+		stat: stat,
+	}
+}
@@ -3,6 +3,7 @@ package deploy
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,12 +11,18 @@ import (
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/dnss"
 	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/instance"
 	"github.com/pritunl/pritunl-cloud/interfaces"
+	"github.com/pritunl/pritunl-cloud/iproute"
 	"github.com/pritunl/pritunl-cloud/node"
 	"github.com/pritunl/pritunl-cloud/state"
+	"github.com/pritunl/pritunl-cloud/store"
 	"github.com/pritunl/pritunl-cloud/utils"
 	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -26,6 +33,202 @@ type Namespace struct {
 	stat *state.State
 }
 
+type peerIface struct {
+	Iface    string
+	VlanId   int
+	Network  string
+	Network6 string
+}
+
+// peers links the instance namespace to each peered VPC with a vlan
+// interface on the internal interface and routes the peer networks to it.
+// Return traffic is routed by the peer instance namespace, traffic is
+// bridged to the instance and filtered by the instance firewall.
+func (n *Namespace) peers(inst *instance.Instance) (err error) {
+	namespace := vm.GetNamespace(inst.Id, 0)
+	internalIface := vm.GetIfaceInternal(inst.Id, 0)
+
+	newPeers := []*peerIface{}
+	newIfaces := set.NewSet()
+	keys := []string{}
+
+	for _, vc := range n.stat.VpcPeers(inst.Vpc) {
+		network, e := vc.GetNetwork()
+		if e != nil {
+			err = e
+			return
+		}
+
+		network6, e := vc.GetNetwork6()
+		if e != nil {
+			err = e
+			return
+		}
+
+		peer := &peerIface{
+			Iface:    vm.GetIfacePeer(inst.Id, vc.Id),
+			VlanId:   vc.VpcId,
+			Network:  network.String(),
+			Network6: network6.String(),
+		}
+
+		newPeers = append(newPeers, peer)
+		newIfaces.Add(peer.Iface)
+		keys = append(keys, fmt.Sprintf("%s-%d-%s-%s", peer.Iface,
+			peer.VlanId, peer.Network, peer.Network6))
+	}
+
+	key := strings.Join(keys, ",")
+
+	peersStore, ok := store.GetPeers(inst.Id)
+	if ok && peersStore.Key == key {
+		return
+	}
+
+	ifaces, err := iproute.IfaceGetAll(namespace)
+	if err != nil {
+		return
+	}
+
+	internalExists := false
+	curIfaces := set.NewSet()
+	for _, iface := range ifaces {
+		if iface.Name == internalIface {
+			internalExists = true
+		} else if len(iface.Name) == 14 && strings.HasPrefix(
+			iface.Name, "y") {
+
+			curIfaces.Add(iface.Name)
+		}
+	}
+
+	if !internalExists {
+		return
+	}
+
+	remIfaces := curIfaces.Copy()
+	remIfaces.Subtract(newIfaces)
+	for ifaceInf := range remIfaces.Iter() {
+		iface := ifaceInf.(string)
+
+		logrus.WithFields(logrus.Fields{
+			"instance_id": inst.Id.Hex(),
+			"namespace":   namespace,
+			"interface":   iface,
+		}).Info("deploy: Removing instance vpc peer")
+
+		_, err = utils.ExecCombinedOutputLogged(
+			[]string{
+				"Cannot find device",
+			},
+			"ip", "netns", "exec", namespace,
+			"ip", "link", "del", iface,
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, peer := range newPeers {
+		if !curIfaces.Contains(peer.Iface) {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"namespace":   namespace,
+				"interface":   peer.Iface,
+				"network":     peer.Network,
+			}).Info("deploy: Adding instance vpc peer")
+
+			_, err = utils.ExecCombinedOutputLogged(
+				[]string{"File exists"},
+				"ip", "netns", "exec", namespace,
+				"ip", "link",
+				"add", "link", internalIface,
+				"name", peer.Iface,
+				"type", "vlan",
+				"id", strconv.Itoa(peer.VlanId),
+			)
+			if err != nil {
+				return
+			}
+		}
+
+		_, err = utils.ExecCombinedOutputLogged(
+			nil,
+			"ip", "netns", "exec", namespace,
+			"ip", "link",
+			"set", "dev", peer.Iface, "up",
+		)
+		if err != nil {
+			return
+		}
+
+		_, err = utils.ExecCombinedOutputLogged(
+			nil,
+			"ip", "netns", "exec", namespace,
+			"ip", "route",
+			"replace", peer.Network,
+			"dev", peer.Iface,
+			"metric", "97",
+		)
+		if err != nil {
+			return
+		}
+
+		_, err = utils.ExecCombinedOutputLogged(
+			nil,
+			"ip", "netns", "exec", namespace,
+			"ip", "-6", "route",
+			"replace", peer.Network6,
+			"dev", peer.Iface,
+			"metric", "97",
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	store.SetPeers(inst.Id, key)
+
+	return
+}
+
+// dnsServer returns the built-in dns server config for the instance
+// namespace, the server listens on the VPC gateway addresses.
+func (n *Namespace) dnsServer(inst *instance.Instance) (
+	conf *dnss.Config, err error) {
+
+	vc := n.stat.Vpc(inst.Vpc)
+	if vc == nil || !vc.DnsServer || len(inst.PrivateIps) == 0 {
+		return
+	}
+
+	addr := net.ParseIP(strings.Split(inst.PrivateIps[0], "/")[0])
+	if addr == nil {
+		return
+	}
+
+	gateway, err := vc.GetGateway()
+	if err != nil {
+		return
+	}
+
+	conf = &dnss.Config{
+		Namespace:    vm.GetNamespace(inst.Id, 0),
+		Organization: vc.Organization,
+		Vpcs:         []primitive.ObjectID{vc.Id},
+		Addresses: []net.IP{
+			gateway,
+			vc.GetGatewayIp6(addr),
+		},
+	}
+
+	for _, peerVc := range n.stat.VpcPeers(inst.Vpc) {
+		conf.Vpcs = append(conf.Vpcs, peerVc.Id)
+	}
+
+	return
+}
+
 func (n *Namespace) Deploy() (err error) {
 	instances := n.stat.Instances()
 	namespaces := n.stat.Namespaces()
@@ -92,6 +295,12 @@ func (n *Namespace) Deploy() (err error) {
 
 	firstRun = true
 
+	for _, inst := range n.stat.Migrations() {
+		if inst.IsMigrating() {
+			curNamespaces.Add(vm.GetNamespace(inst.Id, 0))
+		}
+	}
+
 	for _, iface := range ifaces {
 		if len(iface) != 14 || !strings.HasPrefix(iface, "v") {
 			continue
@@ -126,6 +335,40 @@ func (n *Namespace) Deploy() (err error) {
 		}
 	}
 
+	namespacesSet := set.NewSet()
+	for _, namespace := range namespaces {
+		namespacesSet.Add(namespace)
+	}
+
+	dnsConfs := []*dnss.Config{}
+	for _, inst := range instances {
+		if !inst.IsActive() || !namespacesSet.Contains(
+			vm.GetNamespace(inst.Id, 0)) {
+
+			continue
+		}
+
+		e := n.peers(inst)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       e,
+			}).Error("deploy: Failed to deploy instance vpc peers")
+		}
+
+		dnsConf, e := n.dnsServer(inst)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"instance_id": inst.Id.Hex(),
+				"error":       e,
+			}).Error("deploy: Failed to deploy instance dns server")
+		} else if dnsConf != nil {
+			dnsConfs = append(dnsConfs, dnsConf)
+		}
+	}
+
+	dnss.Sync(dnsConfs)
+
 	running := n.stat.Running()
 	for _, name := range running {
 		if len(name) != 27 || !strings.HasPrefix(name, "dhclient-i") {
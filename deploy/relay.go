@@ -0,0 +1,185 @@
+package deploy
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+// relay forwards connections between the migration unix sockets of
+// qemu and the tcp connection between the source and destination node.
+type relay struct {
+	listener net.Listener
+	network  string
+	address  string
+	allowed  set.Set
+	conns    set.Set
+	lock     sync.Mutex
+}
+
+func (r *relay) Port() int {
+	addr, ok := r.listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0
+	}
+	return addr.Port
+}
+
+func (r *relay) pipe(conn net.Conn) {
+	defer conn.Close()
+
+	if r.allowed != nil {
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		if err != nil || !r.allowed.Contains(host) {
+			logrus.WithFields(logrus.Fields{
+				"remote_address": conn.RemoteAddr().String(),
+			}).Warn("deploy: Rejected migration connection")
+			return
+		}
+	}
+
+	dest, err := net.Dial(r.network, r.address)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"address": r.address,
+			"error":   err,
+		}).Error("deploy: Failed to connect migration relay")
+		return
+	}
+	defer dest.Close()
+
+	r.lock.Lock()
+	r.conns.Add(conn)
+	r.conns.Add(dest)
+	r.lock.Unlock()
+
+	waiter := sync.WaitGroup{}
+	waiter.Add(2)
+
+	go func() {
+		defer waiter.Done()
+		_, _ = io.Copy(dest, conn)
+		if tcpConn, ok := dest.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		} else if unixConn, ok := dest.(*net.UnixConn); ok {
+			_ = unixConn.CloseWrite()
+		}
+	}()
+
+	go func() {
+		defer waiter.Done()
+		_, _ = io.Copy(conn, dest)
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		} else if unixConn, ok := conn.(*net.UnixConn); ok {
+			_ = unixConn.CloseWrite()
+		}
+	}()
+
+	waiter.Wait()
+
+	r.lock.Lock()
+	r.conns.Remove(conn)
+	r.conns.Remove(dest)
+	r.lock.Unlock()
+}
+
+func (r *relay) run() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go r.pipe(conn)
+	}
+}
+
+func (r *relay) Close() {
+	_ = r.listener.Close()
+
+	r.lock.Lock()
+	for connInf := range r.conns.Iter() {
+		_ = connInf.(net.Conn).Close()
+	}
+	r.lock.Unlock()
+}
+
+// newRelayTcp listens on the address and forwards connections from the
+// allowed hosts to the unix socket.
+func newRelayTcp(listenAddr, sockPath string, allowed set.Set) (
+	r *relay, err error) {
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenAddr, "0"))
+	if err != nil {
+		err = &errortypes.NetworkError{
+			errors.Wrap(err, "deploy: Failed to listen on migration port"),
+		}
+		return
+	}
+
+	r = &relay{
+		listener: listener,
+		network:  "unix",
+		address:  sockPath,
+		allowed:  allowed,
+		conns:    set.NewSet(),
+	}
+
+	go r.run()
+
+	return
+}
+
+// newRelayUnix listens on the unix socket and forwards connections to
+// the tcp address. The socket is only accessible to the owner uid.
+func newRelayUnix(sockPath, destAddr string, uid int) (
+	r *relay, err error) {
+
+	_ = os.Remove(sockPath)
+
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		err = &errortypes.NetworkError{
+			errors.Wrap(err, "deploy: Failed to listen on migration socket"),
+		}
+		return
+	}
+
+	err = os.Chmod(sockPath, 0600)
+	if err != nil {
+		_ = listener.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "deploy: Failed to chmod migration socket"),
+		}
+		return
+	}
+
+	if uid != 0 {
+		err = os.Chown(sockPath, uid, 0)
+		if err != nil {
+			_ = listener.Close()
+			err = &errortypes.WriteError{
+				errors.Wrap(err, "deploy: Failed to chown migration socket"),
+			}
+			return
+		}
+	}
+
+	r = &relay{
+		listener: listener,
+		network:  "tcp",
+		address:  destAddr,
+		conns:    set.NewSet(),
+	}
+
+	go r.run()
+
+	return
+}
@@ -404,6 +404,25 @@ func Start(db *database.Database, virt *vm.VirtualMachine) (err error) {
 	addr6 := vc.GetIp6(addr)
 	gatewayAddr6 := vc.GetGatewayIp6(addr)
 
+	dnsServers := []string{
+		settings.Hypervisor.DnsServerPrimary,
+		settings.Hypervisor.DnsServerSecondary,
+	}
+	dnsServers6 := []string{
+		settings.Hypervisor.DnsServerPrimary6,
+		settings.Hypervisor.DnsServerSecondary6,
+	}
+	if vc.DnsServer {
+		dnsServers = []string{
+			gatewayAddr.String(),
+			settings.Hypervisor.DnsServerPrimary,
+		}
+		dnsServers6 = []string{
+			gatewayAddr6.String(),
+			settings.Hypervisor.DnsServerPrimary6,
+		}
+	}
+
 	jumboFramesExternal := node.Self.JumboFrames
 	jumboFramesInternal := node.Self.JumboFrames ||
 		node.Self.JumboFramesInternal
@@ -427,41 +446,32 @@ func Start(db *database.Database, virt *vm.VirtualMachine) (err error) {
 	}
 
 	server4 := &Server4{
-		Iface:     "br0",
-		ClientIp:  addr.String(),
-		GatewayIp: gatewayAddr.String(),
-		PrefixLen: cidr,
-		DnsServers: []string{
-			settings.Hypervisor.DnsServerPrimary,
-			settings.Hypervisor.DnsServerSecondary,
-		},
-		Mtu:      mtu,
-		Lifetime: 60,
+		Iface:      "br0",
+		ClientIp:   addr.String(),
+		GatewayIp:  gatewayAddr.String(),
+		PrefixLen:  cidr,
+		DnsServers: dnsServers,
+		Mtu:        mtu,
+		Lifetime:   60,
 	}
 	server6 := &Server6{
-		Iface:     "br0",
-		ClientIp:  addr6.String(),
-		GatewayIp: gatewayAddr6.String(),
-		PrefixLen: 64,
-		DnsServers: []string{
-			settings.Hypervisor.DnsServerPrimary6,
-			settings.Hypervisor.DnsServerSecondary6,
-		},
-		Mtu:      mtu,
-		Lifetime: 60,
+		Iface:      "br0",
+		ClientIp:   addr6.String(),
+		GatewayIp:  gatewayAddr6.String(),
+		PrefixLen:  64,
+		DnsServers: dnsServers6,
+		Mtu:        mtu,
+		Lifetime:   60,
 	}
 	serverNdp := &ServerNdp{
-		Iface:     "br0",
-		ClientIp:  addr6.String(),
-		GatewayIp: gatewayAddr6.String(),
-		PrefixLen: 64,
-		DnsServers: []string{
-			settings.Hypervisor.DnsServerPrimary6,
-			settings.Hypervisor.DnsServerSecondary6,
-		},
-		Mtu:      mtu,
-		Lifetime: 60,
-		Delay:    3,
+		Iface:      "br0",
+		ClientIp:   addr6.String(),
+		GatewayIp:  gatewayAddr6.String(),
+		PrefixLen:  64,
+		DnsServers: dnsServers6,
+		Mtu:        mtu,
+		Lifetime:   60,
+		Delay:      3,
 	}
 
 	err = UpdateEbtables(virt.Id, namespace)
@@ -11,4 +11,6 @@ const (
 
 	Qcow2 = "qcow2"
 	Lvm   = "lvm"
+
+	MaxSnapshotRetain = 100
 )
@@ -8,7 +8,9 @@ import (
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/cron"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/event"
@@ -21,28 +23,33 @@ import (
 )
 
 type Disk struct {
-	Id               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name             string             `bson:"name" json:"name"`
-	Comment          string             `bson:"comment" json:"comment"`
-	State            string             `bson:"state" json:"state"`
-	Type             string             `bson:"type" json:"type"`
-	Node             primitive.ObjectID `bson:"node,omitempty" json:"node"`
-	Pool             primitive.ObjectID `bson:"pool,omitempty" json:"pool"`
-	Organization     primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
-	Instance         primitive.ObjectID `bson:"instance,omitempty" json:"instance"`
-	SourceInstance   primitive.ObjectID `bson:"source_instance,omitempty" json:"source_instance"`
-	DeleteProtection bool               `bson:"delete_protection" json:"delete_protection"`
-	Image            primitive.ObjectID `bson:"image,omitempty" json:"image"`
-	RestoreImage     primitive.ObjectID `bson:"restore_image,omitempty" json:"restore_image"`
-	Backing          bool               `bson:"backing" json:"backing"`
-	BackingImage     string             `bson:"backing_image" json:"backing_image"`
-	Index            string             `bson:"index" json:"index"`
-	Size             int                `bson:"size" json:"size"`
-	NewSize          int                `bson:"new_size" json:"new_size"`
-	Backup           bool               `bson:"backup" json:"backup"`
-	LastBackup       time.Time          `bson:"last_backup" json:"last_backup"`
-	curIndex         string             `bson:"-" json:"-"`
-	curInstance      primitive.ObjectID `bson:"-" json:"-"`
+	Id               primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name             string               `bson:"name" json:"name"`
+	Comment          string               `bson:"comment" json:"comment"`
+	State            string               `bson:"state" json:"state"`
+	Type             string               `bson:"type" json:"type"`
+	Node             primitive.ObjectID   `bson:"node,omitempty" json:"node"`
+	Pool             primitive.ObjectID   `bson:"pool,omitempty" json:"pool"`
+	Organization     primitive.ObjectID   `bson:"organization,omitempty" json:"organization"`
+	Instance         primitive.ObjectID   `bson:"instance,omitempty" json:"instance"`
+	SourceInstance   primitive.ObjectID   `bson:"source_instance,omitempty" json:"source_instance"`
+	DeleteProtection bool                 `bson:"delete_protection" json:"delete_protection"`
+	Image            primitive.ObjectID   `bson:"image,omitempty" json:"image"`
+	RestoreImage     primitive.ObjectID   `bson:"restore_image,omitempty" json:"restore_image"`
+	Backing          bool                 `bson:"backing" json:"backing"`
+	BackingImage     string               `bson:"backing_image" json:"backing_image"`
+	Index            string               `bson:"index" json:"index"`
+	Size             int                  `bson:"size" json:"size"`
+	NewSize          int                  `bson:"new_size" json:"new_size"`
+	Backup           bool                 `bson:"backup" json:"backup"`
+	LastBackup       time.Time            `bson:"last_backup" json:"last_backup"`
+	BackupChain      []primitive.ObjectID `bson:"backup_chain" json:"backup_chain"`
+	SnapshotSchedule string               `bson:"snapshot_schedule" json:"snapshot_schedule"`
+	SnapshotRetain   int                  `bson:"snapshot_retain" json:"snapshot_retain"`
+	SnapshotMaxAge   int                  `bson:"snapshot_max_age" json:"snapshot_max_age"`
+	LastSnapshot     time.Time            `bson:"last_snapshot" json:"last_snapshot"`
+	curIndex         string               `bson:"-" json:"-"`
+	curInstance      primitive.ObjectID   `bson:"-" json:"-"`
 }
 
 func (d *Disk) Validate(db *database.Database) (
@@ -118,6 +125,42 @@ func (d *Disk) Validate(db *database.Database) (
 		return
 	}
 
+	d.SnapshotSchedule = strings.TrimSpace(d.SnapshotSchedule)
+	if d.SnapshotSchedule != "" {
+		if d.Type != Qcow2 {
+			errData = &errortypes.ErrorData{
+				Error:   "snapshot_schedule_unsupported",
+				Message: "Disk type does not support snapshots",
+			}
+			return
+		}
+
+		_, e := cron.Parse(d.SnapshotSchedule)
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "snapshot_schedule_invalid",
+				Message: "Snapshot schedule invalid",
+			}
+			return
+		}
+	}
+
+	if d.SnapshotRetain < 0 || d.SnapshotRetain > MaxSnapshotRetain {
+		errData = &errortypes.ErrorData{
+			Error:   "snapshot_retain_invalid",
+			Message: "Snapshot retention count invalid",
+		}
+		return
+	}
+
+	if d.SnapshotMaxAge < 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "snapshot_max_age_invalid",
+			Message: "Snapshot maximum age invalid",
+		}
+		return
+	}
+
 	if d.State == Restore && d.RestoreImage.IsZero() {
 		errData = &errortypes.ErrorData{
 			Error:   "restore_missing_image",
@@ -195,6 +238,60 @@ func (d *Disk) Validate(db *database.Database) (
 	return
 }
 
+func (d *Disk) SnapshotDue(now time.Time) bool {
+	if d.SnapshotSchedule == "" {
+		return false
+	}
+
+	sched, err := cron.Parse(d.SnapshotSchedule)
+	if err != nil {
+		return false
+	}
+
+	last := d.LastSnapshot
+	if last.IsZero() {
+		last = now.Add(-1 * time.Minute)
+	}
+
+	next := sched.Next(last)
+	if next.IsZero() {
+		return false
+	}
+
+	return !next.After(now)
+}
+
+func (d *Disk) ScheduleSnapshot(db *database.Database) (
+	scheduled bool, err error) {
+
+	coll := db.Disks()
+	now := time.Now()
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id":   d.Id,
+		"state": Available,
+	}, &bson.M{
+		"$set": &bson.M{
+			"state":         Snapshot,
+			"last_snapshot": now,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if resp.ModifiedCount == 0 {
+		return
+	}
+
+	d.State = Snapshot
+	d.LastSnapshot = now
+	scheduled = true
+
+	return
+}
+
 func (d *Disk) PreCommit() {
 	d.curIndex = d.Index
 	d.curInstance = d.Instance
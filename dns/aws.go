@@ -1,10 +1,13 @@
 package dns
 
 import (
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
@@ -85,6 +88,36 @@ func (a *Aws) DnsZoneFind(domain string) (zoneId string, err error) {
 	return
 }
 
+func (a *Aws) recordSet(zoneId, domain, recordType string) (
+	recordSet *route53.ResourceRecordSet, err error) {
+
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneId),
+		StartRecordName: aws.String(domain),
+		StartRecordType: aws.String(recordType),
+		MaxItems:        aws.String("1"),
+	}
+
+	result, err := a.sessRoute53.ListResourceRecordSets(input)
+	if err != nil {
+		err = &errortypes.ApiError{
+			errors.Wrap(err, "acme: AWS record list error"),
+		}
+		return
+	}
+
+	for _, recSet := range result.ResourceRecordSets {
+		if recSet.Type != nil && *recSet.Type == recordType &&
+			recSet.Name != nil && matchDomains(*recSet.Name, domain) {
+
+			recordSet = recSet
+			break
+		}
+	}
+
+	return
+}
+
 func (a *Aws) DnsCommit(db *database.Database,
 	domain, recordType string, ops []*Operation) (err error) {
 
@@ -95,31 +128,52 @@ func (a *Aws) DnsCommit(db *database.Database,
 		return
 	}
 
-	action := aws.String("DELETE")
 	resourceRecs := []*route53.ResourceRecord{}
 	values := []string{}
+	valuesSet := set.NewSet()
 	for _, op := range ops {
-		if op.Operation == UPSERT || op.Operation == RETAIN {
-			action = aws.String("UPSERT")
+		val, e := NormalizeValue(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
 		}
+		op.Value = val
 
-		if recordType == "AAAA" {
-			val := normalizeIp(op.Value)
-			if val == "" {
-				err = &errortypes.ParseError{
-					errors.Newf("dns: Invalid ipv6 address %s", op.Value),
-				}
-				return
-			}
-			op.Value = val
+		if op.Operation == DELETE || valuesSet.Contains(op.Value) {
+			continue
 		}
+		valuesSet.Add(op.Value)
 
 		resourceRecs = append(resourceRecs, &route53.ResourceRecord{
-			Value: aws.String(op.Value),
+			Value: aws.String(zoneValue(recordType, op.Value)),
 		})
 		values = append(values, op.Value)
 	}
 
+	var action *string
+	var recordSet *route53.ResourceRecordSet
+	if len(resourceRecs) > 0 {
+		action = aws.String("UPSERT")
+		recordSet = &route53.ResourceRecordSet{
+			Name: aws.String(domain),
+			Type: aws.String(recordType),
+			TTL: aws.Int64(int64(
+				getTtl(ops, settings.Acme.DnsAwsTtl))),
+			ResourceRecords: resourceRecs,
+		}
+	} else {
+		// Route53 only deletes a record set matching the current values
+		action = aws.String("DELETE")
+		recordSet, err = a.recordSet(zoneId, domain, recordType)
+		if err != nil {
+			return
+		}
+
+		if recordSet == nil {
+			return
+		}
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"operation": *action,
 		"domain":    domain,
@@ -130,14 +184,8 @@ func (a *Aws) DnsCommit(db *database.Database,
 		ChangeBatch: &route53.ChangeBatch{
 			Changes: []*route53.Change{
 				{
-					Action: action,
-					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: aws.String(domain),
-						Type: aws.String(recordType),
-						TTL: aws.Int64(int64(
-							settings.Acme.DnsAwsTtl)),
-						ResourceRecords: resourceRecs,
-					},
+					Action:            action,
+					ResourceRecordSet: recordSet,
 				},
 			},
 			Comment: aws.String("Pritunl update record"),
@@ -167,40 +215,83 @@ func (a *Aws) DnsFind(db *database.Database, domain, recordType string) (
 		return
 	}
 
-	input := &route53.ListResourceRecordSetsInput{
-		HostedZoneId:    aws.String(zoneId),
-		StartRecordName: aws.String(domain),
-		StartRecordType: aws.String(recordType),
+	recordSet, err := a.recordSet(zoneId, domain, recordType)
+	if err != nil {
+		return
 	}
 
-	result, err := a.sessRoute53.ListResourceRecordSets(input)
-	if err != nil {
-		err = &errortypes.ApiError{
-			errors.Wrap(err, "acme: AWS record list error"),
+	if recordSet == nil {
+		return
+	}
+
+	for _, record := range recordSet.ResourceRecords {
+		if record.Value == nil {
+			continue
+		}
+
+		val, e := NormalizeValue(recordType, *record.Value)
+		if e != nil || val == "" {
+			continue
 		}
+
+		vals = append(vals, val)
+	}
+
+	return
+}
+
+func (a *Aws) DnsList(db *database.Database, domain string) (
+	records []*ZoneRecord, err error) {
+
+	records = []*ZoneRecord{}
+	domain = cleanDomain(domain)
+
+	zoneId, err := a.DnsZoneFind(domain)
+	if err != nil {
 		return
 	}
 
-	for _, recordSet := range result.ResourceRecordSets {
-		if recordSet.Type != nil && *recordSet.Type == recordType &&
-			recordSet.Name != nil && matchDomains(*recordSet.Name, domain) {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneId),
+	}
 
-			for _, record := range recordSet.ResourceRecords {
-				if record.Value != nil {
-					val := *record.Value
+	err = a.sessRoute53.ListResourceRecordSetsPages(input,
+		func(page *route53.ListResourceRecordSetsOutput, last bool) bool {
+			for _, recSet := range page.ResourceRecordSets {
+				if recSet.Name == nil || recSet.Type == nil {
+					continue
+				}
 
-					if recordType == "AAAA" {
-						val = normalizeIp(val)
+				record := &ZoneRecord{
+					Name: strings.ReplaceAll(
+						cleanDomain(*recSet.Name), "\\052", "*"),
+					Type:   *recSet.Type,
+					Values: []string{},
+				}
+
+				for _, rec := range recSet.ResourceRecords {
+					if rec.Value == nil {
+						continue
 					}
 
-					if val == "" {
+					val, e := NormalizeValue(record.Type, *rec.Value)
+					if e != nil || val == "" {
 						continue
 					}
 
-					vals = append(vals, val)
+					record.Values = append(record.Values, val)
 				}
+
+				records = append(records, record)
 			}
+
+			return true
+		})
+	if err != nil {
+		err = &errortypes.ApiError{
+			errors.Wrap(err, "acme: AWS record list error"),
 		}
+		return
 	}
 
 	return
@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"fmt"
+
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-cloud/database"
@@ -107,11 +109,7 @@ func (c *Cloudflare) DnsCommit(db *database.Database,
 	recordIds := map[string]string{}
 	for _, record := range records {
 		if record.Type == recordType && matchDomains(record.Name, domain) {
-			val := record.Content
-			if recordType == "AAAA" {
-				val = normalizeIp(val)
-			}
-
+			val := cloudflareValue(record)
 			if val == "" {
 				continue
 			}
@@ -121,18 +119,16 @@ func (c *Cloudflare) DnsCommit(db *database.Database,
 	}
 
 	for _, op := range ops {
-		if recordType == "AAAA" {
-			val := normalizeIp(op.Value)
-			if val == "" {
-				err = &errortypes.ParseError{
-					errors.Newf("dns: Invalid ipv6 address %s", op.Value),
-				}
-				return
-			}
-			op.Value = val
+		val, e := NormalizeValue(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
 		}
+		op.Value = val
 	}
 
+	ttl := getTtl(ops, settings.Acme.DnsCloudflareTtl)
+
 	for _, op := range ops {
 		if op.Operation != DELETE {
 			continue
@@ -194,6 +190,12 @@ func (c *Cloudflare) DnsCommit(db *database.Database,
 			delete(recordIds, updateVal)
 		}
 
+		content, priority, data, e := cloudflareParams(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
+		}
+
 		if recordId == "" {
 			logrus.WithFields(logrus.Fields{
 				"operation": "create",
@@ -202,10 +204,12 @@ func (c *Cloudflare) DnsCommit(db *database.Database,
 			}).Info("domain: Cloudflare dns operation")
 
 			createParams := cloudflare.CreateDNSRecordParams{
-				Type:    recordType,
-				Name:    domain,
-				Content: op.Value,
-				TTL:     settings.Acme.DnsCloudflareTtl,
+				Type:     recordType,
+				Name:     domain,
+				Content:  content,
+				Priority: priority,
+				Data:     data,
+				TTL:      ttl,
 			}
 
 			_, err = c.sess.CreateDNSRecord(
@@ -228,11 +232,13 @@ func (c *Cloudflare) DnsCommit(db *database.Database,
 			}).Info("domain: Cloudflare dns operation")
 
 			updateParams := cloudflare.UpdateDNSRecordParams{
-				ID:      recordId,
-				Type:    recordType,
-				Name:    domain,
-				Content: op.Value,
-				TTL:     settings.Acme.DnsCloudflareTtl,
+				ID:       recordId,
+				Type:     recordType,
+				Name:     domain,
+				Content:  content,
+				Priority: priority,
+				Data:     data,
+				TTL:      ttl,
 			}
 
 			_, err = c.sess.UpdateDNSRecord(
@@ -303,23 +309,67 @@ func (c *Cloudflare) DnsFind(db *database.Database,
 
 	for _, record := range records {
 		if record.Type == recordType && matchDomains(record.Name, domain) {
-			val := record.Content
-			if recordType == "AAAA" {
-				val = normalizeIp(val)
-			}
-
+			val := cloudflareValue(record)
 			if val == "" {
 				continue
 			}
 
 			vals = append(vals, val)
-			break
 		}
 	}
 
 	return
 }
 
+func (c *Cloudflare) DnsList(db *database.Database,
+	domain string) (records []*ZoneRecord, err error) {
+
+	records = []*ZoneRecord{}
+	domain = cleanDomain(domain)
+
+	zoneId, err := c.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	recs, _, err := c.sess.ListDNSRecords(
+		db,
+		cloudflare.ZoneIdentifier(zoneId),
+		cloudflare.ListDNSRecordsParams{},
+	)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "acme: Failed to get DNS records"),
+		}
+		return
+	}
+
+	recordSets := map[string]*ZoneRecord{}
+	for _, rec := range recs {
+		name := cleanDomain(rec.Name)
+		key := name + ":" + rec.Type
+
+		record := recordSets[key]
+		if record == nil {
+			record = &ZoneRecord{
+				Name:   name,
+				Type:   rec.Type,
+				Values: []string{},
+			}
+			recordSets[key] = record
+			records = append(records, record)
+		}
+
+		val := cloudflareValue(rec)
+		if val == "" {
+			continue
+		}
+		record.Values = append(record.Values, val)
+	}
+
+	return
+}
+
 func (c *Cloudflare) DnsTxtGet(db *database.Database,
 	domain string) (vals []string, err error) {
 
@@ -491,3 +541,87 @@ func (c *Cloudflare) DnsTxtDelete(db *database.Database,
 
 	return
 }
+
+// cloudflareValue returns the stored value of a record, the priority and
+// structured data of records are separate fields in the api
+func cloudflareValue(record cloudflare.DNSRecord) string {
+	val := record.Content
+	data, _ := record.Data.(map[string]interface{})
+
+	switch record.Type {
+	case "MX":
+		if record.Priority != nil {
+			val = fmt.Sprintf("%d %s", *record.Priority, record.Content)
+		}
+		break
+	case "SRV":
+		if data != nil {
+			val = fmt.Sprintf("%v %v %v %v", data["priority"],
+				data["weight"], data["port"], data["target"])
+		} else if record.Priority != nil {
+			val = fmt.Sprintf("%d %s", *record.Priority, record.Content)
+		}
+		break
+	case "CAA":
+		if data != nil {
+			val = fmt.Sprintf("%v %v %s", data["flags"], data["tag"],
+				quoteTxt(fmt.Sprintf("%v", data["value"])))
+		}
+		break
+	}
+
+	normVal, err := NormalizeValue(record.Type, val)
+	if err != nil {
+		return ""
+	}
+
+	return normVal
+}
+
+func cloudflareParams(recordType, val string) (content string,
+	priority *uint16, data interface{}, err error) {
+
+	switch recordType {
+	case "MX":
+		prio, target, e := ParseMx(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		content = target
+		priority = &prio
+		break
+	case "SRV":
+		prio, weight, port, target, e := ParseSrv(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		data = map[string]interface{}{
+			"priority": prio,
+			"weight":   weight,
+			"port":     port,
+			"target":   target,
+		}
+		break
+	case "CAA":
+		flags, tag, value, e := ParseCaa(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		data = map[string]interface{}{
+			"flags": flags,
+			"tag":   tag,
+			"value": value,
+		}
+		break
+	default:
+		content = val
+	}
+
+	return
+}
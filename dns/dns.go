@@ -8,6 +8,7 @@ import (
 type Operation struct {
 	Operation string
 	Value     string
+	Ttl       int
 }
 
 type Service interface {
@@ -21,3 +22,17 @@ type Service interface {
 	DnsTxtUpsert(db *database.Database, domain, val string) (err error)
 	DnsTxtDelete(db *database.Database, domain, val string) (err error)
 }
+
+// ZoneRecord is a record set returned by a zone listing
+type ZoneRecord struct {
+	Name   string
+	Type   string
+	Values []string
+}
+
+// Lister is implemented by services that can list every record set in the
+// zone of a domain
+type Lister interface {
+	DnsList(db *database.Database, domain string) (
+		records []*ZoneRecord, err error)
+}
@@ -108,19 +108,18 @@ func (o *Oracle) DnsCommit(db *database.Database,
 	values := set.NewSet()
 	oracleOps := []string{}
 
+	ttl := getTtl(ops, settings.Acme.DnsOracleCloudTtl)
+
 	for _, op := range ops {
-		if recordType == "AAAA" {
-			val := normalizeIp(op.Value)
-			if val == "" {
-				err = &errortypes.ParseError{
-					errors.Newf("dns: Invalid ipv6 address %s", op.Value),
-				}
-				return
-			}
-			op.Value = val
+		val, e := NormalizeValue(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
 		}
+		op.Value = val
 
 		values.Add(op.Value)
+		rdata := zoneValue(recordType, op.Value)
 
 		switch op.Operation {
 		case RETAIN:
@@ -128,22 +127,20 @@ func (o *Oracle) DnsCommit(db *database.Database,
 		case UPSERT:
 			oracleOps = append(oracleOps, "add:"+op.Value)
 			items = append(items, dns.RecordOperation{
-				Domain: &domain,
-				Rtype:  utils.PointerString(recordType),
-				Ttl: utils.PointerInt(
-					settings.Acme.DnsOracleCloudTtl),
-				Rdata:     utils.PointerString(op.Value),
+				Domain:    &domain,
+				Rtype:     utils.PointerString(recordType),
+				Ttl:       utils.PointerInt(ttl),
+				Rdata:     utils.PointerString(rdata),
 				Operation: dns.RecordOperationOperationAdd,
 			})
 			break
 		case DELETE:
 			oracleOps = append(oracleOps, "remove:"+op.Value)
 			items = append(items, dns.RecordOperation{
-				Domain: &domain,
-				Rtype:  utils.PointerString(recordType),
-				Ttl: utils.PointerInt(
-					settings.Acme.DnsOracleCloudTtl),
-				Rdata:     utils.PointerString(op.Value),
+				Domain:    &domain,
+				Rtype:     utils.PointerString(recordType),
+				Ttl:       utils.PointerInt(ttl),
+				Rdata:     utils.PointerString(rdata),
 				Operation: dns.RecordOperationOperationRemove,
 			})
 			break
@@ -172,12 +169,8 @@ func (o *Oracle) DnsCommit(db *database.Database,
 		if record.Rtype != nil && *record.Rtype == recordType &&
 			record.Rdata != nil {
 
-			val := *record.Rdata
-			if recordType == "AAAA" {
-				val = normalizeIp(val)
-			}
-
-			if val == "" {
+			val, e := NormalizeValue(recordType, *record.Rdata)
+			if e != nil || val == "" {
 				continue
 			}
 
@@ -187,10 +180,9 @@ func (o *Oracle) DnsCommit(db *database.Database,
 
 			oracleOps = append(oracleOps, "remove_unknown:"+*record.Rdata)
 			items = append(items, dns.RecordOperation{
-				Domain: &domain,
-				Rtype:  utils.PointerString(recordType),
-				Ttl: utils.PointerInt(
-					settings.Acme.DnsOracleCloudTtl),
+				Domain:    &domain,
+				Rtype:     utils.PointerString(recordType),
+				Ttl:       utils.PointerInt(ttl),
 				Rdata:     utils.PointerString(*record.Rdata),
 				Operation: dns.RecordOperationOperationRemove,
 			})
@@ -248,17 +240,77 @@ func (o *Oracle) DnsFind(db *database.Database,
 		if record.Rtype != nil && *record.Rtype == recordType &&
 			record.Rdata != nil {
 
-			val := *record.Rdata
-			if recordType == "AAAA" {
-				val = normalizeIp(val)
+			val, e := NormalizeValue(recordType, *record.Rdata)
+			if e != nil || val == "" {
+				continue
 			}
 
-			if val == "" {
+			vals = append(vals, val)
+		}
+	}
+
+	return
+}
+
+func (o *Oracle) DnsList(db *database.Database,
+	domain string) (records []*ZoneRecord, err error) {
+
+	records = []*ZoneRecord{}
+	zoneName := extractDomain(domain)
+
+	client, err := o.provider.GetDnsClient()
+	if err != nil {
+		return
+	}
+
+	recordSets := map[string]*ZoneRecord{}
+	req := dns.GetZoneRecordsRequest{
+		ZoneNameOrId: utils.PointerString(zoneName),
+	}
+
+	for {
+		resp, e := client.GetZoneRecords(db, req)
+		if e != nil {
+			err = &errortypes.ApiError{
+				errors.Wrap(e, "acme: Oracle zone record get error"),
+			}
+			return
+		}
+
+		for _, rec := range resp.Items {
+			if rec.Domain == nil || rec.Rtype == nil {
 				continue
 			}
 
-			vals = append(vals, val)
+			name := cleanDomain(*rec.Domain)
+			key := name + ":" + *rec.Rtype
+
+			record := recordSets[key]
+			if record == nil {
+				record = &ZoneRecord{
+					Name:   name,
+					Type:   *rec.Rtype,
+					Values: []string{},
+				}
+				recordSets[key] = record
+				records = append(records, record)
+			}
+
+			if rec.Rdata == nil {
+				continue
+			}
+
+			val, e := NormalizeValue(record.Type, *rec.Rdata)
+			if e != nil || val == "" {
+				continue
+			}
+			record.Values = append(record.Values, val)
+		}
+
+		if resp.OpcNextPage == nil {
+			break
 		}
+		req.Page = resp.OpcNextPage
 	}
 
 	return
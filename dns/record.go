@@ -0,0 +1,259 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+// Record values are stored in the zone file format with domain names in
+// lowercase without the trailing dot and TXT values unquoted. Providers
+// convert values to and from this format.
+
+// ParseMx parses a MX record value of priority target
+func ParseMx(val string) (priority uint16, target string, err error) {
+	fields := strings.Fields(val)
+	if len(fields) != 2 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid MX record value"),
+		}
+		return
+	}
+
+	n, e := strconv.ParseUint(fields[0], 10, 16)
+	if e != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(e, "dns: Invalid MX record priority"),
+		}
+		return
+	}
+
+	priority = uint16(n)
+	target = cleanName(fields[1])
+
+	if target == "" {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid MX record target"),
+		}
+		return
+	}
+
+	return
+}
+
+// ParseSrv parses a SRV record value of priority weight port target
+func ParseSrv(val string) (priority, weight, port uint16, target string,
+	err error) {
+
+	fields := strings.Fields(val)
+	if len(fields) != 4 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid SRV record value"),
+		}
+		return
+	}
+
+	nums := [3]uint16{}
+	for i := 0; i < 3; i++ {
+		n, e := strconv.ParseUint(fields[i], 10, 16)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "dns: Invalid SRV record value"),
+			}
+			return
+		}
+		nums[i] = uint16(n)
+	}
+
+	priority = nums[0]
+	weight = nums[1]
+	port = nums[2]
+	target = cleanName(fields[3])
+
+	if target == "" {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid SRV record target"),
+		}
+		return
+	}
+
+	return
+}
+
+// ParseCaa parses a CAA record value of flags tag "value"
+func ParseCaa(val string) (flags uint8, tag, value string, err error) {
+	fields := strings.SplitN(strings.TrimSpace(val), " ", 3)
+	if len(fields) != 3 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid CAA record value"),
+		}
+		return
+	}
+
+	n, e := strconv.ParseUint(fields[0], 10, 8)
+	if e != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(e, "dns: Invalid CAA record flags"),
+		}
+		return
+	}
+	flags = uint8(n)
+
+	tag = strings.ToLower(fields[1])
+	if tag == "" {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid CAA record tag"),
+		}
+		return
+	}
+	for _, c := range tag {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			err = &errortypes.ParseError{
+				errors.New("dns: Invalid CAA record tag"),
+			}
+			return
+		}
+	}
+
+	value = unquoteTxt(strings.TrimSpace(fields[2]))
+
+	return
+}
+
+// NormalizeValue converts a record value to the stored format
+func NormalizeValue(recordType, val string) (normVal string, err error) {
+	val = strings.TrimSpace(val)
+
+	switch recordType {
+	case "A":
+		ip := net.ParseIP(val).To4()
+		if ip == nil {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid ipv4 address %s", val),
+			}
+			return
+		}
+		normVal = ip.String()
+		break
+	case "AAAA":
+		normVal = normalizeIp(val)
+		if normVal == "" {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid ipv6 address %s", val),
+			}
+			return
+		}
+		break
+	case "CNAME", "PTR":
+		normVal = cleanName(val)
+		if normVal == "" {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid %s record target", recordType),
+			}
+			return
+		}
+		break
+	case "MX":
+		priority, target, e := ParseMx(val)
+		if e != nil {
+			err = e
+			return
+		}
+		normVal = fmt.Sprintf("%d %s", priority, target)
+		break
+	case "SRV":
+		priority, weight, port, target, e := ParseSrv(val)
+		if e != nil {
+			err = e
+			return
+		}
+		normVal = fmt.Sprintf("%d %d %d %s", priority, weight, port, target)
+		break
+	case "CAA":
+		flags, tag, value, e := ParseCaa(val)
+		if e != nil {
+			err = e
+			return
+		}
+		normVal = fmt.Sprintf("%d %s %s", flags, tag, quoteTxt(value))
+		break
+	case "TXT":
+		normVal = unquoteTxt(val)
+		break
+	default:
+		err = &errortypes.UnknownError{
+			errors.Newf("dns: Unsupported record type %s", recordType),
+		}
+		return
+	}
+
+	return
+}
+
+// zoneValue converts a stored record value to the zone file format with
+// fully qualified names and quoted TXT strings
+func zoneValue(recordType, val string) string {
+	switch recordType {
+	case "CNAME", "PTR", "MX", "SRV":
+		return val + "."
+	case "TXT":
+		return quoteTxtChunks(val)
+	}
+
+	return val
+}
+
+func cleanName(name string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(name), "."))
+}
+
+// unquoteTxt joins the quoted strings of a TXT value, unquoted values are
+// returned unchanged
+func unquoteTxt(val string) string {
+	if !strings.HasPrefix(val, "\"") {
+		return val
+	}
+
+	txt := strings.Builder{}
+	quoted := false
+	escaped := false
+	for _, c := range val {
+		if escaped {
+			txt.WriteRune(c)
+			escaped = false
+			continue
+		}
+
+		if c == '\\' && quoted {
+			escaped = true
+		} else if c == '"' {
+			quoted = !quoted
+		} else if quoted {
+			txt.WriteRune(c)
+		}
+	}
+
+	return txt.String()
+}
+
+func quoteTxt(val string) string {
+	val = strings.ReplaceAll(val, "\\", "\\\\")
+	val = strings.ReplaceAll(val, "\"", "\\\"")
+	return "\"" + val + "\""
+}
+
+// quoteTxtChunks quotes a TXT value split into strings of 255 bytes
+func quoteTxtChunks(val string) string {
+	chunks := []string{}
+	for len(val) > 255 {
+		chunks = append(chunks, quoteTxt(val[:255]))
+		val = val[255:]
+	}
+	chunks = append(chunks, quoteTxt(val))
+
+	return strings.Join(chunks, " ")
+}
@@ -0,0 +1,871 @@
+package dns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/secret"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	rfc2136OpCode  = 5
+	rfc2136Timeout = 10 * time.Second
+	rfc2136MaxUdp  = 512
+
+	classNone      = dnsmessage.Class(254)
+	rfc2136TypeCaa = dnsmessage.Type(257)
+)
+
+type Rfc2136 struct {
+	server    string
+	key       *tsigKey
+	cacheZone map[string]string
+}
+
+type rfc2136Change struct {
+	Delete bool
+	Name   string
+	Type   dnsmessage.Type
+	Ttl    int
+	Data   []byte
+}
+
+func (r *Rfc2136) Connect(db *database.Database,
+	secr *secret.Secret) (err error) {
+
+	if secr.Type != secret.Rfc2136 {
+		err = &errortypes.ApiError{
+			errors.Wrap(err, "acme: Secret type not RFC 2136"),
+		}
+		return
+	}
+
+	r.server = strings.TrimSpace(secr.Region)
+	if r.server == "" {
+		err = &errortypes.ApiError{
+			errors.New("dns: RFC 2136 server address missing"),
+		}
+		return
+	}
+
+	_, _, e := net.SplitHostPort(r.server)
+	if e != nil {
+		r.server = net.JoinHostPort(strings.Trim(r.server, "[]"), "53")
+	}
+
+	r.key, err = parseTsigKey(secr.Key, secr.Value)
+	if err != nil {
+		return
+	}
+
+	r.cacheZone = map[string]string{}
+
+	return
+}
+
+func (r *Rfc2136) DnsZoneFind(db *database.Database, domain string) (
+	zone string, err error) {
+
+	domain = strings.ToLower(cleanDomain(domain))
+
+	zone = r.cacheZone[domain]
+	if zone != "" {
+		return
+	}
+
+	resp, err := r.query(domain, dnsmessage.TypeSOA)
+	if err != nil {
+		return
+	}
+
+	records := append(resp.Answers, resp.Authorities...)
+	for _, record := range records {
+		if record.Header.Type != dnsmessage.TypeSOA {
+			continue
+		}
+
+		name := strings.ToLower(cleanDomain(record.Header.Name.String()))
+		if name == domain || strings.HasSuffix(domain, "."+name) {
+			zone = name
+			break
+		}
+	}
+
+	if zone == "" {
+		err = &errortypes.ApiError{
+			errors.Newf("dns: RFC 2136 zone not found for %s", domain),
+		}
+		return
+	}
+
+	r.cacheZone[domain] = zone
+
+	return
+}
+
+func (r *Rfc2136) DnsCommit(db *database.Database,
+	domain, recordType string, ops []*Operation) (err error) {
+
+	domain = cleanDomain(domain)
+
+	zone, err := r.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	typ, err := rfc2136Type(recordType)
+	if err != nil {
+		return
+	}
+
+	curVals, err := r.DnsFind(db, domain, recordType)
+	if err != nil {
+		return
+	}
+
+	records := map[string]bool{}
+	for _, val := range curVals {
+		records[val] = true
+	}
+
+	for _, op := range ops {
+		val, e := NormalizeValue(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
+		}
+		op.Value = val
+	}
+
+	changes := []*rfc2136Change{}
+
+	for _, op := range ops {
+		if op.Operation != DELETE || !records[op.Value] {
+			continue
+		}
+		delete(records, op.Value)
+
+		logrus.WithFields(logrus.Fields{
+			"operation": "delete",
+			"domain":    domain,
+			"value":     op.Value,
+		}).Info("domain: RFC 2136 dns operation")
+
+		data, e := rfc2136Rdata(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
+		}
+
+		changes = append(changes, &rfc2136Change{
+			Delete: true,
+			Name:   domain,
+			Type:   typ,
+			Data:   data,
+		})
+	}
+
+	retain := map[string]bool{}
+	for _, op := range ops {
+		if op.Operation != RETAIN && op.Operation != UPSERT {
+			continue
+		}
+
+		if retain[op.Value] {
+			continue
+		}
+		retain[op.Value] = true
+
+		if records[op.Value] {
+			delete(records, op.Value)
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"operation": "create",
+			"domain":    domain,
+			"value":     op.Value,
+		}).Info("domain: RFC 2136 dns operation")
+
+		data, e := rfc2136Rdata(recordType, op.Value)
+		if e != nil {
+			err = e
+			return
+		}
+
+		changes = append(changes, &rfc2136Change{
+			Name: domain,
+			Type: typ,
+			Ttl:  getTtl(ops, settings.Acme.DnsRfc2136Ttl),
+			Data: data,
+		})
+	}
+
+	for val := range records {
+		logrus.WithFields(logrus.Fields{
+			"operation": "delete_unknown",
+			"domain":    domain,
+			"value":     val,
+		}).Info("domain: RFC 2136 dns operation")
+
+		data, e := rfc2136Rdata(recordType, val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		changes = append(changes, &rfc2136Change{
+			Delete: true,
+			Name:   domain,
+			Type:   typ,
+			Data:   data,
+		})
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	err = r.update(zone, changes)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) DnsFind(db *database.Database,
+	domain, recordType string) (vals []string, err error) {
+
+	vals = []string{}
+	domain = cleanDomain(domain)
+
+	typ, err := rfc2136Type(recordType)
+	if err != nil {
+		return
+	}
+
+	resp, err := r.query(domain, typ)
+	if err != nil {
+		return
+	}
+
+	for _, record := range resp.Answers {
+		if record.Header.Type != typ ||
+			!matchDomains(record.Header.Name.String(), domain) {
+
+			continue
+		}
+
+		val := rfc2136Value(recordType, record)
+		if val == "" {
+			continue
+		}
+
+		vals = append(vals, val)
+	}
+
+	return
+}
+
+func (r *Rfc2136) DnsTxtGet(db *database.Database,
+	domain string) (vals []string, err error) {
+
+	vals, err = r.DnsFind(db, domain, "TXT")
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) DnsTxtUpsert(db *database.Database,
+	domain, val string) (err error) {
+
+	domain = cleanDomain(domain)
+
+	zone, err := r.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	data, err := rfc2136Rdata("TXT", val)
+	if err != nil {
+		return
+	}
+
+	err = r.update(zone, []*rfc2136Change{
+		&rfc2136Change{
+			Delete: true,
+			Name:   domain,
+			Type:   dnsmessage.TypeTXT,
+		},
+		&rfc2136Change{
+			Name: domain,
+			Type: dnsmessage.TypeTXT,
+			Ttl:  settings.Acme.DnsRfc2136Ttl,
+			Data: data,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) DnsTxtDelete(db *database.Database,
+	domain, val string) (err error) {
+
+	domain = cleanDomain(domain)
+
+	zone, err := r.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	data, err := rfc2136Rdata("TXT", val)
+	if err != nil {
+		return
+	}
+
+	err = r.update(zone, []*rfc2136Change{
+		&rfc2136Change{
+			Delete: true,
+			Name:   domain,
+			Type:   dnsmessage.TypeTXT,
+			Data:   data,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) query(domain string, typ dnsmessage.Type) (
+	resp *dnsmessage.Message, err error) {
+
+	name, err := dnsmessage.NewName(cleanDomain(domain) + ".")
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Invalid domain name"),
+		}
+		return
+	}
+
+	id, err := rfc2136Id()
+	if err != nil {
+		return
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID: id,
+	})
+
+	err = builder.StartQuestions()
+	if err == nil {
+		err = builder.Question(dnsmessage.Question{
+			Name:  name,
+			Type:  typ,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build query"),
+		}
+		return
+	}
+
+	msg, err := builder.Finish()
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build query"),
+		}
+		return
+	}
+
+	resp, err = r.exchange(msg)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) update(zone string, changes []*rfc2136Change) (
+	err error) {
+
+	zoneName, err := dnsmessage.NewName(cleanDomain(zone) + ".")
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Invalid zone name"),
+		}
+		return
+	}
+
+	id, err := rfc2136Id()
+	if err != nil {
+		return
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:     id,
+		OpCode: rfc2136OpCode,
+	})
+
+	// RFC 2136 reuses the question section as the zone section and the
+	// authority section as the update section
+	err = builder.StartQuestions()
+	if err == nil {
+		err = builder.Question(dnsmessage.Question{
+			Name:  zoneName,
+			Type:  dnsmessage.TypeSOA,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+	if err == nil {
+		err = builder.StartAuthorities()
+	}
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build update"),
+		}
+		return
+	}
+
+	for _, change := range changes {
+		name, e := dnsmessage.NewName(cleanDomain(change.Name) + ".")
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "dns: Invalid domain name"),
+			}
+			return
+		}
+
+		header := dnsmessage.ResourceHeader{
+			Name:  name,
+			Class: dnsmessage.ClassINET,
+			TTL:   uint32(change.Ttl),
+		}
+		if change.Delete {
+			header.TTL = 0
+			if change.Data == nil {
+				header.Class = dnsmessage.ClassANY
+			} else {
+				header.Class = classNone
+			}
+		}
+
+		err = builder.UnknownResource(header, dnsmessage.UnknownResource{
+			Type: change.Type,
+			Data: change.Data,
+		})
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "dns: Failed to build update"),
+			}
+			return
+		}
+	}
+
+	msg, err := builder.Finish()
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build update"),
+		}
+		return
+	}
+
+	resp, err := r.exchange(msg)
+	if err != nil {
+		return
+	}
+
+	if resp.Header.RCode != dnsmessage.RCodeSuccess {
+		err = &ServiceError{
+			errors.Newf("dns: RFC 2136 server returned %s",
+				resp.Header.RCode.String()),
+		}
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) exchange(msg []byte) (
+	resp *dnsmessage.Message, err error) {
+
+	signed, reqMac, err := r.key.Sign(msg)
+	if err != nil {
+		return
+	}
+
+	var respData []byte
+	if len(signed) <= rfc2136MaxUdp {
+		respData, err = r.exchangeUdp(signed)
+		if err != nil {
+			return
+		}
+	}
+
+	if respData == nil || len(respData) < 3 || respData[2]&0x02 != 0 {
+		respData, err = r.exchangeTcp(signed)
+		if err != nil {
+			return
+		}
+	}
+
+	resp = &dnsmessage.Message{}
+	err = resp.Unpack(respData)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to parse response"),
+		}
+		return
+	}
+
+	if resp.Header.ID != binary.BigEndian.Uint16(msg[0:2]) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Response id mismatch"),
+		}
+		return
+	}
+
+	if resp.Header.RCode != dnsmessage.RCodeSuccess &&
+		resp.Header.RCode != dnsmessage.RCodeNameError {
+
+		err = &ServiceError{
+			errors.Newf("dns: RFC 2136 server returned %s",
+				resp.Header.RCode.String()),
+		}
+		return
+	}
+
+	err = r.key.Verify(respData, reqMac)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) exchangeUdp(msg []byte) (resp []byte, err error) {
+	conn, err := net.DialTimeout("udp", r.server, rfc2136Timeout)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to connect to RFC 2136 server"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(rfc2136Timeout))
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to set deadline"),
+		}
+		return
+	}
+
+	_, err = conn.Write(msg)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to send request"),
+		}
+		return
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, e := conn.Read(buf)
+		if e != nil {
+			err = &errortypes.ConnectionError{
+				errors.Wrap(e, "dns: Failed to read response"),
+			}
+			return
+		}
+
+		// Ignore stray responses to earlier requests
+		if n < 2 || buf[0] != msg[0] || buf[1] != msg[1] {
+			continue
+		}
+
+		resp = buf[:n]
+		break
+	}
+
+	return
+}
+
+func (r *Rfc2136) exchangeTcp(msg []byte) (resp []byte, err error) {
+	conn, err := net.DialTimeout("tcp", r.server, rfc2136Timeout)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to connect to RFC 2136 server"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(rfc2136Timeout))
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to set deadline"),
+		}
+		return
+	}
+
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	data = append(data, msg...)
+
+	_, err = conn.Write(data)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to send request"),
+		}
+		return
+	}
+
+	lenBuf := make([]byte, 2)
+	_, err = io.ReadFull(conn, lenBuf)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to read response"),
+		}
+		return
+	}
+
+	resp = make([]byte, binary.BigEndian.Uint16(lenBuf))
+	_, err = io.ReadFull(conn, resp)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dns: Failed to read response"),
+		}
+		return
+	}
+
+	return
+}
+
+func rfc2136Id() (id uint16, err error) {
+	idByt, err := utils.RandBytes(2)
+	if err != nil {
+		return
+	}
+
+	id = binary.BigEndian.Uint16(idByt)
+
+	return
+}
+
+func rfc2136Type(recordType string) (typ dnsmessage.Type, err error) {
+	switch recordType {
+	case "A":
+		typ = dnsmessage.TypeA
+		break
+	case "AAAA":
+		typ = dnsmessage.TypeAAAA
+		break
+	case "CNAME":
+		typ = dnsmessage.TypeCNAME
+		break
+	case "MX":
+		typ = dnsmessage.TypeMX
+		break
+	case "SRV":
+		typ = dnsmessage.TypeSRV
+		break
+	case "CAA":
+		typ = rfc2136TypeCaa
+		break
+	case "TXT":
+		typ = dnsmessage.TypeTXT
+		break
+	default:
+		err = &errortypes.UnknownError{
+			errors.Newf("dns: Unsupported record type %s", recordType),
+		}
+		return
+	}
+
+	return
+}
+
+func rfc2136Rdata(recordType, val string) (data []byte, err error) {
+	switch recordType {
+	case "A":
+		ip := net.ParseIP(val).To4()
+		if ip == nil {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid ipv4 address %s", val),
+			}
+			return
+		}
+		data = []byte(ip)
+		break
+	case "AAAA":
+		ip := net.ParseIP(val)
+		if ip == nil || ip.To4() != nil {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid ipv6 address %s", val),
+			}
+			return
+		}
+		data = []byte(ip.To16())
+		break
+	case "CNAME":
+		data, err = rfc2136NameData(val)
+		if err != nil {
+			return
+		}
+		break
+	case "MX":
+		priority, target, e := ParseMx(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		data = binary.BigEndian.AppendUint16(nil, priority)
+
+		nameData, e := rfc2136NameData(target)
+		if e != nil {
+			err = e
+			return
+		}
+		data = append(data, nameData...)
+		break
+	case "SRV":
+		priority, weight, port, target, e := ParseSrv(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		data = binary.BigEndian.AppendUint16(nil, priority)
+		data = binary.BigEndian.AppendUint16(data, weight)
+		data = binary.BigEndian.AppendUint16(data, port)
+
+		nameData, e := rfc2136NameData(target)
+		if e != nil {
+			err = e
+			return
+		}
+		data = append(data, nameData...)
+		break
+	case "CAA":
+		flags, tag, value, e := ParseCaa(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		data = []byte{flags, byte(len(tag))}
+		data = append(data, tag...)
+		data = append(data, value...)
+		break
+	case "TXT":
+		txt := unquoteTxt(val)
+		data = []byte{}
+		for {
+			chunk := txt
+			if len(chunk) > 255 {
+				chunk = chunk[:255]
+			}
+			txt = txt[len(chunk):]
+
+			data = append(data, byte(len(chunk)))
+			data = append(data, chunk...)
+
+			if txt == "" {
+				break
+			}
+		}
+		break
+	default:
+		err = &errortypes.UnknownError{
+			errors.Newf("dns: Unsupported record type %s", recordType),
+		}
+		return
+	}
+
+	return
+}
+
+func rfc2136NameData(name string) (data []byte, err error) {
+	for _, label := range strings.Split(cleanName(name), ".") {
+		if label == "" || len(label) > 63 {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid domain name %s", name),
+			}
+			return
+		}
+
+		data = append(data, byte(len(label)))
+		data = append(data, label...)
+	}
+	data = append(data, 0)
+
+	return
+}
+
+func rfc2136Value(recordType string, record dnsmessage.Resource) string {
+	val := ""
+
+	switch body := record.Body.(type) {
+	case *dnsmessage.AResource:
+		val = net.IP(body.A[:]).String()
+		break
+	case *dnsmessage.AAAAResource:
+		val = net.IP(body.AAAA[:]).String()
+		break
+	case *dnsmessage.CNAMEResource:
+		val = body.CNAME.String()
+		break
+	case *dnsmessage.MXResource:
+		val = fmt.Sprintf("%d %s", body.Pref, body.MX.String())
+		break
+	case *dnsmessage.SRVResource:
+		val = fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight,
+			body.Port, body.Target.String())
+		break
+	case *dnsmessage.TXTResource:
+		val = strings.Join(body.TXT, "")
+		break
+	case *dnsmessage.UnknownResource:
+		if body.Type != rfc2136TypeCaa || len(body.Data) < 2 ||
+			len(body.Data) < 2+int(body.Data[1]) {
+
+			return ""
+		}
+
+		tagLen := int(body.Data[1])
+		val = fmt.Sprintf("%d %s %s", body.Data[0],
+			string(body.Data[2:2+tagLen]),
+			quoteTxt(string(body.Data[2+tagLen:])))
+		break
+	default:
+		return ""
+	}
+
+	normVal, err := NormalizeValue(recordType, val)
+	if err != nil {
+		return ""
+	}
+
+	return normVal
+}
@@ -0,0 +1,372 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testResponder is an in process RFC 2136 server listening for UDP and TCP
+// requests on the same port, requests are verified and recorded. Options
+// must be set before starting the responder
+type testResponder struct {
+	t         *testing.T
+	key       *tsigKey
+	signKey   *tsigKey
+	udp       net.PacketConn
+	tcp       net.Listener
+	truncate  bool
+	unsigned  bool
+	rcode     dnsmessage.RCode
+	lock      sync.Mutex
+	requests  []*dnsmessage.Message
+	protocols []string
+}
+
+func newTestResponder(t *testing.T) (r *testResponder) {
+	r = &testResponder{
+		t:       t,
+		key:     newTestTsigKey(t),
+		signKey: newTestTsigKey(t),
+	}
+
+	for i := 0; i < 10; i++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		port := udp.LocalAddr().(*net.UDPAddr).Port
+		tcp, err := net.Listen("tcp",
+			net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			_ = udp.Close()
+			continue
+		}
+
+		r.udp = udp
+		r.tcp = tcp
+		break
+	}
+
+	if r.udp == nil {
+		t.Fatal("failed to listen on udp and tcp port")
+	}
+
+	return
+}
+
+func (r *testResponder) Start() {
+	go r.runUdp()
+	go r.runTcp()
+}
+
+func (r *testResponder) Close() {
+	_ = r.udp.Close()
+	_ = r.tcp.Close()
+}
+
+func (r *testResponder) client() *Rfc2136 {
+	return &Rfc2136{
+		server:    r.udp.LocalAddr().String(),
+		key:       newTestTsigKey(r.t),
+		cacheZone: map[string]string{},
+	}
+}
+
+func (r *testResponder) recorded() (requests []*dnsmessage.Message,
+	protocols []string) {
+
+	r.lock.Lock()
+	requests = append(requests, r.requests...)
+	protocols = append(protocols, r.protocols...)
+	r.lock.Unlock()
+
+	return
+}
+
+func (r *testResponder) runUdp() {
+	buf := make([]byte, 65535)
+
+	for {
+		n, addr, err := r.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		resp := r.handle(buf[:n], "udp")
+		if resp != nil {
+			_, _ = r.udp.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (r *testResponder) runTcp() {
+	for {
+		conn, err := r.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		lenBuf := make([]byte, 2)
+		_, err = io.ReadFull(conn, lenBuf)
+		if err != nil {
+			_ = conn.Close()
+			continue
+		}
+
+		req := make([]byte, binary.BigEndian.Uint16(lenBuf))
+		_, err = io.ReadFull(conn, req)
+		if err != nil {
+			_ = conn.Close()
+			continue
+		}
+
+		resp := r.handle(req, "tcp")
+		if resp != nil {
+			data := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+			_, _ = conn.Write(append(data, resp...))
+		}
+		_ = conn.Close()
+	}
+}
+
+func (r *testResponder) handle(req []byte, protocol string) []byte {
+	err := r.key.Verify(req, nil)
+	if err != nil {
+		r.t.Errorf("request tsig invalid: %v", err)
+		return nil
+	}
+
+	msg := &dnsmessage.Message{}
+	err = msg.Unpack(req)
+	if err != nil {
+		r.t.Errorf("request invalid: %v", err)
+		return nil
+	}
+
+	r.lock.Lock()
+	r.requests = append(r.requests, msg)
+	r.protocols = append(r.protocols, protocol)
+	r.lock.Unlock()
+
+	tsigRecord := msg.Additionals[len(msg.Additionals)-1]
+	tsig := tsigRecord.Body.(*dnsmessage.UnknownResource).Data
+	off, err := skipName(tsig, 0)
+	if err != nil {
+		r.t.Error(err)
+		return nil
+	}
+	macLen := int(binary.BigEndian.Uint16(tsig[off+8 : off+10]))
+	reqMac := tsig[off+10 : off+10+macLen]
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:        msg.Header.ID,
+		Response:  true,
+		OpCode:    msg.Header.OpCode,
+		RCode:     r.rcode,
+		Truncated: r.truncate && protocol == "udp",
+	})
+	err = builder.StartQuestions()
+	for _, question := range msg.Questions {
+		if err == nil {
+			err = builder.Question(question)
+		}
+	}
+	resp, e := builder.Finish()
+	if err != nil || e != nil {
+		r.t.Errorf("failed to build response: %v %v", err, e)
+		return nil
+	}
+
+	if r.unsigned {
+		return resp
+	}
+
+	resp, _, err = r.signKey.sign(resp, reqMac, uint64(time.Now().Unix()))
+	if err != nil {
+		r.t.Error(err)
+		return nil
+	}
+
+	return resp
+}
+
+func testChanges(t *testing.T) []*rfc2136Change {
+	txtData, err := rfc2136Rdata("TXT", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aData, err := rfc2136Rdata("A", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []*rfc2136Change{
+		&rfc2136Change{
+			Delete: true,
+			Name:   "_acme-challenge.example.com",
+			Type:   dnsmessage.TypeTXT,
+		},
+		&rfc2136Change{
+			Name: "_acme-challenge.example.com",
+			Type: dnsmessage.TypeTXT,
+			Ttl:  60,
+			Data: txtData,
+		},
+		&rfc2136Change{
+			Delete: true,
+			Name:   "www.example.com",
+			Type:   dnsmessage.TypeA,
+			Data:   aData,
+		},
+	}
+}
+
+func TestRfc2136UpdateLayout(t *testing.T) {
+	resp := newTestResponder(t)
+	defer resp.Close()
+	resp.Start()
+
+	err := resp.client().update("example.com", testChanges(t))
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	requests, protocols := resp.recorded()
+	if len(requests) != 1 || protocols[0] != "udp" {
+		t.Fatalf("expected one udp request, got %v", protocols)
+	}
+	msg := requests[0]
+
+	if msg.Header.OpCode != rfc2136OpCode || msg.Header.Response {
+		t.Errorf("unexpected header: %+v", msg.Header)
+	}
+
+	if len(msg.Questions) != 1 {
+		t.Fatalf("expected one zone record, got %d", len(msg.Questions))
+	}
+	zone := msg.Questions[0]
+	if zone.Name.String() != "example.com." ||
+		zone.Type != dnsmessage.TypeSOA ||
+		zone.Class != dnsmessage.ClassINET {
+
+		t.Errorf("unexpected zone: %s", zone.GoString())
+	}
+
+	if len(msg.Answers) != 0 {
+		t.Errorf("expected no prerequisites, got %d", len(msg.Answers))
+	}
+
+	if len(msg.Authorities) != 3 {
+		t.Fatalf("expected three updates, got %d", len(msg.Authorities))
+	}
+
+	deleteAll := msg.Authorities[0].Header
+	if deleteAll.Name.String() != "_acme-challenge.example.com." ||
+		deleteAll.Type != dnsmessage.TypeTXT ||
+		deleteAll.Class != dnsmessage.ClassANY ||
+		deleteAll.TTL != 0 || deleteAll.Length != 0 {
+
+		t.Errorf("unexpected delete rrset: %s", deleteAll.GoString())
+	}
+
+	add := msg.Authorities[1]
+	if add.Header.Name.String() != "_acme-challenge.example.com." ||
+		add.Header.Type != dnsmessage.TypeTXT ||
+		add.Header.Class != dnsmessage.ClassINET ||
+		add.Header.TTL != 60 {
+
+		t.Errorf("unexpected add: %s", add.Header.GoString())
+	}
+	txt, ok := add.Body.(*dnsmessage.TXTResource)
+	if !ok || len(txt.TXT) != 1 || txt.TXT[0] != "token" {
+		t.Errorf("unexpected add data: %s", add.Body.GoString())
+	}
+
+	del := msg.Authorities[2]
+	if del.Header.Name.String() != "www.example.com." ||
+		del.Header.Type != dnsmessage.TypeA ||
+		del.Header.Class != classNone ||
+		del.Header.TTL != 0 {
+
+		t.Errorf("unexpected delete rr: %s", del.Header.GoString())
+	}
+	a, ok := del.Body.(*dnsmessage.AResource)
+	if !ok || net.IP(a.A[:]).String() != "192.0.2.1" {
+		t.Errorf("unexpected delete data: %s", del.Body.GoString())
+	}
+
+	if len(msg.Additionals) != 1 {
+		t.Fatalf("expected tsig record, got %d", len(msg.Additionals))
+	}
+	tsig := msg.Additionals[0].Header
+	if tsig.Name.String() != "test.key." || tsig.Type != tsigType ||
+		tsig.Class != dnsmessage.ClassANY {
+
+		t.Errorf("unexpected tsig: %s", tsig.GoString())
+	}
+}
+
+func TestRfc2136UpdateTcp(t *testing.T) {
+	resp := newTestResponder(t)
+	defer resp.Close()
+	resp.truncate = true
+	resp.Start()
+
+	err := resp.client().update("example.com", testChanges(t))
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	_, protocols := resp.recorded()
+	if len(protocols) != 2 || protocols[0] != "udp" ||
+		protocols[1] != "tcp" {
+
+		t.Errorf("expected udp then tcp request, got %v", protocols)
+	}
+}
+
+func TestRfc2136ResponseUnsigned(t *testing.T) {
+	resp := newTestResponder(t)
+	defer resp.Close()
+	resp.unsigned = true
+	resp.Start()
+
+	err := resp.client().update("example.com", testChanges(t))
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected unsigned response error, got %v", err)
+	}
+}
+
+func TestRfc2136ResponseBadSignature(t *testing.T) {
+	resp := newTestResponder(t)
+	defer resp.Close()
+	resp.signKey.Secret = []byte("other-secret")
+	resp.Start()
+
+	err := resp.client().update("example.com", testChanges(t))
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected bad signature error, got %v", err)
+	}
+}
+
+func TestRfc2136Refused(t *testing.T) {
+	resp := newTestResponder(t)
+	defer resp.Close()
+	resp.rcode = dnsmessage.RCodeRefused
+	resp.Start()
+
+	err := resp.client().update("example.com", testChanges(t))
+	if _, ok := err.(*ServiceError); !ok {
+		t.Errorf("expected service error, got %v", err)
+	}
+}
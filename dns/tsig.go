@@ -0,0 +1,410 @@
+package dns
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	tsigType  = 250
+	tsigFudge = 300
+
+	tsigHmacSha1   = "hmac-sha1"
+	tsigHmacSha256 = "hmac-sha256"
+	tsigHmacSha384 = "hmac-sha384"
+	tsigHmacSha512 = "hmac-sha512"
+)
+
+type tsigKey struct {
+	Name      string
+	Algorithm string
+	Secret    []byte
+}
+
+func (t *tsigKey) hash() (fn func() hash.Hash, err error) {
+	switch t.Algorithm {
+	case tsigHmacSha1:
+		fn = sha1.New
+		break
+	case tsigHmacSha256:
+		fn = sha256.New
+		break
+	case tsigHmacSha384:
+		fn = sha512.New384
+		break
+	case tsigHmacSha512:
+		fn = sha512.New
+		break
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("dns: Unknown TSIG algorithm '%s'", t.Algorithm),
+		}
+		return
+	}
+
+	return
+}
+
+// variables builds the TSIG variables covered by the MAC as described
+// in RFC 8945 section 4.3.3
+func (t *tsigKey) variables(timeSigned uint64, fudge, rcode uint16,
+	other []byte) (data []byte) {
+
+	data = packName(t.Name)
+	data = binary.BigEndian.AppendUint16(data, uint16(dnsmessage.ClassANY))
+	data = binary.BigEndian.AppendUint32(data, 0)
+	data = append(data, packName(t.Algorithm)...)
+	data = appendUint48(data, timeSigned)
+	data = binary.BigEndian.AppendUint16(data, fudge)
+	data = binary.BigEndian.AppendUint16(data, rcode)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(other)))
+	data = append(data, other...)
+
+	return
+}
+
+// Sign appends a TSIG record to an unsigned message and returns the
+// signed message with the request MAC needed to verify the response
+func (t *tsigKey) Sign(msg []byte) (signed, mac []byte, err error) {
+	signed, mac, err = t.sign(msg, nil, uint64(time.Now().Unix()))
+	return
+}
+
+// sign signs the message with the time signed, responses are signed with
+// the request MAC as described in RFC 8945 section 4.3.1
+func (t *tsigKey) sign(msg, reqMac []byte, timeSigned uint64) (
+	signed, mac []byte, err error) {
+
+	if len(msg) < 12 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Message too short to sign"),
+		}
+		return
+	}
+
+	hashFn, err := t.hash()
+	if err != nil {
+		return
+	}
+
+	hsh := hmac.New(hashFn, t.Secret)
+	if reqMac != nil {
+		hsh.Write(binary.BigEndian.AppendUint16(nil, uint16(len(reqMac))))
+		hsh.Write(reqMac)
+	}
+	hsh.Write(msg)
+	hsh.Write(t.variables(timeSigned, tsigFudge, 0, nil))
+	mac = hsh.Sum(nil)
+
+	rdata := packName(t.Algorithm)
+	rdata = appendUint48(rdata, timeSigned)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(mac)))
+	rdata = append(rdata, mac...)
+	rdata = append(rdata, msg[0:2]...)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0)
+
+	signed = make([]byte, len(msg), len(msg)+len(rdata)+64)
+	copy(signed, msg)
+
+	signed = append(signed, packName(t.Name)...)
+	signed = binary.BigEndian.AppendUint16(signed, tsigType)
+	signed = binary.BigEndian.AppendUint16(
+		signed, uint16(dnsmessage.ClassANY))
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+
+	binary.BigEndian.PutUint16(signed[10:12],
+		binary.BigEndian.Uint16(signed[10:12])+1)
+
+	return
+}
+
+// Verify checks the TSIG record of a response against the MAC of the
+// request that was sent, requests are verified with a nil MAC
+func (t *tsigKey) Verify(msg, reqMac []byte) (err error) {
+	if len(msg) < 12 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Response too short"),
+		}
+		return
+	}
+
+	arCount := binary.BigEndian.Uint16(msg[10:12])
+	if arCount == 0 {
+		err = &errortypes.VerificationError{
+			errors.New("dns: Response not signed"),
+		}
+		return
+	}
+
+	off := 12
+	for i := 0; i < int(binary.BigEndian.Uint16(msg[4:6])); i++ {
+		off, err = skipName(msg, off)
+		if err != nil {
+			return
+		}
+		off += 4
+	}
+
+	rrCount := int(binary.BigEndian.Uint16(msg[6:8])) +
+		int(binary.BigEndian.Uint16(msg[8:10])) + int(arCount) - 1
+	for i := 0; i < rrCount; i++ {
+		off, err = skipRecord(msg, off)
+		if err != nil {
+			return
+		}
+	}
+	tsigOff := off
+
+	off, err = skipName(msg, off)
+	if err != nil {
+		return
+	}
+
+	if off+10 > len(msg) ||
+		binary.BigEndian.Uint16(msg[off:off+2]) != tsigType {
+
+		err = &errortypes.VerificationError{
+			errors.New("dns: Response not signed"),
+		}
+		return
+	}
+	rdLen := int(binary.BigEndian.Uint16(msg[off+8 : off+10]))
+	off += 10
+
+	rdata := msg[off:]
+	if len(rdata) < rdLen {
+		err = &errortypes.ParseError{
+			errors.New("dns: Response TSIG record truncated"),
+		}
+		return
+	}
+	rdata = rdata[:rdLen]
+
+	rOff, err := skipName(rdata, 0)
+	if err != nil {
+		return
+	}
+
+	if rOff+10 > len(rdata) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Response TSIG record truncated"),
+		}
+		return
+	}
+	timeSigned := readUint48(rdata[rOff : rOff+6])
+	fudge := binary.BigEndian.Uint16(rdata[rOff+6 : rOff+8])
+	macLen := int(binary.BigEndian.Uint16(rdata[rOff+8 : rOff+10]))
+	rOff += 10
+
+	if rOff+macLen+6 > len(rdata) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Response TSIG record truncated"),
+		}
+		return
+	}
+	mac := rdata[rOff : rOff+macLen]
+	rOff += macLen
+	origId := rdata[rOff : rOff+2]
+	rcode := binary.BigEndian.Uint16(rdata[rOff+2 : rOff+4])
+	otherLen := int(binary.BigEndian.Uint16(rdata[rOff+4 : rOff+6]))
+	rOff += 6
+
+	if rOff+otherLen > len(rdata) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Response TSIG record truncated"),
+		}
+		return
+	}
+	other := rdata[rOff : rOff+otherLen]
+
+	if rcode != 0 {
+		err = &errortypes.VerificationError{
+			errors.Newf("dns: Server TSIG error %d", rcode),
+		}
+		return
+	}
+
+	hashFn, err := t.hash()
+	if err != nil {
+		return
+	}
+
+	unsigned := make([]byte, tsigOff)
+	copy(unsigned, msg[:tsigOff])
+	copy(unsigned[0:2], origId)
+	binary.BigEndian.PutUint16(unsigned[10:12], arCount-1)
+
+	hsh := hmac.New(hashFn, t.Secret)
+	if reqMac != nil {
+		hsh.Write(binary.BigEndian.AppendUint16(nil, uint16(len(reqMac))))
+		hsh.Write(reqMac)
+	}
+	hsh.Write(unsigned)
+	hsh.Write(t.variables(timeSigned, fudge, rcode, other))
+
+	if !hmac.Equal(hsh.Sum(nil), mac) {
+		err = &errortypes.VerificationError{
+			errors.New("dns: Response TSIG signature invalid"),
+		}
+		return
+	}
+
+	now := time.Now().Unix()
+	if now > int64(timeSigned)+int64(fudge) ||
+		now < int64(timeSigned)-int64(fudge) {
+
+		err = &errortypes.VerificationError{
+			errors.New("dns: Response TSIG time outside fudge"),
+		}
+		return
+	}
+
+	return
+}
+
+// parseTsigKey parses a key in the nsupdate format of [algorithm:]name
+// with a base64 encoded secret, the algorithm defaults to hmac-sha256
+func parseTsigKey(key, secr string) (tsig *tsigKey, err error) {
+	key = strings.TrimSpace(key)
+
+	algorithm := tsigHmacSha256
+	name := key
+	if strings.Contains(key, ":") {
+		parts := strings.SplitN(key, ":", 2)
+		algorithm = strings.ToLower(parts[0])
+		name = parts[1]
+	}
+
+	name = strings.ToLower(strings.Trim(name, "."))
+	if name == "" {
+		err = &errortypes.ParseError{
+			errors.New("dns: Missing TSIG key name"),
+		}
+		return
+	}
+
+	secrBytes, err := base64.StdEncoding.DecodeString(
+		strings.TrimSpace(secr))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to decode TSIG secret"),
+		}
+		return
+	}
+
+	tsig = &tsigKey{
+		Name:      name,
+		Algorithm: algorithm,
+		Secret:    secrBytes,
+	}
+
+	_, err = tsig.hash()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func packName(name string) (data []byte) {
+	name = strings.ToLower(strings.Trim(name, "."))
+
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			data = append(data, byte(len(label)))
+			data = append(data, label...)
+		}
+	}
+	data = append(data, 0)
+
+	return
+}
+
+func skipName(msg []byte, off int) (next int, err error) {
+	for {
+		if off >= len(msg) {
+			err = &errortypes.ParseError{
+				errors.New("dns: Name exceeds message"),
+			}
+			return
+		}
+
+		n := int(msg[off])
+		off += 1
+
+		switch n & 0xc0 {
+		case 0x00:
+			if n == 0 {
+				next = off
+				return
+			}
+			off += n
+			break
+		case 0xc0:
+			next = off + 1
+			if next > len(msg) {
+				err = &errortypes.ParseError{
+					errors.New("dns: Name exceeds message"),
+				}
+			}
+			return
+		default:
+			err = &errortypes.ParseError{
+				errors.New("dns: Invalid name label"),
+			}
+			return
+		}
+	}
+}
+
+func skipRecord(msg []byte, off int) (next int, err error) {
+	off, err = skipName(msg, off)
+	if err != nil {
+		return
+	}
+
+	if off+10 > len(msg) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Record exceeds message"),
+		}
+		return
+	}
+
+	next = off + 10 + int(binary.BigEndian.Uint16(msg[off+8:off+10]))
+	if next > len(msg) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Record exceeds message"),
+		}
+		return
+	}
+
+	return
+}
+
+func appendUint48(data []byte, val uint64) []byte {
+	return append(data,
+		byte(val>>40), byte(val>>32), byte(val>>24),
+		byte(val>>16), byte(val>>8), byte(val),
+	)
+}
+
+func readUint48(data []byte) uint64 {
+	return uint64(data[0])<<40 | uint64(data[1])<<32 |
+		uint64(data[2])<<24 | uint64(data[3])<<16 |
+		uint64(data[4])<<8 | uint64(data[5])
+}
@@ -0,0 +1,125 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+// Update message for _acme-challenge.example.com TXT "token" in the
+// example.com zone with id 0x1234
+const testTsigMsg = "123428000001000000010000076578616d706c6503636f6d" +
+	"00000600010f5f61636d652d6368616c6c656e6765076578616d706c6503636f" +
+	"6d00001000010000003c000605746f6b656e"
+
+// MAC and signed message computed with the RFC 8945 section 4.3.3 digest
+// layout for key test.key, hmac-sha256, time signed 1700000000, fudge 300
+const (
+	testTsigMac = "ba9dcb23b0961b435d2a43cd38e7a174" +
+		"072e67765cb708526067cde80823ea1f"
+	testTsigSigned = "123428000001000000010001076578616d706c6503636f6d" +
+		"00000600010f5f61636d652d6368616c6c656e6765076578616d706c6503636f" +
+		"6d00001000010000003c000605746f6b656e0474657374036b65790000fa00ff" +
+		"00000000003d0b686d61632d7368613235360000006553f100012c0020" +
+		testTsigMac + "123400000000"
+)
+
+func newTestTsigKey(t *testing.T) *tsigKey {
+	key, err := parseTsigKey("hmac-sha256:test.key.",
+		"cHJpdHVubC1jbG91ZC10c2lnLXRlc3Qta2V5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func decodeHex(t *testing.T, val string) []byte {
+	data, err := hex.DecodeString(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTsigSignVector(t *testing.T) {
+	key := newTestTsigKey(t)
+	msg := decodeHex(t, testTsigMsg)
+
+	signed, mac, err := key.sign(msg, nil, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hex.EncodeToString(mac) != testTsigMac {
+		t.Errorf("unexpected mac: %x", mac)
+	}
+
+	if hex.EncodeToString(signed) != testTsigSigned {
+		t.Errorf("unexpected signed message: %x", signed)
+	}
+
+	if !bytes.Equal(msg, decodeHex(t, testTsigMsg)) {
+		t.Error("unsigned message modified")
+	}
+}
+
+func TestTsigVerify(t *testing.T) {
+	key := newTestTsigKey(t)
+	msg := decodeHex(t, testTsigMsg)
+	now := uint64(time.Now().Unix())
+
+	_, reqMac, err := key.sign(msg, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := decodeHex(t, testTsigMsg)
+	resp[2] |= 0x80
+
+	signed, _, err := key.sign(resp, reqMac, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = key.Verify(signed, reqMac)
+	if err != nil {
+		t.Fatalf("verify signed response: %v", err)
+	}
+
+	err = key.Verify(resp, reqMac)
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected unsigned response error, got %v", err)
+	}
+
+	err = key.Verify(signed, make([]byte, len(reqMac)))
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected request mac mismatch error, got %v", err)
+	}
+
+	modified := append([]byte{}, signed...)
+	modified[len(msg)-1] ^= 0xff
+	err = key.Verify(modified, reqMac)
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected modified response error, got %v", err)
+	}
+
+	otherKey := newTestTsigKey(t)
+	otherKey.Secret = []byte("other-secret")
+	err = otherKey.Verify(signed, reqMac)
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected wrong key error, got %v", err)
+	}
+
+	expired, _, err := key.sign(resp, reqMac, now-2*tsigFudge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = key.Verify(expired, reqMac)
+	if _, ok := err.(*errortypes.VerificationError); !ok {
+		t.Errorf("expected time outside fudge error, got %v", err)
+	}
+}
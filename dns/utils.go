@@ -30,3 +30,14 @@ func extractDomain(domain string) string {
 func cleanDomain(domain string) string {
 	return strings.Trim(domain, ".")
 }
+
+// getTtl returns the record set ttl from the operations or the provider
+// default if unset
+func getTtl(ops []*Operation, defaultTtl int) int {
+	for _, op := range ops {
+		if op.Ttl > 0 {
+			return op.Ttl
+		}
+	}
+	return defaultTtl
+}
@@ -0,0 +1,24 @@
+package dnss
+
+import (
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	Port   = 53
+	Tld    = "internal"
+	Ttl    = 30
+	MaxUdp = 512
+
+	refreshRate    = 10 * time.Second
+	retryRate      = 10 * time.Second
+	forwardTimeout = 3 * time.Second
+	streamTimeout  = 10 * time.Second
+	maxCname       = 8
+	maxRequests    = 64
+	maxStreams     = 16
+
+	typeCaa = dnsmessage.Type(257)
+)
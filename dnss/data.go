@@ -0,0 +1,229 @@
+package dnss
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/domain"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/vpc"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var (
+	curData     = newData()
+	curDataLock = sync.RWMutex{}
+	targetVpcs  = set.NewSet()
+	targetOrgs  = set.NewSet()
+	targetsLock = sync.Mutex{}
+	refresh     = make(chan bool, 1)
+	refreshOnce = sync.Once{}
+)
+
+// data holds the zones loaded from the database, VPC zones are stored with
+// a reverse zone containing the PTR records of the VPC addresses.
+type data struct {
+	Vpcs    map[primitive.ObjectID]*zone
+	Reverse map[primitive.ObjectID]*zone
+	Orgs    map[primitive.ObjectID][]*zone
+}
+
+func newData() *data {
+	return &data{
+		Vpcs:    map[primitive.ObjectID]*zone{},
+		Reverse: map[primitive.ObjectID]*zone{},
+		Orgs:    map[primitive.ObjectID][]*zone{},
+	}
+}
+
+func getData() *data {
+	curDataLock.RLock()
+	dat := curData
+	curDataLock.RUnlock()
+	return dat
+}
+
+func load(db *database.Database, vpcIds, orgIds []primitive.ObjectID) (
+	dat *data, err error) {
+
+	dat = newData()
+	serial := uint32(time.Now().Unix())
+
+	if len(vpcIds) > 0 {
+		vcs, e := vpc.GetIds(db, vpcIds)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, vc := range vcs {
+			name := label(vc.Name)
+			if name == "" {
+				continue
+			}
+
+			dat.Vpcs[vc.Id] = newZone(name+"."+Tld, serial)
+			dat.Reverse[vc.Id] = newZone("arpa", serial)
+		}
+
+		insts, e := instance.GetAllNameAddresses(db, &bson.M{
+			"vpc": &bson.M{
+				"$in": vpcIds,
+			},
+		})
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, inst := range insts {
+			zne := dat.Vpcs[inst.Vpc]
+			revZne := dat.Reverse[inst.Vpc]
+			host := label(inst.Name)
+			if zne == nil || host == "" {
+				continue
+			}
+			name := host + "." + zne.Name
+
+			addrs := append([]string{}, inst.PrivateIps...)
+			addrs = append(addrs, inst.PrivateIps6...)
+
+			for _, addrStr := range addrs {
+				addr := net.ParseIP(strings.Split(addrStr, "/")[0])
+				if addr == nil {
+					continue
+				}
+
+				body, typ := addressBody(addr)
+				zne.Add(name, typ, body)
+
+				revZne.Add(reverseName(addr), dnsmessage.TypePTR,
+					&dnsmessage.PTRResource{
+						PTR: newName(name),
+					})
+			}
+		}
+	}
+
+	if len(orgIds) > 0 {
+		domns, e := domain.GetAll(db, &bson.M{
+			"organization": &bson.M{
+				"$in": orgIds,
+			},
+			"type": domain.Builtin,
+		})
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, domn := range domns {
+			rootDomain := strings.ToLower(strings.Trim(domn.RootDomain, "."))
+			if rootDomain == "" {
+				continue
+			}
+
+			err = domn.LoadRecords(db)
+			if err != nil {
+				return
+			}
+
+			zne := newZone(rootDomain, serial)
+
+			for _, rec := range domn.Records {
+				name := rootDomain
+				subDomain := strings.ToLower(strings.Trim(rec.SubDomain, "."))
+				if subDomain != "" && subDomain != "@" {
+					name = subDomain + "." + rootDomain
+				}
+
+				e = zne.AddValue(name, rec.Type, rec.Value, rec.Ttl)
+				if e != nil {
+					logrus.WithFields(logrus.Fields{
+						"domain_id": domn.Id.Hex(),
+						"record_id": rec.Id.Hex(),
+						"error":     e,
+					}).Warn("dnss: Skipping invalid domain record")
+					continue
+				}
+			}
+
+			dat.Orgs[domn.Organization] = append(
+				dat.Orgs[domn.Organization], zne)
+		}
+	}
+
+	return
+}
+
+func setTargets(vpcIds, orgIds set.Set) {
+	targetsLock.Lock()
+	changed := !targetVpcs.IsEqual(vpcIds) || !targetOrgs.IsEqual(orgIds)
+	targetVpcs = vpcIds
+	targetOrgs = orgIds
+	targetsLock.Unlock()
+
+	refreshOnce.Do(func() {
+		go refresher()
+	})
+
+	if changed {
+		select {
+		case refresh <- true:
+		default:
+		}
+	}
+}
+
+func getTargets() (vpcIds, orgIds []primitive.ObjectID) {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+
+	vpcIds = []primitive.ObjectID{}
+	for vpcIdInf := range targetVpcs.Iter() {
+		vpcIds = append(vpcIds, vpcIdInf.(primitive.ObjectID))
+	}
+
+	orgIds = []primitive.ObjectID{}
+	for orgIdInf := range targetOrgs.Iter() {
+		orgIds = append(orgIds, orgIdInf.(primitive.ObjectID))
+	}
+
+	return
+}
+
+func refresher() {
+	for {
+		select {
+		case <-refresh:
+		case <-time.After(refreshRate):
+		}
+
+		vpcIds, orgIds := getTargets()
+
+		dat := newData()
+		if len(vpcIds) > 0 || len(orgIds) > 0 {
+			db := database.GetDatabase()
+			d, err := load(db, vpcIds, orgIds)
+			db.Close()
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": err,
+				}).Error("dnss: Failed to load dns records")
+				continue
+			}
+			dat = d
+		}
+
+		curDataLock.Lock()
+		curData = dat
+		curDataLock.Unlock()
+	}
+}
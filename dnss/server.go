@@ -0,0 +1,599 @@
+package dnss
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sys/unix"
+)
+
+// Config of the server for an instance namespace, the first VPC is the
+// instance VPC followed by the peered VPCs.
+type Config struct {
+	Namespace    string
+	Organization primitive.ObjectID
+	Vpcs         []primitive.ObjectID
+	Addresses    []net.IP
+}
+
+func (c *Config) key() string {
+	key := c.Namespace
+	for _, addr := range c.Addresses {
+		key += "-" + addr.String()
+	}
+	return key
+}
+
+// server answers dns queries on the VPC gateway addresses of a namespace.
+// Sockets belong to the namespace they are created in, once created the
+// sockets are served from goroutines in the host namespace which allows
+// forwarded queries to reach the upstream servers.
+type server struct {
+	conf     *Config
+	confLock sync.Mutex
+	stop     chan bool
+	requests chan struct{}
+	streams  chan struct{}
+}
+
+func (s *server) Config() *Config {
+	s.confLock.Lock()
+	defer s.confLock.Unlock()
+	return s.conf
+}
+
+func (s *server) SetConfig(conf *Config) {
+	s.confLock.Lock()
+	s.conf = conf
+	s.confLock.Unlock()
+}
+
+func (s *server) open() (conns []net.PacketConn, lstns []net.Listener,
+	err error) {
+
+	namespace := s.Config().Namespace
+
+	nsFile, err := os.Open(filepath.Join("/var/run/netns", namespace))
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dnss: Failed to open namespace"),
+		}
+		return
+	}
+	defer nsFile.Close()
+
+	err = unix.Setns(int(nsFile.Fd()), unix.CLONE_NEWNET)
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "dnss: Failed to set namespace"),
+		}
+		return
+	}
+
+	for _, addr := range s.Config().Addresses {
+		listenAddr := net.JoinHostPort(addr.String(), strconv.Itoa(Port))
+
+		conn, e := net.ListenPacket("udp", listenAddr)
+		if e != nil {
+			err = &errortypes.ExecError{
+				errors.Wrap(e, "dnss: Failed to listen on udp"),
+			}
+			break
+		}
+		conns = append(conns, conn)
+
+		lstn, e := net.Listen("tcp", listenAddr)
+		if e != nil {
+			err = &errortypes.ExecError{
+				errors.Wrap(e, "dnss: Failed to listen on tcp"),
+			}
+			break
+		}
+		lstns = append(lstns, lstn)
+	}
+
+	if err != nil {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		for _, lstn := range lstns {
+			lstn.Close()
+		}
+		conns = nil
+		lstns = nil
+	}
+
+	return
+}
+
+// run locks the goroutine to a thread which is moved into the namespace,
+// the thread is discarded when the goroutine exits.
+func (s *server) run() {
+	runtime.LockOSThread()
+
+	var conns []net.PacketConn
+	var lstns []net.Listener
+	for {
+		var err error
+		conns, lstns, err = s.open()
+		if err == nil {
+			break
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"namespace": s.Config().Namespace,
+			"error":     err,
+		}).Error("dnss: Failed to start server")
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(retryRate):
+		}
+	}
+
+	for _, conn := range conns {
+		go s.servePacket(conn)
+	}
+	for _, lstn := range lstns {
+		go s.serveStream(lstn)
+	}
+
+	<-s.stop
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	for _, lstn := range lstns {
+		lstn.Close()
+	}
+}
+
+func (s *server) servePacket(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"namespace": s.Config().Namespace,
+				"error":     err,
+			}).Error("dnss: Server read error")
+
+			time.Sleep(time.Second)
+			continue
+		}
+
+		req := make([]byte, n)
+		copy(req, buf[:n])
+
+		select {
+		case s.requests <- struct{}{}:
+		default:
+			continue
+		}
+
+		go func() {
+			defer func() {
+				<-s.requests
+			}()
+
+			resp := s.handle(req, false)
+			if resp != nil {
+				_, _ = conn.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+func (s *server) serveStream(lstn net.Listener) {
+	for {
+		conn, err := lstn.Accept()
+		if err != nil {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"namespace": s.Config().Namespace,
+				"error":     err,
+			}).Error("dnss: Server accept error")
+
+			time.Sleep(time.Second)
+			continue
+		}
+
+		select {
+		case s.streams <- struct{}{}:
+		default:
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			defer func() {
+				<-s.streams
+			}()
+
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		_ = conn.SetDeadline(time.Now().Add(streamTimeout))
+
+		req, err := readStream(conn)
+		if err != nil {
+			return
+		}
+
+		resp := s.handle(req, true)
+		if resp == nil {
+			return
+		}
+
+		err = writeStream(conn, resp)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) handle(req []byte, stream bool) (resp []byte) {
+	parser := dnsmessage.Parser{}
+	header, err := parser.Start(req)
+	if err != nil || header.Response {
+		return
+	}
+
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 header.ID,
+			Response:           true,
+			OpCode:             header.OpCode,
+			RecursionDesired:   header.RecursionDesired,
+			RecursionAvailable: true,
+		},
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		msg.Header.RCode = dnsmessage.RCodeFormatError
+		resp, _ = msg.Pack()
+		return
+	}
+	msg.Questions = []dnsmessage.Question{question}
+
+	if header.OpCode != 0 {
+		msg.Header.RCode = dnsmessage.RCodeNotImplemented
+		resp, _ = msg.Pack()
+		return
+	}
+
+	if question.Class != dnsmessage.ClassINET &&
+		question.Class != dnsmessage.ClassANY {
+
+		resp = s.forward(req, stream)
+		if resp == nil {
+			msg.Header.RCode = dnsmessage.RCodeServerFailure
+			resp, _ = msg.Pack()
+		}
+		return
+	}
+
+	conf := s.Config()
+	dat := getData()
+
+	ok := s.resolve(dat, conf, msg, cleanName(question.Name),
+		question.Type)
+	if !ok {
+		resp = s.forward(req, stream)
+		if resp == nil {
+			msg.Header.RCode = dnsmessage.RCodeServerFailure
+			resp, _ = msg.Pack()
+		}
+		return
+	}
+
+	resp, err = msg.Pack()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"namespace": conf.Namespace,
+			"name":      question.Name.String(),
+			"error":     err,
+		}).Error("dnss: Failed to pack response")
+
+		msg.Answers = nil
+		msg.Authorities = nil
+		msg.Header.RCode = dnsmessage.RCodeServerFailure
+		resp, _ = msg.Pack()
+		return
+	}
+
+	if !stream && len(resp) > MaxUdp {
+		msg.Answers = nil
+		msg.Authorities = nil
+		msg.Header.Truncated = true
+		resp, _ = msg.Pack()
+	}
+
+	return
+}
+
+func (s *server) lookup(dat *data, conf *Config, name string) (zne *zone) {
+	// Reverse zones only answer known addresses, other reverse lookups
+	// are forwarded
+	for _, vpcId := range conf.Vpcs {
+		revZne := dat.Reverse[vpcId]
+		if revZne != nil && len(revZne.Records[name]) > 0 {
+			zne = revZne
+			return
+		}
+	}
+
+	zones := []*zone{}
+	for _, vpcId := range conf.Vpcs {
+		if vpcZne := dat.Vpcs[vpcId]; vpcZne != nil {
+			zones = append(zones, vpcZne)
+		}
+	}
+	zones = append(zones, dat.Orgs[conf.Organization]...)
+
+	for _, z := range zones {
+		if z.Contains(name) && (zne == nil || len(z.Name) > len(zne.Name)) {
+			zne = z
+		}
+	}
+
+	return
+}
+
+// resolve answers names in the served zones and follows CNAME records,
+// returns false if the name should be forwarded
+func (s *server) resolve(dat *data, conf *Config, msg *dnsmessage.Message,
+	name string, typ dnsmessage.Type) bool {
+
+	zne := s.lookup(dat, conf, name)
+	if zne == nil {
+		return false
+	}
+	msg.Header.Authoritative = true
+
+	for i := 0; i < maxCname; i++ {
+		records := zne.Get(name, conf.Addresses)
+		if len(records) == 0 {
+			if !zne.Exists(name) {
+				msg.Header.RCode = dnsmessage.RCodeNameError
+			}
+			msg.Authorities = []dnsmessage.Resource{zne.Soa()}
+			return true
+		}
+
+		matched := false
+		var cname *dnsmessage.CNAMEResource
+		for _, record := range records {
+			if typ == dnsmessage.TypeALL || record.Header.Type == typ {
+				msg.Answers = append(msg.Answers, record)
+				matched = true
+			} else if record.Header.Type == dnsmessage.TypeCNAME {
+				msg.Answers = append(msg.Answers, record)
+				cname = record.Body.(*dnsmessage.CNAMEResource)
+			}
+		}
+
+		if matched || cname == nil {
+			if !matched {
+				msg.Authorities = []dnsmessage.Resource{zne.Soa()}
+			}
+			return true
+		}
+
+		name = cleanName(cname.CNAME)
+		zne = s.lookup(dat, conf, name)
+		if zne == nil {
+			msg.Answers = append(msg.Answers, s.forwardQuery(name, typ)...)
+			return true
+		}
+	}
+
+	return true
+}
+
+func (s *server) upstreams() (servers []string) {
+	for _, addr := range []string{
+		settings.Hypervisor.DnsServerPrimary,
+		settings.Hypervisor.DnsServerSecondary,
+		settings.Hypervisor.DnsServerPrimary6,
+		settings.Hypervisor.DnsServerSecondary6,
+	} {
+		if addr != "" {
+			servers = append(servers, net.JoinHostPort(addr, "53"))
+		}
+	}
+
+	return
+}
+
+// forward sends a query to the upstream servers from the host namespace
+func (s *server) forward(req []byte, stream bool) (resp []byte) {
+	for _, upstream := range s.upstreams() {
+		var err error
+		if stream {
+			resp, err = exchangeStream(upstream, req)
+		} else {
+			resp, err = exchangePacket(upstream, req)
+		}
+		if err == nil {
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"namespace": s.Config().Namespace,
+			"upstream":  upstream,
+			"error":     err,
+		}).Warn("dnss: Failed to forward query")
+	}
+
+	resp = nil
+	return
+}
+
+// forwardQuery resolves a CNAME target outside of the served zones
+func (s *server) forwardQuery(name string, typ dnsmessage.Type) (
+	records []dnsmessage.Resource) {
+
+	req := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               uint16(time.Now().UnixNano()),
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{
+			{
+				Name:  newName(name),
+				Type:  typ,
+				Class: dnsmessage.ClassINET,
+			},
+		},
+	}
+
+	reqData, err := req.Pack()
+	if err != nil {
+		return
+	}
+
+	respData := s.forward(reqData, false)
+	if respData == nil {
+		return
+	}
+
+	resp := &dnsmessage.Message{}
+	err = resp.Unpack(respData)
+	if err != nil || resp.Header.ID != req.Header.ID {
+		return
+	}
+
+	records = resp.Answers
+
+	return
+}
+
+func exchangePacket(addr string, req []byte) (resp []byte, err error) {
+	conn, err := net.DialTimeout("udp", addr, forwardTimeout)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dnss: Failed to connect to upstream"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(forwardTimeout))
+
+	_, err = conn.Write(req)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dnss: Failed to write to upstream"),
+		}
+		return
+	}
+
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dnss: Failed to read from upstream"),
+		}
+		return
+	}
+
+	resp = buf[:n]
+
+	return
+}
+
+func exchangeStream(addr string, req []byte) (resp []byte, err error) {
+	conn, err := net.DialTimeout("tcp", addr, forwardTimeout)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "dnss: Failed to connect to upstream"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(forwardTimeout))
+
+	err = writeStream(conn, req)
+	if err != nil {
+		return
+	}
+
+	resp, err = readStream(conn)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func readStream(conn net.Conn) (msg []byte, err error) {
+	lenBuf := make([]byte, 2)
+	_, err = io.ReadFull(conn, lenBuf)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dnss: Failed to read message length"),
+		}
+		return
+	}
+
+	msg = make([]byte, binary.BigEndian.Uint16(lenBuf))
+	_, err = io.ReadFull(conn, msg)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dnss: Failed to read message"),
+		}
+		return
+	}
+
+	return
+}
+
+func writeStream(conn net.Conn, msg []byte) (err error) {
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	data = append(data, msg...)
+
+	_, err = conn.Write(data)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dnss: Failed to write message"),
+		}
+		return
+	}
+
+	return
+}
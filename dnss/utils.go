@@ -0,0 +1,112 @@
+package dnss
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var (
+	servers     = map[string]*server{}
+	serversLock = sync.Mutex{}
+)
+
+// Sync starts a server for each namespace config and stops the servers of
+// namespaces no longer served. Servers are only restarted when the listen
+// addresses change.
+func Sync(confs []*Config) {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+
+	vpcIds := set.NewSet()
+	orgIds := set.NewSet()
+	newConfs := map[string]*Config{}
+	for _, conf := range confs {
+		newConfs[conf.Namespace] = conf
+
+		for _, vpcId := range conf.Vpcs {
+			vpcIds.Add(vpcId)
+		}
+		orgIds.Add(conf.Organization)
+	}
+
+	for namespace, srv := range servers {
+		conf := newConfs[namespace]
+		if conf == nil || conf.key() != srv.Config().key() {
+			close(srv.stop)
+			delete(servers, namespace)
+		}
+	}
+
+	for namespace, conf := range newConfs {
+		srv := servers[namespace]
+		if srv != nil {
+			srv.SetConfig(conf)
+			continue
+		}
+
+		srv = &server{
+			conf:     conf,
+			stop:     make(chan bool),
+			requests: make(chan struct{}, maxRequests),
+			streams:  make(chan struct{}, maxStreams),
+		}
+		servers[namespace] = srv
+
+		go srv.run()
+	}
+
+	setTargets(vpcIds, orgIds)
+}
+
+// label converts a name to a lowercase dns label
+func label(name string) string {
+	name = strings.ToLower(name)
+
+	lbl := strings.Builder{}
+	for _, c := range name {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' {
+			lbl.WriteRune(c)
+		} else {
+			lbl.WriteRune('-')
+		}
+	}
+
+	name = strings.Trim(lbl.String(), "-")
+	if len(name) > 63 {
+		name = strings.Trim(name[:63], "-")
+	}
+
+	return name
+}
+
+func newName(name string) dnsmessage.Name {
+	n, _ := dnsmessage.NewName(strings.Trim(name, ".") + ".")
+	return n
+}
+
+func cleanName(name dnsmessage.Name) string {
+	return strings.ToLower(strings.Trim(name.String(), "."))
+}
+
+func reverseName(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa",
+			ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+
+	ip6 := ip.To16()
+	nibbles := make([]string, 0, 32)
+	for i := len(ip6) - 1; i >= 0; i-- {
+		nibbles = append(nibbles,
+			fmt.Sprintf("%x", ip6[i]&0xf),
+			fmt.Sprintf("%x", ip6[i]>>4),
+		)
+	}
+
+	return strings.Join(nibbles, ".") + ".ip6.arpa"
+}
@@ -0,0 +1,241 @@
+package dnss
+
+import (
+	"net"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/dns"
+	"github.com/pritunl/pritunl-cloud/domain"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// zone holds the records of a zone served by the built-in server, names
+// are stored in lowercase without the trailing dot.
+type zone struct {
+	Name    string
+	Serial  uint32
+	Records map[string][]dnsmessage.Resource
+}
+
+func (z *zone) Contains(name string) bool {
+	return name == z.Name || strings.HasSuffix(name, "."+z.Name)
+}
+
+// Exists returns true if the name has records or is an empty non-terminal
+// of names with records.
+func (z *zone) Exists(name string) bool {
+	if name == z.Name || name == "ns."+z.Name {
+		return true
+	}
+
+	if _, ok := z.Records[name]; ok {
+		return true
+	}
+
+	suffix := "." + name
+	for recName := range z.Records {
+		if strings.HasSuffix(recName, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the records of a name including the SOA and NS records
+// generated for the zone apex. The name server is answered with the
+// addresses of the server handling the query.
+func (z *zone) Get(name string, addrs []net.IP) (
+	records []dnsmessage.Resource) {
+
+	records = append(records, z.Records[name]...)
+
+	if name == z.Name {
+		records = append(records, z.Soa(), z.Ns())
+	}
+
+	if name == "ns."+z.Name {
+		for _, addr := range addrs {
+			body, typ := addressBody(addr)
+			records = append(records, newResource(name, typ, body))
+		}
+	}
+
+	return
+}
+
+func (z *zone) Soa() dnsmessage.Resource {
+	return newResource(z.Name, dnsmessage.TypeSOA, &dnsmessage.SOAResource{
+		NS:      newName("ns." + z.Name),
+		MBox:    newName("hostmaster." + z.Name),
+		Serial:  z.Serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		MinTTL:  Ttl,
+	})
+}
+
+func (z *zone) Ns() dnsmessage.Resource {
+	return newResource(z.Name, dnsmessage.TypeNS, &dnsmessage.NSResource{
+		NS: newName("ns." + z.Name),
+	})
+}
+
+func (z *zone) Add(name string, typ dnsmessage.Type,
+	body dnsmessage.ResourceBody) {
+
+	z.Records[name] = append(z.Records[name], newResource(name, typ, body))
+}
+
+// AddValue adds a record from the type and value stored in the domain
+// records collection, a ttl of zero uses the default ttl
+func (z *zone) AddValue(name, recType, val string, ttl int) (err error) {
+	var typ dnsmessage.Type
+	var body dnsmessage.ResourceBody
+
+	switch recType {
+	case domain.A, domain.AAAA:
+		ip := net.ParseIP(val)
+		if ip == nil {
+			err = &errortypes.ParseError{
+				errors.Newf("dnss: Invalid address %s", val),
+			}
+			return
+		}
+
+		body, typ = addressBody(ip)
+		if (recType == domain.A) != (typ == dnsmessage.TypeA) {
+			err = &errortypes.ParseError{
+				errors.Newf("dnss: Invalid %s address %s", recType, val),
+			}
+			return
+		}
+		break
+	case domain.TXT:
+		txt := []string{}
+		for len(val) > 255 {
+			txt = append(txt, val[:255])
+			val = val[255:]
+		}
+		txt = append(txt, val)
+
+		typ = dnsmessage.TypeTXT
+		body = &dnsmessage.TXTResource{
+			TXT: txt,
+		}
+		break
+	case domain.CNAME:
+		typ = dnsmessage.TypeCNAME
+		body = &dnsmessage.CNAMEResource{
+			CNAME: newName(val),
+		}
+		break
+	case domain.PTR:
+		typ = dnsmessage.TypePTR
+		body = &dnsmessage.PTRResource{
+			PTR: newName(val),
+		}
+		break
+	case domain.MX:
+		priority, target, e := dns.ParseMx(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		typ = dnsmessage.TypeMX
+		body = &dnsmessage.MXResource{
+			Pref: priority,
+			MX:   newName(target),
+		}
+		break
+	case domain.SRV:
+		priority, weight, port, target, e := dns.ParseSrv(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		typ = dnsmessage.TypeSRV
+		body = &dnsmessage.SRVResource{
+			Priority: priority,
+			Weight:   weight,
+			Port:     port,
+			Target:   newName(target),
+		}
+		break
+	case domain.CAA:
+		flags, tag, value, e := dns.ParseCaa(val)
+		if e != nil {
+			err = e
+			return
+		}
+
+		data := []byte{flags, byte(len(tag))}
+		data = append(data, tag...)
+		data = append(data, value...)
+
+		typ = typeCaa
+		body = &dnsmessage.UnknownResource{
+			Type: typeCaa,
+			Data: data,
+		}
+		break
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("dnss: Unknown record type %s", recType),
+		}
+		return
+	}
+
+	res := newResource(name, typ, body)
+	if ttl > 0 {
+		res.Header.TTL = uint32(ttl)
+	}
+	z.Records[name] = append(z.Records[name], res)
+
+	return
+}
+
+func newZone(name string, serial uint32) *zone {
+	return &zone{
+		Name:    name,
+		Serial:  serial,
+		Records: map[string][]dnsmessage.Resource{},
+	}
+}
+
+func newResource(name string, typ dnsmessage.Type,
+	body dnsmessage.ResourceBody) dnsmessage.Resource {
+
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{
+			Name:  newName(name),
+			Type:  typ,
+			Class: dnsmessage.ClassINET,
+			TTL:   Ttl,
+		},
+		Body: body,
+	}
+}
+
+func addressBody(ip net.IP) (body dnsmessage.ResourceBody,
+	typ dnsmessage.Type) {
+
+	if ip4 := ip.To4(); ip4 != nil {
+		res := &dnsmessage.AResource{}
+		copy(res.A[:], ip4)
+		body = res
+		typ = dnsmessage.TypeA
+	} else {
+		res := &dnsmessage.AAAAResource{}
+		copy(res.AAAA[:], ip.To16())
+		body = res
+		typ = dnsmessage.TypeAAAA
+	}
+
+	return
+}
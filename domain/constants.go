@@ -4,8 +4,28 @@ const (
 	AWS         = "aws"
 	Cloudflare  = "cloudflare"
 	OracleCloud = "oracle_cloud"
+	Rfc2136     = "rfc2136"
+	Builtin     = "builtin"
 
-	A    = "A"
-	AAAA = "AAAA"
-	TXT  = "TXT"
+	A     = "A"
+	AAAA  = "AAAA"
+	TXT   = "TXT"
+	CNAME = "CNAME"
+	MX    = "MX"
+	SRV   = "SRV"
+	CAA   = "CAA"
+	PTR   = "PTR"
+
+	MinTtl = 60
+	MaxTtl = 604800
 )
+
+var driftTypes = []string{
+	A,
+	AAAA,
+	CNAME,
+	MX,
+	SRV,
+	CAA,
+	TXT,
+}
@@ -22,6 +22,9 @@ type Domain struct {
 	Type         string             `bson:"type" json:"type"`
 	Secret       primitive.ObjectID `bson:"secret" json:"secret"`
 	RootDomain   string             `bson:"root_domain" json:"root_domain"`
+	Drift        []*Drift           `bson:"drift" json:"drift"`
+	DriftChecked time.Time          `bson:"drift_checked" json:"drift_checked"`
+	DriftNames   []string           `bson:"drift_names" json:"-"`
 	Records      []*Record          `bson:"-" json:"records"`
 	OrigRecords  []*Record          `bson:"-" json:"-"`
 }
@@ -47,6 +50,11 @@ func (d *Domain) Validate(db *database.Database) (
 		break
 	case OracleCloud:
 		break
+	case Rfc2136:
+		break
+	case Builtin:
+		d.Secret = primitive.NilObjectID
+		break
 	default:
 		errData = &errortypes.ErrorData{
 			Error:   "type_invalid",
@@ -55,12 +63,33 @@ func (d *Domain) Validate(db *database.Database) (
 		return
 	}
 
-	if d.Secret.IsZero() {
-		errData = &errortypes.ErrorData{
-			Error:   "secret_invalid",
-			Message: "Secret invalid",
+	if d.Type != Builtin {
+		if d.Secret.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "secret_invalid",
+				Message: "Secret invalid",
+			}
+			return
 		}
-		return
+
+		exists, e := secret.ExistsOrg(db, d.Organization, d.Secret)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if !exists {
+			errData = &errortypes.ErrorData{
+				Error:   "secret_invalid",
+				Message: "Secret invalid",
+			}
+			return
+		}
+	}
+
+	origIds := set.NewSet()
+	for _, record := range d.OrigRecords {
+		origIds.Add(record.Id)
 	}
 
 	newRecords := []*Record{}
@@ -71,6 +100,16 @@ func (d *Domain) Validate(db *database.Database) (
 			continue
 		}
 
+		if record.Id.IsZero() {
+			record.Operation = INSERT
+		} else if !origIds.Contains(record.Id) {
+			errData = &errortypes.ErrorData{
+				Error:   "record_id_invalid",
+				Message: "Record does not belong to domain",
+			}
+			return
+		}
+
 		errData, err = record.Validate(db)
 		if err != nil {
 			return
@@ -80,10 +119,61 @@ func (d *Domain) Validate(db *database.Database) (
 			return
 		}
 
+		if d.Type != Builtin && record.Type == PTR {
+			errData = &errortypes.ErrorData{
+				Error:   "record_type_unsupported",
+				Message: "Record type only supported by built-in DNS server",
+			}
+			return
+		}
+
 		newRecords = append(newRecords, record)
 	}
 	d.Records = newRecords
 
+	names := map[string]set.Set{}
+	ttls := map[string]int{}
+	for _, record := range d.Records {
+		if record.Operation == DELETE {
+			continue
+		}
+
+		types := names[record.SubDomain]
+		if types == nil {
+			types = set.NewSet()
+			names[record.SubDomain] = types
+		}
+
+		if record.Type == CNAME && (record.SubDomain == "" ||
+			types.Contains(CNAME)) {
+
+			errData = &errortypes.ErrorData{
+				Error:   "record_cname_conflict",
+				Message: "CNAME record cannot share a name with other records",
+			}
+			return
+		}
+		types.Add(record.Type)
+
+		if types.Contains(CNAME) && types.Len() > 1 {
+			errData = &errortypes.ErrorData{
+				Error:   "record_cname_conflict",
+				Message: "CNAME record cannot share a name with other records",
+			}
+			return
+		}
+
+		key := record.SubDomain + ":" + record.Type
+		if ttl, ok := ttls[key]; ok && ttl != record.Ttl {
+			errData = &errortypes.ErrorData{
+				Error:   "record_ttl_inconsistent",
+				Message: "Records with the same name and type must have the same TTL",
+			}
+			return
+		}
+		ttls[key] = record.Ttl
+	}
+
 	return
 }
 
@@ -92,9 +182,12 @@ func (d *Domain) PreCommit() {
 }
 
 func (d *Domain) CommitRecords(db *database.Database) (err error) {
-	secr, err := secret.GetOrg(db, d.Organization, d.Secret)
-	if err != nil {
-		return
+	var secr *secret.Secret
+	if d.Type != Builtin {
+		secr, err = secret.GetOrg(db, d.Organization, d.Secret)
+		if err != nil {
+			return
+		}
 	}
 
 	newRecords := []*Record{}
@@ -127,6 +220,61 @@ func (d *Domain) CommitRecords(db *database.Database) (err error) {
 		}
 	}
 
+	// Unknown provider values of the committed record sets are removed by
+	// the provider
+	if len(d.Drift) > 0 {
+		drift := []*Drift{}
+		for _, drft := range d.Drift {
+			if batches[drft.SubDomain+":"+drft.Type] == nil {
+				drift = append(drift, drft)
+			}
+		}
+
+		if len(drift) != len(d.Drift) {
+			d.Drift = drift
+			err = d.CommitFields(db, set.NewSet("drift"))
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// Names no longer used by a record are checked for values left at the
+	// provider on the next drift check
+	if d.Type != Builtin {
+		names := set.NewSet()
+		for _, record := range d.Records {
+			if record.Operation != DELETE {
+				names.Add(record.SubDomain)
+			}
+		}
+
+		driftNames := set.NewSet()
+		for _, name := range d.DriftNames {
+			driftNames.Add(name)
+		}
+
+		changed := false
+		for _, record := range d.OrigRecords {
+			if names.Contains(record.SubDomain) ||
+				driftNames.Contains(record.SubDomain) {
+
+				continue
+			}
+
+			driftNames.Add(record.SubDomain)
+			d.DriftNames = append(d.DriftNames, record.SubDomain)
+			changed = true
+		}
+
+		if changed {
+			err = d.CommitFields(db, set.NewSet("drift_names"))
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	return
 }
 
@@ -161,6 +309,7 @@ func (d *Domain) UpdateRecords(db *database.Database, secr *secret.Secret,
 			ops = append(ops, &dns.Operation{
 				Operation: dns.UPSERT,
 				Value:     rec.Value,
+				Ttl:       rec.Ttl,
 			})
 			break
 		case DELETE:
@@ -173,25 +322,30 @@ func (d *Domain) UpdateRecords(db *database.Database, secr *secret.Secret,
 			ops = append(ops, &dns.Operation{
 				Operation: dns.RETAIN,
 				Value:     rec.Value,
+				Ttl:       rec.Ttl,
 			})
 		}
 	}
 
 	domain := subDomain + "." + d.RootDomain
 
-	svc, err := d.GetDnsService(db)
-	if err != nil {
-		return
-	}
+	// Built-in domains are served directly from the records collection
+	if d.Type != Builtin {
+		svc, e := d.GetDnsService(db)
+		if e != nil {
+			err = e
+			return
+		}
 
-	err = svc.Connect(db, secr)
-	if err != nil {
-		return
-	}
+		err = svc.Connect(db, secr)
+		if err != nil {
+			return
+		}
 
-	err = svc.DnsCommit(db, domain, dnsType, ops)
-	if err != nil {
-		return
+		err = svc.DnsCommit(db, domain, dnsType, ops)
+		if err != nil {
+			return
+		}
 	}
 
 	for _, rec := range records {
@@ -277,6 +431,9 @@ func (d *Domain) GetDnsService(db *database.Database) (
 	case OracleCloud:
 		svc = &dns.Oracle{}
 		break
+	case Rfc2136:
+		svc = &dns.Rfc2136{}
+		break
 	default:
 		err = &errortypes.UnknownError{
 			errors.Newf("domain: Unknown domain type"),
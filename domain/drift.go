@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/dns"
+	"github.com/pritunl/pritunl-cloud/secret"
+)
+
+// Drift is a provider record value that is not in the records collection
+type Drift struct {
+	SubDomain string `bson:"sub_domain" json:"sub_domain"`
+	Type      string `bson:"type" json:"type"`
+	Value     string `bson:"value" json:"value"`
+}
+
+// CheckDrift compares the provider records of the zone with records in
+// the domain and stores the unknown provider values. Providers that cannot
+// list the zone are checked at the record names, previous drift names and
+// names no longer used by a record. Returns true if the drift changed since
+// the last check.
+func (d *Domain) CheckDrift(db *database.Database) (
+	changed bool, err error) {
+
+	if d.Type == Builtin {
+		return
+	}
+
+	secr, err := secret.GetOrg(db, d.Organization, d.Secret)
+	if err != nil {
+		return
+	}
+
+	err = d.LoadRecords(db)
+	if err != nil {
+		return
+	}
+
+	svc, err := d.GetDnsService(db)
+	if err != nil {
+		return
+	}
+
+	err = svc.Connect(db, secr)
+	if err != nil {
+		return
+	}
+
+	known := map[string]set.Set{}
+	for _, rec := range d.Records {
+		vals := known[rec.SubDomain]
+		if vals == nil {
+			vals = set.NewSet()
+			known[rec.SubDomain] = vals
+		}
+
+		val, e := dns.NormalizeValue(rec.Type, rec.Value)
+		if e != nil {
+			val = rec.Value
+		}
+		vals.Add(rec.Type + ":" + val)
+	}
+
+	var drift []*Drift
+	if lister, ok := svc.(dns.Lister); ok {
+		drift, err = d.listDrift(db, lister, known)
+	} else {
+		drift, err = d.findDrift(db, svc, known)
+	}
+	if err != nil {
+		return
+	}
+
+	changed = len(drift) != len(d.Drift)
+	if !changed {
+		for i, drft := range drift {
+			if *drft != *d.Drift[i] {
+				changed = true
+				break
+			}
+		}
+	}
+
+	d.Drift = drift
+	d.DriftChecked = time.Now()
+	d.DriftNames = []string{}
+
+	err = d.CommitFields(db, set.NewSet(
+		"drift", "drift_checked", "drift_names"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func driftValues(subDomain, recType string, provVals []string,
+	vals set.Set) (drift []*Drift) {
+
+	sort.Strings(provVals)
+	for _, val := range provVals {
+		if vals != nil && vals.Contains(recType+":"+val) {
+			continue
+		}
+
+		drift = append(drift, &Drift{
+			SubDomain: subDomain,
+			Type:      recType,
+			Value:     val,
+		})
+	}
+
+	return
+}
+
+// listDrift checks every record set in the provider zone under the root
+// domain
+func (d *Domain) listDrift(db *database.Database, lister dns.Lister,
+	known map[string]set.Set) (drift []*Drift, err error) {
+
+	drift = []*Drift{}
+
+	records, err := lister.DnsList(db, d.RootDomain)
+	if err != nil {
+		return
+	}
+
+	types := set.NewSet()
+	for _, recType := range driftTypes {
+		types.Add(recType)
+	}
+
+	rootDomain := strings.ToLower(strings.Trim(d.RootDomain, "."))
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Type < records[j].Type
+	})
+
+	for _, record := range records {
+		if !types.Contains(record.Type) {
+			continue
+		}
+
+		name := strings.ToLower(record.Name)
+		subDomain := ""
+		if name != rootDomain {
+			if !strings.HasSuffix(name, "."+rootDomain) {
+				continue
+			}
+			subDomain = strings.TrimSuffix(name, "."+rootDomain)
+		}
+
+		drift = append(drift, driftValues(subDomain, record.Type,
+			record.Values, known[subDomain])...)
+	}
+
+	return
+}
+
+// findDrift checks the record names, previous drift names and names no
+// longer used by a record
+func (d *Domain) findDrift(db *database.Database, svc dns.Service,
+	known map[string]set.Set) (drift []*Drift, err error) {
+
+	drift = []*Drift{}
+
+	names := set.NewSet()
+	for subDomain := range known {
+		names.Add(subDomain)
+	}
+	for _, drft := range d.Drift {
+		names.Add(drft.SubDomain)
+	}
+	for _, name := range d.DriftNames {
+		names.Add(name)
+	}
+
+	subDomains := []string{}
+	for nameInf := range names.Iter() {
+		subDomains = append(subDomains, nameInf.(string))
+	}
+	sort.Strings(subDomains)
+
+	for _, subDomain := range subDomains {
+		for _, recType := range driftTypes {
+			provVals, e := svc.DnsFind(
+				db, subDomain+"."+d.RootDomain, recType)
+			if e != nil {
+				err = e
+				return
+			}
+
+			drift = append(drift, driftValues(subDomain, recType,
+				provVals, known[subDomain])...)
+		}
+	}
+
+	return
+}
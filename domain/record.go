@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/pritunl/mongo-go-driver/bson"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/dns"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 )
 
@@ -19,6 +21,7 @@ type Record struct {
 	SubDomain string             `bson:"sub_domain" json:"sub_domain"`
 	Type      string             `bson:"type" json:"type"`
 	Value     string             `bson:"value" json:"value"`
+	Ttl       int                `bson:"ttl" json:"ttl"`
 	Operation string             `bson:"-" json:"operation"`
 }
 
@@ -33,14 +36,19 @@ func (r *Record) Validate(db *database.Database) (
 		return
 	}
 
+	r.SubDomain = strings.ToLower(strings.Trim(
+		strings.TrimSpace(r.SubDomain), "."))
+	if r.SubDomain == "@" {
+		r.SubDomain = ""
+	}
+
 	switch r.Type {
-	case A:
-		break
-	case AAAA:
+	case A, AAAA, CNAME, MX, SRV, CAA, TXT, PTR:
 		break
 	default:
-		err = &errortypes.UnknownError{
-			errors.New("domain: Unknown record type"),
+		errData = &errortypes.ErrorData{
+			Error:   "record_type_invalid",
+			Message: "Record type invalid",
 		}
 		return
 	}
@@ -53,14 +61,39 @@ func (r *Record) Validate(db *database.Database) (
 		return
 	}
 
+	val, e := dns.NormalizeValue(r.Type, r.Value)
+	if e != nil {
+		errData = &errortypes.ErrorData{
+			Error:   "value_invalid",
+			Message: fmt.Sprintf("Record value invalid for %s record", r.Type),
+		}
+		return
+	}
+	r.Value = val
+
+	if r.Ttl != 0 && (r.Ttl < MinTtl || r.Ttl > MaxTtl) {
+		errData = &errortypes.ErrorData{
+			Error: "ttl_invalid",
+			Message: fmt.Sprintf("Record TTL must be between %d and %d",
+				MinTtl, MaxTtl),
+		}
+		return
+	}
+
 	return
 }
 
 func (r *Record) Commit(db *database.Database) (err error) {
 	coll := db.DomainsRecords()
 
-	err = coll.Commit(r.Id, r)
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id":    r.Id,
+		"domain": r.Domain,
+	}, &bson.M{
+		"$set": r,
+	})
 	if err != nil {
+		err = database.ParseError(err)
 		return
 	}
 
@@ -72,14 +105,37 @@ func (r *Record) CommitFields(db *database.Database, fields set.Set) (
 
 	coll := db.DomainsRecords()
 
-	err = coll.CommitFields(r.Id, r, fields)
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id":    r.Id,
+		"domain": r.Domain,
+	}, database.SelectFieldsAll(r, fields))
 	if err != nil {
+		err = database.ParseError(err)
 		return
 	}
 
 	return
 }
 
+func (r *Record) Remove(db *database.Database) (err error) {
+	coll := db.DomainsRecords()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id":    r.Id,
+		"domain": r.Domain,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+		} else {
+			return
+		}
+	}
+
+	return
+}
+
 func (r *Record) Insert(db *database.Database) (err error) {
 	coll := db.DomainsRecords()
 
@@ -7,4 +7,9 @@ const (
 	Udp       = "udp"
 	Multicast = "multicast"
 	Broadcast = "broadcast"
+
+	LogDrop   = "log_drop"
+	LogAccept = "log_accept"
+
+	CounterDrop = "drop"
 )
@@ -1,38 +1,91 @@
 package firewall
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/addrgroup"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 )
 
+// Rule allows traffic matching the protocol and port. For egress rules the
+// source IPs are matched against the destination address. Source roles
+// match the instances with the network role in the same VPC and source
+// groups match the addresses of the address groups, both are resolved to
+// source IPs when the node loads the rules.
 type Rule struct {
-	SourceIps []string `bson:"source_ips" json:"source_ips"`
-	Protocol  string   `bson:"protocol" json:"protocol"`
-	Port      string   `bson:"port" json:"port"`
+	SourceIps    []string             `bson:"source_ips" json:"source_ips"`
+	SourceRoles  []string             `bson:"source_roles" json:"source_roles"`
+	SourceGroups []primitive.ObjectID `bson:"source_groups" json:"source_groups"`
+	Protocol     string               `bson:"protocol" json:"protocol"`
+	Port         string               `bson:"port" json:"port"`
+	CounterKey   string               `bson:"-" json:"counter_key"`
+	key          string
 }
 
+// sourceId returns a short hash of the rule sources before resolution
+func (r *Rule) sourceId() string {
+	sources := []string{}
+	for _, sourceIp := range r.SourceIps {
+		sources = append(sources, "ip:"+sourceIp)
+	}
+	for _, role := range r.SourceRoles {
+		sources = append(sources, "role:"+role)
+	}
+	for _, groupId := range r.SourceGroups {
+		sources = append(sources, "group:"+groupId.Hex())
+	}
+	sort.Strings(sources)
+
+	hash := sha1.Sum([]byte(strings.Join(sources, ",")))
+	return hex.EncodeToString(hash[:4])
+}
+
+// Key returns the counter key of the rule, the key includes the sources
+// so only rules with the same protocol, port and sources are merged and
+// share a key. Resolved rules keep the key of the original rule.
+func (r *Rule) Key() string {
+	if r.key != "" {
+		return r.key
+	}
+
+	if r.Port == "" {
+		return r.Protocol + "_" + r.sourceId()
+	}
+	return r.Protocol + "_" + strings.Replace(r.Port, "-", "_", 1) +
+		"_" + r.sourceId()
+}
+
+// SetNameEgress returns the ipset name of the egress destinations.
+func (r *Rule) SetNameEgress(ipv6 bool) (name string) {
+	name = r.SetName(ipv6)
+	if name != "" {
+		name = name[:3] + "e" + name[3:]
+	}
+
+	return
+}
+
+// SetName returns the ipset name of the rule sources, rules with different
+// sources use separate sets.
 func (r *Rule) SetName(ipv6 bool) (name string) {
 	switch r.Protocol {
-	case All:
-		if ipv6 {
-			name = "pr6_all"
-		} else {
-			name = "pr4_all"
-		}
-		break
-	case Icmp:
+	case All, Icmp, Tcp, Udp:
 		if ipv6 {
-			name = "pr6_icmp"
+			name = "pr6_" + r.Key()
 		} else {
-			name = "pr4_icmp"
+			name = "pr4_" + r.Key()
 		}
 		break
 	case Multicast:
@@ -49,21 +102,6 @@ func (r *Rule) SetName(ipv6 bool) (name string) {
 			name = "pr4_broad"
 		}
 		break
-	case Tcp, Udp:
-		if ipv6 {
-			name = fmt.Sprintf(
-				"pr6_%s_%s",
-				r.Protocol,
-				strings.Replace(r.Port, "-", "_", 1),
-			)
-		} else {
-			name = fmt.Sprintf(
-				"pr4_%s_%s",
-				r.Protocol,
-				strings.Replace(r.Port, "-", "_", 1),
-			)
-		}
-		break
 	default:
 		break
 	}
@@ -71,27 +109,64 @@ func (r *Rule) SetName(ipv6 bool) (name string) {
 	return
 }
 
-type Firewall struct {
-	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name         string             `bson:"name" json:"name"`
-	Comment      string             `bson:"comment" json:"comment"`
-	Organization primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
-	NetworkRoles []string           `bson:"network_roles" json:"network_roles"`
-	Ingress      []*Rule            `bson:"ingress" json:"ingress"`
+// Counter is the packet and byte count of the iptables rules generated for
+// a firewall rule.
+type Counter struct {
+	Packets int64 `bson:"packets" json:"packets"`
+	Bytes   int64 `bson:"bytes" json:"bytes"`
 }
 
-func (f *Firewall) Validate(db *database.Database) (
-	errData *errortypes.ErrorData, err error) {
+func (c *Counter) Add(counter *Counter) {
+	if counter == nil {
+		return
+	}
+	c.Packets += counter.Packets
+	c.Bytes += counter.Bytes
+}
 
-	if f.NetworkRoles == nil {
-		f.NetworkRoles = []string{}
+// Counters is the sum of the rule counters on a node for every namespace
+// the firewall is applied to. Rules are keyed by Rule.Key and the drop
+// key counts the packets which matched no rule, in log only mode these
+// packets are logged and accepted.
+type Counters struct {
+	Timestamp time.Time           `bson:"timestamp" json:"timestamp"`
+	Ingress   map[string]*Counter `bson:"ingress" json:"ingress"`
+	Egress    map[string]*Counter `bson:"egress" json:"egress"`
+}
+
+func NewCounters() *Counters {
+	return &Counters{
+		Timestamp: time.Now(),
+		Ingress:   map[string]*Counter{},
+		Egress:    map[string]*Counter{},
 	}
+}
 
-	if f.Ingress == nil {
-		f.Ingress = []*Rule{}
+type Firewall struct {
+	Id           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name         string               `bson:"name" json:"name"`
+	Comment      string               `bson:"comment" json:"comment"`
+	Organization primitive.ObjectID   `bson:"organization,omitempty" json:"organization"`
+	NetworkRoles []string             `bson:"network_roles" json:"network_roles"`
+	LogOnly      bool                 `bson:"log_only" json:"log_only"`
+	Ingress      []*Rule              `bson:"ingress" json:"ingress"`
+	Egress       []*Rule              `bson:"egress" json:"egress"`
+	Counters     map[string]*Counters `bson:"counters" json:"counters"`
+}
+
+func validateRules(rules []*Rule, egress bool) (
+	errData *errortypes.ErrorData) {
+
+	direction := "ingress"
+	addrType := "source_ip"
+	addrName := "source"
+	if egress {
+		direction = "egress"
+		addrType = "destination_ip"
+		addrName = "destination"
 	}
 
-	for _, rule := range f.Ingress {
+	for _, rule := range rules {
 		switch rule.Protocol {
 		case All:
 			rule.Port = ""
@@ -99,22 +174,31 @@ func (f *Firewall) Validate(db *database.Database) (
 		case Icmp:
 			rule.Port = ""
 			break
-		case Tcp, Udp, Multicast, Broadcast:
+		case Multicast, Broadcast:
+			if egress {
+				errData = &errortypes.ErrorData{
+					Error:   fmt.Sprintf("invalid_%s_rule_protocol", direction),
+					Message: fmt.Sprintf("Invalid %s rule protocol", direction),
+				}
+				return
+			}
+			fallthrough
+		case Tcp, Udp:
 			ports := strings.Split(rule.Port, "-")
 
 			portInt, e := strconv.Atoi(ports[0])
 			if e != nil {
 				errData = &errortypes.ErrorData{
-					Error:   "invalid_ingress_rule_port",
-					Message: "Invalid ingress rule port",
+					Error:   fmt.Sprintf("invalid_%s_rule_port", direction),
+					Message: fmt.Sprintf("Invalid %s rule port", direction),
 				}
 				return
 			}
 
 			if portInt < 1 || portInt > 65535 {
 				errData = &errortypes.ErrorData{
-					Error:   "invalid_ingress_rule_port",
-					Message: "Invalid ingress rule port",
+					Error:   fmt.Sprintf("invalid_%s_rule_port", direction),
+					Message: fmt.Sprintf("Invalid %s rule port", direction),
 				}
 				return
 			}
@@ -124,16 +208,16 @@ func (f *Firewall) Validate(db *database.Database) (
 				portInt2, e := strconv.Atoi(ports[1])
 				if e != nil {
 					errData = &errortypes.ErrorData{
-						Error:   "invalid_ingress_rule_port",
-						Message: "Invalid ingress rule port",
+						Error:   fmt.Sprintf("invalid_%s_rule_port", direction),
+						Message: fmt.Sprintf("Invalid %s rule port", direction),
 					}
 					return
 				}
 
 				if portInt < 1 || portInt > 65535 || portInt2 <= portInt {
 					errData = &errortypes.ErrorData{
-						Error:   "invalid_ingress_rule_port",
-						Message: "Invalid ingress rule port",
+						Error:   fmt.Sprintf("invalid_%s_rule_port", direction),
+						Message: fmt.Sprintf("Invalid %s rule port", direction),
 					}
 					return
 				}
@@ -146,20 +230,45 @@ func (f *Firewall) Validate(db *database.Database) (
 			break
 		default:
 			errData = &errortypes.ErrorData{
-				Error:   "invalid_ingress_rule_protocol",
-				Message: "Invalid ingress rule protocol",
+				Error:   fmt.Sprintf("invalid_%s_rule_protocol", direction),
+				Message: fmt.Sprintf("Invalid %s rule protocol", direction),
 			}
 			return
 		}
 
 		if rule.Protocol == Multicast || rule.Protocol == Broadcast {
 			rule.SourceIps = []string{}
+			rule.SourceRoles = []string{}
+			rule.SourceGroups = []primitive.ObjectID{}
 		} else {
+			sourceRoles := []string{}
+			sourceRolesSet := set.NewSet()
+			for _, role := range rule.SourceRoles {
+				role = strings.TrimSpace(role)
+				if role == "" || sourceRolesSet.Contains(role) {
+					continue
+				}
+				sourceRolesSet.Add(role)
+				sourceRoles = append(sourceRoles, role)
+			}
+			rule.SourceRoles = sourceRoles
+
+			sourceGroups := []primitive.ObjectID{}
+			sourceGroupsSet := set.NewSet()
+			for _, groupId := range rule.SourceGroups {
+				if groupId.IsZero() || sourceGroupsSet.Contains(groupId) {
+					continue
+				}
+				sourceGroupsSet.Add(groupId)
+				sourceGroups = append(sourceGroups, groupId)
+			}
+			rule.SourceGroups = sourceGroups
+
 			for i, sourceIp := range rule.SourceIps {
 				if sourceIp == "" {
 					errData = &errortypes.ErrorData{
-						Error:   "invalid_ingress_rule_source_ip",
-						Message: "Empty ingress rule source IP",
+						Error:   fmt.Sprintf("invalid_%s_rule_%s", direction, addrType),
+						Message: fmt.Sprintf("Empty %s rule %s IP", direction, addrName),
 					}
 					return
 				}
@@ -175,8 +284,8 @@ func (f *Firewall) Validate(db *database.Database) (
 				_, sourceCidr, e := net.ParseCIDR(sourceIp)
 				if e != nil {
 					errData = &errortypes.ErrorData{
-						Error:   "invalid_ingress_rule_source_ip",
-						Message: "Invalid ingress rule source IP",
+						Error:   fmt.Sprintf("invalid_%s_rule_%s", direction, addrType),
+						Message: fmt.Sprintf("Invalid %s rule %s IP", direction, addrName),
 					}
 					return
 				}
@@ -189,6 +298,116 @@ func (f *Firewall) Validate(db *database.Database) (
 	return
 }
 
+func (f *Firewall) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	if f.NetworkRoles == nil {
+		f.NetworkRoles = []string{}
+	}
+
+	if f.Ingress == nil {
+		f.Ingress = []*Rule{}
+	}
+
+	if f.Egress == nil {
+		f.Egress = []*Rule{}
+	}
+
+	errData = validateRules(f.Ingress, false)
+	if errData != nil {
+		return
+	}
+
+	errData = validateRules(f.Egress, true)
+	if errData != nil {
+		return
+	}
+
+	groupIds := []primitive.ObjectID{}
+	groupIdsSet := set.NewSet()
+	for _, rule := range append(f.Ingress, f.Egress...) {
+		if f.Organization.IsZero() && len(rule.SourceRoles) > 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_rule_source_role",
+				Message: "Network role sources not supported on node firewall",
+			}
+			return
+		}
+
+		for _, groupId := range rule.SourceGroups {
+			if groupIdsSet.Contains(groupId) {
+				continue
+			}
+			groupIdsSet.Add(groupId)
+			groupIds = append(groupIds, groupId)
+		}
+	}
+
+	if len(groupIds) > 0 {
+		groups, e := addrgroup.GetMulti(db, groupIds)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, group := range groups {
+			if group.Organization == f.Organization {
+				groupIdsSet.Remove(group.Id)
+			}
+		}
+
+		if groupIdsSet.Len() > 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_rule_source_group",
+				Message: "Invalid rule address group",
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (f *Firewall) Json() {
+	for _, rule := range f.Ingress {
+		rule.CounterKey = rule.Key()
+	}
+	for _, rule := range f.Egress {
+		rule.CounterKey = rule.Key()
+	}
+
+	if f.Counters == nil || len(f.Counters) == 0 {
+		return
+	}
+
+	for key, counters := range f.Counters {
+		if time.Since(counters.Timestamp) > 3*time.Minute {
+			delete(f.Counters, key)
+		}
+	}
+
+	return
+}
+
+func (f *Firewall) CommitCounters(db *database.Database,
+	nodeId primitive.ObjectID, counters *Counters) (err error) {
+
+	coll := db.Firewalls()
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id": f.Id,
+	}, &bson.M{
+		"$set": &bson.M{
+			"counters." + nodeId.Hex(): counters,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func (f *Firewall) Commit(db *database.Database) (err error) {
 	coll := db.Firewalls()
 
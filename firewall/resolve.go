@@ -0,0 +1,185 @@
+package firewall
+
+import (
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/addrgroup"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/instance"
+)
+
+// resolver converts the network role and address group sources of rules
+// to source IPs, caching the addresses while loading the node rules.
+type resolver struct {
+	db     *database.Database
+	roles  map[string][]string
+	groups map[primitive.ObjectID][]string
+}
+
+func (r *resolver) getRole(vpcId primitive.ObjectID, role string) (
+	addrs []string, err error) {
+
+	key := vpcId.Hex() + "-" + role
+	addrs, ok := r.roles[key]
+	if ok {
+		return
+	}
+
+	insts, err := instance.GetAllAddresses(r.db, &bson.M{
+		"vpc":           vpcId,
+		"network_roles": role,
+	})
+	if err != nil {
+		return
+	}
+
+	addrs = []string{}
+	for _, inst := range insts {
+		for _, addr := range inst.PrivateIps {
+			addrs = append(addrs, addr+"/32")
+		}
+		for _, addr := range inst.PrivateIps6 {
+			addrs = append(addrs, addr+"/128")
+		}
+	}
+
+	r.roles[key] = addrs
+
+	return
+}
+
+func (r *resolver) loadGroups(fires []*Firewall) (err error) {
+	groupIds := []primitive.ObjectID{}
+
+	for _, fire := range fires {
+		for _, rule := range append(fire.Ingress, fire.Egress...) {
+			for _, groupId := range rule.SourceGroups {
+				if _, ok := r.groups[groupId]; ok {
+					continue
+				}
+				r.groups[groupId] = []string{}
+				groupIds = append(groupIds, groupId)
+			}
+		}
+	}
+
+	if len(groupIds) == 0 {
+		return
+	}
+
+	groups, err := addrgroup.GetMulti(r.db, groupIds)
+	if err != nil {
+		return
+	}
+
+	for _, group := range groups {
+		r.groups[group.Id] = group.Addresses
+	}
+
+	return
+}
+
+func (r *resolver) resolveRules(vpcId primitive.ObjectID,
+	rules []*Rule) (resolved []*Rule, err error) {
+
+	resolved = []*Rule{}
+
+	for _, rule := range rules {
+		if len(rule.SourceRoles) == 0 && len(rule.SourceGroups) == 0 {
+			resolved = append(resolved, rule)
+			continue
+		}
+
+		sourceIps := []string{}
+		sourceIpsSet := set.NewSet()
+		addSources := func(addrs []string) {
+			for _, addr := range addrs {
+				if sourceIpsSet.Contains(addr) {
+					continue
+				}
+				sourceIpsSet.Add(addr)
+				sourceIps = append(sourceIps, addr)
+			}
+		}
+
+		addSources(rule.SourceIps)
+
+		if !vpcId.IsZero() {
+			for _, role := range rule.SourceRoles {
+				addrs, e := r.getRole(vpcId, role)
+				if e != nil {
+					err = e
+					return
+				}
+				addSources(addrs)
+			}
+		}
+
+		for _, groupId := range rule.SourceGroups {
+			addSources(r.groups[groupId])
+		}
+
+		resolved = append(resolved, &Rule{
+			SourceIps: sourceIps,
+			Protocol:  rule.Protocol,
+			Port:      rule.Port,
+			key:       rule.Key(),
+		})
+	}
+
+	return
+}
+
+// Resolve returns copies of the firewalls with the rule sources resolved
+// for an instance in the VPC. Role sources are skipped without a VPC.
+func (r *resolver) Resolve(vpcId primitive.ObjectID, fires []*Firewall) (
+	resolved []*Firewall, err error) {
+
+	err = r.loadGroups(fires)
+	if err != nil {
+		return
+	}
+
+	resolved = []*Firewall{}
+	for _, fire := range fires {
+		ingress, e := r.resolveRules(vpcId, fire.Ingress)
+		if e != nil {
+			err = e
+			return
+		}
+
+		egress, e := r.resolveRules(vpcId, fire.Egress)
+		if e != nil {
+			err = e
+			return
+		}
+
+		resolvedFire := *fire
+		resolvedFire.Ingress = ingress
+		resolvedFire.Egress = egress
+		resolved = append(resolved, &resolvedFire)
+	}
+
+	return
+}
+
+func newResolver(db *database.Database) *resolver {
+	return &resolver{
+		db:     db,
+		roles:  map[string][]string{},
+		groups: map[primitive.ObjectID][]string{},
+	}
+}
+
+// ResolveNode resolves the rule sources of node firewalls.
+func ResolveNode(db *database.Database, fires []*Firewall) (
+	resolved []*Firewall, err error) {
+
+	resolved, err = newResolver(db).Resolve(primitive.NilObjectID, fires)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -1,7 +1,6 @@
 package firewall
 
 import (
-	"fmt"
 	"sort"
 
 	"github.com/dropbox/godropbox/container/set"
@@ -367,20 +366,26 @@ func RemoveMultiOrg(db *database.Database, orgId primitive.ObjectID,
 	return
 }
 
-func MergeIngress(fires []*Firewall) (rules []*Rule) {
+func mergeRules(fires []*Firewall, egress bool) (rules []*Rule) {
 	rules = []*Rule{}
 	rulesMap := map[string]*Rule{}
 	rulesKey := []string{}
 
 	for _, fire := range fires {
-		for _, ingress := range fire.Ingress {
-			key := fmt.Sprintf("%s-%s", ingress.Protocol, ingress.Port)
+		fireRules := fire.Ingress
+		if egress {
+			fireRules = fire.Egress
+		}
+
+		for _, fireRule := range fireRules {
+			key := fireRule.Key()
 			rule := rulesMap[key]
 			if rule == nil {
 				rule = &Rule{
-					Protocol:  ingress.Protocol,
-					Port:      ingress.Port,
-					SourceIps: ingress.SourceIps,
+					Protocol:  fireRule.Protocol,
+					Port:      fireRule.Port,
+					SourceIps: fireRule.SourceIps,
+					key:       key,
 				}
 				rulesMap[key] = rule
 				rulesKey = append(rulesKey, key)
@@ -390,7 +395,7 @@ func MergeIngress(fires []*Firewall) (rules []*Rule) {
 					sourceIps.Add(sourceIp)
 				}
 
-				for _, sourceIp := range ingress.SourceIps {
+				for _, sourceIp := range fireRule.SourceIps {
 					if sourceIps.Contains(sourceIp) {
 						continue
 					}
@@ -409,9 +414,70 @@ func MergeIngress(fires []*Firewall) (rules []*Rule) {
 	return
 }
 
-func GetAllIngress(db *database.Database, nodeSelf *node.Node,
+func MergeIngress(fires []*Firewall) (rules []*Rule) {
+	rules = mergeRules(fires, false)
+	return
+}
+
+func MergeEgress(fires []*Firewall) (rules []*Rule) {
+	rules = mergeRules(fires, true)
+	return
+}
+
+// LogMode is the NFLOG mode of the namespace rules. Packets matching no
+// rule are logged when any of the firewalls is log only and are accepted
+// instead of dropped when all of the firewalls are log only.
+type LogMode struct {
+	Ingress string
+	Egress  string
+}
+
+func getLogMode(fires []*Firewall, egress bool) string {
+	total := 0
+	logOnly := 0
+
+	for _, fire := range fires {
+		if egress && len(fire.Egress) == 0 {
+			continue
+		}
+
+		total += 1
+		if fire.LogOnly {
+			logOnly += 1
+		}
+	}
+
+	if logOnly == 0 {
+		return ""
+	} else if logOnly == total {
+		return LogAccept
+	}
+	return LogDrop
+}
+
+func GetLogMode(fires []*Firewall) (mode *LogMode) {
+	mode = &LogMode{
+		Ingress: getLogMode(fires, false),
+		Egress:  getLogMode(fires, true),
+	}
+
+	if mode.Ingress == "" && mode.Egress == "" {
+		mode = nil
+	}
+
+	return
+}
+
+// GetAllRules loads the merged rules of the node and instance namespaces.
+// Log modes are keyed by namespace with the node firewall stored in the
+// host namespace 0.
+func GetAllRules(db *database.Database, nodeSelf *node.Node,
 	instances []*instance.Instance) (nodeFirewall []*Rule,
-	firewalls map[string][]*Rule, err error) {
+	firewalls map[string][]*Rule, firewallsEgress map[string][]*Rule,
+	logModes map[string]*LogMode, err error) {
+
+	resolv := newResolver(db)
+	logModes = map[string]*LogMode{}
 
 	if nodeSelf.Firewall {
 		fires, e := GetRoles(db, nodeSelf.NetworkRoles)
@@ -420,11 +486,23 @@ func GetAllIngress(db *database.Database, nodeSelf *node.Node,
 			return
 		}
 
+		fires, e = resolv.Resolve(primitive.NilObjectID, fires)
+		if e != nil {
+			err = e
+			return
+		}
+
 		ingress := MergeIngress(fires)
 		nodeFirewall = ingress
+
+		logMode := GetLogMode(fires)
+		if logMode != nil {
+			logModes["0"] = logMode
+		}
 	}
 
 	firewalls = map[string][]*Rule{}
+	firewallsEgress = map[string][]*Rule{}
 	for _, inst := range instances {
 		if !inst.IsActive() {
 			continue
@@ -440,6 +518,12 @@ func GetAllIngress(db *database.Database, nodeSelf *node.Node,
 				return
 			}
 
+			fires, e = resolv.Resolve(inst.Vpc, fires)
+			if e != nil {
+				err = e
+				return
+			}
+
 			_, ok := firewalls[namespace]
 			if ok {
 				logrus.WithFields(logrus.Fields{
@@ -456,6 +540,16 @@ func GetAllIngress(db *database.Database, nodeSelf *node.Node,
 
 			ingress := MergeIngress(fires)
 			firewalls[namespace] = ingress
+
+			egress := MergeEgress(fires)
+			if len(egress) > 0 {
+				firewallsEgress[namespace] = egress
+			}
+
+			logMode := GetLogMode(fires)
+			if logMode != nil {
+				logModes[namespace] = logMode
+			}
 		}
 	}
 
@@ -17,6 +17,7 @@ import (
 type Image struct {
 	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Disk         primitive.ObjectID `bson:"disk,omitempty" json:"disk"`
+	Parent       primitive.ObjectID `bson:"parent,omitempty" json:"parent"`
 	Name         string             `bson:"name" json:"name"`
 	Comment      string             `bson:"comment" json:"comment"`
 	Organization primitive.ObjectID `bson:"organization" json:"organization"`
@@ -265,6 +266,34 @@ func (i *Image) Insert(db *database.Database) (err error) {
 func (i *Image) Upsert(db *database.Database) (err error) {
 	coll := db.Images()
 
+	data := bson.M{
+		"disk":          i.Disk,
+		"name":          i.Name,
+		"organization":  i.Organization,
+		"signed":        i.Signed,
+		"type":          i.Type,
+		"firmware":      i.Firmware,
+		"storage":       i.Storage,
+		"key":           i.Key,
+		"last_modified": i.LastModified,
+		"storage_class": i.StorageClass,
+		"etag":          i.Etag,
+	}
+
+	if !i.Parent.IsZero() {
+		data["parent"] = i.Parent
+	}
+
+	update := bson.M{
+		"$set": data,
+	}
+
+	if !i.Id.IsZero() {
+		update["$setOnInsert"] = &bson.M{
+			"_id": i.Id,
+		}
+	}
+
 	opts := &options.UpdateOptions{}
 	opts.SetUpsert(true)
 	_, err = coll.UpdateOne(
@@ -273,21 +302,7 @@ func (i *Image) Upsert(db *database.Database) (err error) {
 			"storage": i.Storage,
 			"key":     i.Key,
 		},
-		&bson.M{
-			"$set": &bson.M{
-				"disk":          i.Disk,
-				"name":          i.Name,
-				"organization":  i.Organization,
-				"signed":        i.Signed,
-				"type":          i.Type,
-				"firmware":      i.Firmware,
-				"storage":       i.Storage,
-				"key":           i.Key,
-				"last_modified": i.LastModified,
-				"storage_class": i.StorageClass,
-				"etag":          i.Etag,
-			},
-		},
+		update,
 		opts,
 	)
 	if err != nil {
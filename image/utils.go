@@ -142,6 +142,28 @@ func ExistsOrg(db *database.Database, orgId, imgId primitive.ObjectID) (
 	return
 }
 
+// HasChildren returns true if an incremental backup image uses the image as
+// its parent
+func HasChildren(db *database.Database, imgId primitive.ObjectID) (
+	exists bool, err error) {
+
+	coll := db.Images()
+
+	n, err := coll.CountDocuments(db, &bson.M{
+		"parent": imgId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if n > 0 {
+		exists = true
+	}
+
+	return
+}
+
 func GetAll(db *database.Database, query *bson.M, page, pageCount int64) (
 	imgs []*Image, count int64, err error) {
 
@@ -203,6 +225,52 @@ func GetAll(db *database.Database, query *bson.M, page, pageCount int64) (
 	return
 }
 
+func GetDiskSnapshots(db *database.Database, diskId primitive.ObjectID) (
+	imgs []*Image, err error) {
+
+	coll := db.Images()
+	imgs = []*Image{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"disk": diskId,
+			"key": &bson.M{
+				"$regex": "^snapshot/",
+			},
+		},
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"_id", -1},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		img := &Image{}
+		err = cursor.Decode(img)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		imgs = append(imgs, img)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func GetAllNames(db *database.Database, query *bson.M) (
 	images []*Image, err error) {
 
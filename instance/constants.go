@@ -13,6 +13,13 @@ const (
 	Destroy   = "destroy"
 	Linux     = "linux"
 	BSD       = "bsd"
+
+	MigratePending   = "pending"
+	MigratePrepared  = "prepared"
+	MigrateReady     = "ready"
+	MigrateMigrating = "migrating"
+	MigrateComplete  = "complete"
+	MigrateFailed    = "failed"
 )
 
 var (
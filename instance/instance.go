@@ -58,6 +58,8 @@ type Instance struct {
 	PublicMac           string             `bson:"-" json:"public_mac"`
 	VmState             string             `bson:"vm_state" json:"vm_state"`
 	VmTimestamp         time.Time          `bson:"vm_timestamp" json:"vm_timestamp"`
+	GuestStatus         string             `bson:"guest_status" json:"guest_status"`
+	GuestTimestamp      time.Time          `bson:"guest_timestamp" json:"guest_timestamp"`
 	Restart             bool               `bson:"restart" json:"restart"`
 	RestartBlockIp      bool               `bson:"restart_block_ip" json:"restart_block_ip"`
 	Uefi                bool               `bson:"uefi" json:"uefi"`
@@ -86,6 +88,7 @@ type Instance struct {
 	Node                primitive.ObjectID `bson:"node,omitempty" json:"node"`
 	Shape               primitive.ObjectID `bson:"shape,omitempty" json:"node"`
 	Domain              primitive.ObjectID `bson:"domain,omitempty" json:"domain"`
+	Pod                 primitive.ObjectID `bson:"pod,omitempty" json:"pod"`
 	Name                string             `bson:"name" json:"name"`
 	Comment             string             `bson:"comment" json:"comment"`
 	RootEnabled         bool               `bson:"root_enabled" json:"root_enabled"`
@@ -106,6 +109,15 @@ type Instance struct {
 	SpicePassword       string             `bson:"spice_password" json:"spice_password"`
 	SpicePort           int                `bson:"spice_port" json:"spice_port"`
 	Gui                 bool               `bson:"gui" json:"gui"`
+	MigrateNode         primitive.ObjectID `bson:"migrate_node,omitempty" json:"migrate_node"`
+	MigrateState        string             `bson:"migrate_state" json:"migrate_state"`
+	MigrateOffline      bool               `bson:"migrate_offline" json:"migrate_offline"`
+	MigrateAddress      string             `bson:"migrate_address" json:"-"`
+	MigratePort         int                `bson:"migrate_port" json:"-"`
+	MigrateNbdPort      int                `bson:"migrate_nbd_port" json:"-"`
+	MigrateDisks        []*MigrateDisk     `bson:"migrate_disks" json:"-"`
+	MigrateError        string             `bson:"migrate_error" json:"migrate_error"`
+	MigrateTimestamp    time.Time          `bson:"migrate_timestamp" json:"migrate_timestamp"`
 	Virt                *vm.VirtualMachine `bson:"-" json:"-"`
 	curVpc              primitive.ObjectID `bson:"-" json:"-"`
 	curSubnet           primitive.ObjectID `bson:"-" json:"-"`
@@ -135,6 +147,14 @@ func (i *Instance) Validate(db *database.Database) (
 		return
 	}
 
+	if i.IsMigrating() && i.State != Start {
+		errData = &errortypes.ErrorData{
+			Error:   "instance_migrating",
+			Message: "Instance state cannot change while migrating",
+		}
+		return
+	}
+
 	if i.Organization.IsZero() {
 		errData = &errortypes.ErrorData{
 			Error:   "organization_required",
@@ -539,7 +559,9 @@ func (i *Instance) Format() {
 func (i *Instance) Json() {
 	switch i.State {
 	case Start:
-		if i.Restart || i.RestartBlockIp {
+		if i.IsMigrating() {
+			i.Status = "Migrating"
+		} else if i.Restart || i.RestartBlockIp {
 			i.Status = "Restart Required"
 		} else {
 			switch i.VmState {
@@ -761,9 +783,9 @@ func (i *Instance) Insert(db *database.Database) (err error) {
 	}
 
 	for n := 0; n < 2000; n++ {
-		_, err = coll.InsertOne(db, i)
-		if err != nil {
-			err = database.ParseError(err)
+		resp, e := coll.InsertOne(db, i)
+		if e != nil {
+			err = database.ParseError(e)
 			if _, ok := err.(*database.DuplicateKeyError); ok {
 				i.GenerateUnixId()
 				err = nil
@@ -772,6 +794,8 @@ func (i *Instance) Insert(db *database.Database) (err error) {
 			return
 		}
 
+		i.Id = resp.InsertedID.(primitive.ObjectID)
+
 		return
 	}
 
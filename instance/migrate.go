@@ -0,0 +1,439 @@
+package instance
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/pool"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/pritunl/pritunl-cloud/zone"
+)
+
+type MigrateDisk struct {
+	Id   primitive.ObjectID `bson:"id" json:"id"`
+	Size int64              `bson:"size" json:"size"`
+	Port int                `bson:"port" json:"port"`
+}
+
+func (i *Instance) IsMigrating() bool {
+	return i.MigrateState != "" && i.MigrateState != MigrateFailed
+}
+
+// IsMigrateExpired returns true when an active migration has not
+// progressed within the migrate timeout.
+func (i *Instance) IsMigrateExpired() bool {
+	if !i.IsMigrating() || i.MigrateTimestamp.IsZero() {
+		return false
+	}
+
+	return time.Since(i.MigrateTimestamp) > time.Duration(
+		settings.Hypervisor.MigrateTimeout)*time.Second
+}
+
+func (i *Instance) ValidateMigrate(db *database.Database,
+	nde *node.Node) (errData *errortypes.ErrorData, err error) {
+
+	if i.State != Start || i.VmState != vm.Running {
+		errData = &errortypes.ErrorData{
+			Error:   "instance_not_running",
+			Message: "Instance must be running to migrate",
+		}
+		return
+	}
+
+	errData, err = i.validateMove(db, nde)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// ValidateMigrateOffline validates moving the instance to the node by
+// stopping the instance and copying the disks.
+func (i *Instance) ValidateMigrateOffline(db *database.Database,
+	nde *node.Node) (errData *errortypes.ErrorData, err error) {
+
+	if i.State == Destroy {
+		errData = &errortypes.ErrorData{
+			Error:   "instance_destroying",
+			Message: "Instance is being destroyed",
+		}
+		return
+	}
+
+	errData, err = i.validateMove(db, nde)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (i *Instance) validateMove(db *database.Database,
+	nde *node.Node) (errData *errortypes.ErrorData, err error) {
+
+	if i.IsMigrating() {
+		errData = &errortypes.ErrorData{
+			Error:   "instance_migrating",
+			Message: "Instance is already migrating",
+		}
+		return
+	}
+
+	if nde.Id == i.Node {
+		errData = &errortypes.ErrorData{
+			Error:   "migrate_node_invalid",
+			Message: "Instance is already on node",
+		}
+		return
+	}
+
+	if nde.Maintenance {
+		errData = &errortypes.ErrorData{
+			Error:   "migrate_node_maintenance",
+			Message: "Node is in maintenance",
+		}
+		return
+	}
+
+	if i.Tpm || len(i.PciDevices) > 0 || len(i.UsbDevices) > 0 ||
+		len(i.DriveDevices) > 0 || len(i.Isos) > 0 {
+
+		errData = &errortypes.ErrorData{
+			Error: "migrate_devices_unsupported",
+			Message: "Instances with TPM, ISO or passthrough devices " +
+				"cannot be migrated",
+		}
+		return
+	}
+
+	if i.OracleSubnet != "" {
+		errData = &errortypes.ErrorData{
+			Error:   "migrate_oracle_unsupported",
+			Message: "Oracle instances cannot be migrated",
+		}
+		return
+	}
+
+	dsks, err := disk.GetInstance(db, i.Id)
+	if err != nil {
+		return
+	}
+
+	for _, dsk := range dsks {
+		if dsk.State != disk.Available {
+			errData = &errortypes.ErrorData{
+				Error:   "migrate_disks_busy",
+				Message: "Instance disks must be available to migrate",
+			}
+			return
+		}
+	}
+
+	if time.Since(nde.Timestamp) > 30*time.Second {
+
+		errData = &errortypes.ErrorData{
+			Error:   "migrate_node_offline",
+			Message: "Node is offline",
+		}
+		return
+	}
+
+	curNde, err := node.Get(db, i.Node)
+	if err != nil {
+		return
+	}
+
+	if nde.Zone.IsZero() || curNde.Zone.IsZero() {
+		errData = &errortypes.ErrorData{
+			Error:   "migrate_zone_invalid",
+			Message: "Node is not in a zone",
+		}
+		return
+	}
+
+	if i.DiskType == disk.Lvm {
+		pl, e := pool.Get(db, i.DiskPool)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if pl.Zone != nde.Zone {
+			errData = &errortypes.ErrorData{
+				Error:   "migrate_zone_invalid",
+				Message: "Node must be in the disk pool zone",
+			}
+			return
+		}
+	} else {
+		curZne, e := zone.Get(db, curNde.Zone)
+		if e != nil {
+			err = e
+			return
+		}
+
+		zne, e := zone.Get(db, nde.Zone)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if curZne.Datacenter != zne.Datacenter {
+			errData = &errortypes.ErrorData{
+				Error:   "migrate_datacenter_invalid",
+				Message: "Node must be in the same datacenter",
+			}
+			return
+		}
+	}
+
+	return
+}
+
+// Migrate marks the instance pending migration to the node. The update
+// is conditional on no other migration being active. Offline migrations
+// stop the instance and copy the disks before starting it on the node.
+func (i *Instance) Migrate(db *database.Database,
+	nde *node.Node, offline bool) (started bool, err error) {
+
+	coll := db.Instances()
+	now := time.Now()
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id":  i.Id,
+		"node": i.Node,
+		"migrate_state": &bson.M{
+			"$in": []string{"", MigrateFailed},
+		},
+	}, &bson.M{
+		"$set": &bson.M{
+			"migrate_node":      nde.Id,
+			"migrate_state":     MigratePending,
+			"migrate_offline":   offline,
+			"migrate_address":   "",
+			"migrate_port":      0,
+			"migrate_nbd_port":  0,
+			"migrate_disks":     []*MigrateDisk{},
+			"migrate_error":     "",
+			"migrate_timestamp": now,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if resp.ModifiedCount == 0 {
+		return
+	}
+
+	i.MigrateNode = nde.Id
+	i.MigrateState = MigratePending
+	i.MigrateOffline = offline
+	i.MigrateTimestamp = now
+	started = true
+
+	return
+}
+
+// UpdateMigrate sets the migration fields only if the migration is still
+// in the expected state. This prevents the source and destination node
+// from overwriting each other.
+func UpdateMigrate(db *database.Database, instId primitive.ObjectID,
+	curState string, doc bson.M) (updated bool, err error) {
+
+	coll := db.Instances()
+
+	doc["migrate_timestamp"] = time.Now()
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id":           instId,
+		"migrate_state": curState,
+	}, &bson.M{
+		"$set": doc,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	updated = resp.ModifiedCount > 0
+
+	return
+}
+
+func SetMigrateFailed(db *database.Database, instId primitive.ObjectID,
+	curState string, msg string) (updated bool, err error) {
+
+	updated, err = UpdateMigrate(db, instId, curState, bson.M{
+		"migrate_state": MigrateFailed,
+		"migrate_error": msg,
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// SetMigrateError records a migration that could not be started such as
+// when no node is available. It is stored as a failed offline migration
+// which is not retried by an evacuation.
+func SetMigrateError(db *database.Database, instId primitive.ObjectID,
+	msg string) (err error) {
+
+	coll := db.Instances()
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id": instId,
+		"migrate_state": &bson.M{
+			"$in": []string{"", MigrateFailed},
+		},
+	}, &bson.M{
+		"$set": &bson.M{
+			"migrate_state":     MigrateFailed,
+			"migrate_offline":   true,
+			"migrate_error":     msg,
+			"migrate_timestamp": time.Now(),
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// ClearMigrate resets the migration fields once the destination node has
+// finished the migration.
+func ClearMigrate(db *database.Database, instId primitive.ObjectID,
+	curState string) (err error) {
+
+	coll := db.Instances()
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id":           instId,
+		"migrate_state": curState,
+	}, &bson.M{
+		"$set": &bson.M{
+			"migrate_state":    "",
+			"migrate_address":  "",
+			"migrate_port":     0,
+			"migrate_nbd_port": 0,
+			"migrate_disks":    []*MigrateDisk{},
+		},
+		"$unset": &bson.M{
+			"migrate_node": 1,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// AbortMigrate releases a failed migration from the destination node and
+// keeps the error for display.
+func AbortMigrate(db *database.Database, instId primitive.ObjectID) (
+	err error) {
+
+	coll := db.Instances()
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id":           instId,
+		"migrate_state": MigrateFailed,
+	}, &bson.M{
+		"$set": &bson.M{
+			"migrate_address":  "",
+			"migrate_port":     0,
+			"migrate_nbd_port": 0,
+			"migrate_disks":    []*MigrateDisk{},
+		},
+		"$unset": &bson.M{
+			"migrate_node": 1,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// CompleteMigrate hands ownership of the instance and its disks to the
+// destination node. Qcow2 disks have been flattened by the block mirror
+// or disk copy and no longer use a backing image.
+func CompleteMigrate(db *database.Database, inst *Instance,
+	nde *node.Node) (updated bool, err error) {
+
+	coll := db.Instances()
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id":           inst.Id,
+		"node":          inst.Node,
+		"migrate_state": MigrateMigrating,
+	}, &bson.M{
+		"$set": &bson.M{
+			"node":              nde.Id,
+			"zone":              nde.Zone,
+			"migrate_state":     MigrateComplete,
+			"migrate_timestamp": time.Now(),
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if resp.ModifiedCount == 0 {
+		return
+	}
+	updated = true
+
+	if inst.DiskType != disk.Lvm {
+		_, err = db.Disks().UpdateMany(db, &bson.M{
+			"instance": inst.Id,
+			"node":     inst.Node,
+		}, &bson.M{
+			"$set": &bson.M{
+				"node":          nde.Id,
+				"backing":       false,
+				"backing_image": "",
+				"backup_chain":  []primitive.ObjectID{},
+			},
+		})
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	return
+}
+
+// GetMigrations returns the instances migrating to the node including
+// failed migrations that have not been cleaned up by the node.
+func GetMigrations(db *database.Database, ndeId primitive.ObjectID) (
+	insts []*Instance, err error) {
+
+	insts, err = GetAll(db, &bson.M{
+		"migrate_node": ndeId,
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -278,6 +278,92 @@ func GetAllName(db *database.Database, query *bson.M) (
 	return
 }
 
+func GetAllAddresses(db *database.Database, query *bson.M) (
+	instances []*Instance, err error) {
+
+	coll := db.Instances()
+	instances = []*Instance{}
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Projection: &bson.D{
+				{"private_ips", 1},
+				{"private_ips6", 1},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		inst := &Instance{}
+		err = cursor.Decode(inst)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		instances = append(instances, inst)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAllNameAddresses(db *database.Database, query *bson.M) (
+	instances []*Instance, err error) {
+
+	coll := db.Instances()
+	instances = []*Instance{}
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Projection: &bson.D{
+				{"name", 1},
+				{"vpc", 1},
+				{"private_ips", 1},
+				{"private_ips6", 1},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		inst := &Instance{}
+		err = cursor.Decode(inst)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		instances = append(instances, inst)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func GetAllPaged(db *database.Database, query *bson.M,
 	page, pageCount int64) (insts []*Instance, count int64, err error) {
 
@@ -11,7 +11,9 @@ type State struct {
 	Namespaces map[string]*Sets
 }
 
-func (s *State) AddIngress(namespace string, ingress []*firewall.Rule) {
+func (s *State) addRules(namespace string, rules []*firewall.Rule,
+	egress bool) {
+
 	sets := s.Namespaces[namespace]
 	if sets == nil {
 		sets = &Sets{
@@ -21,9 +23,16 @@ func (s *State) AddIngress(namespace string, ingress []*firewall.Rule) {
 		s.Namespaces[namespace] = sets
 	}
 
-	for _, rule := range ingress {
-		name := rule.SetName(false)
-		name6 := rule.SetName(true)
+	for _, rule := range rules {
+		name := ""
+		name6 := ""
+		if egress {
+			name = rule.SetNameEgress(false)
+			name6 = rule.SetNameEgress(true)
+		} else {
+			name = rule.SetName(false)
+			name6 = rule.SetName(true)
+		}
 
 		if name == "" || name6 == "" || rule.Protocol == firewall.Multicast ||
 			rule.Protocol == firewall.Broadcast {
@@ -57,6 +66,14 @@ func (s *State) AddIngress(namespace string, ingress []*firewall.Rule) {
 	}
 }
 
+func (s *State) AddIngress(namespace string, ingress []*firewall.Rule) {
+	s.addRules(namespace, ingress, false)
+}
+
+func (s *State) AddEgress(namespace string, egress []*firewall.Rule) {
+	s.addRules(namespace, egress, true)
+}
+
 func (s *State) AddSourceDestCheck(namespace, addr6 string) {
 	sets := s.Namespaces[namespace]
 	if sets == nil {
@@ -102,7 +119,9 @@ type NamesState struct {
 	Namespaces map[string]*Names
 }
 
-func (n *NamesState) AddIngress(namespace string, ingress []*firewall.Rule) {
+func (n *NamesState) addRules(namespace string, rules []*firewall.Rule,
+	egress bool) {
+
 	sets := n.Namespaces[namespace]
 	if sets == nil {
 		sets = &Names{
@@ -112,9 +131,16 @@ func (n *NamesState) AddIngress(namespace string, ingress []*firewall.Rule) {
 		n.Namespaces[namespace] = sets
 	}
 
-	for _, rule := range ingress {
-		name := rule.SetName(false)
-		name6 := rule.SetName(true)
+	for _, rule := range rules {
+		name := ""
+		name6 := ""
+		if egress {
+			name = rule.SetNameEgress(false)
+			name6 = rule.SetNameEgress(true)
+		} else {
+			name = rule.SetName(false)
+			name6 = rule.SetName(true)
+		}
 
 		if name == "" || name6 == "" || rule.Protocol == firewall.Multicast ||
 			rule.Protocol == firewall.Broadcast {
@@ -138,6 +164,14 @@ func (n *NamesState) AddIngress(namespace string, ingress []*firewall.Rule) {
 	}
 }
 
+func (n *NamesState) AddIngress(namespace string, ingress []*firewall.Rule) {
+	n.addRules(namespace, ingress, false)
+}
+
+func (n *NamesState) AddEgress(namespace string, egress []*firewall.Rule) {
+	n.addRules(namespace, egress, true)
+}
+
 func (n *NamesState) AddSourceDestCheck(namespace string) {
 	sets := n.Namespaces[namespace]
 	if sets == nil {
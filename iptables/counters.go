@@ -0,0 +1,127 @@
+package iptables
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pritunl/pritunl-cloud/firewall"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+// parseCounter parses the packet and byte counter of a iptables-save line
+// such as [10:840] -A FORWARD ...
+func parseCounter(field string) (counter *firewall.Counter) {
+	if !strings.HasPrefix(field, "[") || !strings.HasSuffix(field, "]") {
+		return
+	}
+
+	vals := strings.Split(field[1:len(field)-1], ":")
+	if len(vals) != 2 {
+		return
+	}
+
+	packets, err := strconv.ParseInt(vals[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	bytes, err := strconv.ParseInt(vals[1], 10, 64)
+	if err != nil {
+		return
+	}
+
+	counter = &firewall.Counter{
+		Packets: packets,
+		Bytes:   bytes,
+	}
+
+	return
+}
+
+func loadCounters(namespace string, counters *firewall.Counters,
+	ipv6 bool) (err error) {
+
+	Lock()
+	defer Unlock()
+
+	saveCmd := "iptables-save"
+	if ipv6 {
+		saveCmd = "ip6tables-save"
+	}
+
+	output := ""
+	if namespace == "0" {
+		output, err = utils.ExecOutput("", saveCmd, "-c", "-t", "filter")
+		if err != nil {
+			return
+		}
+	} else {
+		output, err = utils.ExecOutput("",
+			"ip", "netns", "exec", namespace, saveCmd, "-c", "-t", "filter")
+		if err != nil {
+			return
+		}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		comment := ""
+		for i, field := range fields {
+			if field == "--comment" && i+1 < len(fields) {
+				comment = strings.Trim(fields[i+1], "\"")
+				break
+			}
+		}
+
+		var counterMap map[string]*firewall.Counter
+		key := ""
+		if strings.HasPrefix(comment, "pritunl_cloud_rule_") {
+			counterMap = counters.Ingress
+			key = comment[19:]
+		} else if strings.HasPrefix(comment, "pritunl_cloud_head_") {
+			counterMap = counters.Ingress
+			key = comment[19:]
+		} else if strings.HasPrefix(comment, "pritunl_cloud_egress_") {
+			counterMap = counters.Egress
+			key = comment[21:]
+		} else {
+			continue
+		}
+
+		counter := parseCounter(fields[0])
+		if counter == nil {
+			continue
+		}
+
+		total := counterMap[key]
+		if total == nil {
+			total = &firewall.Counter{}
+			counterMap[key] = total
+		}
+		total.Add(counter)
+	}
+
+	return
+}
+
+// GetCounters returns the ipv4 and ipv6 counters of the firewall rules in
+// the namespace keyed by firewall.Rule.Key.
+func GetCounters(namespace string) (counters *firewall.Counters, err error) {
+	counters = firewall.NewCounters()
+
+	err = loadCounters(namespace, counters, false)
+	if err != nil {
+		return
+	}
+
+	err = loadCounters(namespace, counters, true)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -1,12 +1,14 @@
 package iptables
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/firewall"
+	"github.com/pritunl/pritunl-cloud/nflog"
 	"github.com/pritunl/pritunl-cloud/utils"
 	"github.com/pritunl/pritunl-cloud/vpc"
 	"github.com/sirupsen/logrus"
@@ -74,6 +76,49 @@ func (r *Rules) commentCommandSdc(inCmd []string) (cmd []string) {
 	return
 }
 
+func (r *Rules) commentCommandEgress(inCmd []string) (cmd []string) {
+	cmd = append(inCmd,
+		"-m", "comment",
+		"--comment", "pritunl_cloud_egress",
+	)
+
+	return
+}
+
+// commentCommandKey adds the comment of a rule counted by GetCounters,
+// the key is appended to the comment prefix.
+func (r *Rules) commentCommandKey(inCmd []string, prefix, key string) (
+	cmd []string) {
+
+	cmd = append(inCmd,
+		"-m", "comment",
+		"--comment", prefix+"_"+key,
+	)
+
+	return
+}
+
+// logCommand adds a rate limited NFLOG rule for packets which matched no
+// firewall rule, read by the nflog listener of the namespace.
+func (r *Rules) logCommand(inCmd []string, prefix string) (cmd []string) {
+	cmd = append(inCmd,
+		"-m", "limit",
+		"--limit", nflog.Limit,
+	)
+	if prefix == nflog.PrefixEgress {
+		cmd = r.commentCommandEgress(cmd)
+	} else {
+		cmd = r.commentCommand(cmd, false)
+	}
+	cmd = append(cmd,
+		"-j", "NFLOG",
+		"--nflog-prefix", prefix,
+		"--nflog-group", strconv.Itoa(nflog.Group),
+	)
+
+	return
+}
+
 func (r *Rules) commentCommandMap(inCmd []string) (cmd []string) {
 	cmd = append(inCmd,
 		"-m", "comment",
@@ -180,6 +225,16 @@ func (r *Rules) Apply() (err error) {
 		return
 	}
 
+	err = r.run("", r.Egress, "-A", false)
+	if err != nil {
+		return
+	}
+
+	err = r.run("", r.Egress6, "-A", true)
+	if err != nil {
+		return
+	}
+
 	err = r.run("nat", r.Maps, "-A", false)
 	if err != nil {
 		return
@@ -501,6 +556,18 @@ func (r *Rules) Remove() (err error) {
 	}
 	r.Ingress6 = [][]string{}
 
+	err = r.run("", r.Egress, "-D", false)
+	if err != nil {
+		return
+	}
+	r.Egress = [][]string{}
+
+	err = r.run("", r.Egress6, "-D", true)
+	if err != nil {
+		return
+	}
+	r.Egress6 = [][]string{}
+
 	err = r.run("nat", r.Maps, "-D", false)
 	if err != nil {
 		return
@@ -713,7 +780,15 @@ func (r *Rules) RemoveNat() (err error) {
 }
 
 func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
-	sourceDestCheck bool, ingress []*firewall.Rule) (rules *Rules) {
+	sourceDestCheck bool, ingress, egress []*firewall.Rule,
+	logMode *firewall.LogMode) (rules *Rules) {
+
+	ingressLog := ""
+	egressLog := ""
+	if logMode != nil {
+		ingressLog = logMode.Ingress
+		egressLog = logMode.Egress
+	}
 
 	rules = &Rules{
 		Namespace:        namespace,
@@ -724,6 +799,8 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 		SourceDestCheck6: [][]string{},
 		Ingress:          [][]string{},
 		Ingress6:         [][]string{},
+		Egress:           [][]string{},
+		Egress6:          [][]string{},
 		Maps:             [][]string{},
 		Maps6:            [][]string{},
 		Holds:            [][]string{},
@@ -958,7 +1035,8 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 			if rule.Protocol == firewall.Multicast ||
 				rule.Protocol == firewall.Broadcast {
 
-				cmd = rules.commentCommandHeader(cmd)
+				cmd = rules.commentCommandKey(cmd,
+					"pritunl_cloud_head", rule.Key())
 				cmd = append(cmd,
 					"-j", "ACCEPT",
 				)
@@ -969,7 +1047,8 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 					rules.Header = append(rules.Header, cmd)
 				}
 			} else {
-				cmd = rules.commentCommand(cmd, false)
+				cmd = rules.commentCommandKey(cmd,
+					"pritunl_cloud_rule", rule.Key())
 				cmd = append(cmd,
 					"-j", "ACCEPT",
 				)
@@ -1019,6 +1098,35 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 	)
 	rules.Ingress6 = append(rules.Ingress6, cmd)
 
+	dropTarget := "DROP"
+	if ingressLog == firewall.LogAccept {
+		dropTarget = "ACCEPT"
+	}
+
+	if ingressLog != "" {
+		cmd = rules.newCommand()
+		if rules.Interface != "host" {
+			cmd = append(cmd,
+				"-m", "physdev",
+				"--physdev-out", rules.Interface,
+				"--physdev-is-bridged",
+			)
+		}
+		cmd = rules.logCommand(cmd, nflog.PrefixIngress)
+		rules.Ingress = append(rules.Ingress, cmd)
+
+		cmd = rules.newCommand()
+		if rules.Interface != "host" {
+			cmd = append(cmd,
+				"-m", "physdev",
+				"--physdev-out", rules.Interface,
+				"--physdev-is-bridged",
+			)
+		}
+		cmd = rules.logCommand(cmd, nflog.PrefixIngress)
+		rules.Ingress6 = append(rules.Ingress6, cmd)
+	}
+
 	cmd = rules.newCommand()
 	if rules.Interface != "host" {
 		cmd = append(cmd,
@@ -1027,9 +1135,10 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 			"--physdev-is-bridged",
 		)
 	}
-	cmd = rules.commentCommand(cmd, false)
+	cmd = rules.commentCommandKey(cmd,
+		"pritunl_cloud_rule", firewall.CounterDrop)
 	cmd = append(cmd,
-		"-j", "DROP",
+		"-j", dropTarget,
 	)
 	rules.Ingress = append(rules.Ingress, cmd)
 
@@ -1041,12 +1150,17 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 			"--physdev-is-bridged",
 		)
 	}
-	cmd = rules.commentCommand(cmd, false)
+	cmd = rules.commentCommandKey(cmd,
+		"pritunl_cloud_rule", firewall.CounterDrop)
 	cmd = append(cmd,
-		"-j", "DROP",
+		"-j", dropTarget,
 	)
 	rules.Ingress6 = append(rules.Ingress6, cmd)
 
+	if len(egress) > 0 {
+		generateEgress(rules, egress, egressLog)
+	}
+
 	if vc != nil && vc.Maps != nil {
 		for _, mp := range vc.Maps {
 			if mp.Type != vpc.Destination {
@@ -1088,9 +1202,263 @@ func generateVirt(vc *vpc.Vpc, namespace, iface, addr, addr6 string,
 	return
 }
 
+// generateEgress restricts traffic sent from the instance to the egress
+// rules. Return traffic of allowed connections along with address and
+// neighbor discovery traffic is always permitted.
+func generateEgress(rules *Rules, egress []*firewall.Rule, logMode string) {
+	physdev := []string{
+		"-m", "physdev",
+		"--physdev-in", rules.Interface,
+		"--physdev-is-bridged",
+	}
+
+	cmd := rules.newCommand()
+	cmd = append(cmd, physdev...)
+	cmd = append(cmd,
+		"-m", "conntrack",
+		"--ctstate", "RELATED,ESTABLISHED",
+	)
+	cmd = rules.commentCommandEgress(cmd)
+	cmd = append(cmd,
+		"-j", "ACCEPT",
+	)
+	rules.Egress = append(rules.Egress, cmd)
+
+	cmd = rules.newCommand()
+	cmd = append(cmd, physdev...)
+	cmd = append(cmd,
+		"-m", "conntrack",
+		"--ctstate", "RELATED,ESTABLISHED",
+	)
+	cmd = rules.commentCommandEgress(cmd)
+	cmd = append(cmd,
+		"-j", "ACCEPT",
+	)
+	rules.Egress6 = append(rules.Egress6, cmd)
+
+	cmd = rules.newCommand()
+	cmd = append(cmd,
+		"-p", "udp",
+	)
+	cmd = append(cmd, physdev...)
+	cmd = append(cmd,
+		"-m", "udp",
+		"--dport", "67",
+	)
+	cmd = rules.commentCommandEgress(cmd)
+	cmd = append(cmd,
+		"-j", "ACCEPT",
+	)
+	rules.Egress = append(rules.Egress, cmd)
+
+	cmd = rules.newCommand()
+	cmd = append(cmd,
+		"-p", "udp",
+	)
+	cmd = append(cmd, physdev...)
+	cmd = append(cmd,
+		"-m", "udp",
+		"--dport", "547",
+	)
+	cmd = rules.commentCommandEgress(cmd)
+	cmd = append(cmd,
+		"-j", "ACCEPT",
+	)
+	rules.Egress6 = append(rules.Egress6, cmd)
+
+	// Router solicitation, neighbor solicitation and advertisement
+	for _, icmpType := range []string{"133", "135", "136"} {
+		cmd = rules.newCommand()
+		cmd = append(cmd,
+			"-p", "ipv6-icmp",
+		)
+		cmd = append(cmd, physdev...)
+		cmd = append(cmd,
+			"-m", "icmp6",
+			"--icmpv6-type", icmpType,
+		)
+		cmd = rules.commentCommandEgress(cmd)
+		cmd = append(cmd,
+			"-j", "ACCEPT",
+		)
+		rules.Egress6 = append(rules.Egress6, cmd)
+	}
+
+	for _, rule := range egress {
+		all4 := false
+		all6 := false
+		set4 := false
+		set6 := false
+		setName := rule.SetNameEgress(false)
+		setName6 := rule.SetNameEgress(true)
+
+		if setName == "" || setName6 == "" {
+			continue
+		}
+
+		for _, destIp := range rule.SourceIps {
+			ipv6 := strings.Contains(destIp, ":")
+
+			if destIp == "0.0.0.0/0" {
+				if all4 {
+					continue
+				}
+				all4 = true
+			} else if destIp == "::/0" {
+				if all6 {
+					continue
+				}
+				all6 = true
+			} else {
+				if ipv6 {
+					if set6 {
+						continue
+					}
+					set6 = true
+				} else {
+					if set4 {
+						continue
+					}
+					set4 = true
+				}
+			}
+
+			cmd = rules.newCommand()
+
+			switch rule.Protocol {
+			case firewall.All:
+				break
+			case firewall.Icmp:
+				if ipv6 {
+					cmd = append(cmd,
+						"-p", "ipv6-icmp",
+					)
+				} else {
+					cmd = append(cmd,
+						"-p", "icmp",
+					)
+				}
+				break
+			case firewall.Tcp, firewall.Udp:
+				cmd = append(cmd,
+					"-p", rule.Protocol,
+				)
+				break
+			default:
+				continue
+			}
+
+			if destIp != "0.0.0.0/0" && destIp != "::/0" {
+				if ipv6 {
+					cmd = append(cmd,
+						"-m", "set",
+						"--match-set", setName6, "dst",
+					)
+				} else {
+					cmd = append(cmd,
+						"-m", "set",
+						"--match-set", setName, "dst",
+					)
+				}
+			}
+
+			cmd = append(cmd, physdev...)
+
+			switch rule.Protocol {
+			case firewall.Tcp, firewall.Udp:
+				cmd = append(cmd,
+					"-m", rule.Protocol,
+					"--dport", strings.Replace(rule.Port, "-", ":", 1),
+					"-m", "conntrack",
+					"--ctstate", "NEW",
+				)
+				break
+			}
+
+			cmd = rules.commentCommandKey(cmd,
+				"pritunl_cloud_egress", rule.Key())
+			cmd = append(cmd,
+				"-j", "ACCEPT",
+			)
+
+			if ipv6 {
+				rules.Egress6 = append(rules.Egress6, cmd)
+			} else {
+				rules.Egress = append(rules.Egress, cmd)
+			}
+		}
+	}
+
+	cmd = rules.newCommand()
+	cmd = append(cmd, physdev...)
+	cmd = append(cmd,
+		"-m", "conntrack",
+		"--ctstate", "INVALID",
+	)
+	cmd = rules.commentCommandEgress(cmd)
+	cmd = append(cmd,
+		"-j", "DROP",
+	)
+	rules.Egress = append(rules.Egress, cmd)
+
+	cmd = rules.newCommand()
+	cmd = append(cmd, physdev...)
+	cmd = append(cmd,
+		"-m", "conntrack",
+		"--ctstate", "INVALID",
+	)
+	cmd = rules.commentCommandEgress(cmd)
+	cmd = append(cmd,
+		"-j", "DROP",
+	)
+	rules.Egress6 = append(rules.Egress6, cmd)
+
+	dropTarget := "DROP"
+	if logMode == firewall.LogAccept {
+		dropTarget = "ACCEPT"
+	}
+
+	if logMode != "" {
+		cmd = rules.newCommand()
+		cmd = append(cmd, physdev...)
+		cmd = rules.logCommand(cmd, nflog.PrefixEgress)
+		rules.Egress = append(rules.Egress, cmd)
+
+		cmd = rules.newCommand()
+		cmd = append(cmd, physdev...)
+		cmd = rules.logCommand(cmd, nflog.PrefixEgress)
+		rules.Egress6 = append(rules.Egress6, cmd)
+	}
+
+	cmd = rules.newCommand()
+	cmd = append(cmd, physdev...)
+	cmd = rules.commentCommandKey(cmd,
+		"pritunl_cloud_egress", firewall.CounterDrop)
+	cmd = append(cmd,
+		"-j", dropTarget,
+	)
+	rules.Egress = append(rules.Egress, cmd)
+
+	cmd = rules.newCommand()
+	cmd = append(cmd, physdev...)
+	cmd = rules.commentCommandKey(cmd,
+		"pritunl_cloud_egress", firewall.CounterDrop)
+	cmd = append(cmd,
+		"-j", dropTarget,
+	)
+	rules.Egress6 = append(rules.Egress6, cmd)
+}
+
 func generateInternal(namespace, iface string, nat, nat6, dhcp, dhcp6 bool,
 	natAddr, natPubAddr, natAddr6, natPubAddr6 string,
-	oracleNatPubAddr string, ingress []*firewall.Rule) (rules *Rules) {
+	oracleNatPubAddr string, ingress []*firewall.Rule,
+	logMode *firewall.LogMode) (rules *Rules) {
+
+	// Packets are counted and logged on the virtual interface
+	dropTarget := "DROP"
+	if logMode != nil && logMode.Ingress == firewall.LogAccept {
+		dropTarget = "ACCEPT"
+	}
 
 	rules = &Rules{
 		Namespace:        namespace,
@@ -1101,6 +1469,8 @@ func generateInternal(namespace, iface string, nat, nat6, dhcp, dhcp6 bool,
 		SourceDestCheck6: [][]string{},
 		Ingress:          [][]string{},
 		Ingress6:         [][]string{},
+		Egress:           [][]string{},
+		Egress6:          [][]string{},
 		Maps:             [][]string{},
 		Maps6:            [][]string{},
 		Holds:            [][]string{},
@@ -1340,7 +1710,7 @@ func generateInternal(namespace, iface string, nat, nat6, dhcp, dhcp6 bool,
 	}
 	cmd = rules.commentCommand(cmd, false)
 	cmd = append(cmd,
-		"-j", "DROP",
+		"-j", dropTarget,
 	)
 	rules.Ingress = append(rules.Ingress, cmd)
 
@@ -1352,15 +1722,20 @@ func generateInternal(namespace, iface string, nat, nat6, dhcp, dhcp6 bool,
 	}
 	cmd = rules.commentCommand(cmd, false)
 	cmd = append(cmd,
-		"-j", "DROP",
+		"-j", dropTarget,
 	)
 	rules.Ingress6 = append(rules.Ingress6, cmd)
 
 	return
 }
 
-func generate(namespace, iface string, ingress []*firewall.Rule) (
-	rules *Rules) {
+func generate(namespace, iface string, ingress []*firewall.Rule,
+	logMode *firewall.LogMode) (rules *Rules) {
+
+	ingressLog := ""
+	if logMode != nil {
+		ingressLog = logMode.Ingress
+	}
 
 	rules = &Rules{
 		Namespace:        namespace,
@@ -1371,6 +1746,8 @@ func generate(namespace, iface string, ingress []*firewall.Rule) (
 		SourceDestCheck6: [][]string{},
 		Ingress:          [][]string{},
 		Ingress6:         [][]string{},
+		Egress:           [][]string{},
+		Egress6:          [][]string{},
 		Holds:            [][]string{},
 		Holds6:           [][]string{},
 	}
@@ -1545,7 +1922,8 @@ func generate(namespace, iface string, ingress []*firewall.Rule) (
 			if rule.Protocol == firewall.Multicast ||
 				rule.Protocol == firewall.Broadcast {
 
-				cmd = rules.commentCommandHeader(cmd)
+				cmd = rules.commentCommandKey(cmd,
+					"pritunl_cloud_head", rule.Key())
 				cmd = append(cmd,
 					"-j", "ACCEPT",
 				)
@@ -1556,7 +1934,8 @@ func generate(namespace, iface string, ingress []*firewall.Rule) (
 					rules.Header = append(rules.Header, cmd)
 				}
 			} else {
-				cmd = rules.commentCommand(cmd, false)
+				cmd = rules.commentCommandKey(cmd,
+					"pritunl_cloud_rule", rule.Key())
 				cmd = append(cmd,
 					"-j", "ACCEPT",
 				)
@@ -1602,15 +1981,41 @@ func generate(namespace, iface string, ingress []*firewall.Rule) (
 	)
 	rules.Ingress6 = append(rules.Ingress6, cmd)
 
+	dropTarget := "DROP"
+	if ingressLog == firewall.LogAccept {
+		dropTarget = "ACCEPT"
+	}
+
+	if ingressLog != "" {
+		cmd = rules.newCommand()
+		if rules.Interface != "host" {
+			cmd = append(cmd,
+				"-o", rules.Interface,
+			)
+		}
+		cmd = rules.logCommand(cmd, nflog.PrefixIngress)
+		rules.Ingress = append(rules.Ingress, cmd)
+
+		cmd = rules.newCommand()
+		if rules.Interface != "host" {
+			cmd = append(cmd,
+				"-o", rules.Interface,
+			)
+		}
+		cmd = rules.logCommand(cmd, nflog.PrefixIngress)
+		rules.Ingress6 = append(rules.Ingress6, cmd)
+	}
+
 	cmd = rules.newCommand()
 	if rules.Interface != "host" {
 		cmd = append(cmd,
 			"-o", rules.Interface,
 		)
 	}
-	cmd = rules.commentCommand(cmd, false)
+	cmd = rules.commentCommandKey(cmd,
+		"pritunl_cloud_rule", firewall.CounterDrop)
 	cmd = append(cmd,
-		"-j", "DROP",
+		"-j", dropTarget,
 	)
 	rules.Ingress = append(rules.Ingress, cmd)
 
@@ -1620,9 +2025,10 @@ func generate(namespace, iface string, ingress []*firewall.Rule) (
 			"-o", rules.Interface,
 		)
 	}
-	cmd = rules.commentCommand(cmd, false)
+	cmd = rules.commentCommandKey(cmd,
+		"pritunl_cloud_rule", firewall.CounterDrop)
 	cmd = append(cmd,
-		"-j", "DROP",
+		"-j", dropTarget,
 	)
 	rules.Ingress6 = append(rules.Ingress6, cmd)
 
@@ -18,6 +18,8 @@ type Rules struct {
 	SourceDestCheck6 [][]string
 	Ingress          [][]string
 	Ingress6         [][]string
+	Egress           [][]string
+	Egress6          [][]string
 	Maps             [][]string
 	Maps6            [][]string
 	Holds            [][]string
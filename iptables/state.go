@@ -1,6 +1,7 @@
 package iptables
 
 import (
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/firewall"
 	"github.com/pritunl/pritunl-cloud/instance"
@@ -12,11 +13,14 @@ import (
 
 type State struct {
 	Interfaces map[string]*Rules
+	Logs       set.Set
 }
 
 func LoadState(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 	instances []*instance.Instance, nodeFirewall []*firewall.Rule,
-	firewalls map[string][]*firewall.Rule) (state *State) {
+	firewalls map[string][]*firewall.Rule,
+	firewallsEgress map[string][]*firewall.Rule,
+	logModes map[string]*firewall.LogMode) (state *State) {
 
 	vpcsMap := map[primitive.ObjectID]*vpc.Vpc{}
 	for _, vc := range vpcs {
@@ -34,10 +38,15 @@ func LoadState(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 
 	state = &State{
 		Interfaces: map[string]*Rules{},
+		Logs:       set.NewSet(),
 	}
 
 	if nodeFirewall != nil {
-		state.Interfaces["0-host"] = generate("0", "host", nodeFirewall)
+		state.Interfaces["0-host"] = generate("0", "host", nodeFirewall,
+			logModes["0"])
+		if logModes["0"] != nil {
+			state.Logs.Add("0")
+		}
 	}
 
 	hostNetwork := !nodeSelf.HostBlock.IsZero()
@@ -94,6 +103,11 @@ func LoadState(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 			continue
 		}
 
+		logMode := logModes[namespace]
+		if logMode != nil {
+			state.Logs.Add(namespace)
+		}
+
 		// TODO Move to netconf
 
 		dhcp := false
@@ -118,33 +132,35 @@ func LoadState(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 
 			rules := generateInternal(namespace, ifaceExternal,
 				true, nat6, dhcp, dhcp6, addr, pubAddr, addr6, pubAddr6,
-				oracleAddr, ingress)
+				oracleAddr, ingress, logMode)
 			state.Interfaces[namespace+"-"+ifaceExternal] = rules
 		} else if nodeNetworkMode6 != node.Disabled &&
 			nodeNetworkMode6 != node.Oracle {
 
 			rules := generateInternal(namespace, ifaceExternal,
 				false, true, dhcp, dhcp6, addr, pubAddr, addr6, pubAddr6,
-				oracleAddr, ingress)
+				oracleAddr, ingress, logMode)
 			state.Interfaces[namespace+"-"+ifaceExternal] = rules
 		}
 
 		if nodeNetworkMode == node.Oracle {
 			rules := generateInternal(namespace, oracleIface,
 				true, false, false, false, addr, pubAddr, addr6, pubAddr6,
-				oracleAddr, ingress)
+				oracleAddr, ingress, logMode)
 
 			state.Interfaces[namespace+"-"+oracleIface] = rules
 		}
 
 		if hostNetwork {
 			rules := generateInternal(namespace, ifaceHost,
-				false, false, false, false, "", "", "", "", "", ingress)
+				false, false, false, false, "", "", "", "", "", ingress,
+				logMode)
 			state.Interfaces[namespace+"-"+ifaceHost] = rules
 		}
 
 		rules := generateVirt(vpcsMap[inst.Vpc], namespace, iface, addr,
-			addr6, !inst.SkipSourceDestCheck, ingress)
+			addr6, !inst.SkipSourceDestCheck, ingress,
+			firewallsEgress[namespace], logMode)
 		state.Interfaces[namespace+"-"+iface] = rules
 	}
 
@@ -8,6 +8,7 @@ import (
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/firewall"
 	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/nflog"
 	"github.com/pritunl/pritunl-cloud/node"
 	"github.com/pritunl/pritunl-cloud/utils"
 	"github.com/pritunl/pritunl-cloud/vpc"
@@ -218,13 +219,14 @@ func (u *Update) reload() (err error) {
 		return
 	}
 
-	nodeFirewall, firewalls, err := firewall.GetAllIngress(
-		db, node.Self, instances)
+	nodeFirewall, firewalls, firewallsEgress, logModes,
+		err := firewall.GetAllRules(db, node.Self, instances)
 	if err != nil {
 		return
 	}
 
-	err = Init(namespaces, vpcs, instances, nodeFirewall, firewalls)
+	err = Init(namespaces, vpcs, instances, nodeFirewall, firewalls,
+		firewallsEgress, logModes)
 	if err != nil {
 		return
 	}
@@ -246,6 +248,8 @@ func ApplyUpdate(newState *State, namespaces []string, recover bool) {
 
 	curState = newState
 
+	nflog.Sync(newState.Logs)
+
 	stateLock.Unlock(lockId)
 
 	if recover {
@@ -257,9 +261,12 @@ func ApplyUpdate(newState *State, namespaces []string, recover bool) {
 
 func UpdateState(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 	instances []*instance.Instance, namespaces []string,
-	nodeFirewall []*firewall.Rule, firewalls map[string][]*firewall.Rule) {
+	nodeFirewall []*firewall.Rule, firewalls map[string][]*firewall.Rule,
+	firewallsEgress map[string][]*firewall.Rule,
+	logModes map[string]*firewall.LogMode) {
 
-	newState := LoadState(nodeSelf, vpcs, instances, nodeFirewall, firewalls)
+	newState := LoadState(nodeSelf, vpcs, instances, nodeFirewall,
+		firewalls, firewallsEgress, logModes)
 
 	ApplyUpdate(newState, namespaces, false)
 
@@ -268,9 +275,12 @@ func UpdateState(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 
 func UpdateStateRecover(nodeSelf *node.Node, vpcs []*vpc.Vpc,
 	instances []*instance.Instance, namespaces []string,
-	nodeFirewall []*firewall.Rule, firewalls map[string][]*firewall.Rule) {
+	nodeFirewall []*firewall.Rule, firewalls map[string][]*firewall.Rule,
+	firewallsEgress map[string][]*firewall.Rule,
+	logModes map[string]*firewall.LogMode) {
 
-	newState := LoadState(nodeSelf, vpcs, instances, nodeFirewall, firewalls)
+	newState := LoadState(nodeSelf, vpcs, instances, nodeFirewall,
+		firewalls, firewallsEgress, logModes)
 
 	ApplyUpdate(newState, namespaces, true)
 
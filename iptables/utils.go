@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/firewall"
@@ -36,6 +37,8 @@ func diffRules(a, b *Rules) bool {
 		len(a.SourceDestCheck6) != len(b.SourceDestCheck6) ||
 		len(a.Ingress) != len(b.Ingress) ||
 		len(a.Ingress6) != len(b.Ingress6) ||
+		len(a.Egress) != len(b.Egress) ||
+		len(a.Egress6) != len(b.Egress6) ||
 		len(a.Maps) != len(b.Maps) ||
 		len(a.Maps6) != len(b.Maps6) ||
 		len(a.Holds) != len(b.Holds) ||
@@ -74,6 +77,16 @@ func diffRules(a, b *Rules) bool {
 			return true
 		}
 	}
+	for i := range a.Egress {
+		if diffCmd(a.Egress[i], b.Egress[i]) {
+			return true
+		}
+	}
+	for i := range a.Egress6 {
+		if diffCmd(a.Egress6[i], b.Egress6[i]) {
+			return true
+		}
+	}
 	for i := range a.Maps {
 		if diffCmd(a.Maps[i], b.Maps[i]) {
 			return true
@@ -150,8 +163,11 @@ func loadIptables(namespace, instIface string, state *State,
 		holdComment := strings.Contains(line, "pritunl_cloud_hold")
 		headComment := strings.Contains(line, "pritunl_cloud_head")
 		sdcComment := strings.Contains(line, "pritunl_cloud_sdc")
+		egressComment := strings.Contains(line, "pritunl_cloud_egress")
+
+		if !ruleComment && !holdComment && !headComment && !sdcComment &&
+			!egressComment {
 
-		if !ruleComment && !holdComment && !headComment && !sdcComment {
 			continue
 		}
 
@@ -198,6 +214,35 @@ func loadIptables(namespace, instIface string, state *State,
 					break
 				}
 			}
+		} else if egressComment {
+			if cmd[0] != "FORWARD" {
+				logrus.WithFields(logrus.Fields{
+					"iptables_rule": line,
+				}).Error("iptables: Invalid iptables egress chain")
+
+				err = &errortypes.ParseError{
+					errors.New("iptables: Invalid iptables egress chain"),
+				}
+				return
+			}
+
+			for i, item := range cmd {
+				if item == "--physdev-in" {
+					if len(cmd) < i+2 {
+						logrus.WithFields(logrus.Fields{
+							"iptables_rule": line,
+						}).Error("iptables: Invalid iptables egress interface")
+
+						err = &errortypes.ParseError{
+							errors.New(
+								"iptables: Invalid iptables egress interface"),
+						}
+						return
+					}
+					iface = cmd[i+1]
+					break
+				}
+			}
 		} else if namespace != "0" {
 			if cmd[0] != "FORWARD" {
 				logrus.WithFields(logrus.Fields{
@@ -264,6 +309,8 @@ func loadIptables(namespace, instIface string, state *State,
 				SourceDestCheck6: [][]string{},
 				Ingress:          [][]string{},
 				Ingress6:         [][]string{},
+				Egress:           [][]string{},
+				Egress6:          [][]string{},
 				Maps:             [][]string{},
 				Maps6:            [][]string{},
 				Holds:            [][]string{},
@@ -284,11 +331,11 @@ func loadIptables(namespace, instIface string, state *State,
 			} else {
 				rules.SourceDestCheck = append(rules.SourceDestCheck, cmd)
 			}
-		} else if sdcComment {
+		} else if egressComment {
 			if ipv6 {
-				rules.SourceDestCheck6 = append(rules.SourceDestCheck6, cmd)
+				rules.Egress6 = append(rules.Egress6, cmd)
 			} else {
-				rules.SourceDestCheck = append(rules.SourceDestCheck, cmd)
+				rules.Egress = append(rules.Egress, cmd)
 			}
 		} else {
 			if headComment {
@@ -378,6 +425,8 @@ func loadIptables(namespace, instIface string, state *State,
 						SourceDestCheck6: [][]string{},
 						Ingress:          [][]string{},
 						Ingress6:         [][]string{},
+						Egress:           [][]string{},
+						Egress6:          [][]string{},
 						Maps:             [][]string{},
 						Maps6:            [][]string{},
 						Holds:            [][]string{},
@@ -571,6 +620,8 @@ func loadIptables(namespace, instIface string, state *State,
 				SourceDestCheck6: [][]string{},
 				Ingress:          [][]string{},
 				Ingress6:         [][]string{},
+				Egress:           [][]string{},
+				Egress6:          [][]string{},
 				Holds:            [][]string{},
 				Holds6:           [][]string{},
 			}
@@ -602,6 +653,8 @@ func loadIptables(namespace, instIface string, state *State,
 				SourceDestCheck6: [][]string{},
 				Ingress:          [][]string{},
 				Ingress6:         [][]string{},
+				Egress:           [][]string{},
+				Egress6:          [][]string{},
 				Holds:            [][]string{},
 				Holds6:           [][]string{},
 			}
@@ -684,7 +737,9 @@ func RecoverNode() (err error) {
 
 func Init(namespaces []string, vpcs []*vpc.Vpc,
 	instances []*instance.Instance, nodeFirewall []*firewall.Rule,
-	firewalls map[string][]*firewall.Rule) (err error) {
+	firewalls map[string][]*firewall.Rule,
+	firewallsEgress map[string][]*firewall.Rule,
+	logModes map[string]*firewall.LogMode) (err error) {
 
 	_, err = utils.ExecCombinedOutputLogged(
 		nil, "sysctl", "-w", "net.ipv6.conf.all.accept_ra=2",
@@ -741,6 +796,7 @@ func Init(namespaces []string, vpcs []*vpc.Vpc,
 
 	state := &State{
 		Interfaces: map[string]*Rules{},
+		Logs:       set.NewSet(),
 	}
 
 	err = loadIptables("0", "", state, false)
@@ -779,7 +835,7 @@ func Init(namespaces []string, vpcs []*vpc.Vpc,
 	curState = state
 
 	UpdateState(node.Self, vpcs, instances,
-		namespaces, nodeFirewall, firewalls)
+		namespaces, nodeFirewall, firewalls, firewallsEgress, logModes)
 
 	return
 }
@@ -2,6 +2,8 @@ package lvm
 
 import (
 	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
@@ -17,6 +19,7 @@ import (
 var (
 	cachedNodePools          []*pool.Pool
 	cachedNodePoolsTimestamp time.Time
+	poolsStateTimestamp      time.Time
 )
 
 type report struct {
@@ -94,3 +97,83 @@ func GetAvailablePools(db *database.Database, zoneId primitive.ObjectID) (
 
 	return
 }
+
+func UpdatePoolsState(db *database.Database, zoneId primitive.ObjectID) (
+	err error) {
+
+	if time.Since(poolsStateTimestamp) < 60*time.Second {
+		return
+	}
+	poolsStateTimestamp = time.Now()
+
+	pools, err := pool.GetAll(db, &bson.M{
+		"zone": zoneId,
+	})
+	if err != nil {
+		return
+	}
+
+	if len(pools) == 0 {
+		return
+	}
+
+	output, err := utils.ExecCombinedOutput("",
+		"vgs", "--reportformat", "json", "--units", "b", "--nosuffix")
+	if err != nil {
+		return
+	}
+
+	reprt := &report{}
+	err = json.Unmarshal([]byte(output), reprt)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "lvm: Failed to unmarshal vgs report"),
+		}
+		return
+	}
+
+	vgs := map[string]*vgDetails{}
+	if reprt.Report != nil {
+		for _, reportGroup := range reprt.Report {
+			if reportGroup.Vg != nil {
+				for _, reportVg := range reportGroup.Vg {
+					vgs[reportVg.VgName] = reportVg
+				}
+			}
+		}
+	}
+
+	for _, pl := range pools {
+		vg := vgs[pl.VgName]
+		if vg == nil {
+			continue
+		}
+
+		size, e := strconv.ParseInt(strings.TrimSpace(vg.VgSize), 10, 64)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "lvm: Failed to parse vg size"),
+			}
+			return
+		}
+
+		free, e := strconv.ParseInt(strings.TrimSpace(vg.VgFree), 10, 64)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "lvm: Failed to parse vg free"),
+			}
+			return
+		}
+
+		pl.Size = size
+		pl.Free = free
+		pl.Timestamp = time.Now()
+
+		err = pl.CommitFields(db, set.NewSet("size", "free", "timestamp"))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -26,6 +26,8 @@ Commands:
   disable-policies  Disable all policies
   mtu-check         Check and show instance MTUs
   backup            Backup local data
+  backup-verify     Verify local data backup
+  backup-restore    Restore local data backup
 `
 
 func Init() {
@@ -142,6 +144,20 @@ func main() {
 			panic(err)
 		}
 		return
+	case "backup-verify":
+		InitLimited()
+		err := cmd.BackupVerify()
+		if err != nil {
+			panic(err)
+		}
+		return
+	case "backup-restore":
+		InitLimited()
+		err := cmd.BackupRestore()
+		if err != nil {
+			panic(err)
+		}
+		return
 	case "dhcp4-server":
 		err := cmd.Dhcp4Server()
 		if err != nil {
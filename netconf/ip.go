@@ -294,6 +294,7 @@ func (n *NetConf) ipDatabase(db *database.Database) (err error) {
 	store.RemAddress(n.Virt.Id)
 	store.RemRoutes(n.Virt.Id)
 	store.RemArp(n.Virt.Id)
+	store.RemPeers(n.Virt.Id)
 
 	hostIps := []string{}
 	if n.HostAddr != nil {
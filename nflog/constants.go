@@ -0,0 +1,24 @@
+package nflog
+
+const (
+	Group         = 29
+	Limit         = "10/sec"
+	PrefixIngress = "pritunl_ingress"
+	PrefixEgress  = "pritunl_egress"
+)
+
+const (
+	netlinkNetfilter = 12
+	nfnlSubsysUlog   = 4
+	nfnlMsgPacket    = 0
+	nfnlMsgConfig    = 1
+
+	nfulaCfgCmd  = 1
+	nfulaCfgMode = 2
+	nfulaPayload = 9
+	nfulaPrefix  = 10
+
+	nfulnlCfgCmdBind = 1
+	nfulnlCopyPacket = 2
+	copyRange        = 128
+)
@@ -0,0 +1,245 @@
+package nflog
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/log"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// listener reads the NFLOG packets of a network namespace. Netlink sockets
+// belong to the namespace they are created in so each namespace with log
+// rules requires a listener.
+type listener struct {
+	namespace string
+	stop      chan bool
+}
+
+func (l *listener) message(cmd []byte, attrType uint16,
+	resId uint16) []byte {
+
+	attrLen := 4 + len(cmd)
+	msgLen := 16 + 4 + align(attrLen, attrLen+3)
+	msg := make([]byte, msgLen)
+
+	binary.LittleEndian.PutUint32(msg[0:4], uint32(msgLen))
+	binary.LittleEndian.PutUint16(msg[4:6], nfnlSubsysUlog<<8|nfnlMsgConfig)
+	binary.LittleEndian.PutUint16(msg[6:8],
+		syscall.NLM_F_REQUEST|syscall.NLM_F_ACK)
+	msg[16] = syscall.AF_UNSPEC
+	binary.BigEndian.PutUint16(msg[18:20], resId)
+	binary.LittleEndian.PutUint16(msg[20:22], uint16(attrLen))
+	binary.LittleEndian.PutUint16(msg[22:24], attrType)
+	copy(msg[24:], cmd)
+
+	return msg
+}
+
+func (l *listener) config(fd int, msg []byte) (err error) {
+	err = syscall.Sendto(fd, msg, 0, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+	})
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "nflog: Failed to send netlink config"),
+		}
+		return
+	}
+
+	buf := make([]byte, 4096)
+	n, _, err := syscall.Recvfrom(fd, buf, 0)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "nflog: Failed to read netlink config"),
+		}
+		return
+	}
+
+	if n >= 20 && binary.LittleEndian.Uint16(buf[4:6]) ==
+		syscall.NLMSG_ERROR {
+
+		code := int32(binary.LittleEndian.Uint32(buf[16:20]))
+		if code != 0 {
+			err = &errortypes.WriteError{
+				errors.Wrapf(syscall.Errno(-code),
+					"nflog: Netlink config error"),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (l *listener) open() (fd int, err error) {
+	if l.namespace != "0" {
+		nsFile, e := os.Open(filepath.Join("/var/run/netns", l.namespace))
+		if e != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "nflog: Failed to open namespace"),
+			}
+			return
+		}
+		defer nsFile.Close()
+
+		e = unix.Setns(int(nsFile.Fd()), unix.CLONE_NEWNET)
+		if e != nil {
+			err = &errortypes.ExecError{
+				errors.Wrap(e, "nflog: Failed to set namespace"),
+			}
+			return
+		}
+	}
+
+	fd, err = syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW,
+		netlinkNetfilter)
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "nflog: Failed to open netlink socket"),
+		}
+		return
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+	})
+	if err != nil {
+		syscall.Close(fd)
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "nflog: Failed to bind netlink socket"),
+		}
+		return
+	}
+
+	err = l.config(fd, l.message([]byte{nfulnlCfgCmdBind, 0, 0, 0},
+		nfulaCfgCmd, Group))
+	if err != nil {
+		syscall.Close(fd)
+		return
+	}
+
+	mode := make([]byte, 8)
+	binary.BigEndian.PutUint32(mode[0:4], copyRange)
+	mode[4] = nfulnlCopyPacket
+	err = l.config(fd, l.message(mode[:6], nfulaCfgMode, Group))
+	if err != nil {
+		syscall.Close(fd)
+		return
+	}
+
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET,
+		syscall.SO_RCVTIMEO, &syscall.Timeval{
+			Sec: 1,
+		})
+	if err != nil {
+		syscall.Close(fd)
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "nflog: Failed to set socket timeout"),
+		}
+		return
+	}
+
+	return
+}
+
+func (l *listener) handle(pkt *Packet) {
+	db := database.GetDatabase()
+	defer db.Close()
+
+	entry := &log.Entry{
+		Level:     log.Info,
+		Timestamp: time.Now(),
+		Message:   "nflog: Firewall unmatched packet",
+		Fields: map[string]interface{}{
+			"namespace":        l.namespace,
+			"direction":        pkt.Direction(),
+			"protocol":         pkt.Protocol,
+			"source":           pkt.Source,
+			"source_port":      pkt.SourcePort,
+			"destination":      pkt.Destination,
+			"destination_port": pkt.DestinationPort,
+		},
+	}
+
+	err := entry.Insert(db)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"namespace": l.namespace,
+			"error":     err,
+		}).Error("nflog: Failed to insert log entry")
+	}
+}
+
+// run locks the goroutine to a thread which is moved into the namespace,
+// the thread is discarded when the goroutine exits.
+func (l *listener) run() {
+	runtime.LockOSThread()
+
+	fd := 0
+	for {
+		var err error
+		fd, err = l.open()
+		if err == nil {
+			break
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"namespace": l.namespace,
+			"error":     err,
+		}).Error("nflog: Failed to start listener")
+
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+	defer syscall.Close(fd)
+
+	buf := make([]byte, 65536)
+	for {
+		select {
+		case <-l.stop:
+			return
+		default:
+		}
+
+		n, _, e := syscall.Recvfrom(fd, buf, 0)
+		if e != nil {
+			if e == syscall.EAGAIN || e == syscall.EINTR {
+				continue
+			}
+
+			if e == syscall.ENOBUFS {
+				logrus.WithFields(logrus.Fields{
+					"namespace": l.namespace,
+				}).Warn("nflog: Listener buffer overrun")
+				continue
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"namespace": l.namespace,
+				"error":     e,
+			}).Error("nflog: Listener read error")
+
+			time.Sleep(3 * time.Second)
+			continue
+		}
+
+		for _, pkt := range parseMessages(buf[:n]) {
+			if pkt.Prefix != PrefixIngress && pkt.Prefix != PrefixEgress {
+				continue
+			}
+			l.handle(pkt)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package nflog
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+)
+
+type Packet struct {
+	Prefix          string
+	Protocol        string
+	Source          string
+	SourcePort      int
+	Destination     string
+	DestinationPort int
+}
+
+func (p *Packet) Direction() string {
+	switch p.Prefix {
+	case PrefixIngress:
+		return "ingress"
+	case PrefixEgress:
+		return "egress"
+	}
+	return ""
+}
+
+func parseProtocol(proto byte) string {
+	switch proto {
+	case 1:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	case 58:
+		return "ipv6-icmp"
+	}
+	return ""
+}
+
+func parsePayload(pkt *Packet, payload []byte) {
+	if len(payload) < 1 {
+		return
+	}
+
+	var proto byte
+	var transport []byte
+
+	switch payload[0] >> 4 {
+	case 4:
+		if len(payload) < 20 {
+			return
+		}
+		hdrLen := int(payload[0]&0x0f) * 4
+		proto = payload[9]
+		pkt.Source = net.IP(payload[12:16]).String()
+		pkt.Destination = net.IP(payload[16:20]).String()
+		if len(payload) > hdrLen {
+			transport = payload[hdrLen:]
+		}
+	case 6:
+		if len(payload) < 40 {
+			return
+		}
+		proto = payload[6]
+		pkt.Source = net.IP(payload[8:24]).String()
+		pkt.Destination = net.IP(payload[24:40]).String()
+		transport = payload[40:]
+	default:
+		return
+	}
+
+	pkt.Protocol = parseProtocol(proto)
+	if (proto == 6 || proto == 17) && len(transport) >= 4 {
+		pkt.SourcePort = int(binary.BigEndian.Uint16(transport[0:2]))
+		pkt.DestinationPort = int(binary.BigEndian.Uint16(transport[2:4]))
+	}
+}
+
+// parseMessages parses the NFLOG packet messages from a netlink read.
+func parseMessages(data []byte) (pkts []*Packet) {
+	pkts = []*Packet{}
+
+	for len(data) >= 16 {
+		msgLen := int(binary.LittleEndian.Uint32(data[0:4]))
+		msgType := binary.LittleEndian.Uint16(data[4:6])
+		if msgLen < 16 || msgLen > len(data) {
+			return
+		}
+
+		msg := data[16:msgLen]
+		data = data[align(msgLen, len(data)):]
+
+		if msgType != nfnlSubsysUlog<<8|nfnlMsgPacket || len(msg) < 4 {
+			continue
+		}
+
+		pkt := &Packet{}
+		attrs := msg[4:]
+		for len(attrs) >= 4 {
+			attrLen := int(binary.LittleEndian.Uint16(attrs[0:2]))
+			attrType := binary.LittleEndian.Uint16(attrs[2:4]) & 0x3fff
+			if attrLen < 4 || attrLen > len(attrs) {
+				break
+			}
+
+			value := attrs[4:attrLen]
+			switch attrType {
+			case nfulaPrefix:
+				pkt.Prefix = strings.TrimRight(string(value), "\x00")
+			case nfulaPayload:
+				parsePayload(pkt, value)
+			}
+
+			attrs = attrs[align(attrLen, len(attrs)):]
+		}
+
+		pkts = append(pkts, pkt)
+	}
+
+	return
+}
+
+func align(n, max int) int {
+	n = (n + 3) &^ 3
+	if n > max {
+		n = max
+	}
+	return n
+}
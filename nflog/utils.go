@@ -0,0 +1,41 @@
+package nflog
+
+import (
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+)
+
+var (
+	listeners     = map[string]*listener{}
+	listenersLock = sync.Mutex{}
+)
+
+// Sync starts a listener for each namespace with log rules and stops the
+// listeners of namespaces no longer logging.
+func Sync(namespaces set.Set) {
+	listenersLock.Lock()
+	defer listenersLock.Unlock()
+
+	for namespace, lstn := range listeners {
+		if !namespaces.Contains(namespace) {
+			close(lstn.stop)
+			delete(listeners, namespace)
+		}
+	}
+
+	for namespaceInf := range namespaces.Iter() {
+		namespace := namespaceInf.(string)
+		if _, ok := listeners[namespace]; ok {
+			continue
+		}
+
+		lstn := &listener{
+			namespace: namespace,
+			stop:      make(chan bool),
+		}
+		listeners[namespace] = lstn
+
+		go lstn.run()
+	}
+}
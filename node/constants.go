@@ -25,4 +25,8 @@ const (
 	Oracle   = "oracle"
 
 	Restart = "restart"
+
+	EvacuateActive   = "active"
+	EvacuateComplete = "complete"
+	EvacuateFailed   = "failed"
 )
@@ -0,0 +1,57 @@
+package node
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+)
+
+// Evacuation is the progress of moving the instances off a node in
+// maintenance. It is updated by the node being evacuated.
+type Evacuation struct {
+	State     string    `bson:"state" json:"state"`
+	Start     time.Time `bson:"start" json:"start"`
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+	Total     int       `bson:"total" json:"total"`
+	Remaining int       `bson:"remaining" json:"remaining"`
+	Migrating int       `bson:"migrating" json:"migrating"`
+	Failed    int       `bson:"failed" json:"failed"`
+}
+
+func (e *Evacuation) Equal(evac *Evacuation) bool {
+	if evac == nil {
+		return false
+	}
+
+	return e.State == evac.State &&
+		e.Start.Equal(evac.Start) &&
+		e.Total == evac.Total &&
+		e.Remaining == evac.Remaining &&
+		e.Migrating == evac.Migrating &&
+		e.Failed == evac.Failed
+}
+
+// SetEvacuation updates the evacuation progress only while the node is
+// still set to evacuate.
+func SetEvacuation(db *database.Database, ndeId primitive.ObjectID,
+	evac *Evacuation) (err error) {
+
+	coll := db.Nodes()
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id":      ndeId,
+		"evacuate": true,
+	}, &bson.M{
+		"$set": &bson.M{
+			"evacuation": evac,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
@@ -117,11 +117,16 @@ type Node struct {
 	VirtPath                string               `bson:"virt_path" json:"virt_path"`
 	CachePath               string               `bson:"cache_path" json:"cache_path"`
 	TempPath                string               `bson:"temp_path" json:"temp_path"`
+	BackupPath              string               `bson:"backup_path" json:"backup_path"`
+	BackupBootTest          bool                 `bson:"backup_boot_test" json:"backup_boot_test"`
 	OracleUser              string               `bson:"oracle_user" json:"oracle_user"`
 	OraclePrivateKey        string               `bson:"oracle_private_key" json:"-"`
 	OraclePublicKey         string               `bson:"oracle_public_key" json:"oracle_public_key"`
 	OracleHostRoute         bool                 `bson:"oracle_host_route" json:"oracle_host_route"`
 	Operation               string               `bson:"operation" json:"operation"`
+	Maintenance             bool                 `bson:"maintenance" json:"maintenance"`
+	Evacuate                bool                 `bson:"evacuate" json:"evacuate"`
+	Evacuation              *Evacuation          `bson:"evacuation,omitempty" json:"evacuation"`
 	oracleSubnetsNamed      []*OracleSubnet      `bson:"-" json:"-"`
 	reqLock                 sync.Mutex           `bson:"-" json:"-"`
 	reqCount                *list.List           `bson:"-" json:"-"`
@@ -210,11 +215,16 @@ func (n *Node) Copy() *Node {
 		VirtPath:                n.VirtPath,
 		CachePath:               n.CachePath,
 		TempPath:                n.TempPath,
+		BackupPath:              n.BackupPath,
+		BackupBootTest:          n.BackupBootTest,
 		OracleUser:              n.OracleUser,
 		OraclePrivateKey:        n.OraclePrivateKey,
 		OraclePublicKey:         n.OraclePublicKey,
 		OracleHostRoute:         n.OracleHostRoute,
 		Operation:               n.Operation,
+		Maintenance:             n.Maintenance,
+		Evacuate:                n.Evacuate,
+		Evacuation:              n.Evacuation,
 		dcId:                    n.dcId,
 		dcZoneId:                n.dcZoneId,
 	}
@@ -412,6 +422,19 @@ func (n *Node) Validate(db *database.Database) (
 		n.Hypervisor = Kvm
 	}
 
+	n.BackupPath = strings.TrimSpace(n.BackupPath)
+	if n.BackupPath != "" && !path.IsAbs(n.BackupPath) {
+		errData = &errortypes.ErrorData{
+			Error:   "node_backup_path_invalid",
+			Message: "Backup path must be absolute",
+		}
+		return
+	}
+
+	if !n.Maintenance {
+		n.Evacuate = false
+	}
+
 	switch n.Vga {
 	case Std, Vmware, Virtio:
 		n.VgaRender = ""
@@ -974,6 +997,21 @@ func (n *Node) GetRemoteAddr(r *http.Request) (addr string) {
 	return
 }
 
+// GetTrustedRemoteAddr returns the address added by the trusted proxy in
+// front of the node, earlier forwarded for entries are set by the client
+func (n *Node) GetTrustedRemoteAddr(r *http.Request) (addr string) {
+	if n.ForwardedForHeader != "" {
+		hops := strings.Split(r.Header.Get(n.ForwardedForHeader), ",")
+		addr = strings.TrimSpace(hops[len(hops)-1])
+		if addr != "" {
+			return
+		}
+	}
+
+	addr = utils.StripPort(r.RemoteAddr)
+	return
+}
+
 func (n *Node) update(db *database.Database) (err error) {
 	coll := db.Nodes()
 
@@ -1070,11 +1108,16 @@ func (n *Node) update(db *database.Database) (err error) {
 	n.VirtPath = nde.VirtPath
 	n.CachePath = nde.CachePath
 	n.TempPath = nde.TempPath
+	n.BackupPath = nde.BackupPath
+	n.BackupBootTest = nde.BackupBootTest
 	n.OracleUser = nde.OracleUser
 	n.OraclePrivateKey = nde.OraclePrivateKey
 	n.OraclePublicKey = nde.OraclePublicKey
 	n.OracleHostRoute = nde.OracleHostRoute
 	n.Operation = nde.Operation
+	n.Maintenance = nde.Maintenance
+	n.Evacuate = nde.Evacuate
+	n.Evacuation = nde.Evacuation
 
 	return
 }
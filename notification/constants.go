@@ -0,0 +1,10 @@
+package notification
+
+const (
+	Webhook = "webhook"
+	Slack   = "slack"
+	Smtp    = "smtp"
+
+	Firing   = "firing"
+	Resolved = "resolved"
+)
@@ -0,0 +1,388 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+var (
+	client = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	publicDialer = &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: utils.PublicControl,
+	}
+	publicClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         publicDialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+)
+
+const (
+	sendAttempts = 3
+	sendBackoff  = 2 * time.Second
+	sendTimeout  = 20 * time.Second
+)
+
+type Message struct {
+	Id         string             `json:"id"`
+	State      string             `json:"state"`
+	Alert      primitive.ObjectID `json:"alert"`
+	AlertName  string             `json:"alert_name"`
+	Resource   string             `json:"resource"`
+	Source     primitive.ObjectID `json:"source"`
+	SourceName string             `json:"source_name"`
+	Level      int                `json:"level"`
+	Message    string             `json:"message"`
+	Timestamp  time.Time          `json:"timestamp"`
+}
+
+var headerReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+func (m *Message) Subject() string {
+	alertName := headerReplacer.Replace(m.AlertName)
+	sourceName := headerReplacer.Replace(m.SourceName)
+
+	if m.State == Resolved {
+		return fmt.Sprintf("[RESOLVED] %s: %s", alertName, sourceName)
+	}
+	return fmt.Sprintf("[ALERT] %s: %s", alertName, sourceName)
+}
+
+func (m *Message) Text() string {
+	if m.State == Resolved {
+		return fmt.Sprintf("%s - %s", m.Subject(), "Alert resolved")
+	}
+	return fmt.Sprintf("%s - %s", m.Subject(), m.Message)
+}
+
+type slackData struct {
+	Text string `json:"text"`
+}
+
+func (n *Notification) post(ctx context.Context, body []byte,
+	headers map[string]string) (err error) {
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		n.Url,
+		bytes.NewBuffer(body),
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "notification: Failed to create request"),
+		}
+		return
+	}
+
+	req.Header.Set("User-Agent", "pritunl-cloud")
+	req.Header.Set("Content-Type", "application/json")
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+
+	clnt := client
+	if !n.Organization.IsZero() {
+		clnt = publicClient
+	}
+
+	resp, err := clnt.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "notification: Request failed"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body := ""
+		data, _ := ioutil.ReadAll(resp.Body)
+		if data != nil {
+			body = string(data)
+		}
+
+		err = &errortypes.RequestError{
+			errors.Newf(
+				"notification: Request error %d - %s",
+				resp.StatusCode, body),
+		}
+		return
+	}
+
+	return
+}
+
+func (n *Notification) sendWebhook(ctx context.Context, msg *Message) (
+	err error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "notification: Failed to marshal message"),
+		}
+		return
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	hash := hmac.New(sha256.New, []byte(n.Secret))
+	hash.Write([]byte(timestamp + "."))
+	hash.Write(body)
+	sig := hex.EncodeToString(hash.Sum(nil))
+
+	err = n.post(ctx, body, map[string]string{
+		"X-Pritunl-Timestamp": timestamp,
+		"X-Pritunl-Signature": "sha256=" + sig,
+		"X-Pritunl-Delivery":  msg.Id,
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *Notification) sendSlack(ctx context.Context, msg *Message) (
+	err error) {
+	body, err := json.Marshal(&slackData{
+		Text: msg.Text(),
+	})
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "notification: Failed to marshal message"),
+		}
+		return
+	}
+
+	err = n.post(ctx, body, nil)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *Notification) sendSmtp(ctx context.Context, msg *Message) (
+	err error) {
+
+	addr := net.JoinHostPort(n.SmtpHost, strconv.Itoa(n.SmtpPort))
+	tlsConf := &tls.Config{
+		ServerName: n.SmtpHost,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+	}
+	if !n.Organization.IsZero() {
+		dialer = publicDialer
+	}
+	if n.SmtpPort == 465 {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    tlsConf,
+		}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "notification: Failed to connect to SMTP"),
+		}
+		return
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	clnt, err := smtp.NewClient(conn, n.SmtpHost)
+	if err != nil {
+		conn.Close()
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "notification: Failed to create SMTP client"),
+		}
+		return
+	}
+	defer clnt.Close()
+
+	if n.SmtpPort != 465 {
+		if ok, _ := clnt.Extension("STARTTLS"); ok {
+			err = clnt.StartTLS(tlsConf)
+			if err != nil {
+				err = &errortypes.ConnectionError{
+					errors.Wrap(err, "notification: SMTP STARTTLS failed"),
+				}
+				return
+			}
+		}
+	}
+
+	if n.SmtpUsername != "" {
+		err = clnt.Auth(smtp.PlainAuth(
+			"", n.SmtpUsername, n.SmtpPassword, n.SmtpHost))
+		if err != nil {
+			err = &errortypes.AuthenticationError{
+				errors.Wrap(err, "notification: SMTP auth failed"),
+			}
+			return
+		}
+	}
+
+	err = clnt.Mail(n.SmtpFrom)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "notification: SMTP sender rejected"),
+		}
+		return
+	}
+
+	for _, addr := range n.SmtpTo {
+		err = clnt.Rcpt(addr)
+		if err != nil {
+			err = &errortypes.RequestError{
+				errors.Wrap(err, "notification: SMTP recipient rejected"),
+			}
+			return
+		}
+	}
+
+	writer, err := clnt.Data()
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "notification: SMTP data failed"),
+		}
+		return
+	}
+
+	body := strings.Join([]string{
+		"From: " + n.SmtpFrom,
+		"To: " + strings.Join(n.SmtpTo, ", "),
+		"Subject: " + msg.Subject(),
+		"Date: " + msg.Timestamp.Format(time.RFC1123Z),
+		"Message-ID: <" + msg.Id + "@pritunl-cloud>",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		msg.Text(),
+		"",
+	}, "\r\n")
+
+	_, err = writer.Write([]byte(body))
+	if err != nil {
+		writer.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "notification: SMTP write failed"),
+		}
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "notification: SMTP write failed"),
+		}
+		return
+	}
+
+	err = clnt.Quit()
+	if err != nil {
+		err = nil
+	}
+
+	return
+}
+
+func (n *Notification) send(ctx context.Context, msg *Message) (
+	err error) {
+
+	switch n.Type {
+	case Webhook:
+		err = n.sendWebhook(ctx, msg)
+		break
+	case Slack:
+		err = n.sendSlack(ctx, msg)
+		break
+	case Smtp:
+		err = n.sendSmtp(ctx, msg)
+		break
+	default:
+		err = &errortypes.UnknownError{
+			errors.Newf("notification: Unknown type '%s'", n.Type),
+		}
+	}
+
+	return
+}
+
+// Send delivers the message with retries, all attempts must complete within
+// the send timeout to avoid blocking alert checks on slow endpoints
+func (n *Notification) Send(msg *Message) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	for i := 0; i < sendAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(sendBackoff * time.Duration(i)):
+			}
+		}
+
+		err = n.send(ctx, msg)
+		if err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (n *Notification) SendTest() (err error) {
+	msg := &Message{
+		Id:         primitive.NewObjectID().Hex(),
+		State:      Firing,
+		AlertName:  "Test",
+		SourceName: n.Name,
+		Message:    "Test notification message",
+		Timestamp:  time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	err = n.send(ctx, msg)
+	if err != nil {
+		return
+	}
+
+	return
+}
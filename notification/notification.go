@@ -0,0 +1,220 @@
+package notification
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+type Notification struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Comment      string             `bson:"comment" json:"comment"`
+	Organization primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
+	Type         string             `bson:"type" json:"type"`
+	Url          string             `bson:"url" json:"url"`
+	Secret       string             `bson:"secret" json:"secret"`
+	SmtpHost     string             `bson:"smtp_host" json:"smtp_host"`
+	SmtpPort     int                `bson:"smtp_port" json:"smtp_port"`
+	SmtpUsername string             `bson:"smtp_username" json:"smtp_username"`
+	SmtpPassword string             `bson:"smtp_password" json:"-"`
+	SmtpFrom     string             `bson:"smtp_from" json:"smtp_from"`
+	SmtpTo       []string           `bson:"smtp_to" json:"smtp_to"`
+}
+
+func (n *Notification) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	if n.SmtpTo == nil {
+		n.SmtpTo = []string{}
+	}
+
+	switch n.Type {
+	case Webhook, "":
+		n.Type = Webhook
+
+		if !n.validUrl() {
+			errData = &errortypes.ErrorData{
+				Error:   "url_invalid",
+				Message: "Webhook URL must be a valid public HTTPS URL",
+			}
+			return
+		}
+
+		if n.Secret == "" {
+			n.Secret, err = utils.RandStr(32)
+			if err != nil {
+				return
+			}
+		}
+
+		n.clearSmtp()
+		break
+	case Slack:
+		if !n.validUrl() {
+			errData = &errortypes.ErrorData{
+				Error:   "url_invalid",
+				Message: "Incoming webhook URL must be a valid public HTTPS URL",
+			}
+			return
+		}
+
+		n.Secret = ""
+		n.clearSmtp()
+		break
+	case Smtp:
+		n.SmtpHost = strings.TrimSpace(n.SmtpHost)
+		if n.SmtpHost == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "smtp_host_required",
+				Message: "Missing required SMTP host",
+			}
+			return
+		}
+
+		if !n.Organization.IsZero() && !utils.PublicHost(n.SmtpHost) {
+			errData = &errortypes.ErrorData{
+				Error:   "smtp_host_invalid",
+				Message: "SMTP host must be a public address",
+			}
+			return
+		}
+
+		if n.SmtpPort == 0 {
+			n.SmtpPort = 587
+		}
+		if n.SmtpPort < 1 || n.SmtpPort > 65535 {
+			errData = &errortypes.ErrorData{
+				Error:   "smtp_port_invalid",
+				Message: "SMTP port invalid",
+			}
+			return
+		}
+
+		if !strings.Contains(n.SmtpFrom, "@") ||
+			strings.ContainsAny(n.SmtpFrom, "\r\n") {
+
+			errData = &errortypes.ErrorData{
+				Error:   "smtp_from_invalid",
+				Message: "SMTP from address invalid",
+			}
+			return
+		}
+
+		recipients := []string{}
+		for _, addr := range n.SmtpTo {
+			addr = strings.TrimSpace(addr)
+			if addr == "" {
+				continue
+			}
+
+			if !strings.Contains(addr, "@") ||
+				strings.ContainsAny(addr, "\r\n") {
+
+				errData = &errortypes.ErrorData{
+					Error:   "smtp_to_invalid",
+					Message: "SMTP recipient address invalid",
+				}
+				return
+			}
+
+			recipients = append(recipients, addr)
+		}
+		n.SmtpTo = recipients
+
+		if len(n.SmtpTo) == 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "smtp_to_required",
+				Message: "Missing required SMTP recipient",
+			}
+			return
+		}
+
+		n.Url = ""
+		n.Secret = ""
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "type_invalid",
+			Message: "Notification type invalid",
+		}
+		return
+	}
+
+	return
+}
+
+// validUrl checks the notification URL is HTTPS, organization
+// notifications must also resolve to public addresses
+func (n *Notification) validUrl() bool {
+	u, err := url.Parse(n.Url)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return false
+	}
+
+	if !n.Organization.IsZero() && !utils.PublicHost(u.Hostname()) {
+		return false
+	}
+
+	return true
+}
+
+func (n *Notification) clearSmtp() {
+	n.SmtpHost = ""
+	n.SmtpPort = 0
+	n.SmtpUsername = ""
+	n.SmtpPassword = ""
+	n.SmtpFrom = ""
+	n.SmtpTo = []string{}
+}
+
+func (n *Notification) Commit(db *database.Database) (err error) {
+	coll := db.Notifications()
+
+	err = coll.Commit(n.Id, n)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *Notification) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.Notifications()
+
+	err = coll.CommitFields(n.Id, n, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *Notification) Insert(db *database.Database) (err error) {
+	coll := db.Notifications()
+
+	if !n.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("notification: Notification already exists"),
+		}
+		return
+	}
+
+	resp, err := coll.InsertOne(db, n)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	n.Id = resp.InsertedID.(primitive.ObjectID)
+
+	return
+}
@@ -0,0 +1,251 @@
+package notification
+
+import (
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/mongo-go-driver/mongo/options"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+func Get(db *database.Database, notfId primitive.ObjectID) (
+	notf *Notification, err error) {
+
+	coll := db.Notifications()
+	notf = &Notification{}
+
+	err = coll.FindOneId(notfId, notf)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetOrg(db *database.Database, orgId, notfId primitive.ObjectID) (
+	notf *Notification, err error) {
+
+	coll := db.Notifications()
+	notf = &Notification{}
+
+	err = coll.FindOne(db, &bson.M{
+		"_id":          notfId,
+		"organization": orgId,
+	}).Decode(notf)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetMulti(db *database.Database, notfIds []primitive.ObjectID) (
+	notfs []*Notification, err error) {
+
+	coll := db.Notifications()
+	notfs = []*Notification{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"_id": &bson.M{
+				"$in": notfIds,
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		notf := &Notification{}
+		err = cursor.Decode(notf)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		notfs = append(notfs, notf)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAll(db *database.Database) (notfs []*Notification, err error) {
+	coll := db.Notifications()
+	notfs = []*Notification{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		notf := &Notification{}
+		err = cursor.Decode(notf)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		notfs = append(notfs, notf)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAllPaged(db *database.Database, query *bson.M,
+	page, pageCount int64) (notfs []*Notification, count int64, err error) {
+
+	coll := db.Notifications()
+	notfs = []*Notification{}
+
+	if len(*query) == 0 {
+		count, err = coll.EstimatedDocumentCount(db)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	} else {
+		count, err = coll.CountDocuments(db, query)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = utils.Min64(page, maxPage)
+	skip := utils.Min64(page*pageCount, count)
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"name", 1},
+			},
+			Skip:  &skip,
+			Limit: &pageCount,
+		},
+	)
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		notf := &Notification{}
+		err = cursor.Decode(notf)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		notfs = append(notfs, notf)
+		notf = &Notification{}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func Remove(db *database.Database,
+	notfId primitive.ObjectID) (err error) {
+
+	coll := db.Notifications()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": notfId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func RemoveOrg(db *database.Database, orgId, notfId primitive.ObjectID) (
+	err error) {
+
+	coll := db.Notifications()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id":          notfId,
+		"organization": orgId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		switch err.(type) {
+		case *database.NotFoundError:
+			err = nil
+		default:
+			return
+		}
+	}
+
+	return
+}
+
+func RemoveMulti(db *database.Database, notfIds []primitive.ObjectID) (
+	err error) {
+
+	coll := db.Notifications()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": &bson.M{
+			"$in": notfIds,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func RemoveMultiOrg(db *database.Database, orgId primitive.ObjectID,
+	notfIds []primitive.ObjectID) (err error) {
+
+	coll := db.Notifications()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": &bson.M{
+			"$in": notfIds,
+		},
+		"organization": orgId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
@@ -85,6 +85,19 @@ func GetCacheDir(virtId primitive.ObjectID) string {
 	return path.Join(GetCachesDir(), virtId.Hex())
 }
 
+func GetMigrateSockPath(virtId primitive.ObjectID) string {
+	return path.Join(GetCacheDir(virtId), "migrate.sock")
+}
+
+func GetMigrateNbdSockPath(virtId primitive.ObjectID) string {
+	return path.Join(GetCacheDir(virtId), "migrate_nbd.sock")
+}
+
+func GetMigrateDiskSockPath(virtId, diskId primitive.ObjectID) string {
+	return path.Join(GetCacheDir(virtId),
+		fmt.Sprintf("migrate_%s.sock", diskId.Hex()))
+}
+
 func GetOvmfDir() string {
 	return path.Join(node.Self.GetVirtPath(), "ovmf")
 }
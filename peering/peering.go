@@ -0,0 +1,248 @@
+package peering
+
+import (
+	"net"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vpc"
+)
+
+type Peering struct {
+	Id               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name             string             `bson:"name" json:"name"`
+	Comment          string             `bson:"comment" json:"comment"`
+	Datacenter       primitive.ObjectID `bson:"datacenter" json:"datacenter"`
+	Organization     primitive.ObjectID `bson:"organization" json:"organization"`
+	Vpc              primitive.ObjectID `bson:"vpc" json:"vpc"`
+	Accepted         bool               `bson:"accepted" json:"accepted"`
+	PeerOrganization primitive.ObjectID `bson:"peer_organization" json:"peer_organization"`
+	PeerVpc          primitive.ObjectID `bson:"peer_vpc" json:"peer_vpc"`
+	PeerAccepted     bool               `bson:"peer_accepted" json:"peer_accepted"`
+	Active           bool               `bson:"active" json:"active"`
+}
+
+func (p *Peering) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	if p.Vpc.IsZero() {
+		errData = &errortypes.ErrorData{
+			Error:   "vpc_required",
+			Message: "Missing required VPC",
+		}
+		return
+	}
+
+	if p.PeerVpc.IsZero() {
+		errData = &errortypes.ErrorData{
+			Error:   "peer_vpc_required",
+			Message: "Missing required peer VPC",
+		}
+		return
+	}
+
+	if p.Vpc == p.PeerVpc {
+		errData = &errortypes.ErrorData{
+			Error:   "peer_vpc_invalid",
+			Message: "Cannot peer VPC with itself",
+		}
+		return
+	}
+
+	vc, err := vpc.Get(db, p.Vpc)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "vpc_not_found",
+				Message: "VPC does not exist",
+			}
+		}
+		return
+	}
+
+	peerVc, err := vpc.Get(db, p.PeerVpc)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "peer_vpc_not_found",
+				Message: "Peer VPC does not exist",
+			}
+		}
+		return
+	}
+
+	p.Organization = vc.Organization
+	p.PeerOrganization = peerVc.Organization
+	p.Datacenter = vc.Datacenter
+
+	if vc.Datacenter != peerVc.Datacenter {
+		errData = &errortypes.ErrorData{
+			Error:   "peer_vpc_datacenter_invalid",
+			Message: "Peer VPC must be in the same datacenter",
+		}
+		return
+	}
+
+	if vc.VpcId == peerVc.VpcId {
+		errData = &errortypes.ErrorData{
+			Error:   "peer_vpc_id_conflict",
+			Message: "Cannot peer VPCs with the same VPC ID",
+		}
+		return
+	}
+
+	if p.Organization == p.PeerOrganization {
+		p.Accepted = true
+		p.PeerAccepted = true
+	}
+	p.Active = p.Accepted && p.PeerAccepted
+
+	network, err := vc.GetNetwork()
+	if err != nil {
+		return
+	}
+
+	peerNetwork, err := peerVc.GetNetwork()
+	if err != nil {
+		return
+	}
+
+	if utils.NetworkOverlaps(network, peerNetwork) {
+		errData = &errortypes.ErrorData{
+			Error:   "peer_vpc_network_overlap",
+			Message: "Peer VPC network overlaps with VPC network",
+		}
+		return
+	}
+
+	peerings, err := GetAll(db, &bson.M{
+		"_id": &bson.M{
+			"$ne": p.Id,
+		},
+		"$or": []*bson.M{
+			&bson.M{
+				"vpc": &bson.M{
+					"$in": []primitive.ObjectID{p.Vpc, p.PeerVpc},
+				},
+			},
+			&bson.M{
+				"peer_vpc": &bson.M{
+					"$in": []primitive.ObjectID{p.Vpc, p.PeerVpc},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	for _, peering := range peerings {
+		if peering.HasVpc(p.Vpc) && peering.HasVpc(p.PeerVpc) {
+			errData = &errortypes.ErrorData{
+				Error:   "peering_duplicate",
+				Message: "VPCs are already peered",
+			}
+			return
+		}
+
+		// Each side routes to the networks of all of its peers, these
+		// networks must not overlap with the network of the new peer
+		var curVpcId primitive.ObjectID
+		var newNetwork *net.IPNet
+		if peering.HasVpc(p.Vpc) {
+			curVpcId = peering.GetPeer(p.Vpc)
+			newNetwork = peerNetwork
+		} else {
+			curVpcId = peering.GetPeer(p.PeerVpc)
+			newNetwork = network
+		}
+
+		curVc, e := vpc.Get(db, curVpcId)
+		if e != nil {
+			if _, ok := e.(*database.NotFoundError); ok {
+				continue
+			}
+			err = e
+			return
+		}
+
+		curNetwork, e := curVc.GetNetwork()
+		if e != nil {
+			err = e
+			return
+		}
+
+		if utils.NetworkOverlaps(curNetwork, newNetwork) {
+			errData = &errortypes.ErrorData{
+				Error:   "peer_vpc_network_overlap",
+				Message: "Peer VPC network overlaps with existing peering",
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (p *Peering) HasVpc(vpcId primitive.ObjectID) bool {
+	return p.Vpc == vpcId || p.PeerVpc == vpcId
+}
+
+func (p *Peering) GetPeer(vpcId primitive.ObjectID) primitive.ObjectID {
+	if p.Vpc == vpcId {
+		return p.PeerVpc
+	}
+	return p.Vpc
+}
+
+func (p *Peering) Commit(db *database.Database) (err error) {
+	coll := db.VpcPeerings()
+
+	err = coll.Commit(p.Id, p)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Peering) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.VpcPeerings()
+
+	err = coll.CommitFields(p.Id, p, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Peering) Insert(db *database.Database) (err error) {
+	coll := db.VpcPeerings()
+
+	if !p.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("peering: Peering already exists"),
+		}
+		return
+	}
+
+	resp, err := coll.InsertOne(db, p)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	p.Id = resp.InsertedID.(primitive.ObjectID)
+
+	return
+}
@@ -0,0 +1,181 @@
+package peering
+
+import (
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/mongo-go-driver/mongo/options"
+	"github.com/pritunl/pritunl-cloud/database"
+)
+
+func Get(db *database.Database, peeringId primitive.ObjectID) (
+	peering *Peering, err error) {
+
+	coll := db.VpcPeerings()
+	peering = &Peering{}
+
+	err = coll.FindOneId(peeringId, peering)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetOrg(db *database.Database, orgId, peeringId primitive.ObjectID) (
+	peering *Peering, err error) {
+
+	coll := db.VpcPeerings()
+	peering = &Peering{}
+
+	err = coll.FindOne(db, &bson.M{
+		"_id": peeringId,
+		"$or": []*bson.M{
+			&bson.M{
+				"organization": orgId,
+			},
+			&bson.M{
+				"peer_organization": orgId,
+			},
+		},
+	}).Decode(peering)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAll(db *database.Database, query *bson.M) (
+	peerings []*Peering, err error) {
+
+	coll := db.VpcPeerings()
+	peerings = []*Peering{}
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"name", 1},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		peering := &Peering{}
+		err = cursor.Decode(peering)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		peerings = append(peerings, peering)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAllOrg(db *database.Database, orgId primitive.ObjectID) (
+	peerings []*Peering, err error) {
+
+	peerings, err = GetAll(db, &bson.M{
+		"$or": []*bson.M{
+			&bson.M{
+				"organization": orgId,
+			},
+			&bson.M{
+				"peer_organization": orgId,
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetDatacenter returns the peered VPC IDs of each VPC in the datacenter
+// for peerings that have been accepted by both organizations.
+func GetDatacenter(db *database.Database, dcId primitive.ObjectID) (
+	peers map[primitive.ObjectID][]primitive.ObjectID, err error) {
+
+	peers = map[primitive.ObjectID][]primitive.ObjectID{}
+
+	peerings, err := GetAll(db, &bson.M{
+		"datacenter": dcId,
+		"active":     true,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, peering := range peerings {
+		peers[peering.Vpc] = append(peers[peering.Vpc], peering.PeerVpc)
+		peers[peering.PeerVpc] = append(peers[peering.PeerVpc], peering.Vpc)
+	}
+
+	return
+}
+
+func Remove(db *database.Database, peeringId primitive.ObjectID) (
+	err error) {
+
+	coll := db.VpcPeerings()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id": peeringId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		switch err.(type) {
+		case *database.NotFoundError:
+			err = nil
+		default:
+			return
+		}
+	}
+
+	return
+}
+
+func RemoveOrg(db *database.Database, orgId, peeringId primitive.ObjectID) (
+	err error) {
+
+	coll := db.VpcPeerings()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id": peeringId,
+		"$or": []*bson.M{
+			&bson.M{
+				"organization": orgId,
+			},
+			&bson.M{
+				"peer_organization": orgId,
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		switch err.(type) {
+		case *database.NotFoundError:
+			err = nil
+		default:
+			return
+		}
+	}
+
+	return
+}
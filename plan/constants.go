@@ -3,4 +3,27 @@ package plan
 const (
 	Rolling  = "rolling"
 	Recreate = "recreate"
+
+	None     = "none"
+	Guest    = "guest"
+	Balancer = "balancer"
+
+	Running         = "running"
+	Completed       = "completed"
+	RolledBack      = "rolled_back"
+	PartialRollback = "partial_rollback"
+	Failed          = "failed"
+
+	Replace = "replace"
+	Restart = "restart"
+
+	Pending   = "pending"
+	Stopping  = "stopping"
+	Replacing = "replacing"
+	Replaced  = "replaced"
+	Reverted  = "reverted"
+
+	DefaultTimeout = 600
+	MinTimeout     = 30
+	MaxTimeout     = 7200
 )
@@ -4,16 +4,22 @@ import (
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/balancer"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 )
 
 type Plan struct {
-	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name         string             `bson:"name" json:"name"`
-	Comment      string             `bson:"comment" json:"comment"`
-	Organization primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
-	Type         string             `bson:"type" json:"type"`
+	Id             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name           string             `bson:"name" json:"name"`
+	Comment        string             `bson:"comment" json:"comment"`
+	Organization   primitive.ObjectID `bson:"organization,omitempty" json:"organization"`
+	Type           string             `bson:"type" json:"type"`
+	MaxUnavailable int                `bson:"max_unavailable" json:"max_unavailable"`
+	MaxSurge       int                `bson:"max_surge" json:"max_surge"`
+	HealthCheck    string             `bson:"health_check" json:"health_check"`
+	Balancer       primitive.ObjectID `bson:"balancer,omitempty" json:"balancer"`
+	Timeout        int                `bson:"timeout" json:"timeout"`
 }
 
 func (p *Plan) Validate(db *database.Database) (
@@ -41,6 +47,73 @@ func (p *Plan) Validate(db *database.Database) (
 		return
 	}
 
+	if p.MaxUnavailable < 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "max_unavailable_invalid",
+			Message: "Max unavailable cannot be negative",
+		}
+		return
+	}
+
+	if p.MaxSurge < 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "max_surge_invalid",
+			Message: "Max surge cannot be negative",
+		}
+		return
+	}
+
+	if p.Type == Rolling && p.MaxUnavailable == 0 && p.MaxSurge == 0 {
+		p.MaxSurge = 1
+	}
+
+	if p.Timeout == 0 {
+		p.Timeout = DefaultTimeout
+	} else if p.Timeout < MinTimeout || p.Timeout > MaxTimeout {
+		errData = &errortypes.ErrorData{
+			Error:   "timeout_invalid",
+			Message: "Health timeout invalid",
+		}
+		return
+	}
+
+	switch p.HealthCheck {
+	case None, "":
+		p.HealthCheck = None
+		p.Balancer = primitive.NilObjectID
+		break
+	case Guest:
+		p.Balancer = primitive.NilObjectID
+		break
+	case Balancer:
+		if p.Balancer.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "balancer_required",
+				Message: "Missing required balancer for health check",
+			}
+			return
+		}
+
+		_, err = balancer.GetOrg(db, p.Organization, p.Balancer)
+		if err != nil {
+			if _, ok := err.(*database.NotFoundError); ok {
+				err = nil
+				errData = &errortypes.ErrorData{
+					Error:   "balancer_invalid",
+					Message: "Health check balancer does not exist",
+				}
+			}
+			return
+		}
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "health_check_invalid",
+			Message: "Health check type invalid",
+		}
+		return
+	}
+
 	return
 }
 
@@ -78,11 +151,13 @@ func (p *Plan) Insert(db *database.Database) (err error) {
 		return
 	}
 
-	_, err = coll.InsertOne(db, p)
+	resp, err := coll.InsertOne(db, p)
 	if err != nil {
 		err = database.ParseError(err)
 		return
 	}
 
+	p.Id = resp.InsertedID.(primitive.ObjectID)
+
 	return
 }
@@ -0,0 +1,119 @@
+package plan
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+type Target struct {
+	Instance    primitive.ObjectID `bson:"instance" json:"instance"`
+	Replacement primitive.ObjectID `bson:"replacement,omitempty" json:"replacement"`
+	State       string             `bson:"state" json:"state"`
+	Surge       bool               `bson:"surge" json:"surge"`
+	Start       time.Time          `bson:"start" json:"start"`
+}
+
+type Step struct {
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+	Action    string             `bson:"action" json:"action"`
+	Instance  primitive.ObjectID `bson:"instance,omitempty" json:"instance"`
+	Message   string             `bson:"message" json:"message"`
+}
+
+type Run struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Plan         primitive.ObjectID `bson:"plan" json:"plan"`
+	Organization primitive.ObjectID `bson:"organization" json:"organization"`
+	Pod          primitive.ObjectID `bson:"pod,omitempty" json:"pod"`
+	Role         string             `bson:"role" json:"role"`
+	Type         string             `bson:"type" json:"type"`
+	Action       string             `bson:"action" json:"action"`
+	State        string             `bson:"state" json:"state"`
+	Hash         string             `bson:"hash" json:"-"`
+	Timestamp    time.Time          `bson:"timestamp" json:"timestamp"`
+	Finished     time.Time          `bson:"finished" json:"finished"`
+	Targets      []*Target          `bson:"targets" json:"targets"`
+	Steps        []*Step            `bson:"steps" json:"steps"`
+}
+
+func (r *Run) HasTarget(instId primitive.ObjectID) bool {
+	for _, target := range r.Targets {
+		if target.Instance == instId || target.Replacement == instId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Run) Active() bool {
+	return r.State == Running
+}
+
+func (r *Run) Step(action string, instId primitive.ObjectID,
+	message string) {
+
+	r.Steps = append(r.Steps, &Step{
+		Timestamp: time.Now(),
+		Action:    action,
+		Instance:  instId,
+		Message:   message,
+	})
+}
+
+func (r *Run) Commit(db *database.Database) (err error) {
+	coll := db.PlansRun()
+
+	err = coll.Commit(r.Id, r)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Run) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.PlansRun()
+
+	err = coll.CommitFields(r.Id, r, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Run) Insert(db *database.Database) (err error) {
+	coll := db.PlansRun()
+
+	if !r.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("plan: Run already exists"),
+		}
+		return
+	}
+
+	if r.Targets == nil {
+		r.Targets = []*Target{}
+	}
+	if r.Steps == nil {
+		r.Steps = []*Step{}
+	}
+
+	resp, err := coll.InsertOne(db, r)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	r.Id = resp.InsertedID.(primitive.ObjectID)
+
+	return
+}
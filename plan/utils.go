@@ -271,3 +271,211 @@ func RemoveMultiOrg(db *database.Database, orgId primitive.ObjectID,
 
 	return
 }
+
+func GetRun(db *database.Database, runId primitive.ObjectID) (
+	run *Run, err error) {
+
+	coll := db.PlansRun()
+	run = &Run{}
+
+	err = coll.FindOneId(runId, run)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetRunOrg(db *database.Database, orgId, plnId, runId primitive.ObjectID) (
+	run *Run, err error) {
+
+	coll := db.PlansRun()
+	run = &Run{}
+
+	err = coll.FindOne(db, &bson.M{
+		"_id":          runId,
+		"plan":         plnId,
+		"organization": orgId,
+	}).Decode(run)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetRuns(db *database.Database, query *bson.M) (
+	runs []*Run, err error) {
+
+	coll := db.PlansRun()
+	runs = []*Run{}
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"timestamp", 1},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		run := &Run{}
+		err = cursor.Decode(run)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		runs = append(runs, run)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetRunsPaged(db *database.Database, query *bson.M,
+	page, pageCount int64) (runs []*Run, count int64, err error) {
+
+	coll := db.PlansRun()
+	runs = []*Run{}
+
+	count, err = coll.CountDocuments(db, query)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = utils.Min64(page, maxPage)
+	skip := utils.Min64(page*pageCount, count)
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		&options.FindOptions{
+			Sort: &bson.D{
+				{"timestamp", -1},
+			},
+			Skip:  &skip,
+			Limit: &pageCount,
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		run := &Run{}
+		err = cursor.Decode(run)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		runs = append(runs, run)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetPodRun(db *database.Database, podId primitive.ObjectID) (
+	run *Run, err error) {
+
+	coll := db.PlansRun()
+	run = &Run{}
+
+	err = coll.FindOne(
+		db,
+		&bson.M{
+			"pod": podId,
+		},
+		&options.FindOneOptions{
+			Sort: &bson.D{
+				{"timestamp", -1},
+			},
+		},
+	).Decode(run)
+	if err != nil {
+		err = database.ParseError(err)
+		if _, ok := err.(*database.NotFoundError); ok {
+			run = nil
+			err = nil
+		}
+		return
+	}
+
+	return
+}
+
+func RunningExists(db *database.Database, orgId, podId primitive.ObjectID,
+	role string, instIds []primitive.ObjectID) (exists bool, err error) {
+
+	coll := db.PlansRun()
+
+	overlap := []*bson.M{}
+	if !podId.IsZero() {
+		overlap = append(overlap, &bson.M{
+			"pod": podId,
+		})
+	}
+	if role != "" {
+		overlap = append(overlap, &bson.M{
+			"role": role,
+		})
+	}
+	if len(instIds) > 0 {
+		overlap = append(overlap, &bson.M{
+			"targets.instance": &bson.M{
+				"$in": instIds,
+			},
+		}, &bson.M{
+			"targets.replacement": &bson.M{
+				"$in": instIds,
+			},
+		})
+	}
+
+	if len(overlap) == 0 {
+		return
+	}
+
+	n, err := coll.CountDocuments(db, &bson.M{
+		"organization": orgId,
+		"state":        Running,
+		"$or":          overlap,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if n > 0 {
+		exists = true
+	}
+
+	return
+}
@@ -0,0 +1,165 @@
+package planner
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/balancer"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/plan"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vm"
+)
+
+var (
+	checkClient = &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				MinVersion:         tls.VersionTLS12,
+				MaxVersion:         tls.VersionTLS13,
+			},
+		},
+		Timeout: 5 * time.Second,
+	}
+)
+
+func checkUrl(proto, host string, port int, pth string) bool {
+	u := &url.URL{
+		Scheme: proto,
+		Host:   utils.FormatHostPort(host, port),
+		Path:   pth,
+	}
+
+	resp, err := checkClient.Get(u.String())
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func balancerHealthy(db *database.Database, balncId primitive.ObjectID,
+	inst *instance.Instance) (healthy bool, err error) {
+
+	balnc, err := balancer.Get(db, balncId)
+	if err != nil {
+		return
+	}
+
+	addrs := set.NewSet()
+	for _, addr := range inst.PrivateIps {
+		addrs.Add(addr)
+	}
+	for _, addr := range inst.PrivateIps6 {
+		addrs.Add(addr)
+	}
+	for _, addr := range inst.PublicIps {
+		addrs.Add(addr)
+	}
+	for _, addr := range inst.PublicIps6 {
+		addrs.Add(addr)
+	}
+	for _, addr := range inst.OraclePrivateIps {
+		addrs.Add(addr)
+	}
+	for _, addr := range inst.OraclePublicIps {
+		addrs.Add(addr)
+	}
+
+	if addrs.Len() == 0 {
+		return
+	}
+
+	keys := []string{}
+	for _, backend := range balnc.Backends {
+		if addrs.Contains(backend.Hostname) {
+			keys = append(keys, fmt.Sprintf(
+				"%s:%d", backend.Hostname, backend.Port))
+		}
+	}
+
+	if len(keys) > 0 {
+		online := set.NewSet()
+		for _, state := range balnc.States {
+			if state == nil || time.Since(state.Timestamp) > 1*time.Minute {
+				continue
+			}
+
+			for _, key := range state.Online {
+				online.Add(key)
+			}
+		}
+
+		for _, key := range keys {
+			if !online.Contains(key) {
+				return
+			}
+		}
+
+		healthy = true
+		return
+	}
+
+	// Instance is not yet a configured backend, check the balancer
+	// health path directly against the instance public address
+	if len(inst.PublicIps) == 0 {
+		return
+	}
+
+	checked := set.NewSet()
+	for _, backend := range balnc.Backends {
+		key := fmt.Sprintf("%s:%d", backend.Protocol, backend.Port)
+		if checked.Contains(key) {
+			continue
+		}
+		checked.Add(key)
+
+		if !checkUrl(backend.Protocol, inst.PublicIps[0], backend.Port,
+			balnc.CheckPath) {
+
+			return
+		}
+	}
+
+	healthy = checked.Len() > 0
+
+	return
+}
+
+func isHealthy(db *database.Database, pln *plan.Plan,
+	inst *instance.Instance, start time.Time) (healthy bool, err error) {
+
+	if inst.State != instance.Start || inst.VmState != vm.Running ||
+		inst.VmTimestamp.Before(start) {
+
+		return
+	}
+
+	switch pln.HealthCheck {
+	case plan.Guest:
+		healthy = inst.GuestStatus == vm.GuestOnline &&
+			inst.GuestTimestamp.After(inst.VmTimestamp) &&
+			time.Since(inst.GuestTimestamp) < 2*time.Minute
+		break
+	case plan.Balancer:
+		healthy, err = balancerHealthy(db, pln.Balancer, inst)
+		if err != nil {
+			return
+		}
+		break
+	default:
+		healthy = true
+	}
+
+	return
+}
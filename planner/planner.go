@@ -0,0 +1,347 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/plan"
+	"github.com/pritunl/pritunl-cloud/pod"
+	"github.com/sirupsen/logrus"
+)
+
+type Executor struct {
+	run     *plan.Run
+	pln     *plan.Plan
+	pd      *pod.Pod
+	changed bool
+}
+
+func (e *Executor) fail(db *database.Database, instId primitive.ObjectID,
+	message string) (err error) {
+
+	logrus.WithFields(logrus.Fields{
+		"run_id":      e.run.Id.Hex(),
+		"plan_id":     e.run.Plan.Hex(),
+		"instance_id": instId.Hex(),
+		"message":     message,
+	}).Warn("planner: Plan run failed, rolling back")
+
+	e.run.Step(plan.Failed, instId, message)
+
+	failed := false
+	partial := false
+	for _, target := range e.run.Targets {
+		switch target.State {
+		case plan.Replacing:
+			if e.run.Action == plan.Restart {
+				e.run.Step(plan.Reverted, target.Instance,
+					"Instance restart already applied, cannot be rolled back")
+				target.State = plan.Replaced
+				partial = true
+				break
+			}
+
+			if !target.Replacement.IsZero() {
+				e2 := instance.Delete(db, target.Replacement)
+				if e2 != nil {
+					if _, ok := e2.(*database.NotFoundError); !ok {
+						e.revertFailed(target, target.Replacement,
+							"Failed to remove replacement instance", e2)
+						failed = true
+						break
+					}
+				}
+				e.run.Step(plan.Reverted, target.Replacement,
+					"Removed replacement instance")
+			}
+
+			if !target.Surge {
+				e2 := instance.SetState(db, target.Instance, instance.Start)
+				if e2 != nil {
+					e.revertFailed(target, target.Instance,
+						"Failed to restart original instance", e2)
+					failed = true
+					break
+				}
+				e.run.Step(plan.Reverted, target.Instance,
+					"Restarted original instance")
+			}
+
+			target.State = plan.Reverted
+			break
+		case plan.Replaced:
+			e.run.Step(plan.Reverted, target.Instance,
+				"Instance already replaced, cannot be rolled back")
+			partial = true
+			break
+		}
+	}
+
+	if failed {
+		e.run.State = plan.Failed
+	} else if partial {
+		e.run.State = plan.PartialRollback
+	} else {
+		e.run.State = plan.RolledBack
+	}
+	e.run.Finished = time.Now()
+	e.changed = true
+
+	return
+}
+
+func (e *Executor) revertFailed(target *plan.Target,
+	instId primitive.ObjectID, message string, err error) {
+
+	logrus.WithFields(logrus.Fields{
+		"run_id":      e.run.Id.Hex(),
+		"instance_id": instId.Hex(),
+		"error":       err,
+	}).Error("planner: " + message)
+
+	e.run.Step(plan.Failed, instId,
+		fmt.Sprintf("%s: %s", message, err.Error()))
+	target.State = plan.Failed
+}
+
+func (e *Executor) start(db *database.Database, target *plan.Target,
+	surge bool) (err error) {
+
+	target.Start = time.Now()
+	target.Surge = surge
+
+	switch e.run.Action {
+	case plan.Replace:
+		if !surge {
+			err = instance.SetState(db, target.Instance, instance.Stop)
+			if err != nil {
+				return
+			}
+			e.run.Step(plan.Replacing, target.Instance,
+				"Stopped instance for replacement")
+
+			target.State = plan.Replacing
+			e.changed = true
+		}
+
+		inst, e2 := e.pd.CreateReplacement(db)
+		if e2 != nil {
+			err = e2
+			return
+		}
+
+		target.Replacement = inst.Id
+		e.run.Step(plan.Replacing, inst.Id,
+			fmt.Sprintf("Created replacement instance %s", inst.Name))
+		break
+	case plan.Restart:
+		err = instance.SetState(db, target.Instance, instance.Restart)
+		if err != nil {
+			return
+		}
+		e.run.Step(plan.Replacing, target.Instance, "Restarting instance")
+		break
+	}
+
+	target.State = plan.Replacing
+	e.changed = true
+
+	return
+}
+
+func (e *Executor) check(db *database.Database, target *plan.Target) (
+	failed bool, err error) {
+
+	instId := target.Instance
+	if e.run.Action == plan.Replace {
+		instId = target.Replacement
+	}
+
+	inst, err := instance.Get(db, instId)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = e.fail(db, instId, "Instance no longer exists")
+			failed = true
+		}
+		return
+	}
+
+	healthy, err := isHealthy(db, e.pln, inst, target.Start)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"run_id":      e.run.Id.Hex(),
+			"instance_id": instId.Hex(),
+			"error":       err,
+		}).Error("planner: Failed to check instance health")
+		err = nil
+		healthy = false
+	}
+
+	if !healthy {
+		timeout := time.Duration(e.pln.Timeout) * time.Second
+		if time.Since(target.Start) > timeout {
+			err = e.fail(db, instId, "Instance health check timed out")
+			failed = true
+		}
+		return
+	}
+
+	e.run.Step(plan.Replaced, instId, "Instance healthy")
+
+	if e.run.Action == plan.Replace {
+		err = instance.Delete(db, target.Instance)
+		if err != nil {
+			if _, ok := err.(*database.NotFoundError); ok {
+				err = nil
+			} else {
+				return
+			}
+		}
+		e.run.Step(plan.Replaced, target.Instance,
+			"Removed replaced instance")
+	}
+
+	target.State = plan.Replaced
+	e.changed = true
+
+	return
+}
+
+func (e *Executor) limits() (limit, surge int) {
+	if e.pln.Type == plan.Recreate {
+		limit = len(e.run.Targets)
+		return
+	}
+
+	if e.run.Action == plan.Replace {
+		surge = e.pln.MaxSurge
+	}
+	limit = surge + e.pln.MaxUnavailable
+	if limit < 1 {
+		limit = 1
+	}
+
+	return
+}
+
+func (e *Executor) Process(db *database.Database) (err error) {
+	inFlight := 0
+	surgeInFlight := 0
+
+	for _, target := range e.run.Targets {
+		if target.State != plan.Replacing {
+			continue
+		}
+
+		failed, e2 := e.check(db, target)
+		if e2 != nil {
+			err = e2
+			return
+		}
+		if failed {
+			return
+		}
+
+		if target.State == plan.Replacing {
+			inFlight += 1
+			if target.Surge {
+				surgeInFlight += 1
+			}
+		}
+	}
+
+	limit, surge := e.limits()
+
+	for _, target := range e.run.Targets {
+		if inFlight >= limit {
+			break
+		}
+
+		if target.State != plan.Pending {
+			continue
+		}
+
+		targetSurge := surgeInFlight < surge
+		err = e.start(db, target, targetSurge)
+		if err != nil {
+			err = e.fail(db, target.Instance, fmt.Sprintf(
+				"Failed to start instance replacement: %s", err.Error()))
+			return
+		}
+
+		inFlight += 1
+		if targetSurge {
+			surgeInFlight += 1
+		}
+	}
+
+	for _, target := range e.run.Targets {
+		if target.State != plan.Replaced {
+			return
+		}
+	}
+
+	e.run.State = plan.Completed
+	e.run.Finished = time.Now()
+	e.run.Step(plan.Completed, primitive.NilObjectID, "Plan run completed")
+	e.changed = true
+
+	return
+}
+
+func (e *Executor) Commit(db *database.Database) (err error) {
+	if !e.changed {
+		return
+	}
+
+	err = e.run.Commit(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func NewExecutor(db *database.Database, run *plan.Run) (
+	exec *Executor, err error) {
+
+	exec = &Executor{
+		run: run,
+	}
+
+	pln, err := plan.Get(db, run.Plan)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			run.State = plan.Failed
+			run.Finished = time.Now()
+			run.Step(plan.Failed, primitive.NilObjectID, "Plan not found")
+			exec.changed = true
+			exec.pln = nil
+		}
+		return
+	}
+	exec.pln = pln
+
+	if run.Action == plan.Replace {
+		pd, e := pod.Get(db, run.Pod)
+		if e != nil {
+			err = e
+			if _, ok := err.(*database.NotFoundError); ok {
+				err = nil
+				run.State = plan.Failed
+				run.Finished = time.Now()
+				run.Step(plan.Failed, primitive.NilObjectID,
+					"Pod not found")
+				exec.changed = true
+			}
+			return
+		}
+		exec.pd = pd
+	}
+
+	return
+}
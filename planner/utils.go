@@ -0,0 +1,198 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/plan"
+	"github.com/pritunl/pritunl-cloud/pod"
+	"github.com/sirupsen/logrus"
+)
+
+type instancesName []*instance.Instance
+
+func (n instancesName) Len() int {
+	return len(n)
+}
+
+func (n instancesName) Less(i, j int) bool {
+	return n[i].Name < n[j].Name
+}
+
+func (n instancesName) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+func newRun(pln *plan.Plan, insts []*instance.Instance) (run *plan.Run) {
+	sort.Sort(instancesName(insts))
+
+	run = &plan.Run{
+		Plan:         pln.Id,
+		Organization: pln.Organization,
+		Type:         pln.Type,
+		State:        plan.Running,
+		Timestamp:    time.Now(),
+		Targets:      []*plan.Target{},
+	}
+
+	for _, inst := range insts {
+		run.Targets = append(run.Targets, &plan.Target{
+			Instance: inst.Id,
+			State:    plan.Pending,
+		})
+	}
+
+	return
+}
+
+func runActive(db *database.Database, pln *plan.Plan,
+	podId primitive.ObjectID, role string, insts []*instance.Instance) (
+	errData *errortypes.ErrorData, err error) {
+
+	instIds := []primitive.ObjectID{}
+	for _, inst := range insts {
+		instIds = append(instIds, inst.Id)
+	}
+
+	exists, err := plan.RunningExists(db, pln.Organization, podId,
+		role, instIds)
+	if err != nil {
+		return
+	}
+
+	if exists {
+		errData = &errortypes.ErrorData{
+			Error:   "run_active",
+			Message: "Target already has an active plan run",
+		}
+		return
+	}
+
+	return
+}
+
+func StartPod(db *database.Database, pln *plan.Plan, pd *pod.Pod) (
+	run *plan.Run, errData *errortypes.ErrorData, err error) {
+
+	insts, err := instance.GetAll(db, &bson.M{
+		"pod": pd.Id,
+		"state": &bson.M{
+			"$ne": instance.Destroy,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	errData, err = runActive(db, pln, pd.Id, "", insts)
+	if err != nil || errData != nil {
+		return
+	}
+
+	run = newRun(pln, insts)
+	run.Pod = pd.Id
+	run.Action = plan.Replace
+	run.Hash = pd.SpecHash()
+	run.Step(plan.Running, primitive.NilObjectID,
+		fmt.Sprintf("Replacing %d pod instances", len(run.Targets)))
+
+	err = run.Insert(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func StartRole(db *database.Database, pln *plan.Plan, role string) (
+	run *plan.Run, errData *errortypes.ErrorData, err error) {
+
+	insts, err := instance.GetAll(db, &bson.M{
+		"organization":  pln.Organization,
+		"network_roles": role,
+		"state":         instance.Start,
+	})
+	if err != nil {
+		return
+	}
+
+	errData, err = runActive(db, pln, primitive.NilObjectID, role, insts)
+	if err != nil || errData != nil {
+		return
+	}
+
+	run = newRun(pln, insts)
+	run.Role = role
+	run.Action = plan.Restart
+	run.Step(plan.Running, primitive.NilObjectID,
+		fmt.Sprintf("Restarting %d instances with role %s",
+			len(run.Targets), role))
+
+	err = run.Insert(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func ExecuteAll(db *database.Database) (err error) {
+	runs, err := plan.GetRuns(db, &bson.M{
+		"state": plan.Running,
+	})
+	if err != nil {
+		return
+	}
+
+	changed := false
+
+	for _, run := range runs {
+		exec, e := NewExecutor(db, run)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"run_id":  run.Id.Hex(),
+				"plan_id": run.Plan.Hex(),
+				"error":   e,
+			}).Error("planner: Failed to load plan run")
+			continue
+		}
+
+		if run.Active() {
+			e = exec.Process(db)
+			if e != nil {
+				logrus.WithFields(logrus.Fields{
+					"run_id":  run.Id.Hex(),
+					"plan_id": run.Plan.Hex(),
+					"error":   e,
+				}).Error("planner: Failed to process plan run")
+			}
+		}
+
+		if exec.changed {
+			changed = true
+		}
+
+		e = exec.Commit(db)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"run_id":  run.Id.Hex(),
+				"plan_id": run.Plan.Hex(),
+				"error":   e,
+			}).Error("planner: Failed to commit plan run")
+		}
+	}
+
+	if changed {
+		event.PublishDispatch(db, "plan.change")
+		event.PublishDispatch(db, "instance.change")
+	}
+
+	return
+}
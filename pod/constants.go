@@ -0,0 +1,8 @@
+package pod
+
+const (
+	Instance = "instance"
+
+	SpecVersion = 1
+	MaxCount    = 64
+)
@@ -0,0 +1,576 @@
+package pod
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/plan"
+	"github.com/sirupsen/logrus"
+)
+
+type instancesName []*instance.Instance
+
+func (n instancesName) Len() int {
+	return len(n)
+}
+
+func (n instancesName) Less(i, j int) bool {
+	return n[i].Name < n[j].Name
+}
+
+func (n instancesName) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+func (s *Spec) Match(pd *Pod, inst *instance.Instance,
+	disks []*disk.Disk) bool {
+
+	if inst.Zone != pd.Zone || inst.Shape != s.ShapeId ||
+		inst.Image != s.ImageId || inst.InitDiskSize != s.RootSize() {
+
+		return false
+	}
+
+	extra := []*disk.Disk{}
+	for _, dsk := range disks {
+		if dsk.Index != "0" && dsk.State != disk.Destroy {
+			extra = append(extra, dsk)
+		}
+	}
+
+	if len(s.Disks) > 0 {
+		if len(extra) != len(s.Disks)-1 {
+			return false
+		}
+
+		for i, dsk := range extra {
+			if dsk.Size != s.Disks[i+1].Size {
+				return false
+			}
+		}
+	} else if len(extra) != 0 {
+		return false
+	}
+
+	return true
+}
+
+func (s *Spec) update(db *database.Database, inst *instance.Instance) (
+	changed bool, err error) {
+
+	inst.PreCommit()
+
+	fields := set.NewSet()
+
+	if inst.Vpc != s.VpcId {
+		inst.Vpc = s.VpcId
+		fields.Add("vpc")
+	}
+
+	if inst.Subnet != s.SubnetId {
+		inst.Subnet = s.SubnetId
+		fields.Add("subnet")
+	}
+
+	if inst.Processors != s.Processors {
+		inst.Processors = s.Processors
+		fields.Add("processors")
+	}
+
+	if inst.Memory != s.Memory {
+		inst.Memory = s.Memory
+		fields.Add("memory")
+	}
+
+	if !rolesEqual(inst.NetworkRoles, s.NetworkRoles) {
+		inst.NetworkRoles = s.NetworkRoles
+		fields.Add("network_roles")
+	}
+
+	cloudType := s.CloudType()
+	if cloudType != "" && inst.CloudType != cloudType {
+		inst.CloudType = cloudType
+		fields.Add("cloud_type")
+	}
+
+	if inst.CloudScript != s.CloudScript() {
+		inst.CloudScript = s.CloudScript()
+		fields.Add("cloud_script")
+	}
+
+	if fields.Len() == 0 {
+		return
+	}
+
+	errData, err := inst.Validate(db)
+	if err != nil {
+		return
+	}
+
+	if errData != nil {
+		err = &errortypes.ApiError{
+			errors.Newf(
+				"pod: Instance validate error %s",
+				errData.Message,
+			),
+		}
+		return
+	}
+
+	_, err = inst.PostCommit(db)
+	if err != nil {
+		return
+	}
+
+	err = inst.CommitFields(db, fields)
+	if err != nil {
+		return
+	}
+
+	changed = true
+	return
+}
+
+func (s *Spec) create(db *database.Database, pd *Pod, name string) (
+	inst *instance.Instance, err error) {
+
+	inst = &instance.Instance{
+		State:        instance.Start,
+		Organization: pd.Organization,
+		Zone:         pd.Zone,
+		Vpc:          s.VpcId,
+		Subnet:       s.SubnetId,
+		Shape:        s.ShapeId,
+		Image:        s.ImageId,
+		Pod:          pd.Id,
+		Name:         name,
+		Comment:      pd.Comment,
+		InitDiskSize: s.RootSize(),
+		Memory:       s.Memory,
+		Processors:   s.Processors,
+		NetworkRoles: s.NetworkRoles,
+		CloudType:    s.CloudType(),
+		CloudScript:  s.CloudScript(),
+	}
+
+	errData, err := inst.Validate(db)
+	if err != nil {
+		return
+	}
+
+	if errData != nil {
+		err = &errortypes.ApiError{
+			errors.Newf(
+				"pod: Instance validate error %s",
+				errData.Message,
+			),
+		}
+		return
+	}
+
+	err = inst.Insert(db)
+	if err != nil {
+		return
+	}
+
+	for i := 1; i < len(s.Disks); i++ {
+		dsk := &disk.Disk{
+			Name:         name + "-" + strconv.Itoa(i),
+			Organization: inst.Organization,
+			Instance:     inst.Id,
+			Index:        strconv.Itoa(i),
+			Type:         inst.DiskType,
+			Node:         inst.Node,
+			Pool:         inst.DiskPool,
+			Size:         s.Disks[i].Size,
+		}
+
+		errData, err = dsk.Validate(db)
+		if err != nil {
+			return
+		}
+
+		if errData != nil {
+			err = &errortypes.ApiError{
+				errors.Newf(
+					"pod: Disk validate error %s",
+					errData.Message,
+				),
+			}
+			return
+		}
+
+		err = dsk.Insert(db)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (p *Pod) nextName(spc *Spec, names set.Set) (name string) {
+
+	index := 0
+	for {
+		index += 1
+		name = spc.InstanceName(p.Name, index)
+		if !names.Contains(name) || index > MaxCount*2 {
+			break
+		}
+	}
+	names.Add(name)
+
+	return
+}
+
+func (p *Pod) CreateReplacement(db *database.Database) (
+	inst *instance.Instance, err error) {
+
+	spc, errData, err := p.GetSpec(db)
+	if err != nil {
+		return
+	}
+
+	if errData != nil {
+		err = &errortypes.ApiError{
+			errors.Newf(
+				"pod: Spec validate error %s",
+				errData.Message,
+			),
+		}
+		return
+	}
+
+	insts, err := instance.GetAll(db, &bson.M{
+		"pod": p.Id,
+	})
+	if err != nil {
+		return
+	}
+
+	names := set.NewSet()
+	for _, inst := range insts {
+		names.Add(inst.Name)
+	}
+
+	name := p.nextName(spc, names)
+
+	logrus.WithFields(logrus.Fields{
+		"pod_id":        p.Id.Hex(),
+		"instance_name": name,
+	}).Info("pod: Creating pod replacement instance")
+
+	inst, err = spc.create(db, p, name)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Pod) startRun(db *database.Database,
+	outdated []primitive.ObjectID) (started bool, err error) {
+
+	pln, err := plan.GetOrg(db, p.Organization, p.Plan)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+		}
+		return
+	}
+
+	hash := p.SpecHash()
+
+	lastRun, err := plan.GetPodRun(db, p.Id)
+	if err != nil {
+		return
+	}
+
+	if lastRun != nil && lastRun.Hash == hash &&
+		(lastRun.State == plan.RolledBack ||
+			lastRun.State == plan.PartialRollback ||
+			lastRun.State == plan.Failed) {
+
+		return
+	}
+
+	exists, err := plan.RunningExists(db, p.Organization, p.Id, "",
+		outdated)
+	if err != nil {
+		return
+	}
+
+	if exists {
+		return
+	}
+
+	run := &plan.Run{
+		Plan:         pln.Id,
+		Organization: p.Organization,
+		Pod:          p.Id,
+		Type:         pln.Type,
+		Action:       plan.Replace,
+		State:        plan.Running,
+		Hash:         hash,
+		Timestamp:    time.Now(),
+		Targets:      []*plan.Target{},
+	}
+
+	for _, instId := range outdated {
+		run.Targets = append(run.Targets, &plan.Target{
+			Instance: instId,
+			State:    plan.Pending,
+		})
+	}
+
+	run.Step(plan.Running, primitive.NilObjectID,
+		fmt.Sprintf("Pod spec changed, replacing %d instances",
+			len(outdated)))
+
+	logrus.WithFields(logrus.Fields{
+		"pod_id":    p.Id.Hex(),
+		"plan_id":   pln.Id.Hex(),
+		"instances": len(outdated),
+	}).Info("pod: Starting pod plan run")
+
+	err = run.Insert(db)
+	if err != nil {
+		return
+	}
+
+	started = true
+
+	return
+}
+
+func (p *Pod) Deploy(db *database.Database) (changed bool, err error) {
+	spc, errData, err := p.GetSpec(db)
+	if err != nil {
+		return
+	}
+
+	if errData != nil {
+		err = &errortypes.ApiError{
+			errors.Newf(
+				"pod: Spec validate error %s",
+				errData.Message,
+			),
+		}
+		return
+	}
+
+	insts, err := instance.GetAll(db, &bson.M{
+		"pod": p.Id,
+	})
+	if err != nil {
+		return
+	}
+
+	sort.Sort(instancesName(insts))
+
+	run, err := plan.GetPodRun(db, p.Id)
+	if err != nil {
+		return
+	}
+	if run != nil && !run.Active() {
+		run = nil
+	}
+
+	names := set.NewSet()
+	current := []*instance.Instance{}
+	outdated := []primitive.ObjectID{}
+	pending := 0
+
+	for _, inst := range insts {
+		if inst.State == instance.Destroy {
+			continue
+		}
+		names.Add(inst.Name)
+
+		if run != nil && run.HasTarget(inst.Id) {
+			pending += 1
+			continue
+		}
+
+		disks, e := disk.GetInstance(db, inst.Id)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if !spc.Match(p, inst, disks) {
+			outdated = append(outdated, inst.Id)
+			continue
+		}
+
+		current = append(current, inst)
+	}
+
+	sort.Sort(instancesName(current))
+
+	excess := []primitive.ObjectID{}
+	if len(current) > spc.Count {
+		for _, inst := range current[spc.Count:] {
+			excess = append(excess, inst.Id)
+		}
+		current = current[:spc.Count]
+	}
+
+	for _, inst := range current {
+		updated, e := spc.update(db, inst)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if updated {
+			changed = true
+		}
+	}
+
+	if len(outdated) > 0 && !p.Plan.IsZero() {
+		if run == nil {
+			started, e := p.startRun(db, outdated)
+			if e != nil {
+				err = e
+				return
+			}
+
+			if started {
+				changed = true
+			}
+		}
+
+		pending += len(outdated)
+	} else {
+		excess = append(excess, outdated...)
+	}
+
+	if len(excess) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"pod_id":    p.Id.Hex(),
+			"instances": len(excess),
+		}).Info("pod: Removing pod instances")
+
+		err = instance.DeleteMulti(db, excess)
+		if err != nil {
+			return
+		}
+
+		changed = true
+	}
+
+	for n := len(current) + pending; n < spc.Count; n++ {
+		name := p.nextName(spc, names)
+
+		logrus.WithFields(logrus.Fields{
+			"pod_id":        p.Id.Hex(),
+			"instance_name": name,
+		}).Info("pod: Creating pod instance")
+
+		_, err = spc.create(db, p, name)
+		if err != nil {
+			return
+		}
+
+		changed = true
+	}
+
+	return
+}
+
+func DeployAll(db *database.Database) (err error) {
+	pods, err := GetAll(db, &bson.M{})
+	if err != nil {
+		return
+	}
+
+	podIds := []primitive.ObjectID{}
+	changed := false
+
+	for _, pd := range pods {
+		podIds = append(podIds, pd.Id)
+
+		podChanged, e := pd.Deploy(db)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"pod_id":   pd.Id.Hex(),
+				"pod_name": pd.Name,
+				"error":    e,
+			}).Error("pod: Failed to deploy pod")
+		}
+
+		if podChanged {
+			changed = true
+		}
+	}
+
+	orphans, err := instance.GetAll(db, &bson.M{
+		"pod": &bson.M{
+			"$exists": true,
+			"$nin":    podIds,
+		},
+		"state": &bson.M{
+			"$ne": instance.Destroy,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	if len(orphans) > 0 {
+		orphanIds := []primitive.ObjectID{}
+		for _, inst := range orphans {
+			orphanIds = append(orphanIds, inst.Id)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"instances": len(orphanIds),
+		}).Info("pod: Removing instances from deleted pods")
+
+		err = instance.DeleteMulti(db, orphanIds)
+		if err != nil {
+			return
+		}
+
+		changed = true
+	}
+
+	if changed {
+		event.PublishDispatch(db, "instance.change")
+		event.PublishDispatch(db, "disk.change")
+	}
+
+	return
+}
+
+func rolesEqual(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+
+	xSet := set.NewSet()
+	for _, role := range x {
+		xSet.Add(role)
+	}
+
+	for _, role := range y {
+		if !xSet.Contains(role) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,33 +1,55 @@
 package pod
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/plan"
 )
 
 type Pod struct {
 	Id               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name             string             `bson:"name" json:"name"`
 	Comment          string             `bson:"comment" json:"comment"`
-	Organization     primitive.ObjectID `json:"organization"`
+	Organization     primitive.ObjectID `bson:"organization" json:"organization"`
 	Type             string             `bson:"type" json:"type"`
 	DeleteProtection bool               `bson:"delete_protection" json:"delete_protection"`
 	Zone             primitive.ObjectID `bson:"zone" json:"zone"`
 	Roles            []string           `bson:"roles" json:"roles"`
+	Plan             primitive.ObjectID `bson:"plan,omitempty" json:"plan"`
 	Spec             string             `bson:"spec" json:"spec"`
 }
 
 func (p *Pod) Validate(db *database.Database) (
 	errData *errortypes.ErrorData, err error) {
 
+	if p.Organization.IsZero() {
+		errData = &errortypes.ErrorData{
+			Error:   "organization_required",
+			Message: "Missing required organization",
+		}
+		return
+	}
+
+	if p.Zone.IsZero() {
+		errData = &errortypes.ErrorData{
+			Error:   "zone_required",
+			Message: "Missing required zone",
+		}
+		return
+	}
+
 	if p.Type == "" {
-		p.Type = Todo
+		p.Type = Instance
 	}
 
 	switch p.Type {
-	case Todo:
+	case Instance:
 		break
 	default:
 		errData = &errortypes.ErrorData{
@@ -37,6 +59,63 @@ func (p *Pod) Validate(db *database.Database) (
 		return
 	}
 
+	if p.Roles == nil {
+		p.Roles = []string{}
+	}
+
+	if !p.Plan.IsZero() {
+		exists, e := plan.ExistsOrg(db, p.Organization, p.Plan)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if !exists {
+			errData = &errortypes.ErrorData{
+				Error:   "plan_invalid",
+				Message: "Pod plan does not exist",
+			}
+			return
+		}
+	}
+
+	_, errData, err = p.GetSpec(db)
+	if err != nil {
+		if _, ok := err.(*errortypes.ParseError); ok {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "spec_invalid",
+				Message: "Pod spec could not be parsed",
+			}
+		}
+		return
+	}
+
+	return
+}
+
+func (p *Pod) SpecHash() string {
+	hash := sha256.Sum256([]byte(p.Spec))
+	return hex.EncodeToString(hash[:])
+}
+
+func (p *Pod) GetSpec(db *database.Database) (spc *Spec,
+	errData *errortypes.ErrorData, err error) {
+
+	spc, err = ParseSpec(p.Spec)
+	if err != nil {
+		return
+	}
+
+	if strings.TrimSpace(p.Spec) == "" {
+		return
+	}
+
+	errData, err = spc.Validate(db, p)
+	if err != nil || errData != nil {
+		return
+	}
+
 	return
 }
 
@@ -67,11 +146,13 @@ func (p *Pod) CommitFields(db *database.Database, fields set.Set) (
 func (p *Pod) Insert(db *database.Database) (err error) {
 	coll := db.Pods()
 
-	_, err = coll.InsertOne(db, p)
+	resp, err := coll.InsertOne(db, p)
 	if err != nil {
 		err = database.ParseError(err)
 		return
 	}
 
+	p.Id = resp.InsertedID.(primitive.ObjectID)
+
 	return
 }
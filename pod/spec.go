@@ -0,0 +1,346 @@
+package pod
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/image"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/shape"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vpc"
+	"github.com/pritunl/pritunl-cloud/zone"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	scriptReg   = regexp.MustCompile("^#!")
+	nameVerbReg = regexp.MustCompile("%[-+# 0]*[0-9]*[dxXob]")
+)
+
+type Spec struct {
+	Version      int            `yaml:"version"`
+	Count        int            `yaml:"count"`
+	Name         string         `yaml:"name"`
+	Shape        string         `yaml:"shape"`
+	Image        string         `yaml:"image"`
+	Vpc          string         `yaml:"vpc"`
+	Subnet       string         `yaml:"subnet"`
+	Processors   int            `yaml:"processors"`
+	Memory       int            `yaml:"memory"`
+	Disks        []*SpecDisk    `yaml:"disks"`
+	NetworkRoles []string       `yaml:"network_roles"`
+	CloudInit    *SpecCloudInit `yaml:"cloud_init"`
+
+	ShapeId  primitive.ObjectID `yaml:"-"`
+	ImageId  primitive.ObjectID `yaml:"-"`
+	VpcId    primitive.ObjectID `yaml:"-"`
+	SubnetId primitive.ObjectID `yaml:"-"`
+}
+
+type SpecDisk struct {
+	Size int `yaml:"size"`
+}
+
+type SpecCloudInit struct {
+	Type   string `yaml:"type"`
+	Script string `yaml:"script"`
+}
+
+func (s *Spec) RootSize() int {
+	if len(s.Disks) == 0 {
+		return 0
+	}
+	return s.Disks[0].Size
+}
+
+func (s *Spec) CloudType() string {
+	if s.CloudInit == nil {
+		return ""
+	}
+	return s.CloudInit.Type
+}
+
+func (s *Spec) CloudScript() string {
+	if s.CloudInit == nil {
+		return ""
+	}
+	return s.CloudInit.Script
+}
+
+func (s *Spec) nameFormatted() bool {
+	return strings.Contains(strings.ReplaceAll(s.Name, "%%", ""), "%")
+}
+
+func (s *Spec) InstanceName(podName string, index int) string {
+	if s.Name == "" {
+		return fmt.Sprintf("%s-%d", podName, index)
+	}
+
+	if s.nameFormatted() {
+		return fmt.Sprintf(s.Name, index)
+	}
+
+	name := strings.ReplaceAll(s.Name, "%%", "%")
+	if s.Count > 1 || index > 1 {
+		return fmt.Sprintf("%s-%d", name, index)
+	}
+
+	return name
+}
+
+func (s *Spec) Validate(db *database.Database, pd *Pod) (
+	errData *errortypes.ErrorData, err error) {
+
+	if s.Version == 0 {
+		s.Version = SpecVersion
+	}
+
+	if s.Version != SpecVersion {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_version_invalid",
+			Message: "Pod spec version not supported",
+		}
+		return
+	}
+
+	if s.Count < 0 || s.Count > MaxCount {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_count_invalid",
+			Message: "Pod spec instance count out of range",
+		}
+		return
+	}
+
+	if s.nameFormatted() {
+		name := strings.ReplaceAll(s.Name, "%%", "")
+		if strings.Count(name, "%") != 1 ||
+			len(nameVerbReg.FindAllString(name, -1)) != 1 {
+
+			errData = &errortypes.ErrorData{
+				Error:   "spec_name_invalid",
+				Message: "Pod spec name must contain one integer verb",
+			}
+			return
+		}
+	}
+
+	zne, err := zone.Get(db, pd.Zone)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "zone_invalid",
+				Message: "Pod zone does not exist",
+			}
+		}
+		return
+	}
+
+	shapeId, ok := utils.ParseObjectId(s.Shape)
+	if !ok {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_shape_required",
+			Message: "Pod spec missing required shape",
+		}
+		return
+	}
+
+	shpe, err := shape.Get(db, shapeId)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "spec_shape_invalid",
+				Message: "Pod spec shape does not exist",
+			}
+		}
+		return
+	}
+
+	if shpe.Zone != zne.Id {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_shape_zone_invalid",
+			Message: "Pod spec shape is not in pod zone",
+		}
+		return
+	}
+	s.ShapeId = shpe.Id
+
+	if shpe.Flexible {
+		if s.Processors < 1 || s.Memory < 256 {
+			errData = &errortypes.ErrorData{
+				Error: "spec_resources_required",
+				Message: "Pod spec flexible shape requires processors " +
+					"and memory",
+			}
+			return
+		}
+	} else {
+		s.Processors = shpe.Processors
+		s.Memory = shpe.Memory
+	}
+
+	if s.Image != "" {
+		imageId, ok := utils.ParseObjectId(s.Image)
+		if !ok {
+			errData = &errortypes.ErrorData{
+				Error:   "spec_image_invalid",
+				Message: "Pod spec image invalid",
+			}
+			return
+		}
+
+		img, e := image.GetOrgPublic(db, pd.Organization, imageId)
+		if e != nil {
+			err = e
+			if _, ok := err.(*database.NotFoundError); ok {
+				err = nil
+				errData = &errortypes.ErrorData{
+					Error:   "spec_image_invalid",
+					Message: "Pod spec image does not exist",
+				}
+			}
+			return
+		}
+		s.ImageId = img.Id
+	} else {
+		s.ImageId = primitive.NilObjectID
+	}
+
+	vpcId, ok := utils.ParseObjectId(s.Vpc)
+	if !ok {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_vpc_required",
+			Message: "Pod spec missing required VPC",
+		}
+		return
+	}
+
+	vc, err := vpc.GetOrg(db, pd.Organization, vpcId)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "spec_vpc_invalid",
+				Message: "Pod spec VPC does not exist",
+			}
+		}
+		return
+	}
+
+	if vc.Datacenter != zne.Datacenter {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_vpc_datacenter_invalid",
+			Message: "Pod spec VPC is not in pod datacenter",
+		}
+		return
+	}
+	s.VpcId = vc.Id
+
+	subnetId, ok := utils.ParseObjectId(s.Subnet)
+	if !ok {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_subnet_required",
+			Message: "Pod spec missing required VPC subnet",
+		}
+		return
+	}
+
+	if vc.GetSubnet(subnetId) == nil {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_subnet_invalid",
+			Message: "Pod spec VPC subnet does not exist",
+		}
+		return
+	}
+	s.SubnetId = subnetId
+
+	if s.Disks == nil {
+		s.Disks = []*SpecDisk{}
+	}
+
+	if len(s.Disks) > 10 {
+		errData = &errortypes.ErrorData{
+			Error:   "spec_disks_invalid",
+			Message: "Pod spec has too many disks",
+		}
+		return
+	}
+
+	for _, dsk := range s.Disks {
+		if dsk == nil || dsk.Size < 10 {
+			errData = &errortypes.ErrorData{
+				Error:   "spec_disk_size_invalid",
+				Message: "Pod spec disk size below minimum",
+			}
+			return
+		}
+	}
+
+	roles := []string{}
+	rolesSet := set.NewSet()
+	for _, role := range s.NetworkRoles {
+		role = strings.TrimSpace(role)
+		if role == "" || rolesSet.Contains(role) {
+			continue
+		}
+		rolesSet.Add(role)
+		roles = append(roles, role)
+	}
+	s.NetworkRoles = roles
+
+	if s.CloudInit != nil {
+		if s.CloudInit.Type == "" {
+			s.CloudInit.Type = instance.Linux
+		}
+
+		if !instance.ValidCloudTypes.Contains(s.CloudInit.Type) {
+			errData = &errortypes.ErrorData{
+				Error:   "spec_cloud_type_invalid",
+				Message: "Pod spec cloud init type invalid",
+			}
+			return
+		}
+
+		if s.CloudInit.Script != "" &&
+			!scriptReg.MatchString(s.CloudInit.Script) {
+
+			errData = &errortypes.ErrorData{
+				Error: "spec_cloud_script_invalid",
+				Message: "Pod spec startup script missing shebang " +
+					"on first line",
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func ParseSpec(data string) (spc *Spec, err error) {
+	spc = &Spec{}
+
+	if strings.TrimSpace(data) == "" {
+		return
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(spc)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "pod: Failed to parse spec"),
+		}
+		return
+	}
+
+	return
+}
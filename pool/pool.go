@@ -1,6 +1,8 @@
 package pool
 
 import (
+	"time"
+
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
@@ -15,6 +17,17 @@ type Pool struct {
 	Zone             primitive.ObjectID `bson:"zone" json:"zone"`
 	Type             string             `bson:"type" json:"type"`
 	VgName           string             `bson:"vg_name" json:"vg_name"`
+	Size             int64              `bson:"size" json:"size"`
+	Free             int64              `bson:"free" json:"free"`
+	Timestamp        time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+func (p *Pool) FreePercent() float64 {
+	if p.Size == 0 {
+		return 0
+	}
+
+	return float64(p.Free) / float64(p.Size) * 100
 }
 
 func (p *Pool) Validate(db *database.Database) (
@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/balancer"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/sirupsen/logrus"
+)
+
+type requestInfoKey struct{}
+
+// requestInfo holds the backend and start time of the latest attempt to
+// serve a request.
+type requestInfo struct {
+	backend string
+	start   time.Time
+}
+
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	info := &requestInfo{}
+	return r.WithContext(
+		context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+func getRequestInfo(r *http.Request) *requestInfo {
+	info, _ := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(data []byte) (n int, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err = w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return
+}
+
+func (w *accessWriter) Flush() {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		flusher.Flush()
+	}
+}
+
+func (w *accessWriter) Hijack() (conn net.Conn, rw *bufio.ReadWriter,
+	err error) {
+
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		err = &errortypes.RequestError{
+			errors.New("proxy: Response writer does not support hijack"),
+		}
+		return
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return hijacker.Hijack()
+}
+
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (p *Proxy) accessLog(domain *Domain, r *http.Request,
+	writer *accessWriter, info *requestInfo, start time.Time) {
+
+	status := writer.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	entry := &balancer.AccessLog{
+		Balancer:  domain.Balancer.Id,
+		Node:      node.Self.Id,
+		Timestamp: start,
+		Client:    node.Self.GetRemoteAddr(r),
+		Method:    r.Method,
+		Host:      r.Host,
+		Path:      r.URL.Path,
+		Status:    status,
+		Bytes:     writer.bytes,
+		Backend:   info.backend,
+		Latency:   float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	switch domain.Balancer.AccessLog {
+	case balancer.AccessLogDatabase:
+		select {
+		case p.accessLogs <- entry:
+		default:
+		}
+		break
+	case balancer.AccessLogLogger:
+		logrus.WithFields(logrus.Fields{
+			"balancer_id":   entry.Balancer.Hex(),
+			"balancer_name": domain.Balancer.Name,
+			"client":        entry.Client,
+			"method":        entry.Method,
+			"host":          entry.Host,
+			"path":          entry.Path,
+			"status":        entry.Status,
+			"bytes":         entry.Bytes,
+			"backend":       entry.Backend,
+			"latency":       entry.Latency,
+		}).Info("proxy: Balancer access")
+		break
+	}
+}
+
+func (p *Proxy) syncAccessLogs() (err error) {
+	logs := []*balancer.AccessLog{}
+
+	for len(logs) < 1000 {
+		select {
+		case entry := <-p.accessLogs:
+			logs = append(logs, entry)
+			continue
+		default:
+		}
+		break
+	}
+
+	if len(logs) == 0 {
+		return
+	}
+
+	db := database.GetDatabase()
+	defer db.Close()
+
+	err = balancer.InsertAccessLogs(db, logs)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Proxy) runAccessLogs() {
+	for {
+		time.Sleep(1 * time.Second)
+
+		err := p.syncAccessLogs()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("proxy: Failed to write balancer access logs")
+		}
+	}
+}
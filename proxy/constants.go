@@ -7,3 +7,7 @@ const (
 	UnknownLow  = 2
 	Offline     = 1
 )
+
+const (
+	affinityCookie = "pritunl-cloud-affinity"
+)
@@ -1,11 +1,17 @@
 package proxy
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/tls"
+	"io"
+	"io/ioutil"
 	"math/rand"
+	"net"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,6 +19,9 @@ import (
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/pritunl-cloud/authority"
 	"github.com/pritunl/pritunl-cloud/balancer"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -27,7 +36,6 @@ var (
 	}
 	checkClient = &http.Client{
 		Transport: checkTransport,
-		Timeout:   5 * time.Second,
 	}
 )
 
@@ -39,6 +47,9 @@ type Domain struct {
 	Retries           *int32
 	RetriesPrev       [5]int
 	RetriesTotal      int
+	Rejected          *int32
+	RejectedPrev      [5]int
+	RejectedTotal     int
 	Lock              sync.Mutex
 	ProxyProto        string
 	ProxyPort         int
@@ -47,6 +58,15 @@ type Domain struct {
 	Domain            *balancer.Domain
 	ClientAuthority   *authority.Authority
 	ClientCertificate *tls.Certificate
+	DefaultGroup      bool
+	HealthConfig      *HealthConfig
+	Drains            []*DrainState
+	Metrics           map[string]*Metrics
+	Limiter           *Limiter
+	AllowSources      []*net.IPNet
+	DenySources       []*net.IPNet
+	CheckBody         *regexp.Regexp
+	LastCheck         time.Time
 
 	OnlineWebFirst      []*Handler
 	UnknownHighWebFirst []*Handler
@@ -80,10 +100,40 @@ func (d *Domain) CalculateHash() {
 	h.Write([]byte(d.Balancer.Id.Hex()))
 	h.Write([]byte(d.Balancer.Name))
 	h.Write([]byte(d.Balancer.CheckPath))
+	h.Write([]byte(strconv.Itoa(d.Balancer.CheckInterval)))
+	h.Write([]byte(strconv.Itoa(d.Balancer.CheckTimeout)))
+	for _, code := range d.Balancer.CheckStatusCodes {
+		h.Write([]byte(strconv.Itoa(code)))
+	}
+	h.Write([]byte(d.Balancer.CheckBody))
+	h.Write([]byte(strconv.Itoa(d.Balancer.HealthyThreshold)))
+	h.Write([]byte(strconv.Itoa(d.Balancer.UnhealthyThreshold)))
+	h.Write([]byte(strconv.Itoa(d.Balancer.OutlierErrors)))
+	h.Write([]byte(strconv.Itoa(d.Balancer.OutlierTime)))
 	h.Write([]byte(strconv.FormatBool(d.Balancer.WebSockets)))
+	h.Write([]byte(d.Balancer.Affinity))
+	for _, source := range d.Balancer.AllowSources {
+		h.Write([]byte(source))
+	}
+	h.Write([]byte{0})
+	for _, source := range d.Balancer.DenySources {
+		h.Write([]byte(source))
+	}
+	h.Write([]byte(strconv.Itoa(d.Balancer.RateLimit)))
+	h.Write([]byte(strconv.Itoa(d.Balancer.RateBurst)))
+	h.Write([]byte(strconv.FormatInt(d.Balancer.MaxBodyBytes, 10)))
+	h.Write([]byte(strconv.Itoa(d.Balancer.MaxHeaderBytes)))
 	h.Write([]byte(d.Domain.Domain))
 	h.Write([]byte(d.Domain.Host))
 
+	for _, rule := range d.Domain.Rules {
+		h.Write([]byte(rule.Path))
+		h.Write([]byte(rule.Method))
+		h.Write([]byte(rule.Header))
+		h.Write([]byte(rule.Value))
+		h.Write([]byte(rule.Group))
+	}
+
 	if !d.Balancer.ClientAuthority.IsZero() {
 		h.Write([]byte(d.Balancer.ClientAuthority.Hex()))
 	}
@@ -91,6 +141,8 @@ func (d *Domain) CalculateHash() {
 		h.Write([]byte(backend.Protocol))
 		h.Write([]byte(backend.Hostname))
 		h.Write([]byte(strconv.Itoa(backend.Port)))
+		h.Write([]byte(backend.Group))
+		h.Write([]byte(strconv.Itoa(backend.Weight)))
 	}
 
 	d.Hash = h.Sum(nil)
@@ -128,19 +180,73 @@ func (d *Domain) Init() {
 	unknownHighWebSecond := []*Handler{}
 	unknownHighWebThird := []*Handler{}
 
+	d.HealthConfig = NewHealthConfig(d.Balancer)
+	d.CheckBody = nil
+	if d.Balancer.CheckBody != "" {
+		checkBody, err := regexp.Compile(d.Balancer.CheckBody)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"balancer_id":   d.Balancer.Id.Hex(),
+				"balancer_name": d.Balancer.Name,
+				"check_body":    d.Balancer.CheckBody,
+				"error":         err,
+			}).Error("proxy: Invalid balancer health check body")
+		} else {
+			d.CheckBody = checkBody
+		}
+	}
+
+	d.AllowSources = parseSources(d.Balancer.AllowSources)
+	d.DenySources = parseSources(d.Balancer.DenySources)
+
+	drainTimeout := getDrainTimeout(d.Balancer)
+
+	metrics := map[string]*Metrics{}
+
+	d.Drains = []*DrainState{}
+	d.DefaultGroup = false
 	for i, backend := range d.Balancer.Backends {
+		if backend.Group == "" {
+			d.DefaultGroup = true
+		}
+
+		health := &Health{}
+		drain := &DrainState{}
+		drain.Update(backend.Drain, backend.DrainStart, drainTimeout)
+		d.Drains = append(d.Drains, drain)
+
+		backendKey := backend.Key()
+		backendMetrics := metrics[backendKey]
+		if backendMetrics == nil {
+			backendMetrics = d.Metrics[backendKey]
+			if backendMetrics == nil {
+				backendMetrics = NewMetrics()
+			}
+			metrics[backendKey] = backendMetrics
+		}
+
 		hand := NewHandler(i, UnknownHigh, d.ProxyProto, d.ProxyPort, d,
 			backend, d.ResponseHandler, d.ErrorHandlerFirst)
+		hand.Health = health
+		hand.Drain = drain
+		hand.Metrics = backendMetrics
 		unknownHighWebFirst = append(unknownHighWebFirst, hand)
 
 		hand = NewHandler(i, UnknownHigh, d.ProxyProto, d.ProxyPort, d,
 			backend, d.ResponseHandler, d.ErrorHandlerSecond)
+		hand.Health = health
+		hand.Drain = drain
+		hand.Metrics = backendMetrics
 		unknownHighWebSecond = append(unknownHighWebSecond, hand)
 
 		hand = NewHandler(i, UnknownHigh, d.ProxyProto, d.ProxyPort, d,
 			backend, d.ResponseHandler, d.ErrorHandlerThird)
+		hand.Health = health
+		hand.Drain = drain
+		hand.Metrics = backendMetrics
 		unknownHighWebThird = append(unknownHighWebThird, hand)
 	}
+	d.Metrics = metrics
 
 	d.OnlineWebFirst = []*Handler{}
 	d.UnknownHighWebFirst = unknownHighWebFirst
@@ -163,146 +269,402 @@ func (d *Domain) Init() {
 	d.WebSocketConns = set.NewSet()
 }
 
-func (d *Domain) ServeHTTPFirst(rw http.ResponseWriter, r *http.Request) {
-	atomic.AddInt32(d.Requests, 1)
+// route returns the backend group for the request from the first matching
+// domain rule. Requests that match no rule are sent to the default group, or
+// to all backends when no backend is in the default group.
+func (d *Domain) route(r *http.Request) (group string, all bool) {
+	for _, rule := range d.Domain.Rules {
+		if rule.Path != "" && !strings.HasPrefix(r.URL.Path, rule.Path) {
+			continue
+		}
+
+		if rule.Method != "" && r.Method != rule.Method {
+			continue
+		}
+
+		if rule.Header != "" {
+			vals, ok := r.Header[rule.Header]
+			if !ok {
+				continue
+			}
 
-	onlineWebFirst := d.OnlineWebFirst
-	l := len(onlineWebFirst)
-	if l != 0 {
-		onlineWebFirst[rand.Intn(l)].Serve(rw, r)
+			if rule.Value != "" {
+				matched := false
+				for _, val := range vals {
+					if val == rule.Value {
+						matched = true
+						break
+					}
+				}
+
+				if !matched {
+					continue
+				}
+			}
+		}
+
+		group = rule.Group
 		return
 	}
 
-	unknownHighWebFirst := d.UnknownHighWebFirst
-	l = len(unknownHighWebFirst)
-	if l != 0 {
-		unknownHighWebFirst[rand.Intn(l)].Serve(rw, r)
-		return
+	all = !d.DefaultGroup
+	return
+}
+
+// selectHandler picks a weighted random handler in the group from the
+// tier of handlers, draining handlers do not receive new sessions.
+func selectHandler(hands []*Handler, group string, all bool) (
+	hand *Handler) {
+
+	total := 0
+	for _, h := range hands {
+		if (all || h.Group == group) && !h.Drain.Draining() {
+			total += h.Weight
+		}
 	}
 
-	unknownMidWebFirst := d.UnknownMidWebFirst
-	l = len(unknownMidWebFirst)
-	if l != 0 {
-		unknownMidWebFirst[rand.Intn(l)].Serve(rw, r)
+	if total == 0 {
 		return
 	}
 
-	unknownLowWebFirst := d.UnknownLowWebFirst
-	l = len(unknownLowWebFirst)
-	if l != 0 {
-		unknownLowWebFirst[rand.Intn(l)].Serve(rw, r)
-		return
+	n := rand.Intn(total)
+	for _, h := range hands {
+		if (all || h.Group == group) && !h.Drain.Draining() {
+			n -= h.Weight
+			if n < 0 {
+				hand = h
+				return
+			}
+		}
 	}
 
-	offlineWebFirst := d.OfflineWebFirst
-	l = len(offlineWebFirst)
-	if l != 0 {
-		offlineWebFirst[rand.Intn(l)].Serve(rw, r)
-		return
+	return
+}
+
+// affinityHandler picks the handler in the group with the highest weighted
+// rendezvous score for the client address.
+func affinityHandler(hands []*Handler, group string, all bool,
+	client string) (hand *Handler) {
+
+	bestScore := 0.0
+	for _, h := range hands {
+		if (!all && h.Group != group) || h.Drain.Draining() {
+			continue
+		}
+
+		score := affinityScore(client, h.Key, h.Weight)
+		if hand == nil || score > bestScore {
+			hand = h
+			bestScore = score
+		}
 	}
 
-	rw.WriteHeader(http.StatusBadGateway)
+	return
 }
 
-func (d *Domain) ServeHTTPSecond(rw http.ResponseWriter, r *http.Request) {
-	atomic.AddInt32(d.Retries, 1)
+// cookieHandler returns the handler of an existing cookie session if it is
+// not offline. Draining handlers continue to serve existing sessions until
+// the drain timeout is reached.
+func cookieHandler(tiers [][]*Handler, group string, all bool,
+	affinityId string) (hand *Handler) {
 
-	onlineWebSecond := d.OnlineWebSecond
-	l := len(onlineWebSecond)
-	if l != 0 {
-		onlineWebSecond[rand.Intn(l)].Serve(rw, r)
-		return
+	for _, hands := range tiers {
+		for _, h := range hands {
+			if h.AffinityId != affinityId || h.State == Offline ||
+				(!all && h.Group != group) || h.Drain.Expired() {
+
+				continue
+			}
+
+			hand = h
+			return
+		}
 	}
 
-	unknownHighWebSecond := d.UnknownHighWebSecond
-	l = len(unknownHighWebSecond)
-	if l != 0 {
-		unknownHighWebSecond[rand.Intn(l)].Serve(rw, r)
-		return
+	return
+}
+
+func (d *Domain) serve(rw http.ResponseWriter, r *http.Request,
+	retry bool, tiers [][]*Handler) {
+
+	group, all := d.route(r)
+	affinity := d.Balancer.Affinity
+
+	var hand *Handler
+	affinityId := ""
+
+	if affinity == balancer.AffinityCookie {
+		cookie, err := r.Cookie(affinityCookie)
+		if err == nil {
+			affinityId = cookie.Value
+		}
+
+		if !retry && affinityId != "" {
+			hand = cookieHandler(tiers, group, all, affinityId)
+		}
 	}
 
-	unknownMidWebSecond := d.UnknownMidWebSecond
-	l = len(unknownMidWebSecond)
-	if l != 0 {
-		unknownMidWebSecond[rand.Intn(l)].Serve(rw, r)
-		return
+	if hand == nil {
+		client := ""
+		if affinity == balancer.AffinitySourceIp {
+			client = node.Self.GetRemoteAddr(r)
+		}
+
+		for _, hands := range tiers {
+			if client != "" {
+				hand = affinityHandler(hands, group, all, client)
+			} else {
+				hand = selectHandler(hands, group, all)
+			}
+
+			if hand != nil {
+				break
+			}
+		}
 	}
 
-	unknownLowWebSecond := d.UnknownLowWebSecond
-	l = len(unknownLowWebSecond)
-	if l != 0 {
-		unknownLowWebSecond[rand.Intn(l)].Serve(rw, r)
+	if hand == nil {
+		rw.WriteHeader(http.StatusBadGateway)
 		return
 	}
 
-	offlineWebSecond := d.OfflineWebSecond
-	l = len(offlineWebSecond)
-	if l != 0 {
-		offlineWebSecond[rand.Intn(l)].Serve(rw, r)
+	if affinity == balancer.AffinityCookie && hand.AffinityId != affinityId {
+		cookie := &http.Cookie{
+			Name:     affinityCookie,
+			Value:    hand.AffinityId,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   d.ProxyProto == "https",
+			SameSite: http.SameSiteLaxMode,
+		}
+		rw.Header().Set("Set-Cookie", cookie.String())
+	}
+
+	hand.Serve(rw, r)
+}
+
+// UpdateDrain updates the drain state of the backends in place, the domain
+// lock must be held.
+func (d *Domain) UpdateDrain(balnc *balancer.Balancer) {
+	drainTimeout := getDrainTimeout(balnc)
+
+	for i, backend := range balnc.Backends {
+		if i >= len(d.Drains) {
+			break
+		}
+
+		d.Drains[i].Update(backend.Drain, backend.DrainStart, drainTimeout)
+	}
+}
+
+// CheckDrain closes the remaining WebSocket connections of backends that
+// have reached the drain timeout.
+func (d *Domain) CheckDrain() {
+	expired := []*DrainState{}
+
+	d.Lock.Lock()
+	for _, drain := range d.Drains {
+		if drain.Expire() {
+			expired = append(expired, drain)
+		}
+	}
+	d.Lock.Unlock()
+
+	if len(expired) == 0 {
 		return
 	}
 
-	rw.WriteHeader(http.StatusBadGateway)
+	d.WebSocketConnsLock.Lock()
+	for socketInf := range d.WebSocketConns.Iter() {
+		socket := socketInf.(*webSocketConn)
+		for _, drain := range expired {
+			if socket.drain == drain {
+				socket.Close()
+				break
+			}
+		}
+	}
+	d.WebSocketConnsLock.Unlock()
+}
+
+// Limit enforces the balancer source lists, rate limit and request size
+// limits and returns false if the request was rejected.
+func (d *Domain) Limit(rw http.ResponseWriter, r *http.Request) bool {
+	client := node.Self.GetTrustedRemoteAddr(r)
+
+	if len(d.AllowSources) > 0 || len(d.DenySources) > 0 {
+		ip := net.ParseIP(client)
+		if ip == nil || matchSources(d.DenySources, ip) ||
+			(len(d.AllowSources) > 0 && !matchSources(d.AllowSources, ip)) {
+
+			atomic.AddInt32(d.Rejected, 1)
+			utils.WriteStatus(rw, http.StatusForbidden)
+			return false
+		}
+	}
+
+	if d.Limiter != nil && !d.Limiter.Allow(client) {
+		atomic.AddInt32(d.Rejected, 1)
+		utils.WriteStatus(rw, http.StatusTooManyRequests)
+		return false
+	}
+
+	if d.Balancer.MaxHeaderBytes > 0 &&
+		headerSize(r) > d.Balancer.MaxHeaderBytes {
+
+		atomic.AddInt32(d.Rejected, 1)
+		utils.WriteStatus(rw, http.StatusRequestHeaderFieldsTooLarge)
+		return false
+	}
+
+	if d.Balancer.MaxBodyBytes > 0 {
+		if r.ContentLength > d.Balancer.MaxBodyBytes {
+			atomic.AddInt32(d.Rejected, 1)
+			utils.WriteStatus(rw, http.StatusRequestEntityTooLarge)
+			return false
+		}
+
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(rw, r.Body, d.Balancer.MaxBodyBytes)
+		}
+	}
+
+	return true
+}
+
+func (d *Domain) ServeHTTPFirst(rw http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(d.Requests, 1)
+
+	d.serve(rw, r, false, [][]*Handler{
+		d.OnlineWebFirst,
+		d.UnknownHighWebFirst,
+		d.UnknownMidWebFirst,
+		d.UnknownLowWebFirst,
+		d.OfflineWebFirst,
+	})
+}
+
+func (d *Domain) ServeHTTPSecond(rw http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(d.Retries, 1)
+
+	d.serve(rw, r, true, [][]*Handler{
+		d.OnlineWebSecond,
+		d.UnknownHighWebSecond,
+		d.UnknownMidWebSecond,
+		d.UnknownLowWebSecond,
+		d.OfflineWebSecond,
+	})
 }
 
 func (d *Domain) ServeHTTPThird(rw http.ResponseWriter, r *http.Request) {
 	atomic.AddInt32(d.Retries, 1)
 
-	onlineWebThird := d.OnlineWebThird
-	l := len(onlineWebThird)
-	if l != 0 {
-		onlineWebThird[rand.Intn(l)].Serve(rw, r)
-		return
+	d.serve(rw, r, true, [][]*Handler{
+		d.OnlineWebThird,
+		d.UnknownHighWebThird,
+		d.UnknownMidWebThird,
+		d.UnknownLowWebThird,
+		d.OfflineWebThird,
+	})
+}
+
+func (d *Domain) checkStatus(status int) bool {
+	codes := d.Balancer.CheckStatusCodes
+	if len(codes) == 0 {
+		return status >= 200 && status < 300
 	}
 
-	unknownHighWebThird := d.UnknownHighWebThird
-	l = len(unknownHighWebThird)
-	if l != 0 {
-		unknownHighWebThird[rand.Intn(l)].Serve(rw, r)
-		return
+	for _, code := range codes {
+		if status == code {
+			return true
+		}
 	}
 
-	unknownMidWebThird := d.UnknownMidWebThird
-	l = len(unknownMidWebThird)
-	if l != 0 {
-		unknownMidWebThird[rand.Intn(l)].Serve(rw, r)
-		return
+	return false
+}
+
+func (d *Domain) checkRequest(hand *Handler) bool {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), d.HealthConfig.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", hand.CheckUrl, nil)
+	if err != nil {
+		return false
 	}
 
-	unknownLowWebThird := d.UnknownLowWebThird
-	l = len(unknownLowWebThird)
-	if l != 0 {
-		unknownLowWebThird[rand.Intn(l)].Serve(rw, r)
-		return
+	resp, err := checkClient.Do(req)
+	if err != nil {
+		return false
 	}
+	defer resp.Body.Close()
 
-	offlineWebThird := d.OfflineWebThird
-	l = len(offlineWebThird)
-	if l != 0 {
-		offlineWebThird[rand.Intn(l)].Serve(rw, r)
-		return
+	if !d.checkStatus(resp.StatusCode) {
+		return false
 	}
 
-	rw.WriteHeader(http.StatusBadGateway)
+	if d.CheckBody != nil {
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 65536))
+		if err != nil {
+			return false
+		}
+
+		return d.CheckBody.Match(body)
+	}
+
+	return true
 }
 
 func (d *Domain) checkHandler(hand *Handler) {
-	resp, err := checkClient.Get(hand.CheckUrl)
-	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		if hand.State != Offline {
-			d.offlineHandler(hand)
+	if d.checkRequest(hand) {
+		successes := hand.Health.Success()
+		if hand.State != Online && !hand.Health.Ejected() &&
+			successes >= d.HealthConfig.HealthyThreshold {
+
+			d.upgradeHandler(hand)
 		}
 	} else {
-		if hand.State != Online {
-			d.upgradeHandler(hand)
+		failures := hand.Health.Failure()
+		if hand.State != Offline &&
+			failures >= d.HealthConfig.UnhealthyThreshold {
+
+			d.offlineHandler(hand)
 		}
 	}
 }
 
+// proxyError records a failed proxy request to the backend and ejects the
+// backend once the consecutive error count reaches the outlier threshold.
+func (d *Domain) proxyError(hand *Handler) {
+	hand.Metrics.Error()
+
+	outlierErrors := d.HealthConfig.OutlierErrors
+	if outlierErrors > 0 && hand.Health.Error() >= outlierErrors {
+		hand.Health.Eject(d.HealthConfig.OutlierTime)
+
+		logrus.WithFields(logrus.Fields{
+			"balancer_id":   d.Balancer.Id.Hex(),
+			"balancer_name": d.Balancer.Name,
+			"domain":        d.Domain.Domain,
+			"backend":       hand.Key,
+		}).Warn("proxy: Ejecting balancer backend after proxy errors")
+
+		d.offlineHandler(hand)
+		return
+	}
+
+	d.downgradeHandler(hand)
+}
+
 func (d *Domain) Check() {
 	d.Lock.Lock()
 	defer d.Lock.Unlock()
 
+	if time.Since(d.LastCheck) < d.HealthConfig.Interval {
+		return
+	}
+	d.LastCheck = time.Now()
+
 	for _, hand := range d.OnlineWebFirst {
 		go d.checkHandler(hand)
 	}
@@ -726,7 +1088,7 @@ func (d *Domain) offlineHandler(hand *Handler) {
 		for i, h := range d.OnlineWebFirst {
 			h.Index = i
 		}
-		hand.Index = len(d.UnknownMidWebFirst)
+		hand.Index = len(d.OfflineWebFirst)
 		hand.State = Offline
 		hand.LastState = time.Now()
 		d.OfflineWebFirst = append(d.OfflineWebFirst, hand)
@@ -766,7 +1128,7 @@ func (d *Domain) offlineHandler(hand *Handler) {
 		for i, h := range d.UnknownHighWebFirst {
 			h.Index = i
 		}
-		hand.Index = len(d.UnknownMidWebFirst)
+		hand.Index = len(d.OfflineWebFirst)
 		hand.State = Offline
 		hand.LastState = time.Now()
 		d.OfflineWebFirst = append(d.OfflineWebFirst, hand)
@@ -811,7 +1173,7 @@ func (d *Domain) offlineHandler(hand *Handler) {
 			for i, h := range d.UnknownMidWebFirst {
 				h.Index = i
 			}
-			hand.Index = len(d.UnknownLowWebFirst)
+			hand.Index = len(d.OfflineWebFirst)
 			hand.State = Offline
 			hand.LastState = time.Now()
 			d.OfflineWebFirst = append(d.OfflineWebFirst, hand)
@@ -898,8 +1260,15 @@ func (d *Domain) offlineHandler(hand *Handler) {
 }
 
 func (d *Domain) ResponseHandler(hand *Handler, resp *http.Response) error {
-	if hand.State != Online && resp.StatusCode < 500 {
-		d.upgradeHandler(hand)
+	info := getRequestInfo(resp.Request)
+	if info != nil {
+		hand.Metrics.Record(resp.StatusCode, time.Since(info.start))
+	}
+
+	// Only the active health check promotes a handler, successful
+	// responses reset the outlier errors
+	if resp.StatusCode < 500 {
+		hand.Health.ResetErrors()
 	}
 
 	return nil
@@ -912,7 +1281,18 @@ func (d *Domain) ErrorHandlerFirst(hand *Handler, rw http.ResponseWriter,
 		return
 	}
 
-	d.downgradeHandler(hand)
+	if isMaxBytesError(err) {
+		atomic.AddInt32(d.Rejected, 1)
+		rw.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if r.Context().Err() != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	d.proxyError(hand)
 	d.ServeHTTPSecond(rw, r)
 }
 
@@ -923,7 +1303,18 @@ func (d *Domain) ErrorHandlerSecond(hand *Handler, rw http.ResponseWriter,
 		return
 	}
 
-	d.downgradeHandler(hand)
+	if isMaxBytesError(err) {
+		atomic.AddInt32(d.Rejected, 1)
+		rw.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if r.Context().Err() != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	d.proxyError(hand)
 	d.ServeHTTPThird(rw, r)
 }
 
@@ -934,6 +1325,17 @@ func (d *Domain) ErrorHandlerThird(hand *Handler, rw http.ResponseWriter,
 		return
 	}
 
-	d.downgradeHandler(hand)
+	if isMaxBytesError(err) {
+		atomic.AddInt32(d.Rejected, 1)
+		rw.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if r.Context().Err() != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	d.proxyError(hand)
 	rw.WriteHeader(http.StatusBadGateway)
 }
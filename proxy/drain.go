@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/balancer"
+)
+
+// DrainState tracks a backend that is being taken out of rotation. A
+// draining backend receives no new sessions and the context is canceled
+// once the drain timeout is reached to end the remaining requests.
+type DrainState struct {
+	lock     sync.Mutex
+	draining bool
+	expired  bool
+	end      time.Time
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func (s *DrainState) Update(draining bool, start time.Time,
+	timeout time.Duration) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !draining {
+		s.draining = false
+		s.expired = false
+		s.end = time.Time{}
+		s.ctx = nil
+		s.cancel = nil
+		return
+	}
+
+	if !s.draining {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	s.draining = true
+	s.end = start.Add(timeout)
+}
+
+func (s *DrainState) Draining() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.draining
+}
+
+func (s *DrainState) Expired() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.expired
+}
+
+func (s *DrainState) Context() context.Context {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.ctx
+}
+
+// Expire returns true when the drain timeout has been reached for the
+// first time.
+func (s *DrainState) Expire() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.draining || s.expired || time.Now().Before(s.end) {
+		return false
+	}
+
+	s.expired = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	return true
+}
+
+func getDrainTimeout(balnc *balancer.Balancer) time.Duration {
+	if balnc.DrainTimeout > 0 {
+		return time.Duration(balnc.DrainTimeout) * time.Second
+	}
+	return balancer.DefaultDrainTimeout * time.Second
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/balancer"
+	"github.com/pritunl/pritunl-cloud/utils"
+)
+
+// Health tracks consecutive check results and proxy errors for a backend,
+// shared between the handlers of each retry tier.
+type Health struct {
+	successes int32
+	failures  int32
+	errors    int32
+	ejected   int64
+}
+
+func (h *Health) Success() int32 {
+	atomic.StoreInt32(&h.failures, 0)
+	return atomic.AddInt32(&h.successes, 1)
+}
+
+func (h *Health) Failure() int32 {
+	atomic.StoreInt32(&h.successes, 0)
+	return atomic.AddInt32(&h.failures, 1)
+}
+
+func (h *Health) Error() int32 {
+	return atomic.AddInt32(&h.errors, 1)
+}
+
+func (h *Health) ResetErrors() {
+	atomic.StoreInt32(&h.errors, 0)
+}
+
+func (h *Health) Eject(dur time.Duration) {
+	atomic.StoreInt32(&h.errors, 0)
+	atomic.StoreInt32(&h.successes, 0)
+	atomic.StoreInt64(&h.ejected, time.Now().Add(dur).UnixNano())
+}
+
+func (h *Health) Ejected() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&h.ejected)
+}
+
+type HealthConfig struct {
+	Interval           time.Duration
+	Timeout            time.Duration
+	HealthyThreshold   int32
+	UnhealthyThreshold int32
+	OutlierErrors      int32
+	OutlierTime        time.Duration
+}
+
+func NewHealthConfig(balnc *balancer.Balancer) (conf *HealthConfig) {
+	interval := balnc.CheckInterval
+	if interval == 0 {
+		interval = balancer.DefaultCheckInterval
+	}
+	timeout := balnc.CheckTimeout
+	if timeout == 0 {
+		timeout = balancer.DefaultCheckTimeout
+	}
+	outlierTime := balnc.OutlierTime
+	if outlierTime == 0 {
+		outlierTime = balancer.DefaultOutlierTime
+	}
+
+	conf = &HealthConfig{
+		Interval: time.Duration(interval) * time.Second,
+		Timeout:  time.Duration(timeout) * time.Second,
+		HealthyThreshold: int32(utils.Max(
+			balnc.HealthyThreshold, balancer.DefaultHealthyThreshold)),
+		UnhealthyThreshold: int32(utils.Max(
+			balnc.UnhealthyThreshold, balancer.DefaultUnhealthyThreshold)),
+		OutlierErrors: int32(balnc.OutlierErrors),
+		OutlierTime:   time.Duration(outlierTime) * time.Second,
+	}
+
+	return
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a per client token bucket rate limiter shared between the
+// domains of a balancer.
+type Limiter struct {
+	lock    sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+func (l *Limiter) Update(rate, burst int) {
+	l.lock.Lock()
+	l.rate = float64(rate)
+	l.burst = float64(burst)
+	l.lock.Unlock()
+}
+
+func (l *Limiter) Allow(client string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	buck := l.buckets[client]
+	if buck == nil {
+		buck = &bucket{
+			tokens: l.burst,
+			last:   now,
+		}
+		l.buckets[client] = buck
+	} else {
+		buck.tokens += now.Sub(buck.last).Seconds() * l.rate
+		if buck.tokens > l.burst {
+			buck.tokens = l.burst
+		}
+		buck.last = now
+	}
+
+	if buck.tokens < 1 {
+		return false
+	}
+
+	buck.tokens -= 1
+	return true
+}
+
+// Clean removes the buckets of clients that have refilled to the burst.
+func (l *Limiter) Clean() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	for client, buck := range l.buckets {
+		tokens := buck.tokens + now.Sub(buck.last).Seconds()*l.rate
+		if tokens >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+func NewLimiter(rate, burst int) (limiter *Limiter) {
+	limiter = &Limiter{
+		rate:    float64(rate),
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+	}
+
+	return
+}
+
+func parseSources(sources []string) (networks []*net.IPNet) {
+	networks = []*net.IPNet{}
+
+	for _, source := range sources {
+		_, network, err := net.ParseCIDR(source)
+		if err != nil {
+			continue
+		}
+
+		networks = append(networks, network)
+	}
+
+	return
+}
+
+func matchSources(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func headerSize(r *http.Request) (size int) {
+	size = len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4
+	size += len(r.Host) + 8
+
+	for key, vals := range r.Header {
+		for _, val := range vals {
+			size += len(key) + len(val) + 4
+		}
+	}
+
+	return
+}
+
+func isMaxBytesError(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-cloud/balancer"
+)
+
+type metricsSample struct {
+	requests int64
+	errors   int64
+	latency  []int64
+	statuses [5]int64
+}
+
+func newMetricsSample() metricsSample {
+	return metricsSample{
+		latency: make([]int64, len(balancer.LatencyBuckets)+1),
+	}
+}
+
+func (s *metricsSample) add(sample metricsSample) {
+	s.requests += sample.requests
+	s.errors += sample.errors
+	for i, count := range sample.latency {
+		s.latency[i] += count
+	}
+	for i, count := range sample.statuses {
+		s.statuses[i] += count
+	}
+}
+
+// Metrics records the response latency and status codes of a backend,
+// shared between the handlers of each retry tier. Samples are rotated
+// with the domain request counters to cover the last minute.
+type Metrics struct {
+	lock sync.Mutex
+	cur  metricsSample
+	prev [5]metricsSample
+}
+
+func (m *Metrics) Record(status int, latency time.Duration) {
+	millis := int(latency / time.Millisecond)
+
+	bucket := len(balancer.LatencyBuckets)
+	for i, bound := range balancer.LatencyBuckets {
+		if millis < bound {
+			bucket = i
+			break
+		}
+	}
+
+	m.lock.Lock()
+	m.cur.requests += 1
+	m.cur.latency[bucket] += 1
+	if status >= 100 && status < 600 {
+		m.cur.statuses[status/100-1] += 1
+	}
+	m.lock.Unlock()
+}
+
+func (m *Metrics) Error() {
+	m.lock.Lock()
+	m.cur.errors += 1
+	m.lock.Unlock()
+}
+
+func (m *Metrics) Rotate() {
+	m.lock.Lock()
+	m.prev[0] = m.prev[1]
+	m.prev[1] = m.prev[2]
+	m.prev[2] = m.prev[3]
+	m.prev[3] = m.prev[4]
+	m.prev[4] = m.cur
+	m.cur = newMetricsSample()
+	m.lock.Unlock()
+}
+
+// Export adds the samples from the last minute to the backend state.
+func (m *Metrics) Export(state *balancer.BackendState) {
+	total := newMetricsSample()
+
+	m.lock.Lock()
+	for _, sample := range m.prev {
+		if sample.latency != nil {
+			total.add(sample)
+		}
+	}
+	total.add(m.cur)
+	m.lock.Unlock()
+
+	if state.Latency == nil {
+		state.Latency = make([]int64, len(total.latency))
+	}
+	if state.Statuses == nil {
+		state.Statuses = map[string]int64{}
+	}
+
+	state.Requests += total.requests
+	state.Errors += total.errors
+	for i, count := range total.latency {
+		state.Latency[i] += count
+	}
+	for i, count := range total.statuses {
+		if count > 0 {
+			state.Statuses[strconv.Itoa(i+1)+"xx"] += count
+		}
+	}
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		cur: newMetricsSample(),
+	}
+}
@@ -4,20 +4,25 @@ import (
 	"bytes"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/balancer"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/node"
 	"github.com/pritunl/pritunl-cloud/settings"
 	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/sirupsen/logrus"
 )
 
 type Proxy struct {
-	Domains map[string]*Domain
-	lock    sync.Mutex
+	Domains    map[string]*Domain
+	Streams    map[string]*Stream
+	limiters   map[primitive.ObjectID]*Limiter
+	accessLogs chan *balancer.AccessLog
+	lock       sync.Mutex
 }
 
 type balancerState struct {
@@ -34,7 +39,152 @@ func (p *Proxy) ServeHTTP(hst string, rw http.ResponseWriter,
 		return
 	}
 
-	domain.ServeHTTPFirst(rw, r)
+	r, info := withRequestInfo(r)
+
+	if domain.Balancer.AccessLog == "" {
+		if !domain.Limit(rw, r) {
+			return
+		}
+
+		domain.ServeHTTPFirst(rw, r)
+		return
+	}
+
+	start := time.Now()
+	writer := &accessWriter{
+		ResponseWriter: rw,
+	}
+
+	if domain.Limit(writer, r) {
+		domain.ServeHTTPFirst(writer, r)
+	}
+
+	p.accessLog(domain, r, writer, info, start)
+}
+
+func (p *Proxy) updateStreams(balnc *balancer.Balancer,
+	streams map[string]*Stream) (state *balancer.State) {
+
+	state = &balancer.State{
+		Timestamp:   time.Now(),
+		Online:      []string{},
+		UnknownHigh: []string{},
+		UnknownMid:  []string{},
+		UnknownLow:  []string{},
+		Offline:     []string{},
+		Draining:    []string{},
+		Backends:    []*balancer.BackendState{},
+	}
+
+	for _, backend := range balnc.Backends {
+		if backend.Drain {
+			state.Draining = append(state.Draining, backend.Key())
+		}
+	}
+
+	backendStates := map[string]int{}
+	backendCounters := map[string]*balancer.BackendState{}
+
+	for _, port := range balnc.ListenPorts {
+		key := streamKey(balnc.Type, port)
+
+		if streams[key] != nil {
+			conflictStream := streams[key]
+			logrus.WithFields(logrus.Fields{
+				"first_balancer_id":    conflictStream.Balancer.Id.Hex(),
+				"first_balancer_name":  conflictStream.Balancer.Name,
+				"second_balancer_id":   balnc.Id.Hex(),
+				"second_balancer_name": balnc.Name,
+				"conflict_port":        key,
+			}).Error("proxy: Balancer listen port conflict")
+			continue
+		}
+
+		if port == node.Self.Port ||
+			(port == 80 && !node.Self.NoRedirectServer) {
+
+			logrus.WithFields(logrus.Fields{
+				"balancer_id":   balnc.Id.Hex(),
+				"balancer_name": balnc.Name,
+				"listen":        key,
+			}).Error("proxy: Balancer listen port reserved by node")
+			continue
+		}
+
+		stream := p.Streams[key]
+		if stream != nil && (stream.Balancer.Id != balnc.Id ||
+			!stream.AddressesEqual(streamAddresses())) {
+
+			stream.Close()
+			delete(p.Streams, key)
+			stream = nil
+		}
+
+		if stream == nil {
+			stream = NewStream(balnc, port)
+
+			err := stream.Start()
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"balancer_id":   balnc.Id.Hex(),
+					"balancer_name": balnc.Name,
+					"listen":        key,
+					"error":         err,
+				}).Error("proxy: Failed to start balancer stream")
+				continue
+			}
+		} else {
+			stream.Update(balnc)
+		}
+
+		streams[key] = stream
+
+		stream.Lock.Lock()
+		for _, backend := range stream.Backends {
+			curState, ok := backendStates[backend.Key]
+			if !ok || backend.State < curState {
+				backendStates[backend.Key] = backend.State
+			}
+
+			counter := backendCounters[backend.Key]
+			if counter == nil {
+				counter = &balancer.BackendState{
+					Key: backend.Key,
+				}
+				backendCounters[backend.Key] = counter
+				state.Backends = append(state.Backends, counter)
+			}
+
+			counter.Connections += int(atomic.LoadInt32(
+				backend.Connections))
+			counter.ConnectionsTotal += atomic.LoadInt64(
+				backend.ConnectionsTotal)
+			counter.Errors += atomic.LoadInt64(backend.Errors)
+		}
+		stream.Lock.Unlock()
+	}
+
+	for key, backendState := range backendStates {
+		switch backendState {
+		case Online:
+			state.Online = append(state.Online, key)
+			break
+		case UnknownHigh:
+			state.UnknownHigh = append(state.UnknownHigh, key)
+			break
+		case UnknownMid:
+			state.UnknownMid = append(state.UnknownMid, key)
+			break
+		case UnknownLow:
+			state.UnknownLow = append(state.UnknownLow, key)
+			break
+		default:
+			state.Offline = append(state.Offline, key)
+			break
+		}
+	}
+
+	return
 }
 
 func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
@@ -43,6 +193,9 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 	domains := map[string]*Domain{}
 	domainsName := set.NewSet()
 	remDomains := []*Domain{}
+	streams := map[string]*Stream{}
+	remStreams := []*Stream{}
+	limiters := map[primitive.ObjectID]*Limiter{}
 	states := []*balancerState{}
 
 	proxyProto := node.Self.Protocol
@@ -54,12 +207,31 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 			continue
 		}
 
+		if balnc.IsStream() {
+			states = append(states, &balancerState{
+				Balancer: balnc,
+				State:    p.updateStreams(balnc, streams),
+			})
+			continue
+		}
+
 		onlineWeb := set.NewSet()
 		unknownHighWeb := set.NewSet()
 		unknownMidWeb := set.NewSet()
 		unknownLowWeb := set.NewSet()
 		offlineWeb := set.NewSet()
 
+		var limiter *Limiter
+		if balnc.RateLimit > 0 {
+			limiter = p.limiters[balnc.Id]
+			if limiter == nil {
+				limiter = NewLimiter(balnc.RateLimit, balnc.RateBurst)
+			} else {
+				limiter.Update(balnc.RateLimit, balnc.RateBurst)
+			}
+			limiters[balnc.Id] = limiter
+		}
+
 		state := &balancer.State{
 			Timestamp:   time.Now(),
 			Online:      []string{},
@@ -67,6 +239,15 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 			UnknownMid:  []string{},
 			UnknownLow:  []string{},
 			Offline:     []string{},
+			Draining:    []string{},
+			Backends:    []*balancer.BackendState{},
+		}
+		backendCounters := map[string]*balancer.BackendState{}
+
+		for _, backend := range balnc.Backends {
+			if backend.Drain {
+				state.Draining = append(state.Draining, backend.Key())
+			}
 		}
 
 		for _, domain := range balnc.Domains {
@@ -92,6 +273,8 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 				Domain:     domain,
 				Requests:   new(int32),
 				Retries:    new(int32),
+				Rejected:   new(int32),
+				Limiter:    limiter,
 			}
 			proxyDomain.CalculateHash()
 
@@ -99,6 +282,7 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 			if curDomain != nil && curDomain.Balancer.Id == balnc.Id {
 				state.Requests += curDomain.RequestsTotal
 				state.Retries += curDomain.RetriesTotal
+				state.Rejected += curDomain.RejectedTotal
 				state.WebSockets += curDomain.WebSocketConns.Len()
 
 				curDomain.Lock.Lock()
@@ -118,7 +302,21 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 					offlineWeb.Add(hand.Key)
 				}
 
+				for key, metrics := range curDomain.Metrics {
+					counter := backendCounters[key]
+					if counter == nil {
+						counter = &balancer.BackendState{
+							Key: key,
+						}
+						backendCounters[key] = counter
+						state.Backends = append(state.Backends, counter)
+					}
+
+					metrics.Export(counter)
+				}
+
 				if bytes.Equal(curDomain.Hash, proxyDomain.Hash) {
+					curDomain.UpdateDrain(balnc)
 					domains[domain.Domain] = curDomain
 					curDomain.Lock.Unlock()
 					continue
@@ -129,6 +327,10 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 					proxyDomain.Retries = curDomain.Retries
 					proxyDomain.RetriesPrev = curDomain.RetriesPrev
 					proxyDomain.RetriesTotal = curDomain.RetriesTotal
+					proxyDomain.Rejected = curDomain.Rejected
+					proxyDomain.RejectedPrev = curDomain.RejectedPrev
+					proxyDomain.RejectedTotal = curDomain.RejectedTotal
+					proxyDomain.Metrics = curDomain.Metrics
 					curDomain.Lock.Unlock()
 
 					remDomains = append(remDomains, curDomain)
@@ -195,9 +397,21 @@ func (p *Proxy) Update(db *database.Database, balncs []*balancer.Balancer) (
 		}
 	}
 
+	for key, stream := range p.Streams {
+		if streams[key] != stream {
+			remStreams = append(remStreams, stream)
+		}
+	}
+
 	p.Domains = domains
+	p.Streams = streams
+	p.limiters = limiters
 	p.lock.Unlock()
 
+	for _, stream := range remStreams {
+		stream.Close()
+	}
+
 	for _, domain := range remDomains {
 		domain.WebSocketConnsLock.Lock()
 		for socketInf := range domain.WebSocketConns.Iter() {
@@ -253,6 +467,28 @@ func (p *Proxy) syncCount() {
 		retTotal += int(*ret)
 		dom.RetriesPrev = retPrev
 		dom.RetriesTotal = retTotal
+
+		rej := dom.Rejected
+		dom.Rejected = new(int32)
+		rejPrev := dom.RejectedPrev
+		rejTotal := rejPrev[0] + rejPrev[1] + rejPrev[2] +
+			rejPrev[3] + rejPrev[4]
+		rejPrev[0] = rejPrev[1]
+		rejPrev[1] = rejPrev[2]
+		rejPrev[2] = rejPrev[3]
+		rejPrev[3] = rejPrev[4]
+		rejPrev[4] = int(*rej)
+		rejTotal += int(*rej)
+		dom.RejectedPrev = rejPrev
+		dom.RejectedTotal = rejTotal
+
+		for _, metrics := range dom.Metrics {
+			metrics.Rotate()
+		}
+	}
+
+	for _, limiter := range p.limiters {
+		limiter.Clean()
 	}
 }
 
@@ -269,19 +505,29 @@ func (p *Proxy) healthCheck() {
 
 	domains := p.Domains
 	for _, dom := range domains {
+		dom.CheckDrain()
 		dom.Check()
 	}
+
+	for _, stream := range p.Streams {
+		stream.CheckDrain()
+		stream.Check()
+	}
 }
 
 func (p *Proxy) runHealthCheck() {
 	for {
-		time.Sleep(5 * time.Second)
+		time.Sleep(1 * time.Second)
 		p.healthCheck()
 	}
 }
 
 func (p *Proxy) Init() {
 	p.Domains = map[string]*Domain{}
+	p.Streams = map[string]*Stream{}
+	p.limiters = map[primitive.ObjectID]*Limiter{}
+	p.accessLogs = make(chan *balancer.AccessLog, 4096)
 	go p.runCounter()
 	go p.runHealthCheck()
+	go p.runAccessLogs()
 }
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/pritunl/pritunl-cloud/balancer"
+)
+
+var proxyProtoV2Sig = []byte{
+	0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a,
+}
+
+func proxyProtoV1(src, dst *net.TCPAddr) []byte {
+	srcIp4 := src.IP.To4()
+	dstIp4 := dst.IP.To4()
+
+	if srcIp4 != nil && dstIp4 != nil {
+		return []byte(fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n",
+			srcIp4.String(), dstIp4.String(), src.Port, dst.Port))
+	} else if srcIp4 == nil && dstIp4 == nil {
+		return []byte(fmt.Sprintf("PROXY TCP6 %s %s %d %d\r\n",
+			src.IP.String(), dst.IP.String(), src.Port, dst.Port))
+	}
+
+	return []byte("PROXY UNKNOWN\r\n")
+}
+
+func proxyProtoV2(src, dst *net.TCPAddr) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(proxyProtoV2Sig)
+
+	// Version 2 with PROXY command
+	buf.WriteByte(0x21)
+
+	srcIp4 := src.IP.To4()
+	dstIp4 := dst.IP.To4()
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports[0:2], uint16(src.Port))
+	binary.BigEndian.PutUint16(ports[2:4], uint16(dst.Port))
+
+	if srcIp4 != nil && dstIp4 != nil {
+		// AF_INET with STREAM
+		buf.WriteByte(0x11)
+		_ = binary.Write(buf, binary.BigEndian, uint16(12))
+		buf.Write(srcIp4)
+		buf.Write(dstIp4)
+		buf.Write(ports)
+	} else if srcIp4 == nil && dstIp4 == nil {
+		// AF_INET6 with STREAM
+		buf.WriteByte(0x21)
+		_ = binary.Write(buf, binary.BigEndian, uint16(36))
+		buf.Write(src.IP.To16())
+		buf.Write(dst.IP.To16())
+		buf.Write(ports)
+	} else {
+		// AF_UNSPEC
+		buf.WriteByte(0x00)
+		_ = binary.Write(buf, binary.BigEndian, uint16(0))
+	}
+
+	return buf.Bytes()
+}
+
+func proxyProtoHeader(version string, conn net.Conn) []byte {
+	src, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+	dst, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+
+	switch version {
+	case balancer.ProxyProtocolV1:
+		return proxyProtoV1(src, dst)
+	case balancer.ProxyProtocolV2:
+		return proxyProtoV2(src, dst)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"context"
+	"crypto/md5"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -26,6 +28,12 @@ import (
 type Handler struct {
 	Key                string
 	Index              int
+	Group              string
+	Weight             int
+	Health             *Health
+	Drain              *DrainState
+	Metrics            *Metrics
+	AffinityId         string
 	State              int
 	Domain             *Domain
 	CheckUrl           string
@@ -85,6 +93,7 @@ func (h *Handler) ServeWS(rw http.ResponseWriter, r *http.Request) {
 		TLSClientConfig:  h.TlsConfig,
 	}
 
+	start := time.Now()
 	backConn, backResp, err = dialer.Dial(u.String(), header)
 	if err != nil {
 		if backResp != nil {
@@ -103,6 +112,8 @@ func (h *Handler) ServeWS(rw http.ResponseWriter, r *http.Request) {
 	}
 	defer backConn.Close()
 
+	h.Metrics.Record(backResp.StatusCode, time.Since(start))
+
 	upgradeHeaders := http.Header{}
 	val := backResp.Header.Get("Sec-Websocket-Protocol")
 	if val != "" {
@@ -128,12 +139,30 @@ func (h *Handler) ServeWS(rw http.ResponseWriter, r *http.Request) {
 		front: frontConn,
 		back:  backConn,
 		r:     r,
+		drain: h.Drain,
 	}
 
 	conn.Run(h.Domain)
 }
 
 func (h *Handler) Serve(rw http.ResponseWriter, r *http.Request) {
+	info := getRequestInfo(r)
+	if info != nil {
+		info.backend = h.Key
+		info.start = time.Now()
+	}
+
+	drainCtx := h.Drain.Context()
+	if drainCtx != nil {
+		ctx, cancel := context.WithCancel(r.Context())
+		stop := context.AfterFunc(drainCtx, cancel)
+		defer func() {
+			stop()
+			cancel()
+		}()
+		r = r.WithContext(ctx)
+	}
+
 	if h.WebSockets && strings.ToLower(
 		r.Header.Get("Upgrade")) == "websocket" {
 
@@ -208,6 +237,8 @@ func NewHandler(index, state int, proxyProto string, proxyPort int,
 		}
 	}
 
+	backendKey := backend.Key()
+
 	writer := &logger.ErrorWriter{
 		Message: "proxy: Balancer server error",
 		Fields: logrus.Fields{
@@ -221,8 +252,11 @@ func NewHandler(index, state int, proxyProto string, proxyPort int,
 	}
 
 	hand = &Handler{
-		Key:            fmt.Sprintf("%s:%d", backend.Hostname, backend.Port),
+		Key:            backendKey,
 		Index:          index,
+		AffinityId:     fmt.Sprintf("%x", md5.Sum([]byte(backendKey)))[:16],
+		Group:          backend.Group,
+		Weight:         utils.Max(backend.Weight, 1),
 		State:          state,
 		Domain:         domain,
 		CheckUrl:       checkUrl.String(),
@@ -0,0 +1,757 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-cloud/balancer"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type StreamBackend struct {
+	Key              string
+	Address          string
+	CheckAddress     string
+	Weight           int
+	State            int
+	LastState        time.Time
+	Health           *Health
+	Drain            *DrainState
+	Connections      *int32
+	ConnectionsTotal *int64
+	Errors           *int64
+}
+
+type udpSession struct {
+	conn       net.Conn
+	packetConn net.PacketConn
+	client     net.Addr
+	backend    *StreamBackend
+	lastActive int64
+}
+
+type Stream struct {
+	Key           string
+	Protocol      string
+	Port          int
+	Addresses     []string
+	ProxyProtocol string
+	Balancer      *balancer.Balancer
+	Backends      []*StreamBackend
+	HealthConfig  *HealthConfig
+	LastCheck     time.Time
+	Lock          sync.Mutex
+	counter       uint32
+	active        int32
+	listeners     []net.Listener
+	packetConns   []net.PacketConn
+	conns         map[net.Conn]*StreamBackend
+	sessions      map[string]*udpSession
+	connsLock     sync.Mutex
+	closed        bool
+}
+
+func streamKey(protocol string, port int) string {
+	return fmt.Sprintf("%s:%d", protocol, port)
+}
+
+// streamAddresses returns the node public addresses, streams are not bound
+// to internal interfaces
+func streamAddresses() (addrs []string) {
+	addrs = []string{}
+
+	for _, addr := range node.Self.PublicIps {
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, addr := range node.Self.PublicIps6 {
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return
+}
+
+func (s *Stream) AddressesEqual(addrs []string) bool {
+	if len(s.Addresses) != len(addrs) {
+		return false
+	}
+
+	for i, addr := range addrs {
+		if s.Addresses[i] != addr {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Stream) Update(balnc *balancer.Balancer) {
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+
+	curBackends := map[string]*StreamBackend{}
+	for _, backend := range s.Backends {
+		curBackends[backend.Key] = backend
+	}
+
+	backends := []*StreamBackend{}
+	added := set.NewSet()
+	for _, backend := range balnc.Backends {
+		port := backend.Port
+		if port == 0 {
+			port = s.Port
+		}
+
+		key := net.JoinHostPort(backend.Hostname, strconv.Itoa(port))
+		if added.Contains(key) {
+			continue
+		}
+		added.Add(key)
+
+		checkAddr := ""
+		if balnc.CheckPort != 0 {
+			checkAddr = net.JoinHostPort(
+				backend.Hostname, strconv.Itoa(balnc.CheckPort))
+		} else if s.Protocol == balancer.Tcp {
+			checkAddr = key
+		}
+
+		streamBackend := curBackends[key]
+		if streamBackend == nil {
+			streamBackend = &StreamBackend{
+				Key:              key,
+				Address:          key,
+				State:            UnknownHigh,
+				LastState:        time.Now(),
+				Connections:      new(int32),
+				ConnectionsTotal: new(int64),
+				Errors:           new(int64),
+				Health:           &Health{},
+				Drain:            &DrainState{},
+			}
+		}
+		streamBackend.CheckAddress = checkAddr
+		streamBackend.Weight = utils.Max(backend.Weight, 1)
+		streamBackend.Drain.Update(backend.Drain, backend.DrainStart,
+			getDrainTimeout(balnc))
+
+		backends = append(backends, streamBackend)
+	}
+
+	s.Balancer = balnc
+	s.ProxyProtocol = balnc.ProxyProtocol
+	s.HealthConfig = NewHealthConfig(balnc)
+	s.Backends = backends
+}
+
+func (s *Stream) pick(exclude set.Set, client string) (
+	backend *StreamBackend) {
+
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+
+	bestState := 0
+	candidates := []*StreamBackend{}
+	for _, back := range s.Backends {
+		if exclude.Contains(back.Key) || back.Drain.Draining() {
+			continue
+		}
+
+		if back.State > bestState {
+			bestState = back.State
+			candidates = []*StreamBackend{back}
+		} else if back.State == bestState {
+			candidates = append(candidates, back)
+		}
+	}
+
+	if s.Balancer.Affinity == balancer.AffinitySourceIp && client != "" {
+		bestScore := 0.0
+		for _, back := range candidates {
+			score := affinityScore(client, back.Key, back.Weight)
+			if backend == nil || score > bestScore {
+				backend = back
+				bestScore = score
+			}
+		}
+		return
+	}
+
+	total := 0
+	for _, back := range candidates {
+		total += back.Weight
+	}
+
+	if total == 0 {
+		return
+	}
+
+	n := int(atomic.AddUint32(&s.counter, 1) % uint32(total))
+	for _, back := range candidates {
+		n -= back.Weight
+		if n < 0 {
+			backend = back
+			return
+		}
+	}
+
+	return
+}
+
+func (s *Stream) setState(backend *StreamBackend, state int) {
+	s.Lock.Lock()
+	if backend.State != state {
+		backend.State = state
+		backend.LastState = time.Now()
+	}
+	s.Lock.Unlock()
+}
+
+// backendControl rejects backend connections to loopback, link local and
+// node addresses after name resolution
+func backendControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &errortypes.ConnectionError{
+			errors.Wrap(err, "proxy: Invalid backend address"),
+		}
+	}
+
+	nodeAddrs := set.NewSet()
+	for _, addr := range node.Self.PublicIps {
+		nodeAddrs.Add(addr)
+	}
+	for _, addr := range node.Self.PublicIps6 {
+		nodeAddrs.Add(addr)
+	}
+	for _, addr := range node.Self.PrivateIps {
+		nodeAddrs.Add(addr)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !balancer.BackendIpAllowed(ip, nodeAddrs) {
+		return &errortypes.ConnectionError{
+			errors.Newf("proxy: Backend address %s not allowed", host),
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) dial(protocol string, client net.Addr) (
+	backend *StreamBackend, conn net.Conn, err error) {
+
+	clientHost, _, _ := net.SplitHostPort(client.String())
+
+	dialer := &net.Dialer{
+		Timeout: time.Duration(settings.Router.DialTimeout) * time.Second,
+		Control: backendControl,
+	}
+	tried := set.NewSet()
+
+	for i := 0; i < 3; i++ {
+		backend = s.pick(tried, clientHost)
+		if backend == nil {
+			break
+		}
+		tried.Add(backend.Key)
+
+		conn, err = dialer.Dial(protocol, backend.Address)
+		if err == nil {
+			backend.Health.ResetErrors()
+			return
+		}
+
+		atomic.AddInt64(backend.Errors, 1)
+		s.setState(backend, Offline)
+
+		s.Lock.Lock()
+		balnc := s.Balancer
+		healthConf := s.HealthConfig
+		s.Lock.Unlock()
+
+		logrus.WithFields(logrus.Fields{
+			"balancer_id":   balnc.Id.Hex(),
+			"balancer_name": balnc.Name,
+			"listen":        s.Key,
+			"backend":       backend.Key,
+			"error":         err,
+		}).Warn("proxy: Failed to connect to stream backend")
+
+		if healthConf.OutlierErrors > 0 &&
+			backend.Health.Error() >= healthConf.OutlierErrors {
+
+			backend.Health.Eject(healthConf.OutlierTime)
+
+			logrus.WithFields(logrus.Fields{
+				"balancer_id":   balnc.Id.Hex(),
+				"balancer_name": balnc.Name,
+				"listen":        s.Key,
+				"backend":       backend.Key,
+			}).Warn("proxy: Ejecting stream backend after connection errors")
+		}
+	}
+
+	backend = nil
+	conn = nil
+	err = &errortypes.ConnectionError{
+		errors.New("proxy: No stream backend available"),
+	}
+
+	return
+}
+
+func (s *Stream) track(backend *StreamBackend, conns ...net.Conn) bool {
+	s.connsLock.Lock()
+	defer s.connsLock.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	for _, conn := range conns {
+		s.conns[conn] = backend
+	}
+
+	return true
+}
+
+func (s *Stream) untrack(conns ...net.Conn) {
+	s.connsLock.Lock()
+	for _, conn := range conns {
+		delete(s.conns, conn)
+	}
+	s.connsLock.Unlock()
+}
+
+func (s *Stream) serveTcp(conn net.Conn) {
+	defer conn.Close()
+
+	backend, dest, err := s.dial("tcp", conn.RemoteAddr())
+	if err != nil {
+		return
+	}
+	defer dest.Close()
+
+	atomic.AddInt32(backend.Connections, 1)
+	atomic.AddInt64(backend.ConnectionsTotal, 1)
+	defer atomic.AddInt32(backend.Connections, -1)
+
+	if !s.track(backend, conn, dest) {
+		return
+	}
+	defer s.untrack(conn, dest)
+
+	header := proxyProtoHeader(s.ProxyProtocol, conn)
+	if header != nil {
+		_, err = dest.Write(header)
+		if err != nil {
+			atomic.AddInt64(backend.Errors, 1)
+			return
+		}
+	}
+
+	timeout := time.Duration(settings.Router.StreamIdleTimeout) * time.Second
+	lastActive := time.Now().UnixNano()
+
+	waiter := sync.WaitGroup{}
+	waiter.Add(2)
+
+	go func() {
+		defer waiter.Done()
+		if copyTcp(dest, conn, timeout, &lastActive) {
+			_ = dest.Close()
+			_ = conn.Close()
+		} else if tcpConn, ok := dest.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		}
+	}()
+
+	go func() {
+		defer waiter.Done()
+		if copyTcp(conn, dest, timeout, &lastActive) {
+			_ = dest.Close()
+			_ = conn.Close()
+		} else if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		}
+	}()
+
+	waiter.Wait()
+}
+
+// copyTcp copies until the source is closed or both directions of the
+// connection have been idle for the timeout, returns true on idle timeout
+// or error
+func copyTcp(dst, src net.Conn, timeout time.Duration,
+	lastActive *int64) (closed bool) {
+
+	buf := make([]byte, 32*1024)
+
+	for {
+		_ = src.SetReadDeadline(time.Now().Add(timeout))
+
+		n, err := src.Read(buf)
+		if n > 0 {
+			atomic.StoreInt64(lastActive, time.Now().UnixNano())
+
+			_ = dst.SetWriteDeadline(time.Now().Add(timeout))
+			_, e := dst.Write(buf[:n])
+			if e != nil {
+				closed = true
+				return
+			}
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				return
+			}
+
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				last := time.Unix(0, atomic.LoadInt64(lastActive))
+				if time.Since(last) < timeout {
+					continue
+				}
+			}
+
+			closed = true
+			return
+		}
+	}
+}
+
+func (s *Stream) runTcp(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		maxConns := int32(settings.Router.StreamMaxConns)
+		if maxConns > 0 && atomic.LoadInt32(&s.active) >= maxConns {
+			_ = conn.Close()
+			continue
+		}
+
+		atomic.AddInt32(&s.active, 1)
+		go func() {
+			defer atomic.AddInt32(&s.active, -1)
+			s.serveTcp(conn)
+		}()
+	}
+}
+
+func (s *Stream) closeSession(key string, sess *udpSession) {
+	s.connsLock.Lock()
+	if s.sessions[key] == sess {
+		delete(s.sessions, key)
+	}
+	s.connsLock.Unlock()
+
+	_ = sess.conn.Close()
+	atomic.AddInt32(sess.backend.Connections, -1)
+}
+
+func (s *Stream) replyUdp(key string, sess *udpSession) {
+	defer s.closeSession(key, sess)
+
+	timeout := time.Duration(settings.Router.UdpSessionTimeout) * time.Second
+	buf := make([]byte, 65535)
+
+	for {
+		_ = sess.conn.SetReadDeadline(time.Now().Add(timeout))
+
+		n, err := sess.conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				lastActive := time.Unix(
+					0, atomic.LoadInt64(&sess.lastActive))
+				if time.Since(lastActive) < timeout {
+					continue
+				}
+			} else {
+				atomic.AddInt64(sess.backend.Errors, 1)
+			}
+			return
+		}
+
+		atomic.StoreInt64(&sess.lastActive, time.Now().UnixNano())
+
+		_, err = sess.packetConn.WriteTo(buf[:n], sess.client)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// evictSession removes the least recently active session when the session
+// limit is reached, must be called with the conns lock held
+func (s *Stream) evictSession() (evicted *udpSession) {
+	maxSessions := settings.Router.UdpMaxSessions
+	if maxSessions <= 0 || len(s.sessions) < maxSessions {
+		return
+	}
+
+	evictedKey := ""
+	for key, sess := range s.sessions {
+		if evicted == nil || atomic.LoadInt64(&sess.lastActive) <
+			atomic.LoadInt64(&evicted.lastActive) {
+
+			evicted = sess
+			evictedKey = key
+		}
+	}
+
+	if evicted != nil {
+		delete(s.sessions, evictedKey)
+	}
+
+	return
+}
+
+func (s *Stream) session(packetConn net.PacketConn, client net.Addr) (
+	sess *udpSession) {
+
+	key := client.String()
+
+	s.connsLock.Lock()
+	sess = s.sessions[key]
+	var evicted *udpSession
+	if sess == nil {
+		evicted = s.evictSession()
+	}
+	s.connsLock.Unlock()
+	if evicted != nil {
+		_ = evicted.conn.Close()
+	}
+	if sess != nil {
+		return
+	}
+
+	backend, conn, err := s.dial("udp", client)
+	if err != nil {
+		return
+	}
+
+	sess = &udpSession{
+		conn:       conn,
+		packetConn: packetConn,
+		client:     client,
+		backend:    backend,
+		lastActive: time.Now().UnixNano(),
+	}
+
+	s.connsLock.Lock()
+	if s.closed {
+		s.connsLock.Unlock()
+		_ = conn.Close()
+		sess = nil
+		return
+	}
+	s.sessions[key] = sess
+	s.connsLock.Unlock()
+
+	atomic.AddInt32(backend.Connections, 1)
+	atomic.AddInt64(backend.ConnectionsTotal, 1)
+
+	go s.replyUdp(key, sess)
+
+	return
+}
+
+func (s *Stream) runUdp(packetConn net.PacketConn) {
+	buf := make([]byte, 65535)
+
+	for {
+		n, client, err := packetConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		sess := s.session(packetConn, client)
+		if sess == nil {
+			continue
+		}
+
+		atomic.StoreInt64(&sess.lastActive, time.Now().UnixNano())
+
+		_, err = sess.conn.Write(buf[:n])
+		if err != nil {
+			atomic.AddInt64(sess.backend.Errors, 1)
+		}
+	}
+}
+
+func (s *Stream) checkBackend(backend *StreamBackend,
+	healthConf *HealthConfig) {
+
+	dialer := &net.Dialer{
+		Timeout: healthConf.Timeout,
+		Control: backendControl,
+	}
+
+	conn, err := dialer.Dial("tcp", backend.CheckAddress)
+	if err != nil {
+		if backend.Health.Failure() >= healthConf.UnhealthyThreshold {
+			s.setState(backend, Offline)
+		}
+		return
+	}
+	_ = conn.Close()
+
+	if backend.Health.Success() >= healthConf.HealthyThreshold &&
+		!backend.Health.Ejected() {
+
+		s.setState(backend, Online)
+	}
+}
+
+// CheckDrain closes the remaining connections and sessions of backends
+// that have reached the drain timeout.
+func (s *Stream) CheckDrain() {
+	expired := set.NewSet()
+
+	s.Lock.Lock()
+	for _, backend := range s.Backends {
+		if backend.Drain.Expire() {
+			expired.Add(backend)
+		}
+	}
+	s.Lock.Unlock()
+
+	if expired.Len() == 0 {
+		return
+	}
+
+	s.connsLock.Lock()
+	for conn, backend := range s.conns {
+		if expired.Contains(backend) {
+			_ = conn.Close()
+		}
+	}
+	for _, sess := range s.sessions {
+		if expired.Contains(sess.backend) {
+			_ = sess.conn.Close()
+		}
+	}
+	s.connsLock.Unlock()
+}
+
+func (s *Stream) Check() {
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+
+	if time.Since(s.LastCheck) < s.HealthConfig.Interval {
+		return
+	}
+	s.LastCheck = time.Now()
+
+	for _, backend := range s.Backends {
+		if backend.CheckAddress == "" {
+			continue
+		}
+
+		go s.checkBackend(backend, s.HealthConfig)
+	}
+}
+
+func (s *Stream) Start() (err error) {
+	if len(s.Addresses) == 0 {
+		err = &errortypes.NotFoundError{
+			errors.New("proxy: No public address for stream"),
+		}
+		return
+	}
+
+	for _, address := range s.Addresses {
+		addr := net.JoinHostPort(address, strconv.Itoa(s.Port))
+
+		switch s.Protocol {
+		case balancer.Tcp:
+			listener, e := net.Listen("tcp", addr)
+			if e != nil {
+				err = &errortypes.NetworkError{
+					errors.Wrap(e, "proxy: Failed to listen on stream port"),
+				}
+				s.Close()
+				return
+			}
+			s.listeners = append(s.listeners, listener)
+
+			go s.runTcp(listener)
+			break
+		case balancer.Udp:
+			packetConn, e := net.ListenPacket("udp", addr)
+			if e != nil {
+				err = &errortypes.NetworkError{
+					errors.Wrap(e, "proxy: Failed to listen on stream port"),
+				}
+				s.Close()
+				return
+			}
+			s.packetConns = append(s.packetConns, packetConn)
+
+			go s.runUdp(packetConn)
+			break
+		default:
+			err = &errortypes.UnknownError{
+				errors.Newf("proxy: Unknown stream protocol '%s'",
+					s.Protocol),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (s *Stream) Close() {
+	for _, listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for _, packetConn := range s.packetConns {
+		_ = packetConn.Close()
+	}
+
+	s.connsLock.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = map[net.Conn]*StreamBackend{}
+	for _, sess := range s.sessions {
+		_ = sess.conn.Close()
+	}
+	s.connsLock.Unlock()
+}
+
+func NewStream(balnc *balancer.Balancer, port int) (s *Stream) {
+	s = &Stream{
+		Key:       streamKey(balnc.Type, port),
+		Protocol:  balnc.Type,
+		Port:      port,
+		Addresses: streamAddresses(),
+		Backends:  []*StreamBackend{},
+		conns:     map[net.Conn]*StreamBackend{},
+		sessions:  map[string]*udpSession{},
+	}
+	s.Update(balnc)
+
+	return
+}
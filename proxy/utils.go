@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"hash/fnv"
+	"math"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
@@ -16,3 +18,16 @@ func WriteError(w http.ResponseWriter, r *http.Request, code int, err error) {
 		"error":  err,
 	}).Error("proxy: Serve error")
 }
+
+// affinityScore returns the weighted rendezvous hash score of the backend
+// key for the client.
+func affinityScore(client, key string, weight int) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(client))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+
+	u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+
+	return float64(weight) / -math.Log(u)
+}
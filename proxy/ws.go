@@ -12,6 +12,7 @@ type webSocketConn struct {
 	r     *http.Request
 	back  *websocket.Conn
 	front *websocket.Conn
+	drain *DrainState
 }
 
 func (w *webSocketConn) Run(domain *Domain) {
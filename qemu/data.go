@@ -92,12 +92,22 @@ func writeOvmfVars(virt *vm.VirtualMachine) (err error) {
 }
 
 func writeService(virt *vm.VirtualMachine) (err error) {
+	err = writeUnit(virt, false)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func writeUnit(virt *vm.VirtualMachine, incoming bool) (err error) {
 	unitPath := paths.GetUnitPath(virt.Id)
 
 	qm, err := NewQemu(virt)
 	if err != nil {
 		return
 	}
+	qm.Incoming = incoming
 
 	output, err := qm.Marshal()
 	if err != nil {
@@ -118,27 +128,8 @@ func writeService(virt *vm.VirtualMachine) (err error) {
 }
 
 func Destroy(db *database.Database, virt *vm.VirtualMachine) (err error) {
-	vmPath := paths.GetVmPath(virt.Id)
 	unitName := paths.GetUnitName(virt.Id)
 	unitPath := paths.GetUnitPath(virt.Id)
-	unitPathServer4 := paths.GetUnitPathDhcp4(virt.Id, 0)
-	unitPathServer6 := paths.GetUnitPathDhcp6(virt.Id, 0)
-	unitPathServerNdp := paths.GetUnitPathNdp(virt.Id, 0)
-	tpmPath := paths.GetTpmPath(virt.Id)
-	unitPathTpm := paths.GetUnitPathTpm(virt.Id)
-	sockPath := paths.GetSockPath(virt.Id)
-	sockQmpPath := paths.GetQmpSockPath(virt.Id)
-	// TODO Backward compatibility
-	sockPathOld := paths.GetSockPath(virt.Id)
-	guestPath := paths.GetGuestPath(virt.Id)
-	// TODO Backward compatibility
-	guestPathOld := paths.GetGuestPathOld(virt.Id)
-	pidPath := paths.GetPidPath(virt.Id)
-	// TODO Backward compatibility
-	pidPathOld := paths.GetPidPathOld(virt.Id)
-	ovmfVarsPath := paths.GetOvmfVarsPath(virt.Id)
-	hugepagesPath := paths.GetHugepagePath(virt.Id)
-	cachePath := paths.GetCacheDir(virt.Id)
 
 	logrus.WithFields(logrus.Fields{
 		"id": virt.Id.Hex(),
@@ -307,6 +298,36 @@ func Destroy(db *database.Database, virt *vm.VirtualMachine) (err error) {
 		}
 	}
 
+	err = removeFiles(virt)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func removeFiles(virt *vm.VirtualMachine) (err error) {
+	vmPath := paths.GetVmPath(virt.Id)
+	unitPath := paths.GetUnitPath(virt.Id)
+	unitPathServer4 := paths.GetUnitPathDhcp4(virt.Id, 0)
+	unitPathServer6 := paths.GetUnitPathDhcp6(virt.Id, 0)
+	unitPathServerNdp := paths.GetUnitPathNdp(virt.Id, 0)
+	tpmPath := paths.GetTpmPath(virt.Id)
+	unitPathTpm := paths.GetUnitPathTpm(virt.Id)
+	sockPath := paths.GetSockPath(virt.Id)
+	sockQmpPath := paths.GetQmpSockPath(virt.Id)
+	// TODO Backward compatibility
+	sockPathOld := paths.GetSockPath(virt.Id)
+	guestPath := paths.GetGuestPath(virt.Id)
+	// TODO Backward compatibility
+	guestPathOld := paths.GetGuestPathOld(virt.Id)
+	pidPath := paths.GetPidPath(virt.Id)
+	// TODO Backward compatibility
+	pidPathOld := paths.GetPidPathOld(virt.Id)
+	ovmfVarsPath := paths.GetOvmfVarsPath(virt.Id)
+	hugepagesPath := paths.GetHugepagePath(virt.Id)
+	cachePath := paths.GetCacheDir(virt.Id)
+
 	err = utils.RemoveAll(vmPath)
 	if err != nil {
 		return
@@ -417,9 +438,11 @@ func Destroy(db *database.Database, virt *vm.VirtualMachine) (err error) {
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 	store.RemAddress(virt.Id)
 	store.RemRoutes(virt.Id)
 	store.RemArp(virt.Id)
+	store.RemPeers(virt.Id)
 
 	return
 }
@@ -441,6 +464,7 @@ func Cleanup(db *database.Database, virt *vm.VirtualMachine) {
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 
 	return
 }
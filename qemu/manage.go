@@ -24,6 +24,7 @@ import (
 	"github.com/pritunl/pritunl-cloud/node"
 	"github.com/pritunl/pritunl-cloud/paths"
 	"github.com/pritunl/pritunl-cloud/pool"
+	"github.com/pritunl/pritunl-cloud/qga"
 	"github.com/pritunl/pritunl-cloud/qmp"
 	"github.com/pritunl/pritunl-cloud/qms"
 	"github.com/pritunl/pritunl-cloud/settings"
@@ -201,6 +202,27 @@ func GetVmInfo(db *database.Database, vmId primitive.ObjectID,
 		}
 	}
 
+	if virt.State == vm.Running && queryQms {
+		guestStore, ok := store.GetGuest(vmId)
+		if !ok || time.Since(guestStore.Timestamp) > refreshRate {
+			guestOnline := guestStore.Online
+
+			e := qga.Ping(paths.GetGuestPath(vmId))
+			if e != nil {
+				virt.GuestStatus = vm.GuestOffline
+			} else {
+				virt.GuestStatus = vm.GuestOnline
+				guestOnline = time.Now()
+			}
+			virt.GuestTimestamp = guestOnline
+
+			store.SetGuest(vmId, virt.GuestStatus, guestOnline)
+		} else {
+			virt.GuestStatus = guestStore.Status
+			virt.GuestTimestamp = guestStore.Online
+		}
+	}
+
 	addrStore, ok := store.GetAddress(virt.Id)
 	if !ok {
 		addr := ""
@@ -381,19 +403,24 @@ func GetVms(db *database.Database,
 			}
 
 			if virt != nil {
+				// Virtual machines without a local instance are incoming
+				// migrations owned by the source node
 				inst := instMap[vmId]
-				if inst != nil && inst.VmState == vm.Running &&
-					(virt.State == vm.Stopped || virt.State == vm.Failed) {
-
-					inst.State = instance.Cleanup
-					e = virt.CommitState(db, instance.Cleanup)
-				} else {
-					e = virt.Commit(db)
-				}
-				if e != nil {
-					logrus.WithFields(logrus.Fields{
-						"error": e,
-					}).Error("qemu: Failed to commit VM state")
+				if inst != nil {
+					if inst.VmState == vm.Running &&
+						(virt.State == vm.Stopped ||
+							virt.State == vm.Failed) {
+
+						inst.State = instance.Cleanup
+						e = virt.CommitState(db, instance.Cleanup)
+					} else {
+						e = virt.Commit(db)
+					}
+					if e != nil {
+						logrus.WithFields(logrus.Fields{
+							"error": e,
+						}).Error("qemu: Failed to commit VM state")
+					}
 				}
 
 				virtsLock.Lock()
@@ -669,6 +696,7 @@ func Create(db *database.Database, inst *instance.Instance,
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 
 	return
 }
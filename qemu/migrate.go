@@ -0,0 +1,528 @@
+package qemu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/cloudinit"
+	"github.com/pritunl/pritunl-cloud/constants"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/dhcps"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/lvm"
+	"github.com/pritunl/pritunl-cloud/paths"
+	"github.com/pritunl/pritunl-cloud/qmp"
+	"github.com/pritunl/pritunl-cloud/settings"
+	"github.com/pritunl/pritunl-cloud/store"
+	"github.com/pritunl/pritunl-cloud/systemd"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
+)
+
+func initMigrateDisks(inst *instance.Instance,
+	virt *vm.VirtualMachine) (err error) {
+
+	sizes := map[string]int64{}
+	for _, dsk := range inst.MigrateDisks {
+		sizes[dsk.Id.Hex()] = dsk.Size
+	}
+
+	if len(virt.Disks) > 0 {
+		err = utils.ExistsMkdir(paths.GetDisksPath(), 0755)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, dsk := range virt.Disks {
+		size, ok := sizes[dsk.Id.Hex()]
+		if !ok || size == 0 {
+			err = &errortypes.NotFoundError{
+				errors.Newf("qemu: Missing migrate disk size for '%s'",
+					dsk.Id.Hex()),
+			}
+			return
+		}
+
+		_ = os.Remove(dsk.Path)
+
+		_, err = utils.ExecCombinedOutputLogged(nil,
+			"qemu-img", "create", "-f", "qcow2",
+			dsk.Path, fmt.Sprintf("%d", size))
+		if err != nil {
+			return
+		}
+	}
+
+	for _, device := range virt.DriveDevices {
+		if device.Type != vm.Lvm {
+			continue
+		}
+
+		err = lvm.ActivateLv(device.VgName, device.LvName)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// PowerOnIncoming starts the virtual machine paused waiting for an
+// incoming migration. Qcow2 disks are created empty and filled by the
+// block mirror from the source node.
+func PowerOnIncoming(db *database.Database, inst *instance.Instance,
+	virt *vm.VirtualMachine) (err error) {
+
+	unitName := paths.GetUnitName(virt.Id)
+	namespace := vm.GetNamespace(virt.Id, 0)
+
+	if constants.Interrupt {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Info("qemu: Starting incoming virtual machine")
+
+	if inst.Vnc {
+		err = inst.InitVncDisplay(db)
+		if err != nil {
+			return
+		}
+		virt.VncDisplay = inst.VncDisplay
+	}
+
+	if inst.Spice {
+		err = inst.InitSpicePort(db)
+		if err != nil {
+			return
+		}
+		virt.SpicePort = inst.SpicePort
+	}
+
+	err = utils.ExistsMkdir(settings.Hypervisor.RunPath, 0755)
+	if err != nil {
+		return
+	}
+
+	err = initMigrateDisks(inst, virt)
+	if err != nil {
+		return
+	}
+
+	err = cloudinit.Write(db, inst, virt, false)
+	if err != nil {
+		return
+	}
+
+	err = initCache(virt)
+	if err != nil {
+		return
+	}
+
+	err = initHugepage(virt)
+	if err != nil {
+		return
+	}
+
+	err = writeOvmfVars(virt)
+	if err != nil {
+		return
+	}
+
+	err = writeUnit(virt, true)
+	if err != nil {
+		return
+	}
+
+	err = initPermissions(virt)
+	if err != nil {
+		return
+	}
+
+	if !virt.HasExternalNetwork() {
+		_, err = utils.ExecCombinedOutputLogged([]string{
+			"File exists",
+		}, "ip", "netns", "add", namespace)
+		if err != nil {
+			return
+		}
+	}
+
+	err = systemd.Start(unitName)
+	if err != nil {
+		return
+	}
+
+	err = Wait(db, virt)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// FinishIncoming configures the network of a migrated virtual machine
+// and announces the new location to the vxlan forwarding tables.
+func FinishIncoming(db *database.Database, inst *instance.Instance,
+	virt *vm.VirtualMachine) (err error) {
+
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Info("qemu: Finishing incoming virtual machine")
+
+	_ = qmp.NbdStop(virt.Id)
+
+	if virt.DhcpServer {
+		err = dhcps.Start(db, virt)
+		if err != nil {
+			return
+		}
+	}
+
+	if virt.Vnc {
+		err = qmp.VncPassword(virt.Id, inst.VncPassword)
+		if err != nil {
+			return
+		}
+	}
+
+	if virt.Spice {
+		err = qmp.SetPassword(virt.Id, qmp.Spice, inst.SpicePassword)
+		if err != nil {
+			return
+		}
+	}
+
+	err = NetworkConf(db, virt)
+	if err != nil {
+		return
+	}
+
+	err = qmp.AnnounceSelf(virt.Id)
+	if err != nil {
+		return
+	}
+
+	store.RemVirt(virt.Id)
+	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
+	store.RemAddress(virt.Id)
+
+	return
+}
+
+// AbortIncoming stops an incoming virtual machine and removes the local
+// disks created for the migration. Disks and database state are still
+// owned by the source node.
+func AbortIncoming(db *database.Database, virt *vm.VirtualMachine) (
+	err error) {
+
+	unitName := paths.GetUnitName(virt.Id)
+
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Warn("qemu: Aborting incoming virtual machine")
+
+	err = systemd.Stop(unitName)
+	if err != nil {
+		return
+	}
+
+	err = NetworkConfClear(db, virt)
+	if err != nil {
+		return
+	}
+
+	err = releaseDisks(virt)
+	if err != nil {
+		return
+	}
+
+	err = removeFiles(virt)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// ReleaseMigrated stops the source virtual machine once the destination
+// node has taken ownership and removes the local unit, network and disks.
+func ReleaseMigrated(db *database.Database, virt *vm.VirtualMachine) (
+	err error) {
+
+	unitName := paths.GetUnitName(virt.Id)
+
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Info("qemu: Releasing migrated virtual machine")
+
+	err = systemd.Stop(unitName)
+	if err != nil {
+		return
+	}
+
+	err = NetworkConfClear(db, virt)
+	if err != nil {
+		return
+	}
+
+	err = releaseDisks(virt)
+	if err != nil {
+		return
+	}
+
+	err = removeFiles(virt)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func releaseDisks(virt *vm.VirtualMachine) (err error) {
+	for _, dsk := range virt.Disks {
+		err = utils.RemoveAll(dsk.Path)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, device := range virt.DriveDevices {
+		if device.Type != vm.Lvm {
+			continue
+		}
+
+		err = lvm.DeactivateLv(device.VgName, device.LvName)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+type offlineDiskInfo struct {
+	VirtualSize int64 `json:"virtual-size"`
+}
+
+// DiskExport serves an empty disk with qemu-nbd on the destination node
+// of an offline migration.
+type DiskExport struct {
+	Disk   primitive.ObjectID
+	Socket string
+	cmd    *exec.Cmd
+	done   chan error
+}
+
+func (e *DiskExport) Close() {
+	_ = e.cmd.Process.Kill()
+	<-e.done
+	_ = os.Remove(e.Socket)
+}
+
+func (e *DiskExport) wait() (err error) {
+	start := time.Now()
+
+	for {
+		select {
+		case exitErr := <-e.done:
+			err = &errortypes.ExecError{
+				errors.Newf("qemu: Disk export exited early %v", exitErr),
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		exists := false
+		exists, err = utils.Exists(e.Socket)
+		if err != nil {
+			return
+		}
+
+		if exists {
+			return
+		}
+
+		if time.Since(start) > 10*time.Second {
+			err = &errortypes.TimeoutError{
+				errors.New("qemu: Disk export socket timeout"),
+			}
+			return
+		}
+	}
+}
+
+// GetOfflineDiskSizes returns the virtual size of the qcow2 disks of a
+// stopped virtual machine.
+func GetOfflineDiskSizes(virt *vm.VirtualMachine) (
+	sizes map[primitive.ObjectID]int64, err error) {
+
+	sizes = map[primitive.ObjectID]int64{}
+
+	for _, dsk := range virt.Disks {
+		output, e := utils.ExecOutput("",
+			"qemu-img", "info", "--output=json", dsk.Path)
+		if e != nil {
+			err = e
+			return
+		}
+
+		info := &offlineDiskInfo{}
+		err = json.Unmarshal([]byte(output), info)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "qemu: Failed to parse qemu disk info"),
+			}
+			return
+		}
+
+		sizes[dsk.Id] = info.VirtualSize
+	}
+
+	return
+}
+
+// ExportOfflineDisks creates the disks of an offline migration on the
+// destination node and exports each disk with qemu-nbd for the source
+// node to copy into.
+func ExportOfflineDisks(inst *instance.Instance,
+	virt *vm.VirtualMachine) (exports []*DiskExport, err error) {
+
+	exports = []*DiskExport{}
+
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Info("qemu: Exporting offline migration disks")
+
+	err = initMigrateDisks(inst, virt)
+	if err != nil {
+		return
+	}
+
+	if len(virt.Disks) == 0 {
+		return
+	}
+
+	err = utils.ExistsMkdir(paths.GetCacheDir(virt.Id), 0755)
+	if err != nil {
+		return
+	}
+
+	for _, dsk := range virt.Disks {
+		sockPath := paths.GetMigrateDiskSockPath(virt.Id, dsk.Id)
+		_ = os.Remove(sockPath)
+
+		cmd := exec.Command("qemu-nbd",
+			"--format=qcow2",
+			"--socket="+sockPath,
+			"--persistent",
+			"--shared=1",
+			"--cache=none",
+			dsk.Path,
+		)
+
+		err = cmd.Start()
+		if err != nil {
+			err = &errortypes.ExecError{
+				errors.Wrap(err, "qemu: Failed to start disk export"),
+			}
+			break
+		}
+
+		export := &DiskExport{
+			Disk:   dsk.Id,
+			Socket: sockPath,
+			cmd:    cmd,
+			done:   make(chan error, 1),
+		}
+		go func() {
+			export.done <- cmd.Wait()
+		}()
+		exports = append(exports, export)
+
+		err = export.wait()
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		for _, export := range exports {
+			export.Close()
+		}
+		exports = nil
+		return
+	}
+
+	return
+}
+
+// CopyOfflineDisk writes a stopped disk to the qemu-nbd export of the
+// destination node. The copy is flattened and no longer uses a backing
+// image.
+func CopyOfflineDisk(dskPath, sockPath string) (err error) {
+	_, err = utils.ExecCombinedOutputLogged(nil,
+		"qemu-img", "convert", "-n",
+		"-f", "qcow2", "-O", "raw",
+		dskPath, fmt.Sprintf("nbd+unix:///?socket=%s", sockPath))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// FinishOffline writes the unit of an offline migrated virtual machine on
+// the destination node. The instance is then started by the deploy with
+// the copied disks.
+func FinishOffline(virt *vm.VirtualMachine) (err error) {
+
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Info("qemu: Finishing offline virtual machine migration")
+
+	err = utils.ExistsMkdir(settings.Hypervisor.RunPath, 0755)
+	if err != nil {
+		return
+	}
+
+	err = writeOvmfVars(virt)
+	if err != nil {
+		return
+	}
+
+	err = writeService(virt)
+	if err != nil {
+		return
+	}
+
+	store.RemVirt(virt.Id)
+	store.RemDisks(virt.Id)
+
+	return
+}
+
+// AbortOffline removes the disks created on the destination node for an
+// offline migration.
+func AbortOffline(virt *vm.VirtualMachine) (err error) {
+	logrus.WithFields(logrus.Fields{
+		"id": virt.Id.Hex(),
+	}).Warn("qemu: Aborting offline virtual machine migration")
+
+	err = releaseDisks(virt)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -144,6 +144,7 @@ func PowerOn(db *database.Database, inst *instance.Instance,
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 
 	return
 }
@@ -237,6 +238,7 @@ func PowerOff(db *database.Database, virt *vm.VirtualMachine) (err error) {
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 
 	return
 }
@@ -274,6 +276,7 @@ func ForcePowerOffErr(db *database.Database, virt *vm.VirtualMachine,
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 
 	return
 }
@@ -310,6 +313,7 @@ func ForcePowerOff(db *database.Database, virt *vm.VirtualMachine) (
 
 	store.RemVirt(virt.Id)
 	store.RemDisks(virt.Id)
+	store.RemGuest(virt.Id)
 
 	return
 }
@@ -96,6 +96,7 @@ type Qemu struct {
 	PciDevices   []*PciDevice
 	DriveDevices []*DriveDevice
 	IscsiDevices []*IscsiDevice
+	Incoming     bool
 }
 
 func (q *Qemu) GetDiskQueues() (queues int) {
@@ -638,6 +639,11 @@ func (q *Qemu) Marshal() (output string, err error) {
 		}
 	}
 
+	if q.Incoming {
+		cmd = append(cmd, "-incoming")
+		cmd = append(cmd, "defer")
+	}
+
 	compositorEnv := ""
 	if q.Gui {
 		compositorEnv, err = compositor.GetEnv(q.GuiUser)
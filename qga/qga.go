@@ -15,6 +15,15 @@ type Command struct {
 	Execute string `json:"execute"`
 }
 
+type ResponseError struct {
+	Class       string `json:"class"`
+	Description string `json:"desc"`
+}
+
+type Response struct {
+	Error *ResponseError `json:"error"`
+}
+
 type Address struct {
 	Type    string `json:"ip-address-type"`
 	Address string `json:"ip-address"`
@@ -123,3 +132,75 @@ func GetInterfaces(sockPath string) (ifaces *Interfaces, err error) {
 
 	return
 }
+
+func Ping(sockPath string) (err error) {
+	conn, err := net.DialTimeout(
+		"unix",
+		sockPath,
+		1*time.Second,
+	)
+	if err != nil {
+		err = &errortypes.ConnectionError{
+			errors.Wrap(err, "qga: Failed to connect to guest agent"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if err != nil {
+		return
+	}
+
+	cmd := &Command{
+		Execute: "guest-ping",
+	}
+
+	cmdByte, err := json.Marshal(cmd)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "qga: Failed to parse guest agent command"),
+		}
+		return
+	}
+
+	_, err = conn.Write(cmdByte)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "qga: Failed to write to guest agent"),
+		}
+		return
+	}
+
+	buffer := make([]byte, 4096)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "qga: Failed to read from guest agent"),
+		}
+		return
+	}
+	buffer = buffer[:n]
+
+	respByt := bytes.Trim(buffer, "\x00")
+	respByt = bytes.TrimSpace(respByt)
+
+	resp := &Response{}
+	err = json.Unmarshal(respByt, resp)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "qga: Failed to parse guest agent response"),
+		}
+		return
+	}
+
+	if resp.Error != nil {
+		err = &errortypes.RequestError{
+			errors.Newf("qga: Guest agent ping error '%s'",
+				resp.Error.Description),
+		}
+		return
+	}
+
+	return
+}
@@ -20,16 +20,26 @@ type driveBackupArgs struct {
 }
 
 type blockDeviceImage struct {
-	Filename string `json:"filename"`
+	Filename    string `json:"filename"`
+	VirtualSize int64  `json:"virtual-size"`
+}
+
+type blockDirtyBitmap struct {
+	Name         string `json:"name"`
+	Recording    bool   `json:"recording"`
+	Persistent   bool   `json:"persistent"`
+	Inconsistent bool   `json:"inconsistent"`
 }
 
 type blockDeviceInserted struct {
-	Image blockDeviceImage `json:"image"`
+	Image        blockDeviceImage    `json:"image"`
+	DirtyBitmaps []*blockDirtyBitmap `json:"dirty-bitmaps"`
 }
 
 type blockDevice struct {
-	Device   string              `json:"device"`
-	Inserted blockDeviceInserted `json:"inserted"`
+	Device       string              `json:"device"`
+	Inserted     blockDeviceInserted `json:"inserted"`
+	DirtyBitmaps []*blockDirtyBitmap `json:"dirty-bitmaps"`
 }
 
 type blockDeviceReturn struct {
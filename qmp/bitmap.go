@@ -0,0 +1,287 @@
+package qmp
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+const BackupBitmap = "pritunl-backup"
+
+type bitmapArgs struct {
+	Node       string `json:"node"`
+	Name       string `json:"name"`
+	Persistent bool   `json:"persistent,omitempty"`
+}
+
+type incrementalBackupArgs struct {
+	JobId       string `json:"job-id"`
+	Device      string `json:"device"`
+	Sync        string `json:"sync"`
+	Target      string `json:"target"`
+	Format      string `json:"format"`
+	Bitmap      string `json:"bitmap,omitempty"`
+	AutoDismiss bool   `json:"auto-dismiss"`
+}
+
+type transactionAction struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type transactionArgs struct {
+	Actions []*transactionAction `json:"actions"`
+}
+
+type jobDismissArgs struct {
+	Id string `json:"id"`
+}
+
+func driveGetBitmap(vmId primitive.ObjectID, dsk *disk.Disk) (
+	name string, bitmap *blockDirtyBitmap, err error) {
+
+	cmd := &Command{
+		Execute: "query-block",
+	}
+
+	returnData := &blockDeviceReturn{}
+	err = RunCommand(vmId, cmd, returnData)
+	if err != nil {
+		return
+	}
+
+	if returnData.Error != nil {
+		err = &errortypes.ApiError{
+			errors.Newf("qmp: Return error %s", returnData.Error.Desc),
+		}
+		return
+	}
+
+	if returnData.Return == nil {
+		err = &errortypes.ParseError{
+			errors.Newf("qmp: Return nil"),
+		}
+		return
+	}
+
+	for _, blockDev := range returnData.Return {
+		idStr := strings.Split(path.Base(
+			blockDev.Inserted.Image.Filename), ".")[0]
+
+		diskId, e := primitive.ObjectIDFromHex(idStr)
+		if e != nil || diskId != dsk.Id {
+			continue
+		}
+
+		name = blockDev.Device
+
+		bitmaps := blockDev.Inserted.DirtyBitmaps
+		if len(bitmaps) == 0 {
+			bitmaps = blockDev.DirtyBitmaps
+		}
+
+		for _, btmp := range bitmaps {
+			if btmp.Name == BackupBitmap {
+				bitmap = btmp
+				break
+			}
+		}
+		break
+	}
+
+	return
+}
+
+func runSimple(vmId primitive.ObjectID, cmd *Command) (err error) {
+	returnData := &CommandReturn{}
+	err = RunCommand(vmId, cmd, returnData)
+	if err != nil {
+		return
+	}
+
+	if returnData.Error != nil {
+		err = &errortypes.ApiError{
+			errors.Newf("qmp: Return error %s", returnData.Error.Desc),
+		}
+		return
+	}
+
+	return
+}
+
+func backupJobWait(vmId primitive.ObjectID, jobId string) (err error) {
+	for {
+		cmd := &Command{
+			Execute: "query-jobs",
+		}
+
+		returnData := &JobStatusReturn{}
+		err = RunCommand(vmId, cmd, returnData)
+		if err != nil {
+			return
+		}
+
+		if returnData.Error != nil {
+			err = &errortypes.ApiError{
+				errors.Newf("qmp: Return error %s",
+					returnData.Error.Desc),
+			}
+			return
+		}
+
+		var job *JobStatus
+		for _, status := range returnData.Return {
+			if status.Id == jobId {
+				job = status
+				break
+			}
+		}
+
+		if job == nil {
+			err = &errortypes.ApiError{
+				errors.Newf("qmp: Backup job %s lost", jobId),
+			}
+			return
+		}
+
+		if job.Status == "concluded" {
+			_ = runSimple(vmId, &Command{
+				Execute: "job-dismiss",
+				Arguments: &jobDismissArgs{
+					Id: jobId,
+				},
+			})
+
+			if job.Error != "" {
+				err = &errortypes.ApiError{
+					errors.Newf("qmp: Backup job error %s", job.Error),
+				}
+			}
+			return
+		}
+
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// BackupDiskIncremental writes an incremental backup of the changes
+// tracked since the last backup when a consistent dirty bitmap exists
+// and full is not set. Otherwise a full backup is written and a new
+// bitmap is started in the same transaction.
+func BackupDiskIncremental(vmId primitive.ObjectID, dsk *disk.Disk,
+	destPth string, full bool) (incremental bool, err error) {
+
+	deviceName, bitmap, err := driveGetBitmap(vmId, dsk)
+	if err != nil {
+		return
+	}
+
+	if deviceName == "" {
+		err = &DiskNotFound{
+			errors.Newf("qmp: Disk not found %s", dsk.Id.Hex()),
+		}
+		return
+	}
+
+	jobId := "backup-" + dsk.Id.Hex()
+
+	if !full && bitmap != nil && !bitmap.Inconsistent && bitmap.Recording {
+		logrus.WithFields(logrus.Fields{
+			"instance_id": vmId.Hex(),
+			"disk_id":     dsk.Id.Hex(),
+		}).Info("qmp: Incremental disk backup")
+
+		err = runSimple(vmId, &Command{
+			Execute: "drive-backup",
+			Arguments: &incrementalBackupArgs{
+				JobId:       jobId,
+				Device:      deviceName,
+				Sync:        "incremental",
+				Target:      destPth,
+				Format:      "qcow2",
+				Bitmap:      BackupBitmap,
+				AutoDismiss: false,
+			},
+		})
+		if err != nil {
+			return
+		}
+
+		err = backupJobWait(vmId, jobId)
+		if err != nil {
+			return
+		}
+
+		incremental = true
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"instance_id": vmId.Hex(),
+		"disk_id":     dsk.Id.Hex(),
+	}).Info("qmp: Full disk backup with new dirty bitmap")
+
+	if bitmap != nil {
+		err = runSimple(vmId, &Command{
+			Execute: "block-dirty-bitmap-remove",
+			Arguments: &bitmapArgs{
+				Node: deviceName,
+				Name: BackupBitmap,
+			},
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	err = runSimple(vmId, &Command{
+		Execute: "transaction",
+		Arguments: &transactionArgs{
+			Actions: []*transactionAction{
+				{
+					Type: "block-dirty-bitmap-add",
+					Data: &bitmapArgs{
+						Node:       deviceName,
+						Name:       BackupBitmap,
+						Persistent: true,
+					},
+				},
+				{
+					Type: "drive-backup",
+					Data: &incrementalBackupArgs{
+						JobId:       jobId,
+						Device:      deviceName,
+						Sync:        "full",
+						Target:      destPth,
+						Format:      "qcow2",
+						AutoDismiss: false,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	err = backupJobWait(vmId, jobId)
+	if err != nil {
+		// Bitmap must not outlive a failed full backup
+		_ = runSimple(vmId, &Command{
+			Execute: "block-dirty-bitmap-remove",
+			Arguments: &bitmapArgs{
+				Node: deviceName,
+				Name: BackupBitmap,
+			},
+		})
+		return
+	}
+
+	return
+}
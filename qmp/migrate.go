@@ -0,0 +1,459 @@
+package qmp
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/errortypes"
+)
+
+const (
+	MigrateActive       = "active"
+	MigratePreSwitch    = "pre-switchover"
+	MigrateDevice       = "device"
+	MigrateCompleted    = "completed"
+	MigrateFailed       = "failed"
+	MigrateCancelled    = "cancelled"
+	migratePollInterval = 500 * time.Millisecond
+)
+
+type migrateCapability struct {
+	Capability string `json:"capability"`
+	State      bool   `json:"state"`
+}
+
+type migrateCapabilitiesArgs struct {
+	Capabilities []*migrateCapability `json:"capabilities"`
+}
+
+type migrateArgs struct {
+	Uri string `json:"uri"`
+}
+
+type migrateContinueArgs struct {
+	State string `json:"state"`
+}
+
+type MigrateRam struct {
+	Transferred int64 `json:"transferred"`
+	Remaining   int64 `json:"remaining"`
+	Total       int64 `json:"total"`
+}
+
+type MigrateInfo struct {
+	Status    string      `json:"status"`
+	ErrorDesc string      `json:"error-desc"`
+	Ram       *MigrateRam `json:"ram"`
+}
+
+type migrateInfoReturn struct {
+	Return *MigrateInfo  `json:"return"`
+	Error  *CommandError `json:"error"`
+}
+
+type socketAddrData struct {
+	Path string `json:"path"`
+}
+
+type socketAddr struct {
+	Type string          `json:"type"`
+	Data *socketAddrData `json:"data"`
+}
+
+type nbdServerArgs struct {
+	Addr *socketAddr `json:"addr"`
+}
+
+type blockExportArgs struct {
+	Type     string `json:"type"`
+	Id       string `json:"id"`
+	NodeName string `json:"node-name"`
+	Name     string `json:"name"`
+	Writable bool   `json:"writable"`
+}
+
+type driveMirrorArgs struct {
+	JobId  string `json:"job-id"`
+	Device string `json:"device"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	Sync   string `json:"sync"`
+	Mode   string `json:"mode"`
+}
+
+type blockJobCancelArgs struct {
+	Device string `json:"device"`
+	Force  bool   `json:"force,omitempty"`
+}
+
+type blockJob struct {
+	Device string `json:"device"`
+	Ready  bool   `json:"ready"`
+	Status string `json:"status"`
+	Len    int64  `json:"len"`
+	Offset int64  `json:"offset"`
+}
+
+type blockJobReturn struct {
+	Return []*blockJob   `json:"return"`
+	Error  *CommandError `json:"error"`
+}
+
+type announceArgs struct {
+	Initial int `json:"initial"`
+	Max     int `json:"max"`
+	Rounds  int `json:"rounds"`
+	Step    int `json:"step"`
+}
+
+func diskNode(dskId primitive.ObjectID) string {
+	return fmt.Sprintf("fd_%s", dskId.Hex())
+}
+
+func mirrorJob(dskId primitive.ObjectID) string {
+	return fmt.Sprintf("mirror_%s", dskId.Hex())
+}
+
+// GetDiskSizes returns the virtual size of each qcow2 disk attached
+// to the virtual machine.
+func GetDiskSizes(vmId primitive.ObjectID) (
+	sizes map[primitive.ObjectID]int64, err error) {
+
+	cmd := &Command{
+		Execute: "query-block",
+	}
+
+	returnData := &blockDeviceReturn{}
+	err = RunCommand(vmId, cmd, returnData)
+	if err != nil {
+		return
+	}
+
+	if returnData.Error != nil {
+		err = &errortypes.ApiError{
+			errors.Newf("qmp: Return error %s", returnData.Error.Desc),
+		}
+		return
+	}
+
+	sizes = map[primitive.ObjectID]int64{}
+	for _, blockDev := range returnData.Return {
+		idStr := strings.Split(path.Base(
+			blockDev.Inserted.Image.Filename), ".")[0]
+
+		diskId, e := primitive.ObjectIDFromHex(idStr)
+		if e != nil {
+			continue
+		}
+
+		sizes[diskId] = blockDev.Inserted.Image.VirtualSize
+	}
+
+	return
+}
+
+func MigrateIncoming(vmId primitive.ObjectID, sockPath string) (err error) {
+	err = runSimple(vmId, &Command{
+		Execute: "migrate-incoming",
+		Arguments: &migrateArgs{
+			Uri: "unix:" + sockPath,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func NbdStart(vmId primitive.ObjectID, sockPath string,
+	dskIds []primitive.ObjectID) (err error) {
+
+	err = runSimple(vmId, &Command{
+		Execute: "nbd-server-start",
+		Arguments: &nbdServerArgs{
+			Addr: &socketAddr{
+				Type: "unix",
+				Data: &socketAddrData{
+					Path: sockPath,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	for _, dskId := range dskIds {
+		err = runSimple(vmId, &Command{
+			Execute: "block-export-add",
+			Arguments: &blockExportArgs{
+				Type:     "nbd",
+				Id:       "export_" + dskId.Hex(),
+				NodeName: diskNode(dskId),
+				Name:     diskNode(dskId),
+				Writable: true,
+			},
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func NbdStop(vmId primitive.ObjectID) (err error) {
+	err = runSimple(vmId, &Command{
+		Execute: "nbd-server-stop",
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func getBlockJobs(vmId primitive.ObjectID) (
+	jobs map[string]*blockJob, err error) {
+
+	returnData := &blockJobReturn{}
+	err = RunCommand(vmId, &Command{
+		Execute: "query-block-jobs",
+	}, returnData)
+	if err != nil {
+		return
+	}
+
+	if returnData.Error != nil {
+		err = &errortypes.ApiError{
+			errors.Newf("qmp: Return error %s", returnData.Error.Desc),
+		}
+		return
+	}
+
+	jobs = map[string]*blockJob{}
+	for _, job := range returnData.Return {
+		jobs[job.Device] = job
+	}
+
+	return
+}
+
+// MirrorStart copies each disk to the matching export on the destination
+// NBD server and keeps the copy in sync until the job is cancelled.
+func MirrorStart(vmId primitive.ObjectID, sockPath string,
+	dskIds []primitive.ObjectID) (err error) {
+
+	for _, dskId := range dskIds {
+		err = runSimple(vmId, &Command{
+			Execute: "drive-mirror",
+			Arguments: &driveMirrorArgs{
+				JobId:  mirrorJob(dskId),
+				Device: diskNode(dskId),
+				Target: fmt.Sprintf("nbd+unix:///%s?socket=%s",
+					diskNode(dskId), sockPath),
+				Format: "raw",
+				Sync:   "full",
+				Mode:   "existing",
+			},
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func MirrorWaitReady(vmId primitive.ObjectID, dskIds []primitive.ObjectID,
+	timeout time.Duration) (err error) {
+
+	start := time.Now()
+
+	for {
+		jobs, e := getBlockJobs(vmId)
+		if e != nil {
+			err = e
+			return
+		}
+
+		ready := true
+		for _, dskId := range dskIds {
+			job := jobs[mirrorJob(dskId)]
+			if job == nil {
+				err = &errortypes.ApiError{
+					errors.Newf("qmp: Mirror job for disk %s lost",
+						dskId.Hex()),
+				}
+				return
+			}
+
+			if !job.Ready {
+				ready = false
+			}
+		}
+
+		if ready {
+			return
+		}
+
+		if time.Since(start) > timeout {
+			err = &errortypes.TimeoutError{
+				errors.New("qmp: Mirror jobs ready timeout"),
+			}
+			return
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// MirrorCancel stops the mirror jobs. A cancelled job that is ready
+// completes the final sync before exiting which must happen before the
+// destination resumes.
+func MirrorCancel(vmId primitive.ObjectID, dskIds []primitive.ObjectID,
+	force bool) (err error) {
+
+	for _, dskId := range dskIds {
+		e := runSimple(vmId, &Command{
+			Execute: "block-job-cancel",
+			Arguments: &blockJobCancelArgs{
+				Device: mirrorJob(dskId),
+				Force:  force,
+			},
+		})
+		if e != nil && !force {
+			err = e
+			return
+		}
+	}
+
+	for i := 0; i < 600; i++ {
+		jobs, e := getBlockJobs(vmId)
+		if e != nil {
+			err = e
+			return
+		}
+
+		active := false
+		for _, dskId := range dskIds {
+			if jobs[mirrorJob(dskId)] != nil {
+				active = true
+				break
+			}
+		}
+
+		if !active {
+			return
+		}
+
+		time.Sleep(migratePollInterval)
+	}
+
+	err = &errortypes.TimeoutError{
+		errors.New("qmp: Mirror jobs cancel timeout"),
+	}
+	return
+}
+
+func MigrateStart(vmId primitive.ObjectID, sockPath string) (err error) {
+	err = runSimple(vmId, &Command{
+		Execute: "migrate-set-capabilities",
+		Arguments: &migrateCapabilitiesArgs{
+			Capabilities: []*migrateCapability{
+				{
+					Capability: "pause-before-switchover",
+					State:      true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	err = runSimple(vmId, &Command{
+		Execute: "migrate",
+		Arguments: &migrateArgs{
+			Uri: "unix:" + sockPath,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func MigrateStatus(vmId primitive.ObjectID) (info *MigrateInfo, err error) {
+	returnData := &migrateInfoReturn{}
+	err = RunCommand(vmId, &Command{
+		Execute: "query-migrate",
+	}, returnData)
+	if err != nil {
+		return
+	}
+
+	if returnData.Error != nil {
+		err = &errortypes.ApiError{
+			errors.Newf("qmp: Return error %s", returnData.Error.Desc),
+		}
+		return
+	}
+
+	info = returnData.Return
+	if info == nil {
+		info = &MigrateInfo{}
+	}
+
+	return
+}
+
+func MigrateContinue(vmId primitive.ObjectID) (err error) {
+	err = runSimple(vmId, &Command{
+		Execute: "migrate-continue",
+		Arguments: &migrateContinueArgs{
+			State: MigratePreSwitch,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func MigrateCancel(vmId primitive.ObjectID) (err error) {
+	err = runSimple(vmId, &Command{
+		Execute: "migrate_cancel",
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// AnnounceSelf sends gratuitous ARP and RARP packets from each network
+// adapter to update switches and vxlan forwarding tables.
+func AnnounceSelf(vmId primitive.ObjectID) (err error) {
+	err = runSimple(vmId, &Command{
+		Execute: "announce-self",
+		Arguments: &announceArgs{
+			Initial: 50,
+			Max:     550,
+			Rounds:  5,
+			Step:    100,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
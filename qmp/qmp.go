@@ -32,6 +32,7 @@ type JobStatus struct {
 	Id     string `json:"id"`
 	Type   string `json:"type"`
 	Status string `json:"status"`
+	Error  string `json:"error"`
 }
 
 type JobStatusReturn struct {
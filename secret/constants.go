@@ -1,5 +1,6 @@
 package secret
 
 const (
-	AWS = "aws"
+	AWS     = "aws"
+	Rfc2136 = "rfc2136"
 )
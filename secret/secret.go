@@ -1,6 +1,10 @@
 package secret
 
 import (
+	"encoding/base64"
+	"net"
+	"strings"
+
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
@@ -30,6 +34,59 @@ func (c *Secret) Validate(db *database.Database) (
 			c.Region = "us-east-1"
 		}
 
+		break
+	case Rfc2136:
+		c.Key = strings.TrimSpace(c.Key)
+		c.Value = strings.TrimSpace(c.Value)
+		c.Region = strings.TrimSpace(c.Region)
+
+		keyName := c.Key
+		if strings.Contains(keyName, ":") {
+			parts := strings.SplitN(keyName, ":", 2)
+			keyName = parts[1]
+
+			switch strings.ToLower(parts[0]) {
+			case "hmac-sha1", "hmac-sha256", "hmac-sha384", "hmac-sha512":
+				break
+			default:
+				errData = &errortypes.ErrorData{
+					Error:   "invalid_tsig_algorithm",
+					Message: "TSIG key algorithm invalid",
+				}
+				return
+			}
+		}
+
+		if strings.Trim(keyName, ".") == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_tsig_key",
+				Message: "TSIG key name required",
+			}
+			return
+		}
+
+		_, e := base64.StdEncoding.DecodeString(c.Value)
+		if c.Value == "" || e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_tsig_secret",
+				Message: "TSIG secret must be base64 encoded",
+			}
+			return
+		}
+
+		if c.Region == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_dns_server",
+				Message: "DNS server address required",
+			}
+			return
+		}
+
+		_, _, e = net.SplitHostPort(c.Region)
+		if e != nil {
+			c.Region = net.JoinHostPort(strings.Trim(c.Region, "[]"), "53")
+		}
+
 		break
 	default:
 		errData = &errortypes.ErrorData{
@@ -3,11 +3,14 @@ package settings
 var Acme *acme
 
 type acme struct {
-	Id           string `bson:"_id"`
-	Url          string `bson:"url" default:"https://acme-v01.api.letsencrypt.org"`
-	DnsRetryRate int    `bson:"dns_retry_rate" default:"3"`
-	DnsTimeout   int    `bson:"dns_timeout" default:"45"`
-	DnsDelay     int    `bson:"dns_delay" default:"15"`
+	Id              string `bson:"_id"`
+	Url             string `bson:"url" default:"https://acme-v01.api.letsencrypt.org"`
+	DnsRetryRate    int    `bson:"dns_retry_rate" default:"3"`
+	DnsTimeout      int    `bson:"dns_timeout" default:"45"`
+	DnsDelay        int    `bson:"dns_delay" default:"15"`
+	DnsRfc2136Ttl   int    `bson:"dns_rfc2136_ttl" default:"60"`
+	RenewBackoff    int    `bson:"renew_backoff" default:"3600"`
+	RenewBackoffMax int    `bson:"renew_backoff_max" default:"86400"`
 }
 
 func newAcme() interface{} {
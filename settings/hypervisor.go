@@ -30,6 +30,9 @@ type hypervisor struct {
 	HostNetworkName     string `bson:"host_network_name" default:"pritunlhost0"`
 	StartTimeout        int    `bson:"start_timeout" default:"45"`
 	StopTimeout         int    `bson:"stop_timeout" default:"180"`
+	MigrateTimeout      int    `bson:"migrate_timeout" default:"600"`
+	EvacuateLimit       int    `bson:"evacuate_limit" default:"2"`
+	EvacuateRetry       int    `bson:"evacuate_retry" default:"120"`
 	RefreshRate         int    `bson:"refresh_rate" default:"90"`
 	SplashTime          int    `bson:"splash_time" default:"60"`
 	NoIpv6PingInit      bool   `bson:"no_ipv6_ping_init"`
@@ -16,6 +16,10 @@ type router struct {
 	HandshakeTimeout    int    `bson:"handshake_timeout" default:"10"`
 	ContinueTimeout     int    `bson:"continue_timeout" default:"10"`
 	MaxHeaderBytes      int    `bson:"max_header_bytes" default:"4194304"`
+	UdpSessionTimeout   int    `bson:"udp_session_timeout" default:"120"`
+	UdpMaxSessions      int    `bson:"udp_max_sessions" default:"10000"`
+	StreamIdleTimeout   int    `bson:"stream_idle_timeout" default:"300"`
+	StreamMaxConns      int    `bson:"stream_max_conns" default:"10000"`
 	SkipVerify          bool   `bson:"skip_verify"`
 }
 
@@ -21,6 +21,7 @@ type system struct {
 	AcmeKeyAlgorithm     string `bson:"acme_key_algorithm" default:"rsa"`
 	DiskBackupWindow     int    `bson:"disk_backup_window" default:"6"`
 	DiskBackupTime       int    `bson:"disk_backup_time" default:"10"`
+	DiskBackupChain      int    `bson:"disk_backup_chain" default:"7"`
 	OracleApiRetryRate   int    `bson:"oracle_api_retry_rate" default:"1"`
 	OracleApiRetryCount  int    `bson:"oracle_api_retry_count" default:"120"`
 	TwilioAccount        string `bson:"twilio_account"`
@@ -47,23 +47,26 @@ func Iptables() (err error) {
 		return
 	}
 
-	nodeFirewall, firewalls, err := firewall.GetAllIngress(
-		db, node.Self, instances)
+	nodeFirewall, firewalls, firewallsEgress, logModes,
+		err := firewall.GetAllRules(db, node.Self, instances)
 	if err != nil {
 		return
 	}
 
-	err = ipset.Init(namespaces, instances, nodeFirewall, firewalls)
+	err = ipset.Init(namespaces, instances, nodeFirewall, firewalls,
+		firewallsEgress)
 	if err != nil {
 		return
 	}
 
-	err = iptables.Init(namespaces, vpcs, instances, nodeFirewall, firewalls)
+	err = iptables.Init(namespaces, vpcs, instances, nodeFirewall, firewalls,
+		firewallsEgress, logModes)
 	if err != nil {
 		return
 	}
 
-	err = ipset.InitNames(namespaces, instances, nodeFirewall, firewalls)
+	err = ipset.InitNames(namespaces, instances, nodeFirewall, firewalls,
+		firewallsEgress)
 	if err != nil {
 		return
 	}
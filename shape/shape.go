@@ -45,16 +45,37 @@ func (s *Shape) Validate(db *database.Database) (
 	return
 }
 
-func (s *Shape) FindNode(db *database.Database, processors, memory int) (
-	nde *node.Node, err error) {
+// FindNodes returns the available nodes for the shape sorted by usage.
+// Nodes in maintenance are excluded.
+func (s *Shape) FindNodes(db *database.Database) (
+	ndes []*node.Node, err error) {
 
-	ndes, err := node.GetAllShape(db, s.Zone, s.Roles)
+	allNdes, err := node.GetAllShape(db, s.Zone, s.Roles)
 	if err != nil {
 		return
 	}
 
+	ndes = []*node.Node{}
+	for _, nde := range allNdes {
+		if nde.Maintenance {
+			continue
+		}
+		ndes = append(ndes, nde)
+	}
+
 	NodeUsageSort(ndes)
 
+	return
+}
+
+func (s *Shape) FindNode(db *database.Database, processors, memory int) (
+	nde *node.Node, err error) {
+
+	ndes, err := s.FindNodes(db)
+	if err != nil {
+		return
+	}
+
 	for _, nd := range ndes {
 		nde = nd
 		return
@@ -16,6 +16,7 @@ import (
 	"github.com/pritunl/pritunl-cloud/firewall"
 	"github.com/pritunl/pritunl-cloud/instance"
 	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/peering"
 	"github.com/pritunl/pritunl-cloud/qemu"
 	"github.com/pritunl/pritunl-cloud/utils"
 	"github.com/pritunl/pritunl-cloud/vm"
@@ -37,15 +38,19 @@ type State struct {
 	interfacesSet    set.Set
 	nodeFirewall     []*firewall.Rule
 	firewalls        map[string][]*firewall.Rule
+	firewallsEgress  map[string][]*firewall.Rule
+	logModes         map[string]*firewall.LogMode
 	disks            []*disk.Disk
 	virtsMap         map[primitive.ObjectID]*vm.VirtualMachine
 	instances        []*instance.Instance
 	instancesMap     map[primitive.ObjectID]*instance.Instance
+	migrations       []*instance.Instance
 	instanceDisks    map[primitive.ObjectID][]*disk.Disk
 	domainRecordsMap map[primitive.ObjectID][]*domain.Record
 	vpcs             []*vpc.Vpc
 	vpcsMap          map[primitive.ObjectID]*vpc.Vpc
 	vpcIpsMap        map[primitive.ObjectID][]*vpc.VpcIp
+	vpcPeersMap      map[primitive.ObjectID][]primitive.ObjectID
 	arpRecords       map[string]set.Set
 	addInstances     set.Set
 	remInstances     set.Set
@@ -92,6 +97,10 @@ func (s *State) Instances() []*instance.Instance {
 	return s.instances
 }
 
+func (s *State) Migrations() []*instance.Instance {
+	return s.migrations
+}
+
 func (s *State) NodeFirewall() []*firewall.Rule {
 	return s.nodeFirewall
 }
@@ -100,6 +109,14 @@ func (s *State) Firewalls() map[string][]*firewall.Rule {
 	return s.firewalls
 }
 
+func (s *State) FirewallsEgress() map[string][]*firewall.Rule {
+	return s.firewallsEgress
+}
+
+func (s *State) LogModes() map[string]*firewall.LogMode {
+	return s.logModes
+}
+
 func (s *State) DomainRecords(instId primitive.ObjectID) []*domain.Record {
 	return s.domainRecordsMap[instId]
 }
@@ -128,6 +145,17 @@ func (s *State) VpcIpsMap() map[primitive.ObjectID][]*vpc.VpcIp {
 	return s.vpcIpsMap
 }
 
+func (s *State) VpcPeers(vpcId primitive.ObjectID) []*vpc.Vpc {
+	vpcs := []*vpc.Vpc{}
+	for _, peerId := range s.vpcPeersMap[vpcId] {
+		vc := s.vpcsMap[peerId]
+		if vc != nil {
+			vpcs = append(vpcs, vc)
+		}
+	}
+	return vpcs
+}
+
 func (s *State) ArpRecords(namespace string) set.Set {
 	return s.arpRecords[namespace]
 }
@@ -270,6 +298,12 @@ func (s *State) init() (err error) {
 	}
 	s.instancesMap = instancesMap
 
+	migrations, err := instance.GetMigrations(db, s.nodeSelf.Id)
+	if err != nil {
+		return
+	}
+	s.migrations = migrations
+
 	curVirts, err := qemu.GetVms(db, instancesMap)
 	if err != nil {
 		return
@@ -286,13 +320,15 @@ func (s *State) init() (err error) {
 	}
 	s.virtsMap = virtsMap
 
-	nodeFirewall, firewalls, err := firewall.GetAllIngress(
-		db, s.nodeSelf, instances)
+	nodeFirewall, firewalls, firewallsEgress, logModes,
+		err := firewall.GetAllRules(db, s.nodeSelf, instances)
 	if err != nil {
 		return
 	}
 	s.nodeFirewall = nodeFirewall
 	s.firewalls = firewalls
+	s.firewallsEgress = firewallsEgress
+	s.logModes = logModes
 
 	vpcs := []*vpc.Vpc{}
 	vpcsId := []primitive.ObjectID{}
@@ -320,6 +356,15 @@ func (s *State) init() (err error) {
 	}
 	s.vpcIpsMap = vpcIpsMap
 
+	vpcPeersMap := map[primitive.ObjectID][]primitive.ObjectID{}
+	if !s.nodeDatacenter.IsZero() {
+		vpcPeersMap, err = peering.GetDatacenter(db, s.nodeDatacenter)
+		if err != nil {
+			return
+		}
+	}
+	s.vpcPeersMap = vpcPeersMap
+
 	s.arpRecords = arp.BuildState(s.instances, s.vpcIpsMap)
 
 	recrds, err := domain.GetRecordAll(db, &bson.M{
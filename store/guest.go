@@ -0,0 +1,43 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+)
+
+var (
+	guestStores     = map[primitive.ObjectID]GuestStore{}
+	guestStoresLock = sync.Mutex{}
+)
+
+type GuestStore struct {
+	Status    string
+	Online    time.Time
+	Timestamp time.Time
+}
+
+func GetGuest(virtId primitive.ObjectID) (guestStore GuestStore, ok bool) {
+	guestStoresLock.Lock()
+	guestStore, ok = guestStores[virtId]
+	guestStoresLock.Unlock()
+
+	return
+}
+
+func SetGuest(virtId primitive.ObjectID, status string, online time.Time) {
+	guestStoresLock.Lock()
+	guestStores[virtId] = GuestStore{
+		Status:    status,
+		Online:    online,
+		Timestamp: time.Now(),
+	}
+	guestStoresLock.Unlock()
+}
+
+func RemGuest(virtId primitive.ObjectID) {
+	guestStoresLock.Lock()
+	delete(guestStores, virtId)
+	guestStoresLock.Unlock()
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+)
+
+var (
+	peersStores     = map[primitive.ObjectID]PeersStore{}
+	peersStoresLock = sync.Mutex{}
+)
+
+type PeersStore struct {
+	Key       string
+	Timestamp time.Time
+}
+
+func GetPeers(instId primitive.ObjectID) (peersStore PeersStore, ok bool) {
+	peersStoresLock.Lock()
+	peersStore, ok = peersStores[instId]
+	peersStoresLock.Unlock()
+
+	return
+}
+
+func SetPeers(instId primitive.ObjectID, key string) {
+	peersStoresLock.Lock()
+	peersStores[instId] = PeersStore{
+		Key:       key,
+		Timestamp: time.Now(),
+	}
+	peersStoresLock.Unlock()
+}
+
+func RemPeers(instId primitive.ObjectID) {
+	peersStoresLock.Lock()
+	delete(peersStores, instId)
+	peersStoresLock.Unlock()
+}
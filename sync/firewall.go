@@ -0,0 +1,156 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/constants"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/firewall"
+	"github.com/pritunl/pritunl-cloud/instance"
+	"github.com/pritunl/pritunl-cloud/iptables"
+	"github.com/pritunl/pritunl-cloud/node"
+	"github.com/pritunl/pritunl-cloud/vm"
+	"github.com/sirupsen/logrus"
+)
+
+// addCounters adds the namespace counters of the rules in the firewall.
+// Rule keys include the rule sources so only identical rules are merged
+// and counted in more than one firewall.
+func addCounters(counters *firewall.Counters, fire *firewall.Firewall,
+	nsCounters *firewall.Counters) {
+
+	added := set.NewSet()
+	add := func(counterMap map[string]*firewall.Counter,
+		nsCounterMap map[string]*firewall.Counter, key string) {
+
+		if added.Contains(key) {
+			return
+		}
+		added.Add(key)
+
+		counter := counterMap[key]
+		if counter == nil {
+			counter = &firewall.Counter{}
+			counterMap[key] = counter
+		}
+		counter.Add(nsCounterMap[key])
+	}
+
+	for _, rule := range fire.Ingress {
+		add(counters.Ingress, nsCounters.Ingress, rule.Key())
+	}
+	add(counters.Ingress, nsCounters.Ingress, firewall.CounterDrop)
+
+	if len(fire.Egress) > 0 {
+		added = set.NewSet()
+		for _, rule := range fire.Egress {
+			add(counters.Egress, nsCounters.Egress, rule.Key())
+		}
+		add(counters.Egress, nsCounters.Egress, firewall.CounterDrop)
+	}
+}
+
+func firewallCountersSync() (err error) {
+	db := database.GetDatabase()
+	defer db.Close()
+
+	namespaceFires := map[string][]*firewall.Firewall{}
+
+	if node.Self.Firewall {
+		fires, e := firewall.GetRoles(db, node.Self.NetworkRoles)
+		if e != nil {
+			err = e
+			return
+		}
+		namespaceFires["0"] = fires
+	}
+
+	if node.Self.IsHypervisor() {
+		instances, e := instance.GetAll(db, &bson.M{
+			"node": node.Self.Id,
+		})
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, inst := range instances {
+			if !inst.IsActive() {
+				continue
+			}
+
+			fires, e := firewall.GetOrgRoles(db,
+				inst.Organization, inst.NetworkRoles)
+			if e != nil {
+				err = e
+				return
+			}
+
+			namespaceFires[vm.GetNamespace(inst.Id, 0)] = fires
+		}
+	}
+
+	firesMap := map[primitive.ObjectID]*firewall.Firewall{}
+	countersMap := map[primitive.ObjectID]*firewall.Counters{}
+
+	for namespace, fires := range namespaceFires {
+		if len(fires) == 0 {
+			continue
+		}
+
+		nsCounters, e := iptables.GetCounters(namespace)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"namespace": namespace,
+				"error":     e,
+			}).Warn("sync: Failed to load firewall counters")
+			continue
+		}
+
+		for _, fire := range fires {
+			counters := countersMap[fire.Id]
+			if counters == nil {
+				counters = firewall.NewCounters()
+				countersMap[fire.Id] = counters
+				firesMap[fire.Id] = fire
+			}
+
+			addCounters(counters, fire, nsCounters)
+		}
+	}
+
+	for fireId, counters := range countersMap {
+		err = firesMap[fireId].CommitCounters(db, node.Self.Id, counters)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func firewallRunner() {
+	time.Sleep(10 * time.Second)
+
+	for {
+		if constants.Shutdown {
+			return
+		}
+
+		err := firewallCountersSync()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("sync: Failed to sync firewall counters")
+		}
+
+		time.Sleep(30 * time.Second)
+	}
+}
+
+func initFirewall() {
+	go firewallRunner()
+}
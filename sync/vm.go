@@ -35,7 +35,8 @@ func syncNodeFirewall() {
 
 	if !node.Self.Firewall {
 		iptables.UpdateState(node.Self, []*vpc.Vpc{}, []*instance.Instance{},
-			[]string{}, nil, map[string][]*firewall.Rule{})
+			[]string{}, nil, map[string][]*firewall.Rule{},
+			map[string][]*firewall.Rule{}, map[string]*firewall.LogMode{})
 		return
 	}
 
@@ -48,11 +49,26 @@ func syncNodeFirewall() {
 			return
 		}
 
+		fires, err = firewall.ResolveNode(db, fires)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("sync: Failed to resolve node firewall rules")
+			return
+		}
+
 		ingress := firewall.MergeIngress(fires)
 
+		logModes := map[string]*firewall.LogMode{}
+		logMode := firewall.GetLogMode(fires)
+		if logMode != nil {
+			logModes["0"] = logMode
+		}
+
 		iptables.UpdateStateRecover(node.Self, []*vpc.Vpc{},
 			[]*instance.Instance{}, []string{}, ingress,
-			map[string][]*firewall.Rule{})
+			map[string][]*firewall.Rule{}, map[string][]*firewall.Rule{},
+			logModes)
 
 		break
 	}
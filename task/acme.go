@@ -9,7 +9,7 @@ import (
 
 var acmeRenew = &Task{
 	Name:    "acme_renew",
-	Hours:   []int{7},
+	Hours:   AllHours,
 	Mins:    []int{45},
 	Handler: acmeRenewHandler,
 }
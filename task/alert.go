@@ -0,0 +1,26 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-cloud/alert"
+	"github.com/pritunl/pritunl-cloud/database"
+)
+
+var alertCheck = &Task{
+	Name:    "alert_check",
+	Hours:   AllHours,
+	Mins:    AllMins,
+	Handler: alertCheckHandler,
+}
+
+func alertCheckHandler(db *database.Database) (err error) {
+	err = alert.CheckAll(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func init() {
+	register(alertCheck)
+}
@@ -0,0 +1,34 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-cloud/backup"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/node"
+)
+
+var backupVerify = &Task{
+	Name:    "backup_verify",
+	Hours:   []int{4},
+	Mins:    []int{30},
+	Local:   true,
+	Handler: backupVerifyHandler,
+}
+
+func backupVerifyHandler(db *database.Database) (err error) {
+	if node.Self.BackupPath == "" {
+		return
+	}
+
+	verify := backup.NewVerify(node.Self.BackupPath, node.Self.BackupBootTest)
+
+	err = verify.Run()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func init() {
+	register(backupVerify)
+}
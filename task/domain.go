@@ -0,0 +1,62 @@
+package task
+
+import (
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/domain"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/sirupsen/logrus"
+)
+
+var domainDrift = &Task{
+	Name:    "domain_drift",
+	Hours:   AllHours,
+	Mins:    []int{25},
+	Handler: domainDriftHandler,
+}
+
+func domainDriftHandler(db *database.Database) (err error) {
+	domns, err := domain.GetAll(db, &bson.M{
+		"type": &bson.M{
+			"$ne": domain.Builtin,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	changed := false
+	for _, domn := range domns {
+		domnChanged, e := domn.CheckDrift(db)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"domain_id":   domn.Id.Hex(),
+				"domain_name": domn.Name,
+				"error":       e,
+			}).Warning("task: Failed to check domain drift")
+			continue
+		}
+
+		if domnChanged {
+			changed = true
+
+			if len(domn.Drift) > 0 {
+				logrus.WithFields(logrus.Fields{
+					"domain_id":   domn.Id.Hex(),
+					"domain_name": domn.Name,
+					"drift":       len(domn.Drift),
+				}).Warning("task: Domain provider has unknown records")
+			}
+		}
+	}
+
+	if changed {
+		event.PublishDispatch(db, "domain.change")
+	}
+
+	return
+}
+
+func init() {
+	register(domainDrift)
+}
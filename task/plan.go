@@ -0,0 +1,27 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/planner"
+)
+
+var planExecute = &Task{
+	Name:       "plan_execute",
+	Hours:      AllHours,
+	Mins:       AllMins,
+	Handler:    planExecuteHandler,
+	RunOnStart: true,
+}
+
+func planExecuteHandler(db *database.Database) (err error) {
+	err = planner.ExecuteAll(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func init() {
+	register(planExecute)
+}
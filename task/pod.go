@@ -0,0 +1,27 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/pod"
+)
+
+var podDeploy = &Task{
+	Name:       "pod_deploy",
+	Hours:      AllHours,
+	Mins:       AllMins,
+	Handler:    podDeployHandler,
+	RunOnStart: true,
+}
+
+func podDeployHandler(db *database.Database) (err error) {
+	err = pod.DeployAll(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func init() {
+	register(podDeploy)
+}
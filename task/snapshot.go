@@ -0,0 +1,109 @@
+package task
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/bson"
+	"github.com/pritunl/pritunl-cloud/data"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/disk"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/sirupsen/logrus"
+)
+
+var snapshotSchedule = &Task{
+	Name:    "snapshot_schedule",
+	Hours:   AllHours,
+	Mins:    AllMins,
+	Handler: snapshotScheduleHandler,
+}
+
+var snapshotPrune = &Task{
+	Name:    "snapshot_prune",
+	Hours:   AllHours,
+	Mins:    TenMins,
+	Handler: snapshotPruneHandler,
+}
+
+func snapshotScheduleHandler(db *database.Database) (err error) {
+	disks, err := disk.GetAll(db, &bson.M{
+		"snapshot_schedule": &bson.M{
+			"$nin": []interface{}{"", nil},
+		},
+		"state": disk.Available,
+	})
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	changed := false
+
+	for _, dsk := range disks {
+		if !dsk.SnapshotDue(now) {
+			continue
+		}
+
+		scheduled, e := dsk.ScheduleSnapshot(db)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if scheduled {
+			logrus.WithFields(logrus.Fields{
+				"disk_id":  dsk.Id.Hex(),
+				"schedule": dsk.SnapshotSchedule,
+			}).Info("task: Scheduling disk snapshot")
+
+			changed = true
+		}
+	}
+
+	if changed {
+		event.PublishDispatch(db, "disk.change")
+	}
+
+	return
+}
+
+func snapshotPruneHandler(db *database.Database) (err error) {
+	disks, err := disk.GetAll(db, &bson.M{
+		"$or": []*bson.M{
+			&bson.M{
+				"snapshot_retain": &bson.M{
+					"$gt": 0,
+				},
+			},
+			&bson.M{
+				"snapshot_max_age": &bson.M{
+					"$gt": 0,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	for _, dsk := range disks {
+		if dsk.State == disk.Snapshot {
+			continue
+		}
+
+		e := data.PruneSnapshots(db, dsk)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"disk_id": dsk.Id.Hex(),
+				"error":   e,
+			}).Error("task: Failed to prune disk snapshots")
+		}
+	}
+
+	return
+}
+
+func init() {
+	register(snapshotSchedule)
+	register(snapshotPrune)
+}
@@ -20,15 +20,16 @@ import (
 )
 
 type alertData struct {
-	Id        primitive.ObjectID `json:"id"`
-	Name      string             `json:"name"`
-	Roles     []string           `json:"roles"`
-	Resource  string             `json:"resource"`
-	Level     int                `json:"level"`
-	Frequency int                `bson:"frequency" json:"frequency"`
-	Ignores   []string           `bson:"ignores" json:"ignores"`
-	ValueInt  int                `json:"value_int"`
-	ValueStr  string             `json:"value_str"`
+	Id            primitive.ObjectID   `json:"id"`
+	Name          string               `json:"name"`
+	Roles         []string             `json:"roles"`
+	Resource      string               `json:"resource"`
+	Level         int                  `json:"level"`
+	Frequency     int                  `bson:"frequency" json:"frequency"`
+	Ignores       []string             `bson:"ignores" json:"ignores"`
+	ValueInt      int                  `json:"value_int"`
+	ValueStr      string               `json:"value_str"`
+	Notifications []primitive.ObjectID `json:"notifications"`
 }
 
 type alertsData struct {
@@ -74,6 +75,7 @@ func alertPut(c *gin.Context) {
 	alrt.Ignores = data.Ignores
 	alrt.ValueInt = data.ValueInt
 	alrt.ValueStr = data.ValueStr
+	alrt.Notifications = data.Notifications
 
 	fields := set.NewSet(
 		"name",
@@ -84,6 +86,7 @@ func alertPut(c *gin.Context) {
 		"ignores",
 		"value_int",
 		"value_str",
+		"notifications",
 	)
 
 	errData, err := alrt.Validate(db)
@@ -131,15 +134,16 @@ func alertPost(c *gin.Context) {
 	}
 
 	alrt := &alert.Alert{
-		Name:         data.Name,
-		Organization: userOrg,
-		Roles:        data.Roles,
-		Resource:     data.Resource,
-		Level:        data.Level,
-		Frequency:    data.Frequency,
-		Ignores:      data.Ignores,
-		ValueInt:     data.ValueInt,
-		ValueStr:     data.ValueStr,
+		Name:          data.Name,
+		Organization:  userOrg,
+		Roles:         data.Roles,
+		Resource:      data.Resource,
+		Level:         data.Level,
+		Frequency:     data.Frequency,
+		Ignores:       data.Ignores,
+		ValueInt:      data.ValueInt,
+		ValueStr:      data.ValueStr,
+		Notifications: data.Notifications,
 	}
 
 	errData, err := alrt.Validate(db)
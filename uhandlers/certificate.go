@@ -14,15 +14,25 @@ import (
 )
 
 type certificateData struct {
-	Id          primitive.ObjectID `json:"id"`
-	Name        string             `json:"name"`
-	Comment     string             `json:"comment"`
-	Type        string             `json:"type"`
-	Key         string             `json:"key"`
-	Certificate string             `json:"certificate"`
-	AcmeDomains []string           `json:"acme_domains"`
-	AcmeAuth    string             `json:"acme_auth"`
-	AcmeSecret  primitive.ObjectID `json:"acme_secret"`
+	Id                 primitive.ObjectID `json:"id"`
+	Name               string             `json:"name"`
+	Comment            string             `json:"comment"`
+	Type               string             `json:"type"`
+	Key                string             `json:"key"`
+	Certificate        string             `json:"certificate"`
+	AcmeDomains        []string           `json:"acme_domains"`
+	AcmeAuth           string             `json:"acme_auth"`
+	AcmeSecret         primitive.ObjectID `json:"acme_secret"`
+	AcmeDirectory      string             `json:"acme_directory"`
+	AcmeRootCa         string             `json:"acme_root_ca"`
+	AcmeEabKeyId       string             `json:"acme_eab_key_id"`
+	AcmeEabHmac        string             `json:"acme_eab_hmac"`
+	AcmePreferredChain string             `json:"acme_preferred_chain"`
+	AcmeKeyType        string             `json:"acme_key_type"`
+}
+
+type certificateRollbackData struct {
+	Hash string `json:"hash"`
 }
 
 func certificatePut(c *gin.Context) {
@@ -66,6 +76,12 @@ func certificatePut(c *gin.Context) {
 		data.AcmeSecret = primitive.NilObjectID
 	}
 
+	if data.Type != certificate.LetsEncrypt &&
+		(data.Key != cert.Key || data.Certificate != cert.Certificate) {
+
+		cert.PushHistory()
+	}
+
 	cert.Name = data.Name
 	cert.Comment = data.Comment
 	cert.Key = data.Key
@@ -75,6 +91,16 @@ func certificatePut(c *gin.Context) {
 	cert.AcmeType = certificate.AcmeDNS
 	cert.AcmeAuth = data.AcmeAuth
 	cert.AcmeSecret = data.AcmeSecret
+	cert.AcmeDirectory = data.AcmeDirectory
+	cert.AcmeRootCa = data.AcmeRootCa
+	cert.AcmeEabKeyId = data.AcmeEabKeyId
+	if cert.AcmeEabKeyId == "" {
+		cert.AcmeEabHmac = ""
+	} else if data.AcmeEabHmac != "" {
+		cert.AcmeEabHmac = data.AcmeEabHmac
+	}
+	cert.AcmePreferredChain = data.AcmePreferredChain
+	cert.AcmeKeyType = data.AcmeKeyType
 
 	fields := set.NewSet(
 		"name",
@@ -84,7 +110,14 @@ func certificatePut(c *gin.Context) {
 		"acme_type",
 		"acme_auth",
 		"acme_secret",
+		"acme_directory",
+		"acme_root_ca",
+		"acme_eab_key_id",
+		"acme_eab_hmac",
+		"acme_preferred_chain",
+		"acme_key_type",
 		"info",
+		"history",
 	)
 
 	if cert.Type != certificate.LetsEncrypt {
@@ -120,6 +153,56 @@ func certificatePut(c *gin.Context) {
 	c.JSON(200, cert)
 }
 
+func certificateRollbackPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+	data := &certificateRollbackData{}
+
+	certId, ok := utils.ParseObjectId(c.Param("cert_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	cert, err := certificate.GetOrg(db, userOrg, certId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	errData, err := cert.Rollback(data.Hash)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = cert.CommitFields(db, set.NewSet(
+		"key", "certificate", "acme_hash", "info", "history"))
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "certificate.change")
+
+	c.JSON(200, cert)
+}
+
 func certificatePost(c *gin.Context) {
 	if demo.Blocked(c) {
 		return
@@ -138,14 +221,20 @@ func certificatePost(c *gin.Context) {
 	}
 
 	cert := &certificate.Certificate{
-		Name:         data.Name,
-		Comment:      data.Comment,
-		Organization: userOrg,
-		Type:         data.Type,
-		AcmeDomains:  data.AcmeDomains,
-		AcmeType:     certificate.AcmeDNS,
-		AcmeAuth:     data.AcmeAuth,
-		AcmeSecret:   data.AcmeSecret,
+		Name:               data.Name,
+		Comment:            data.Comment,
+		Organization:       userOrg,
+		Type:               data.Type,
+		AcmeDomains:        data.AcmeDomains,
+		AcmeType:           certificate.AcmeDNS,
+		AcmeAuth:           data.AcmeAuth,
+		AcmeSecret:         data.AcmeSecret,
+		AcmeDirectory:      data.AcmeDirectory,
+		AcmeRootCa:         data.AcmeRootCa,
+		AcmeEabKeyId:       data.AcmeEabKeyId,
+		AcmeEabHmac:        data.AcmeEabHmac,
+		AcmePreferredChain: data.AcmePreferredChain,
+		AcmeKeyType:        data.AcmeKeyType,
 	}
 
 	if cert.Type != certificate.LetsEncrypt {
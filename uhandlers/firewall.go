@@ -24,7 +24,9 @@ type firewallData struct {
 	Name         string             `json:"name"`
 	Comment      string             `json:"comment"`
 	NetworkRoles []string           `json:"network_roles"`
+	LogOnly      bool               `json:"log_only"`
 	Ingress      []*firewall.Rule   `json:"ingress"`
+	Egress       []*firewall.Rule   `json:"egress"`
 }
 
 type firewallsData struct {
@@ -65,13 +67,17 @@ func firewallPut(c *gin.Context) {
 	fire.Name = data.Name
 	fire.Comment = data.Comment
 	fire.NetworkRoles = data.NetworkRoles
+	fire.LogOnly = data.LogOnly
 	fire.Ingress = data.Ingress
+	fire.Egress = data.Egress
 
 	fields := set.NewSet(
 		"name",
 		"comment",
 		"network_roles",
+		"log_only",
 		"ingress",
+		"egress",
 	)
 
 	errData, err := fire.Validate(db)
@@ -121,7 +127,9 @@ func firewallPost(c *gin.Context) {
 		Comment:      data.Comment,
 		Organization: userOrg,
 		NetworkRoles: data.NetworkRoles,
+		LogOnly:      data.LogOnly,
 		Ingress:      data.Ingress,
+		Egress:       data.Egress,
 	}
 
 	errData, err := fire.Validate(db)
@@ -216,6 +224,8 @@ func firewallGet(c *gin.Context) {
 		return
 	}
 
+	fire.Json()
+
 	c.JSON(200, fire)
 }
 
@@ -262,6 +272,10 @@ func firewallsGet(c *gin.Context) {
 		return
 	}
 
+	for _, fire := range firewalls {
+		fire.Json()
+	}
+
 	data := &firewallsData{
 		Firewalls: firewalls,
 		Count:     count,
@@ -45,6 +45,14 @@ func Register(engine *gin.Engine) {
 	csrfGroup.DELETE("/alert", alertsDelete)
 	csrfGroup.DELETE("/alert/:alert_id", alertDelete)
 
+	csrfGroup.GET("/notification", notificationsGet)
+	csrfGroup.PUT("/notification/:notification_id", notificationPut)
+	csrfGroup.POST("/notification", notificationPost)
+	csrfGroup.POST("/notification/:notification_id/test",
+		notificationTestPost)
+	csrfGroup.DELETE("/notification", notificationsDelete)
+	csrfGroup.DELETE("/notification/:notification_id", notificationDelete)
+
 	engine.GET("/auth/state", authStateGet)
 	dbGroup.POST("/auth/session", authSessionPost)
 	dbGroup.POST("/auth/secondary", authSecondaryPost)
@@ -67,6 +75,7 @@ func Register(engine *gin.Engine) {
 
 	orgGroup.GET("/balancer", balancersGet)
 	orgGroup.GET("/balancer/:balancer_id", balancerGet)
+	orgGroup.GET("/balancer/:balancer_id/log", balancerLogsGet)
 	orgGroup.PUT("/balancer/:balancer_id", balancerPut)
 	orgGroup.POST("/balancer", balancerPost)
 	orgGroup.DELETE("/balancer", balancersDelete)
@@ -76,6 +85,8 @@ func Register(engine *gin.Engine) {
 	orgGroup.GET("/certificate/:cert_id", certificateGet)
 	orgGroup.PUT("/certificate/:cert_id", certificatePut)
 	orgGroup.POST("/certificate", certificatePost)
+	orgGroup.POST("/certificate/:cert_id/rollback",
+		certificateRollbackPost)
 	orgGroup.DELETE("/certificate/:cert_id", certificateDelete)
 
 	engine.GET("/check", checkGet)
@@ -94,6 +105,11 @@ func Register(engine *gin.Engine) {
 	csrfGroup.POST("/device/:device_id/register", deviceWanRegisterPost)
 
 	orgGroup.GET("/domain", domainsGet)
+	orgGroup.GET("/domain/:domain_id", domainGet)
+	orgGroup.PUT("/domain/:domain_id", domainPut)
+	orgGroup.POST("/domain", domainPost)
+	orgGroup.DELETE("/domain", domainsDelete)
+	orgGroup.DELETE("/domain/:domain_id", domainDelete)
 
 	orgGroup.GET("/disk", disksGet)
 	orgGroup.GET("/disk/:disk_id", diskGet)
@@ -112,6 +128,12 @@ func Register(engine *gin.Engine) {
 	orgGroup.DELETE("/firewall", firewallsDelete)
 	orgGroup.DELETE("/firewall/:firewall_id", firewallDelete)
 
+	orgGroup.GET("/addrgroup", addrGroupsGet)
+	orgGroup.GET("/addrgroup/:group_id", addrGroupGet)
+	orgGroup.PUT("/addrgroup/:group_id", addrGroupPut)
+	orgGroup.POST("/addrgroup", addrGroupPost)
+	orgGroup.DELETE("/addrgroup/:group_id", addrGroupDelete)
+
 	orgGroup.GET("/image", imagesGet)
 	orgGroup.GET("/image/:image_id", imageGet)
 	orgGroup.PUT("/image/:image_id", imagePut)
@@ -123,6 +145,7 @@ func Register(engine *gin.Engine) {
 	orgGroup.GET("/instance/:instance_id", instanceGet)
 	orgGroup.GET("/instance/:instance_id/vnc", instanceVncGet)
 	orgGroup.PUT("/instance/:instance_id", instancePut)
+	orgGroup.POST("/instance/:instance_id/migrate", instanceMigratePost)
 	orgGroup.POST("/instance", instancePost)
 	orgGroup.DELETE("/instance", instancesDelete)
 	orgGroup.DELETE("/instance/:instance_id", instanceDelete)
@@ -131,6 +154,23 @@ func Register(engine *gin.Engine) {
 
 	orgGroup.GET("/node", nodesGet)
 
+	orgGroup.GET("/plan", plansGet)
+	orgGroup.GET("/plan/:plan_id", planGet)
+	orgGroup.PUT("/plan/:plan_id", planPut)
+	orgGroup.POST("/plan", planPost)
+	orgGroup.DELETE("/plan", plansDelete)
+	orgGroup.DELETE("/plan/:plan_id", planDelete)
+	orgGroup.GET("/plan/:plan_id/run", planRunsGet)
+	orgGroup.GET("/plan/:plan_id/run/:run_id", planRunGet)
+	orgGroup.POST("/plan/:plan_id/run", planRunPost)
+
+	orgGroup.GET("/pod", podsGet)
+	orgGroup.GET("/pod/:pod_id", podGet)
+	orgGroup.PUT("/pod/:pod_id", podPut)
+	orgGroup.POST("/pod", podPost)
+	orgGroup.DELETE("/pod", podsDelete)
+	orgGroup.DELETE("/pod/:pod_id", podDelete)
+
 	csrfGroup.GET("/pool", poolsGet)
 
 	orgGroup.GET("/secret", secretsGet)
@@ -152,6 +192,12 @@ func Register(engine *gin.Engine) {
 	orgGroup.DELETE("/vpc", vpcsDelete)
 	orgGroup.DELETE("/vpc/:vpc_id", vpcDelete)
 
+	orgGroup.GET("/peering", peeringsGet)
+	orgGroup.GET("/peering/:peering_id", peeringGet)
+	orgGroup.PUT("/peering/:peering_id", peeringPut)
+	orgGroup.POST("/peering", peeringPost)
+	orgGroup.DELETE("/peering/:peering_id", peeringDelete)
+
 	orgGroup.GET("/zone", zonesGet)
 
 	engine.GET("/robots.txt", middlewear.RobotsGet)
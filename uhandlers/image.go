@@ -106,12 +106,17 @@ func imageDelete(c *gin.Context) {
 		return
 	}
 
-	err := data.DeleteImageOrg(db, userOrg, imageId)
+	errData, err := data.DeleteImageOrg(db, userOrg, imageId)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
 		return
 	}
 
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
 	event.PublishDispatch(db, "image.change")
 
 	c.JSON(200, nil)
@@ -132,12 +137,17 @@ func imagesDelete(c *gin.Context) {
 		return
 	}
 
-	err = data.DeleteImagesOrg(db, userOrg, dta)
+	errData, err := data.DeleteImagesOrg(db, userOrg, dta)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
 		return
 	}
 
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
 	event.PublishDispatch(db, "image.change")
 
 	c.JSON(200, nil)
@@ -78,6 +78,10 @@ type instanceData struct {
 	Count               int                `json:"count"`
 }
 
+type instanceMigrateData struct {
+	Node primitive.ObjectID `json:"node"`
+}
+
 type instanceMultiData struct {
 	Ids   []primitive.ObjectID `json:"ids"`
 	State string               `json:"state"`
@@ -787,3 +791,86 @@ func instanceVncGet(c *gin.Context) {
 		return
 	}
 }
+
+func instanceMigratePost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+	dta := &instanceMigrateData{}
+
+	instanceId, ok := utils.ParseObjectId(c.Param("instance_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(dta)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	inst, err := instance.GetOrg(db, userOrg, instanceId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	nde, err := node.Get(db, dta.Node)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	zne, err := zone.Get(db, nde.Zone)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	exists, err := datacenter.ExistsOrg(db, userOrg, zne.Datacenter)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+	if !exists {
+		utils.AbortWithStatus(c, 405)
+		return
+	}
+
+	errData, err := inst.ValidateMigrate(db, nde)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	started, err := inst.Migrate(db, nde, false)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !started {
+		errData = &errortypes.ErrorData{
+			Error:   "instance_migrating",
+			Message: "Instance is already migrating",
+		}
+		c.JSON(400, errData)
+		return
+	}
+
+	event.PublishDispatch(db, "instance.change")
+
+	c.JSON(200, inst)
+}
@@ -0,0 +1,205 @@
+package uhandlers
+
+import (
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/bson/primitive"
+	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/demo"
+	"github.com/pritunl/pritunl-cloud/event"
+	"github.com/pritunl/pritunl-cloud/peering"
+	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/vpc"
+)
+
+type peeringData struct {
+	Id           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	Comment      string             `json:"comment"`
+	Vpc          primitive.ObjectID `json:"vpc"`
+	Accepted     bool               `json:"accepted"`
+	PeerVpc      primitive.ObjectID `json:"peer_vpc"`
+	PeerAccepted bool               `json:"peer_accepted"`
+}
+
+func peeringPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+	data := &peeringData{}
+
+	peeringId, ok := utils.ParseObjectId(c.Param("peering_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	peer, err := peering.GetOrg(db, userOrg, peeringId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	peer.Name = data.Name
+	peer.Comment = data.Comment
+
+	// Each organization can only accept its own side of the peering
+	if peer.Organization == userOrg {
+		peer.Accepted = data.Accepted
+	}
+	if peer.PeerOrganization == userOrg {
+		peer.PeerAccepted = data.PeerAccepted
+	}
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"accepted",
+		"peer_accepted",
+		"active",
+	)
+
+	errData, err := peer.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = peer.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "peering.change")
+
+	c.JSON(200, peer)
+}
+
+func peeringPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+	data := &peeringData{
+		Name: "New Peering",
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	exists, err := vpc.ExistsOrg(db, userOrg, data.Vpc)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+	if !exists {
+		utils.AbortWithStatus(c, 405)
+		return
+	}
+
+	peer := &peering.Peering{
+		Name:     data.Name,
+		Comment:  data.Comment,
+		Vpc:      data.Vpc,
+		Accepted: true,
+		PeerVpc:  data.PeerVpc,
+	}
+
+	errData, err := peer.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = peer.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "peering.change")
+
+	c.JSON(200, peer)
+}
+
+func peeringDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+
+	peeringId, ok := utils.ParseObjectId(c.Param("peering_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := peering.RemoveOrg(db, userOrg, peeringId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	event.PublishDispatch(db, "peering.change")
+
+	c.JSON(200, nil)
+}
+
+func peeringGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+
+	peeringId, ok := utils.ParseObjectId(c.Param("peering_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	peer, err := peering.GetOrg(db, userOrg, peeringId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, peer)
+}
+
+func peeringsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+
+	peerings, err := peering.GetAllOrg(db, userOrg)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, peerings)
+}
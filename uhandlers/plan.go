@@ -8,8 +8,11 @@ import (
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
 	"github.com/pritunl/pritunl-cloud/demo"
+	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/event"
 	"github.com/pritunl/pritunl-cloud/plan"
+	"github.com/pritunl/pritunl-cloud/planner"
+	"github.com/pritunl/pritunl-cloud/pod"
 	"github.com/pritunl/pritunl-cloud/utils"
 	"regexp"
 	"strconv"
@@ -17,10 +20,15 @@ import (
 )
 
 type planData struct {
-	Id      primitive.ObjectID `json:"id"`
-	Name    string             `json:"name"`
-	Comment string             `json:"comment"`
-	Type    string             `json:"type"`
+	Id             primitive.ObjectID `json:"id"`
+	Name           string             `json:"name"`
+	Comment        string             `json:"comment"`
+	Type           string             `json:"type"`
+	MaxUnavailable int                `json:"max_unavailable"`
+	MaxSurge       int                `json:"max_surge"`
+	HealthCheck    string             `json:"health_check"`
+	Balancer       primitive.ObjectID `json:"balancer"`
+	Timeout        int                `json:"timeout"`
 }
 
 type plansData struct {
@@ -28,6 +36,16 @@ type plansData struct {
 	Count int64        `json:"count"`
 }
 
+type planRunData struct {
+	Pod  primitive.ObjectID `json:"pod"`
+	Role string             `json:"role"`
+}
+
+type planRunsData struct {
+	Runs  []*plan.Run `json:"runs"`
+	Count int64       `json:"count"`
+}
+
 func planPut(c *gin.Context) {
 	if demo.Blocked(c) {
 		return
@@ -58,11 +76,21 @@ func planPut(c *gin.Context) {
 	domn.Name = data.Name
 	domn.Comment = data.Comment
 	domn.Type = data.Type
+	domn.MaxUnavailable = data.MaxUnavailable
+	domn.MaxSurge = data.MaxSurge
+	domn.HealthCheck = data.HealthCheck
+	domn.Balancer = data.Balancer
+	domn.Timeout = data.Timeout
 
 	fields := set.NewSet(
 		"name",
 		"comment",
 		"type",
+		"max_unavailable",
+		"max_surge",
+		"health_check",
+		"balancer",
+		"timeout",
 	)
 
 	errData, err := domn.Validate(db)
@@ -105,10 +133,15 @@ func planPost(c *gin.Context) {
 	}
 
 	domn := &plan.Plan{
-		Name:         data.Name,
-		Comment:      data.Comment,
-		Organization: userOrg,
-		Type:         data.Type,
+		Name:           data.Name,
+		Comment:        data.Comment,
+		Organization:   userOrg,
+		Type:           data.Type,
+		MaxUnavailable: data.MaxUnavailable,
+		MaxSurge:       data.MaxSurge,
+		HealthCheck:    data.HealthCheck,
+		Balancer:       data.Balancer,
+		Timeout:        data.Timeout,
 	}
 
 	errData, err := domn.Validate(db)
@@ -254,3 +287,133 @@ func plansGet(c *gin.Context) {
 		c.JSON(200, data)
 	}
 }
+
+func planRunPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+	data := &planRunData{}
+
+	planId, ok := utils.ParseObjectId(c.Param("plan_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	pln, err := plan.GetOrg(db, userOrg, planId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	data.Role = strings.TrimSpace(data.Role)
+	if data.Pod.IsZero() && data.Role == "" {
+		errData := &errortypes.ErrorData{
+			Error:   "target_required",
+			Message: "Missing required pod or network role",
+		}
+		c.JSON(400, errData)
+		return
+	}
+
+	var run *plan.Run
+	var errData *errortypes.ErrorData
+	if !data.Pod.IsZero() {
+		pd, e := pod.GetOrg(db, userOrg, data.Pod)
+		if e != nil {
+			utils.AbortWithError(c, 500, e)
+			return
+		}
+
+		run, errData, err = planner.StartPod(db, pln, pd)
+	} else {
+		run, errData, err = planner.StartRole(db, pln, data.Role)
+	}
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	event.PublishDispatch(db, "plan.change")
+
+	c.JSON(200, run)
+}
+
+func planRunGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+
+	planId, ok := utils.ParseObjectId(c.Param("plan_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	runId, ok := utils.ParseObjectId(c.Param("run_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	run, err := plan.GetRunOrg(db, userOrg, planId, runId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, run)
+}
+
+func planRunsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	userOrg := c.MustGet("organization").(primitive.ObjectID)
+
+	planId, ok := utils.ParseObjectId(c.Param("plan_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+	if pageCount == 0 {
+		pageCount = 20
+	}
+
+	query := bson.M{
+		"plan":         planId,
+		"organization": userOrg,
+	}
+
+	state := strings.TrimSpace(c.Query("state"))
+	if state != "" {
+		query["state"] = state
+	}
+
+	runs, count, err := plan.GetRunsPaged(db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	data := &planRunsData{
+		Runs:  runs,
+		Count: count,
+	}
+
+	c.JSON(200, data)
+}
@@ -12,11 +12,13 @@ import (
 	"github.com/pritunl/mongo-go-driver/bson"
 	"github.com/pritunl/mongo-go-driver/bson/primitive"
 	"github.com/pritunl/pritunl-cloud/database"
+	"github.com/pritunl/pritunl-cloud/datacenter"
 	"github.com/pritunl/pritunl-cloud/demo"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 	"github.com/pritunl/pritunl-cloud/event"
 	"github.com/pritunl/pritunl-cloud/pod"
 	"github.com/pritunl/pritunl-cloud/utils"
+	"github.com/pritunl/pritunl-cloud/zone"
 )
 
 type podData struct {
@@ -28,6 +30,7 @@ type podData struct {
 	DeleteProtection bool               `json:"delete_protection"`
 	Zone             primitive.ObjectID `json:"zone"`
 	Roles            []string           `json:"roles"`
+	Plan             primitive.ObjectID `json:"plan"`
 	Spec             string             `json:"spec"`
 }
 
@@ -60,6 +63,22 @@ func podPut(c *gin.Context) {
 		return
 	}
 
+	zne, err := zone.Get(db, data.Zone)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	exists, err := datacenter.ExistsOrg(db, userOrg, zne.Datacenter)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+	if !exists {
+		utils.AbortWithStatus(c, 405)
+		return
+	}
+
 	pd, err := pod.GetOrg(db, userOrg, podId)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
@@ -72,6 +91,7 @@ func podPut(c *gin.Context) {
 	pd.DeleteProtection = data.DeleteProtection
 	pd.Zone = data.Zone
 	pd.Roles = data.Roles
+	pd.Plan = data.Plan
 	pd.Spec = data.Spec
 
 	fields := set.NewSet(
@@ -82,6 +102,7 @@ func podPut(c *gin.Context) {
 		"delete_protection",
 		"zone",
 		"roles",
+		"plan",
 		"spec",
 	)
 
@@ -127,6 +148,22 @@ func podPost(c *gin.Context) {
 		return
 	}
 
+	zne, err := zone.Get(db, data.Zone)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	exists, err := datacenter.ExistsOrg(db, userOrg, zne.Datacenter)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+	if !exists {
+		utils.AbortWithStatus(c, 405)
+		return
+	}
+
 	pd := &pod.Pod{
 		Name:             data.Name,
 		Comment:          data.Comment,
@@ -135,6 +172,7 @@ func podPost(c *gin.Context) {
 		DeleteProtection: data.DeleteProtection,
 		Zone:             data.Zone,
 		Roles:            data.Roles,
+		Plan:             data.Plan,
 		Spec:             data.Spec,
 	}
 
@@ -8,12 +8,29 @@ import (
 	"os"
 	"path"
 	"strings"
+	"syscall"
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-cloud/errortypes"
 )
 
+var reservedBlocks = []*net.IPNet{
+	mustParseCidr("0.0.0.0/8"),
+	mustParseCidr("100.64.0.0/10"),
+	mustParseCidr("192.0.0.0/24"),
+	mustParseCidr("198.18.0.0/15"),
+	mustParseCidr("240.0.0.0/4"),
+}
+
+func mustParseCidr(cidr string) *net.IPNet {
+	_, block, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return block
+}
+
 func IncIpAddress(ip net.IP) {
 	for j := len(ip) - 1; j >= 0; j-- {
 		ip[j]++
@@ -134,6 +151,10 @@ func NetworkContains(x, y *net.IPNet) bool {
 	return x.Contains(y.IP) && x.Contains(GetLastIpAddress(y))
 }
 
+func NetworkOverlaps(x, y *net.IPNet) bool {
+	return x.Contains(y.IP) || y.Contains(x.IP)
+}
+
 func ParseIpMask(mask string) net.IPMask {
 	maskIp := net.ParseIP(mask)
 	if maskIp == nil {
@@ -272,3 +293,62 @@ func IsInterfaceBridge(iface string) (bridge bool, err error) {
 
 	return
 }
+
+// PublicIp returns false for loopback, private, link-local and other
+// addresses that must not be reachable from organization requests
+func PublicIp(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+
+		return false
+	}
+
+	for _, block := range reservedBlocks {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func PublicHost(host string) bool {
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return PublicIp(ip)
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+
+	for _, ip := range ips {
+		if !PublicIp(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PublicControl rejects connections to non public addresses after name
+// resolution to prevent rebinding to internal networks
+func PublicControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &errortypes.ConnectionError{
+			errors.Wrap(err, "utils: Invalid address"),
+		}
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !PublicIp(ip) {
+		return &errortypes.ConnectionError{
+			errors.Newf("utils: Address %s not allowed", host),
+		}
+	}
+
+	return nil
+}
@@ -164,6 +164,14 @@ func GetIfaceVlan(id primitive.ObjectID, n int) string {
 	return fmt.Sprintf("x%s%d", strings.ToLower(hashSum), n)
 }
 
+func GetIfacePeer(id primitive.ObjectID, peerVpcId primitive.ObjectID) string {
+	hash := md5.New()
+	hash.Write([]byte(id.Hex()))
+	hash.Write([]byte(peerVpcId.Hex()))
+	hashSum := base32.StdEncoding.EncodeToString(hash.Sum(nil))[:12]
+	return fmt.Sprintf("y%s0", strings.ToLower(hashSum))
+}
+
 func GetNamespace(id primitive.ObjectID, n int) string {
 	hash := md5.New()
 	hash.Write([]byte(id.Hex()))
@@ -15,6 +15,8 @@ type VirtualMachine struct {
 	UnixId              int                `json:"unix_id"`
 	State               string             `json:"state"`
 	Timestamp           time.Time          `json:"timestamp"`
+	GuestStatus         string             `json:"-"`
+	GuestTimestamp      time.Time          `json:"-"`
 	QemuVersion         string             `json:"qemu_version"`
 	DiskType            string             `json:"disk_type"`
 	DiskPool            primitive.ObjectID `json:"disk_pool"`
@@ -166,6 +168,11 @@ func (v *VirtualMachine) Commit(db *database.Database) (err error) {
 		data["qemu_version"] = v.QemuVersion
 	}
 
+	if v.GuestStatus != "" {
+		data["guest_status"] = v.GuestStatus
+		data["guest_timestamp"] = v.GuestTimestamp
+	}
+
 	err = coll.UpdateId(v.Id, &bson.M{
 		"$set": data,
 	})
@@ -244,6 +251,11 @@ func (v *VirtualMachine) CommitState(db *database.Database, state string) (
 		data["qemu_version"] = v.QemuVersion
 	}
 
+	if v.GuestStatus != "" {
+		data["guest_status"] = v.GuestStatus
+		data["guest_timestamp"] = v.GuestTimestamp
+	}
+
 	err = coll.UpdateId(v.Id, &bson.M{
 		"$set": data,
 	})
@@ -444,6 +444,23 @@ func Remove(db *database.Database, vcId primitive.ObjectID) (err error) {
 		return
 	}
 
+	coll = db.VpcPeerings()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"$or": []*bson.M{
+			&bson.M{
+				"vpc": vcId,
+			},
+			&bson.M{
+				"peer_vpc": vcId,
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
 	coll = db.Vpcs()
 
 	_, err = coll.DeleteOne(db, &bson.M{
@@ -475,6 +492,25 @@ func RemoveOrg(db *database.Database, orgId, vcId primitive.ObjectID) (
 		return
 	}
 
+	coll = db.VpcPeerings()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"$or": []*bson.M{
+			&bson.M{
+				"vpc":          vcId,
+				"organization": orgId,
+			},
+			&bson.M{
+				"peer_vpc":          vcId,
+				"peer_organization": orgId,
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
 	coll = db.Vpcs()
 
 	_, err = coll.DeleteOne(db, &bson.M{
@@ -507,6 +543,27 @@ func RemoveMulti(db *database.Database, vcIds []primitive.ObjectID) (err error)
 		return
 	}
 
+	coll = db.VpcPeerings()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"$or": []*bson.M{
+			&bson.M{
+				"vpc": &bson.M{
+					"$in": vcIds,
+				},
+			},
+			&bson.M{
+				"peer_vpc": &bson.M{
+					"$in": vcIds,
+				},
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
 	coll = db.Vpcs()
 
 	_, err = coll.DeleteMany(db, &bson.M{
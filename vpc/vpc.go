@@ -42,6 +42,7 @@ type Vpc struct {
 	Routes           []*Route           `bson:"routes" json:"routes"`
 	Maps             []*Map             `bson:"maps" json:"maps"`
 	DeleteProtection bool               `bson:"delete_protection" json:"delete_protection"`
+	DnsServer        bool               `bson:"dns_server" json:"dns_server"`
 	curSubnets       []*Subnet          `bson:"-" json:"-"`
 }
 
@@ -86,6 +86,38 @@ export function commit(cert: CertificateTypes.Certificate): Promise<void> {
 	});
 }
 
+export function rollback(certId: string, hash: string): Promise<void> {
+	let loader = new Loader().loading();
+
+	return new Promise<void>((resolve, reject): void => {
+		SuperAgent
+			.post('/certificate/' + certId + '/rollback')
+			.send({
+				hash: hash,
+			})
+			.set('Accept', 'application/json')
+			.set('Csrf-Token', Csrf.token)
+			.set('Organization', OrganizationsStore.current)
+			.end((err: any, res: SuperAgent.Response): void => {
+				loader.done();
+
+				if (res && res.status === 401) {
+					window.location.href = '/login';
+					resolve();
+					return;
+				}
+
+				if (err) {
+					Alert.errorRes(res, 'Failed to rollback certificate');
+					reject(err);
+					return;
+				}
+
+				resolve();
+			});
+	});
+}
+
 export function create(cert: CertificateTypes.Certificate): Promise<void> {
 	let loader = new Loader().loading();
 
@@ -0,0 +1,357 @@
+/// <reference path="../References.d.ts"/>
+import * as React from 'react';
+import * as AddrGroupTypes from '../types/AddrGroupTypes';
+import * as OrganizationTypes from '../types/OrganizationTypes';
+import * as AddrGroupActions from '../actions/AddrGroupActions';
+import PageInput from './PageInput';
+import PageSelect from './PageSelect';
+import PageInfo from './PageInfo';
+import PageInputButton from './PageInputButton';
+import PageTextArea from './PageTextArea';
+import PageSave from './PageSave';
+import ConfirmButton from './ConfirmButton';
+import Help from './Help';
+import * as Constants from "../Constants";
+
+interface Props {
+	addrGroup: AddrGroupTypes.AddrGroupRo;
+	organizations: OrganizationTypes.OrganizationsRo;
+}
+
+interface State {
+	disabled: boolean;
+	changed: boolean;
+	message: string;
+	addAddress: string;
+	addrGroup: AddrGroupTypes.AddrGroup;
+}
+
+const css = {
+	card: {
+		position: 'relative',
+		padding: '10px 10px 0 10px',
+		marginBottom: '5px',
+	} as React.CSSProperties,
+	remove: {
+		position: 'absolute',
+		top: '5px',
+		right: '5px',
+	} as React.CSSProperties,
+	item: {
+		margin: '9px 5px 0 5px',
+		height: '20px',
+	} as React.CSSProperties,
+	group: {
+		flex: 1,
+		minWidth: '280px',
+		margin: '0 10px',
+	} as React.CSSProperties,
+	save: {
+		paddingBottom: '10px',
+	} as React.CSSProperties,
+};
+
+export default class AddrGroup extends React.Component<Props, State> {
+	constructor(props: any, context: any) {
+		super(props, context);
+		this.state = {
+			disabled: false,
+			changed: false,
+			message: '',
+			addAddress: null,
+			addrGroup: null,
+		};
+	}
+
+	set(name: string, val: any): void {
+		let addrGroup: any;
+
+		if (this.state.changed) {
+			addrGroup = {
+				...this.state.addrGroup,
+			};
+		} else {
+			addrGroup = {
+				...this.props.addrGroup,
+			};
+		}
+
+		addrGroup[name] = val;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			addrGroup: addrGroup,
+		});
+	}
+
+	onAddAddress = (): void => {
+		let addrGroup: AddrGroupTypes.AddrGroup;
+
+		if (!this.state.addAddress) {
+			return;
+		}
+
+		if (this.state.changed) {
+			addrGroup = {
+				...this.state.addrGroup,
+			};
+		} else {
+			addrGroup = {
+				...this.props.addrGroup,
+			};
+		}
+
+		let addresses = [
+			...(addrGroup.addresses || []),
+		];
+
+		let address = this.state.addAddress.trim();
+		if (addresses.indexOf(address) === -1) {
+			addresses.push(address);
+		}
+
+		addrGroup.addresses = addresses;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			addAddress: '',
+			addrGroup: addrGroup,
+		});
+	}
+
+	onRemoveAddress = (address: string): void => {
+		let addrGroup: AddrGroupTypes.AddrGroup;
+
+		if (this.state.changed) {
+			addrGroup = {
+				...this.state.addrGroup,
+			};
+		} else {
+			addrGroup = {
+				...this.props.addrGroup,
+			};
+		}
+
+		let addresses = [
+			...(addrGroup.addresses || []),
+		];
+
+		let i = addresses.indexOf(address);
+		if (i === -1) {
+			return;
+		}
+
+		addresses.splice(i, 1);
+		addrGroup.addresses = addresses;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			addAddress: '',
+			addrGroup: addrGroup,
+		});
+	}
+
+	onSave = (): void => {
+		this.setState({
+			...this.state,
+			disabled: true,
+		});
+		AddrGroupActions.commit(this.state.addrGroup).then((): void => {
+			this.setState({
+				...this.state,
+				message: 'Your changes have been saved',
+				changed: false,
+				disabled: false,
+			});
+
+			setTimeout((): void => {
+				if (!this.state.changed) {
+					this.setState({
+						...this.state,
+						message: '',
+						changed: false,
+						addrGroup: null,
+					});
+				}
+			}, 3000);
+		}).catch((): void => {
+			this.setState({
+				...this.state,
+				message: '',
+				disabled: false,
+			});
+		});
+	}
+
+	onDelete = (): void => {
+		this.setState({
+			...this.state,
+			disabled: true,
+		});
+		AddrGroupActions.remove(this.props.addrGroup.id).then((): void => {
+			this.setState({
+				...this.state,
+				disabled: false,
+			});
+		}).catch((): void => {
+			this.setState({
+				...this.state,
+				disabled: false,
+			});
+		});
+	}
+
+	render(): JSX.Element {
+		let group: AddrGroupTypes.AddrGroup = this.state.addrGroup ||
+			this.props.addrGroup;
+
+		let organizationsSelect: JSX.Element[] = [];
+		organizationsSelect.push(
+			<option key="null" value="">
+				Node Address Group
+			</option>,
+		);
+		if (this.props.organizations.length) {
+			for (let organization of this.props.organizations) {
+				organizationsSelect.push(
+					<option
+						key={organization.id}
+						value={organization.id}
+					>{organization.name}</option>,
+				);
+			}
+		}
+
+		let addresses: JSX.Element[] = [];
+		for (let address of (group.addresses || [])) {
+			addresses.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-primary"
+					style={css.item}
+					key={address}
+				>
+					{address}
+					<button
+						className="bp5-tag-remove"
+						disabled={this.state.disabled}
+						onMouseUp={(): void => {
+							this.onRemoveAddress(address);
+						}}
+					/>
+				</div>,
+			);
+		}
+
+		return <div
+			className="bp5-card"
+			style={css.card}
+		>
+			<div className="layout horizontal wrap">
+				<div style={css.group}>
+					<div style={css.remove}>
+						<ConfirmButton
+							safe={true}
+							className="bp5-minimal bp5-intent-danger bp5-icon-trash"
+							progressClassName="bp5-intent-danger"
+							dialogClassName="bp5-intent-danger bp5-icon-delete"
+							dialogLabel="Delete Address Group"
+							confirmMsg="Permanently delete this address group"
+							confirmInput={true}
+							items={[group.name]}
+							disabled={this.state.disabled}
+							onConfirm={this.onDelete}
+						/>
+					</div>
+					<PageInput
+						label="Name"
+						help="Name of address group"
+						type="text"
+						placeholder="Name"
+						value={group.name}
+						onChange={(val): void => {
+							this.set('name', val);
+						}}
+					/>
+					<PageTextArea
+						label="Comment"
+						help="Address group comment."
+						placeholder="Address group comment"
+						rows={3}
+						value={group.comment}
+						onChange={(val: string): void => {
+							this.set('comment', val);
+						}}
+					/>
+					<label className="bp5-label">
+						Addresses
+						<Help
+							title="Addresses"
+							content="IP addresses and networks in the address group. Firewall rules that reference the address group will match these addresses."
+						/>
+						<div>
+							{addresses}
+						</div>
+					</label>
+					<PageInputButton
+						disabled={this.state.disabled}
+						buttonClass="bp5-intent-success bp5-icon-add"
+						label="Add"
+						type="text"
+						placeholder="Add address"
+						value={this.state.addAddress}
+						onChange={(val): void => {
+							this.setState({
+								...this.state,
+								addAddress: val,
+							});
+						}}
+						onSubmit={this.onAddAddress}
+					/>
+				</div>
+				<div style={css.group}>
+					<PageInfo
+						fields={[
+							{
+								label: 'ID',
+								value: this.props.addrGroup.id || 'None',
+							},
+						]}
+					/>
+					<PageSelect
+						disabled={this.state.disabled}
+						hidden={Constants.user}
+						label="Organization"
+						help="Organization for address group, the organization must match the firewall organization. Select node to create an address group for node firewalls."
+						value={group.organization}
+						onChange={(val): void => {
+							this.set('organization', val);
+						}}
+					>
+						{organizationsSelect}
+					</PageSelect>
+				</div>
+			</div>
+			<PageSave
+				style={css.save}
+				hidden={!this.state.addrGroup}
+				message={this.state.message}
+				changed={this.state.changed}
+				disabled={this.state.disabled}
+				light={true}
+				onCancel={(): void => {
+					this.setState({
+						...this.state,
+						changed: false,
+						addrGroup: null,
+					});
+				}}
+				onSave={this.onSave}
+			/>
+		</div>;
+	}
+}
@@ -0,0 +1,123 @@
+/// <reference path="../References.d.ts"/>
+import * as React from 'react';
+import * as AddrGroupTypes from '../types/AddrGroupTypes';
+import * as OrganizationTypes from '../types/OrganizationTypes';
+import AddrGroupsStore from '../stores/AddrGroupsStore';
+import OrganizationsStore from '../stores/OrganizationsStore';
+import * as AddrGroupActions from '../actions/AddrGroupActions';
+import * as OrganizationActions from '../actions/OrganizationActions';
+import NonState from './NonState';
+import AddrGroup from './AddrGroup';
+import Page from './Page';
+import PageHeader from './PageHeader';
+
+interface State {
+	addrGroups: AddrGroupTypes.AddrGroupsRo;
+	organizations: OrganizationTypes.OrganizationsRo;
+	disabled: boolean;
+}
+
+const css = {
+	header: {
+		marginTop: '-19px',
+	} as React.CSSProperties,
+	heading: {
+		margin: '19px 0 0 0',
+	} as React.CSSProperties,
+	button: {
+		margin: '8px 0 0 8px',
+	} as React.CSSProperties,
+	buttons: {
+		marginTop: '8px',
+	} as React.CSSProperties,
+	noGroups: {
+		height: 'auto',
+	} as React.CSSProperties,
+};
+
+export default class AddrGroups extends React.Component<{}, State> {
+	constructor(props: any, context: any) {
+		super(props, context);
+		this.state = {
+			addrGroups: AddrGroupsStore.addrGroups,
+			organizations: OrganizationsStore.organizations,
+			disabled: false,
+		};
+	}
+
+	componentDidMount(): void {
+		AddrGroupsStore.addChangeListener(this.onChange);
+		OrganizationsStore.addChangeListener(this.onChange);
+		AddrGroupActions.sync();
+		OrganizationActions.sync();
+	}
+
+	componentWillUnmount(): void {
+		AddrGroupsStore.removeChangeListener(this.onChange);
+		OrganizationsStore.removeChangeListener(this.onChange);
+	}
+
+	onChange = (): void => {
+		this.setState({
+			...this.state,
+			addrGroups: AddrGroupsStore.addrGroups,
+			organizations: OrganizationsStore.organizations,
+		});
+	}
+
+	render(): JSX.Element {
+		let groupsDom: JSX.Element[] = [];
+
+		this.state.addrGroups.forEach((
+				group: AddrGroupTypes.AddrGroupRo): void => {
+			groupsDom.push(<AddrGroup
+				key={group.id}
+				addrGroup={group}
+				organizations={this.state.organizations}
+			/>);
+		});
+
+		return <Page>
+			<PageHeader>
+				<div className="layout horizontal wrap" style={css.header}>
+					<h2 style={css.heading}>Address Groups</h2>
+					<div className="flex"/>
+					<div style={css.buttons}>
+						<button
+							className="bp5-button bp5-intent-success bp5-icon-add"
+							style={css.button}
+							disabled={this.state.disabled}
+							type="button"
+							onClick={(): void => {
+								this.setState({
+									...this.state,
+									disabled: true,
+								});
+								AddrGroupActions.create(null).then((): void => {
+									this.setState({
+										...this.state,
+										disabled: false,
+									});
+								}).catch((): void => {
+									this.setState({
+										...this.state,
+										disabled: false,
+									});
+								});
+							}}
+						>New</button>
+					</div>
+				</div>
+			</PageHeader>
+			<div>
+				{groupsDom}
+			</div>
+			<NonState
+				hidden={!!groupsDom.length}
+				iconClass="bp5-icon-ip-address"
+				title="No address groups"
+				description="Add a new address group to get started."
+			/>
+		</Page>;
+	}
+}
@@ -3,6 +3,7 @@ import * as React from 'react';
 import * as BalancerTypes from '../types/BalancerTypes';
 
 interface Props {
+	type: string;
 	backend: BalancerTypes.Backend;
 	onChange: (state: BalancerTypes.Backend) => void;
 	onRemove: () => void;
@@ -11,7 +12,7 @@ interface Props {
 const css = {
 	group: {
 		width: '100%',
-		maxWidth: '310px',
+		maxWidth: '420px',
 		marginTop: '5px',
 	} as React.CSSProperties,
 	protocol: {
@@ -26,7 +27,14 @@ const css = {
 	port: {
 		flex: '0 1 auto',
 		width: '52px',
-		borderRadius: '0 3px 3px 0',
+	} as React.CSSProperties,
+	groupName: {
+		flex: '0 1 auto',
+		width: '70px',
+	} as React.CSSProperties,
+	weight: {
+		flex: '0 1 auto',
+		width: '52px',
 	} as React.CSSProperties,
 };
 
@@ -39,9 +47,10 @@ export default class BalancerBackend extends React.Component<Props, {}> {
 
 	render(): JSX.Element {
 		let backend = this.props.backend;
+		let stream = this.props.type === 'tcp' || this.props.type === 'udp';
 
 		return <div className="bp5-control-group" style={css.group}>
-			<div className="bp5-select" style={css.protocol}>
+			<div className="bp5-select" style={css.protocol} hidden={stream}>
 				<select
 					value={backend.protocol}
 					onChange={(evt): void => {
@@ -76,11 +85,53 @@ export default class BalancerBackend extends React.Component<Props, {}> {
 				type="text"
 				autoCapitalize="off"
 				spellCheck={false}
-				placeholder="Port"
-				value={backend.port}
+				placeholder={stream ? 'Listen' : 'Port'}
+				value={stream ? (backend.port || '') : backend.port}
 				onChange={(evt): void => {
 					let state = this.clone();
 					state.port = parseInt(evt.target.value, 10);
+					if (stream && !state.port) {
+						state.port = 0;
+					}
+					this.props.onChange(state);
+				}}
+			/>
+			<input
+				className="bp5-input"
+				style={css.groupName}
+				hidden={stream}
+				type="text"
+				autoCapitalize="off"
+				spellCheck={false}
+				placeholder="Group"
+				value={backend.group || ''}
+				onChange={(evt): void => {
+					let state = this.clone();
+					state.group = evt.target.value;
+					this.props.onChange(state);
+				}}
+			/>
+			<input
+				className="bp5-input"
+				style={css.weight}
+				type="text"
+				autoCapitalize="off"
+				spellCheck={false}
+				placeholder="Weight"
+				value={backend.weight || ''}
+				onChange={(evt): void => {
+					let state = this.clone();
+					state.weight = parseInt(evt.target.value, 10) || 0;
+					this.props.onChange(state);
+				}}
+			/>
+			<button
+				className={'bp5-button bp5-icon-pause' + (
+					backend.drain ? ' bp5-intent-warning bp5-active' : '')}
+				title={backend.drain ? 'Stop Draining' : 'Drain'}
+				onClick={(): void => {
+					let state = this.clone();
+					state.drain = !state.drain;
 					this.props.onChange(state);
 				}}
 			/>
@@ -12,6 +12,8 @@ import CertificatesStore from '../stores/CertificatesStore';
 import PageInput from './PageInput';
 import PageSelect from './PageSelect';
 import PageInfo from './PageInfo';
+import * as PageInfos from './PageInfo';
+import PageInputButton from './PageInputButton';
 import PageSave from './PageSave';
 import ConfirmButton from './ConfirmButton';
 import Help from './Help';
@@ -35,6 +37,10 @@ interface State {
 	message: string;
 	balancer: BalancerTypes.Balancer;
 	addCert: string;
+	addListenPort: string;
+	addCheckStatus: string;
+	addAllowSource: string;
+	addDenySource: string;
 }
 
 const css = {
@@ -115,6 +121,10 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 			message: '',
 			balancer: null,
 			addCert: null,
+			addListenPort: '',
+			addCheckStatus: '',
+			addAllowSource: '',
+			addDenySource: '',
 		};
 	}
 
@@ -140,6 +150,296 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 		});
 	}
 
+	onChangeType = (val: string): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let stream = val === 'tcp' || val === 'udp';
+		let backends: BalancerTypes.Backend[] = [];
+		for (let backend of (balancer.backends || [])) {
+			let protocol = backend.protocol;
+			if (stream) {
+				protocol = val;
+			} else if (protocol !== 'http' && protocol !== 'https') {
+				protocol = 'http';
+			}
+
+			backends.push({
+				...backend,
+				protocol: protocol,
+			});
+		}
+
+		balancer.type = val;
+		balancer.backends = backends;
+		if (stream && balancer.affinity === 'cookie') {
+			balancer.affinity = '';
+		}
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			balancer: balancer,
+		});
+	}
+
+	onAddListenPort = (): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		let port = parseInt(this.state.addListenPort, 10);
+		if (!port) {
+			return;
+		}
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let listenPorts = [
+			...(balancer.listen_ports || []),
+		];
+
+		if (listenPorts.indexOf(port) === -1) {
+			listenPorts.push(port);
+		}
+
+		listenPorts.sort((a, b) => a - b);
+		balancer.listen_ports = listenPorts;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			addListenPort: '',
+			balancer: balancer,
+		});
+	}
+
+	latencyPercentile(latency: number[], pct: number): string {
+		let total = 0;
+		for (let count of latency) {
+			total += count || 0;
+		}
+		if (!total) {
+			return '-';
+		}
+
+		let target = Math.ceil(total * pct);
+		let count = 0;
+		for (let i = 0; i < latency.length; i++) {
+			count += latency[i] || 0;
+			if (count >= target) {
+				if (i < BalancerTypes.LatencyBuckets.length) {
+					return '<' + BalancerTypes.LatencyBuckets[i] + 'ms';
+				}
+				break;
+			}
+		}
+
+		return '>' + BalancerTypes.LatencyBuckets[
+			BalancerTypes.LatencyBuckets.length - 1] + 'ms';
+	}
+
+	onAddSource = (deny: boolean): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		let source = (deny ? this.state.addDenySource :
+			this.state.addAllowSource).trim();
+		if (!source) {
+			return;
+		}
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let sources = [
+			...((deny ? balancer.deny_sources : balancer.allow_sources) || []),
+		];
+
+		if (sources.indexOf(source) === -1) {
+			sources.push(source);
+		}
+
+		if (deny) {
+			balancer.deny_sources = sources;
+		} else {
+			balancer.allow_sources = sources;
+		}
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			addAllowSource: deny ? this.state.addAllowSource : '',
+			addDenySource: deny ? '' : this.state.addDenySource,
+			balancer: balancer,
+		});
+	}
+
+	onRemoveSource = (deny: boolean, source: string): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let sources = [
+			...((deny ? balancer.deny_sources : balancer.allow_sources) || []),
+		];
+
+		let i = sources.indexOf(source);
+		if (i === -1) {
+			return;
+		}
+
+		sources.splice(i, 1);
+		if (deny) {
+			balancer.deny_sources = sources;
+		} else {
+			balancer.allow_sources = sources;
+		}
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			balancer: balancer,
+		});
+	}
+
+	onRemoveListenPort = (port: number): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let listenPorts = [
+			...(balancer.listen_ports || []),
+		];
+
+		let i = listenPorts.indexOf(port);
+		if (i === -1) {
+			return;
+		}
+
+		listenPorts.splice(i, 1);
+		balancer.listen_ports = listenPorts;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			balancer: balancer,
+		});
+	}
+
+	onAddCheckStatus = (): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		let code = parseInt(this.state.addCheckStatus, 10);
+		if (!code) {
+			return;
+		}
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let codes = [
+			...(balancer.check_status_codes || []),
+		];
+
+		if (codes.indexOf(code) === -1) {
+			codes.push(code);
+		}
+
+		codes.sort((a, b) => a - b);
+		balancer.check_status_codes = codes;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			addCheckStatus: '',
+			balancer: balancer,
+		});
+	}
+
+	onRemoveCheckStatus = (code: number): void => {
+		let balancer: BalancerTypes.Balancer;
+
+		if (this.state.changed) {
+			balancer = {
+				...this.state.balancer,
+			};
+		} else {
+			balancer = {
+				...this.props.balancer,
+			};
+		}
+
+		let codes = [
+			...(balancer.check_status_codes || []),
+		];
+
+		let i = codes.indexOf(code);
+		if (i === -1) {
+			return;
+		}
+
+		codes.splice(i, 1);
+		balancer.check_status_codes = codes;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			balancer: balancer,
+		});
+	}
+
 	onAddBackend = (): void => {
 		let balancer: BalancerTypes.Balancer;
 
@@ -153,12 +453,14 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 			};
 		}
 
+		let stream = balancer.type === 'tcp' || balancer.type === 'udp';
 		let backends = [
 			...balancer.backends,
 			{
-				protocol: 'http',
+				protocol: stream ? balancer.type : 'http',
 				hostname: '',
-				port: 80,
+				port: stream ? 0 : 80,
+				weight: 1,
 			},
 		];
 
@@ -509,6 +811,7 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 			backends.push(
 				<BalancerBackend
 					key={index}
+					type={balancer.type}
 					backend={balancer.backends[index]}
 					onChange={(state: BalancerTypes.Backend): void => {
 						this.onChangeBackend(index, state);
@@ -520,6 +823,88 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 			);
 		}
 
+		let stream = balancer.type === 'tcp' || balancer.type === 'udp';
+
+		let listenPorts: JSX.Element[] = [];
+		for (let port of (balancer.listen_ports || [])) {
+			listenPorts.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-primary"
+					style={css.item}
+					key={port}
+				>
+					{port}
+					<button
+						disabled={this.state.disabled}
+						className="bp5-tag-remove"
+						onMouseUp={(): void => {
+							this.onRemoveListenPort(port);
+						}}
+					/>
+				</div>,
+			);
+		}
+
+		let allowSources: JSX.Element[] = [];
+		for (let source of (balancer.allow_sources || [])) {
+			allowSources.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-primary"
+					style={css.item}
+					key={source}
+				>
+					{source}
+					<button
+						disabled={this.state.disabled}
+						className="bp5-tag-remove"
+						onMouseUp={(): void => {
+							this.onRemoveSource(false, source);
+						}}
+					/>
+				</div>,
+			);
+		}
+
+		let denySources: JSX.Element[] = [];
+		for (let source of (balancer.deny_sources || [])) {
+			denySources.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-danger"
+					style={css.item}
+					key={source}
+				>
+					{source}
+					<button
+						disabled={this.state.disabled}
+						className="bp5-tag-remove"
+						onMouseUp={(): void => {
+							this.onRemoveSource(true, source);
+						}}
+					/>
+				</div>,
+			);
+		}
+
+		let checkStatusCodes: JSX.Element[] = [];
+		for (let code of (balancer.check_status_codes || [])) {
+			checkStatusCodes.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-primary"
+					style={css.item}
+					key={code}
+				>
+					{code}
+					<button
+						disabled={this.state.disabled}
+						className="bp5-tag-remove"
+						onMouseUp={(): void => {
+							this.onRemoveCheckStatus(code);
+						}}
+					/>
+				</div>,
+			);
+		}
+
 		let certificates: JSX.Element[] = [];
 		for (let certId of (balancer.certificates || [])) {
 			let cert = CertificatesStore.certificate(certId);
@@ -594,7 +979,10 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 
 		let requests = 0;
 		let retries = 0;
+		let rejected = 0;
 		let websockets = 0;
+		let connections = 0;
+		let backendCounters: {[index: string]: BalancerTypes.BackendState} = {};
 		let states: string[] = [];
 		let statesMap: {[index: string]: number} = {};
 		let online: string[] = [];
@@ -602,6 +990,7 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 		let unknownMid: string[] = [];
 		let unknownLow: string[] = [];
 		let offline: string[] = [];
+		let draining: {[index: string]: boolean} = {};
 		let backendsClasses: string[] = [];
 
 		if (this.props.balancer.state && balancer.states) {
@@ -613,8 +1002,50 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 
 				requests += state.requests || 0;
 				retries += state.retries || 0;
+				rejected += state.rejected || 0;
 				websockets += state.websockets || 0;
 
+				for (let backendState of (state.backends || [])) {
+					connections += backendState.connections || 0;
+
+					let counter = backendCounters[backendState.key];
+					if (!counter) {
+						counter = {
+							key: backendState.key,
+							connections: 0,
+							connections_total: 0,
+							errors: 0,
+							requests: 0,
+							latency: [],
+							statuses: {},
+						};
+						backendCounters[backendState.key] = counter;
+					}
+					counter.connections += backendState.connections || 0;
+					counter.connections_total +=
+						backendState.connections_total || 0;
+					counter.errors += backendState.errors || 0;
+					counter.requests += backendState.requests || 0;
+
+					let latency = backendState.latency || [];
+					for (let i = 0; i < latency.length; i++) {
+						counter.latency[i] = (counter.latency[i] || 0) + latency[i];
+					}
+
+					let statuses = backendState.statuses || {};
+					for (let status in statuses) {
+						if (!statuses.hasOwnProperty(status)) {
+							continue;
+						}
+						counter.statuses[status] = (counter.statuses[status] || 0) +
+							statuses[status];
+					}
+				}
+
+				for (let backend of (state.draining || [])) {
+					draining[backend] = true;
+				}
+
 				for (let backend of state.offline) {
 					let curState = statesMap[backend];
 					if (curState === undefined || curState > 1) {
@@ -674,27 +1105,32 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 
 			online.sort();
 			for (let backend of online) {
-				states.push(backend + ' - Online');
+				states.push(backend + ' - Online' +
+					(draining[backend] ? ' - Draining' : ''));
 				backendsClasses.push('bp5-text-intent-success');
 			}
 			unknownHigh.sort();
 			for (let backend of unknownHigh) {
-				states.push(backend + ' - Unknown High');
+				states.push(backend + ' - Unknown High' +
+					(draining[backend] ? ' - Draining' : ''));
 				backendsClasses.push('bp5-text-intent-warning');
 			}
 			unknownMid.sort();
 			for (let backend of unknownMid) {
-				states.push(backend + ' - Unknown Mid');
+				states.push(backend + ' - Unknown Mid' +
+					(draining[backend] ? ' - Draining' : ''));
 				backendsClasses.push('bp5-text-intent-warning');
 			}
 			unknownLow.sort();
 			for (let backend of unknownLow) {
-				states.push(backend + ' - Unknown Low');
+				states.push(backend + ' - Unknown Low' +
+					(draining[backend] ? ' - Draining' : ''));
 				backendsClasses.push('bp5-text-intent-warning');
 			}
 			offline.sort();
 			for (let backend of offline) {
-				states.push(backend + ' - Offline');
+				states.push(backend + ' - Offline' +
+					(draining[backend] ? ' - Draining' : ''));
 				backendsClasses.push('bp5-text-intent-danger');
 			}
 		}
@@ -703,6 +1139,97 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 			states = ['-'];
 		}
 
+		let counters: string[] = [];
+		let backendKeys = Object.keys(backendCounters);
+		backendKeys.sort();
+		for (let key of backendKeys) {
+			let counter = backendCounters[key];
+			counters.push(key + ' - ' + counter.connections + ' active, ' +
+				counter.connections_total + ' total, ' + counter.errors +
+				' errors');
+		}
+
+		if (!counters.length) {
+			counters = ['-'];
+		}
+
+		let metrics: string[] = [];
+		for (let key of backendKeys) {
+			let counter = backendCounters[key];
+			if (!counter.requests && !counter.errors) {
+				continue;
+			}
+
+			let metric = key + ' - ' + counter.requests + ' requests, p50 ' +
+				this.latencyPercentile(counter.latency, 0.5) + ', p99 ' +
+				this.latencyPercentile(counter.latency, 0.99);
+
+			let statuses = Object.keys(counter.statuses);
+			statuses.sort();
+			for (let status of statuses) {
+				metric += ', ' + counter.statuses[status] + ' ' + status;
+			}
+			metric += ', ' + counter.errors + ' errors';
+
+			metrics.push(metric);
+		}
+
+		if (!metrics.length) {
+			metrics = ['-'];
+		}
+
+		let infoFields: PageInfos.Field[] = [
+			{
+				label: 'ID',
+				value: this.props.balancer.id || 'Unknown',
+			},
+		];
+		if (stream) {
+			infoFields.push(
+				{
+					label: 'Connections',
+					value: connections,
+				},
+				{
+					label: 'Backends',
+					value: states,
+					valueClasses: backendsClasses,
+				},
+				{
+					label: 'Backend Connections',
+					value: counters,
+				},
+			);
+		} else {
+			infoFields.push(
+				{
+					label: 'Requests',
+					value: requests + '/min',
+				},
+				{
+					label: 'Retries',
+					value: retries + '/min',
+				},
+				{
+					label: 'Rejected',
+					value: rejected + '/min',
+				},
+				{
+					label: 'WebSockets',
+					value: websockets,
+				},
+				{
+					label: 'Backends',
+					value: states,
+					valueClasses: backendsClasses,
+				},
+				{
+					label: 'Backend Latency',
+					value: metrics,
+				},
+			);
+		}
+
 		return <td
 			className="bp5-cell"
 			colSpan={5}
@@ -788,10 +1315,12 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 						help="Load balancer type"
 						value={balancer.type}
 						onChange={(val): void => {
-							this.set('type', val);
+							this.onChangeType(val);
 						}}
 					>
 						<option value="http">HTTP</option>
+						<option value="tcp">TCP</option>
+						<option value="udp">UDP</option>
 					</PageSelect>
 					<PageSelect
 						disabled={this.state.disabled || !hasDatacenters}
@@ -804,18 +1333,49 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 					>
 						{datacentersSelect}
 					</PageSelect>
-					<label style={css.itemsLabel}>
+					<label
+						className="bp5-label"
+						style={css.label}
+						hidden={!stream}
+					>
+						Listen Ports
+						<Help
+							title="Listen Ports"
+							content="Ports that the load balancer will listen on for TCP or UDP connections on each balancer node in the datacenter. Load balancers of the same type in the same datacenter should not share listen ports."
+						/>
+						<div>
+							{listenPorts}
+						</div>
+					</label>
+					<PageInputButton
+						disabled={this.state.disabled}
+						buttonClass="bp5-intent-success bp5-icon-add"
+						hidden={!stream}
+						label="Add"
+						type="text"
+						placeholder="Add port"
+						value={this.state.addListenPort}
+						onChange={(val): void => {
+							this.setState({
+								...this.state,
+								addListenPort: val,
+							});
+						}}
+						onSubmit={this.onAddListenPort}
+					/>
+					<label style={css.itemsLabel} hidden={stream}>
 						External Domains
 						<Help
 							title="External Domains"
-							content="When a request comes into a node the requests host will be used to match the request with the domain of a load balancer. Some internal services will be expecting a specific host such as a web server that serves mutliple websites that is also matching the requests host to one of the mutliple websites. If the internal service is expecting a different host set the host field, otherwise leave it blank. Load balancers that are associated with the same datacenter should not also have the same domains."
+							content="When a request comes into a node the requests host will be used to match the request with the domain of a load balancer. Some internal services will be expecting a specific host such as a web server that serves mutliple websites that is also matching the requests host to one of the mutliple websites. If the internal service is expecting a different host set the host field, otherwise leave it blank. Load balancers that are associated with the same datacenter should not also have the same domains. Rules are checked in order and the first rule where the path prefix, method and header all match will send the request to the backend group of the rule."
 						/>
 					</label>
-					{domains}
+					{stream ? null : domains}
 					<button
 						className="bp5-button bp5-intent-success bp5-icon-add"
 						style={css.itemsAdd}
 						type="button"
+						hidden={stream}
 						onClick={this.onAddDomain}
 					>
 						Add Domain
@@ -824,7 +1384,7 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 						Internal Backends
 						<Help
 							title="Internal Backends"
-							content="After a node receives a request it will be forwarded to the internal servers and the response will be sent back to the user. Multiple internal servers can be added to balance the requests between the servers. If a domain is used with HTTPS the internal server must have a valid certificate. When an IP address is used with HTTPS the internal servers certificate will not be validated."
+							content="After a node receives a request it will be forwarded to the internal servers and the response will be sent back to the user. Multiple internal servers can be added to balance the requests between the servers. If a domain is used with HTTPS the internal server must have a valid certificate. When an IP address is used with HTTPS the internal servers certificate will not be validated. For TCP and UDP balancers leave the port empty to connect to the backend on the same port the connection was received on. Backends can be placed in a named group that domain rules route requests to, backends without a group receive requests that do not match a rule. The weight sets the share of requests a backend receives relative to the other backends in the group. A draining backend receives no new sessions and the remaining sessions are closed after the drain timeout."
 						/>
 					</label>
 					{backends}
@@ -839,32 +1399,11 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 				</div>
 				<div style={css.group}>
 					<PageInfo
-						fields={[
-							{
-								label: 'ID',
-								value: this.props.balancer.id || 'Unknown',
-							},
-							{
-								label: 'Requests',
-								value: requests + '/min',
-							},
-							{
-								label: 'Retries',
-								value: retries + '/min',
-							},
-							{
-								label: 'WebSockets',
-								value: websockets,
-							},
-							{
-								label: 'Backends',
-								value: states,
-								valueClasses: backendsClasses,
-							}
-						]}
+						fields={infoFields}
 					/>
 					<PageSwitch
 						disabled={this.state.disabled}
+						hidden={stream}
 						label="WebSockets"
 						help="Enable or disable WebSocket support on balancer."
 						checked={balancer.websockets}
@@ -887,6 +1426,7 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 					<label
 						className="bp5-label"
 						style={css.label}
+						hidden={stream}
 					>
 						Certificates
 						<Help
@@ -898,6 +1438,7 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 						</div>
 					</label>
 					<PageSelectButton
+						hidden={stream}
 						label="Add Certificate"
 						value={this.state.addCert}
 						disabled={this.state.disabled || !hasCertificates}
@@ -913,8 +1454,9 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 						{certificatesSelect}
 					</PageSelectButton>
 					<PageInput
+						hidden={stream}
 						label="Health Check Path"
-						help="Path to check status of backend servers. Path must return 200-299 status code."
+						help="Path to check status of backend servers. Path must return one of the health check status codes."
 						type="text"
 						placeholder="Enter path"
 						value={balancer.check_path}
@@ -922,6 +1464,286 @@ export default class BalancerDetailed extends React.Component<Props, State> {
 							this.set('check_path', val);
 						}}
 					/>
+					<PageSelect
+						disabled={this.state.disabled}
+						hidden={balancer.type !== 'tcp'}
+						label="Proxy Protocol"
+						help="Send a PROXY protocol header to backends with the original client address. Backends must be configured to accept the header."
+						value={balancer.proxy_protocol || ''}
+						onChange={(val): void => {
+							this.set('proxy_protocol', val);
+						}}
+					>
+						<option value="">Disabled</option>
+						<option value="v1">Version 1</option>
+						<option value="v2">Version 2</option>
+					</PageSelect>
+					<PageSelect
+						disabled={this.state.disabled}
+						label="Session Affinity"
+						help="Send requests from the same client to the same backend. Cookie affinity sets a cookie identifying the backend on the first response. Source IP affinity selects the backend from the client address and is the only option available for TCP and UDP balancers."
+						value={balancer.affinity || ''}
+						onChange={(val): void => {
+							this.set('affinity', val);
+						}}
+					>
+						<option value="">Disabled</option>
+						<option value="cookie" hidden={stream}>Cookie</option>
+						<option value="source_ip">Source IP</option>
+					</PageSelect>
+					<PageInput
+						label="Drain Timeout"
+						help="Number of seconds to allow existing requests, websockets and connections to a draining backend to complete before they are closed."
+						type="text"
+						placeholder="300"
+						value={balancer.drain_timeout || ''}
+						onChange={(val): void => {
+							this.set('drain_timeout', parseInt(val, 10) || 0);
+						}}
+					/>
+					<label
+						className="bp5-label"
+						style={css.label}
+						hidden={stream}
+					>
+						Allowed Sources
+						<Help
+							title="Allowed Sources"
+							content="Client IP addresses or networks allowed to send requests to the load balancer. When empty all clients are allowed. Requests from other clients will be rejected with a 403 status."
+						/>
+						<div>
+							{allowSources}
+						</div>
+					</label>
+					<PageInputButton
+						disabled={this.state.disabled}
+						buttonClass="bp5-intent-success bp5-icon-add"
+						hidden={stream}
+						label="Add"
+						type="text"
+						placeholder="Add source"
+						value={this.state.addAllowSource}
+						onChange={(val): void => {
+							this.setState({
+								...this.state,
+								addAllowSource: val,
+							});
+						}}
+						onSubmit={(): void => {
+							this.onAddSource(false);
+						}}
+					/>
+					<label
+						className="bp5-label"
+						style={css.label}
+						hidden={stream}
+					>
+						Denied Sources
+						<Help
+							title="Denied Sources"
+							content="Client IP addresses or networks that will be rejected with a 403 status. Denied sources take priority over allowed sources."
+						/>
+						<div>
+							{denySources}
+						</div>
+					</label>
+					<PageInputButton
+						disabled={this.state.disabled}
+						buttonClass="bp5-intent-success bp5-icon-add"
+						hidden={stream}
+						label="Add"
+						type="text"
+						placeholder="Add source"
+						value={this.state.addDenySource}
+						onChange={(val): void => {
+							this.setState({
+								...this.state,
+								addDenySource: val,
+							});
+						}}
+						onSubmit={(): void => {
+							this.onAddSource(true);
+						}}
+					/>
+					<PageSelect
+						disabled={this.state.disabled}
+						hidden={stream}
+						label="Access Log"
+						help="Log each request with the method, host, path, status, size, backend and latency. The database option stores the most recent requests in a capped collection available from the balancer log API. The logger option sends the requests to the node log and configured log senders and can only be set by an administrator."
+						value={balancer.access_log || ''}
+						onChange={(val): void => {
+							this.set('access_log', val);
+						}}
+					>
+						<option value="">Disabled</option>
+						<option value="database">Database</option>
+						<option value="logger" hidden={Constants.user}>Logger</option>
+					</PageSelect>
+					<PageInput
+						hidden={stream}
+						label="Rate Limit"
+						help="Number of requests per second allowed from each client IP address. Requests over the limit will be rejected with a 429 status. Leave empty to disable rate limiting."
+						type="text"
+						placeholder="Unlimited"
+						value={balancer.rate_limit || ''}
+						onChange={(val): void => {
+							this.set('rate_limit', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						hidden={stream || !balancer.rate_limit}
+						label="Rate Burst"
+						help="Number of requests a client can send at once before the rate limit is applied. Defaults to the rate limit."
+						type="text"
+						placeholder="Rate limit"
+						value={balancer.rate_burst || ''}
+						onChange={(val): void => {
+							this.set('rate_burst', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						hidden={stream}
+						label="Max Body Size"
+						help="Maximum size of a request body in bytes. Larger requests will be rejected with a 413 status. Leave empty for no limit."
+						type="text"
+						placeholder="Unlimited"
+						value={balancer.max_body_bytes || ''}
+						onChange={(val): void => {
+							this.set('max_body_bytes', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						hidden={stream}
+						label="Max Header Size"
+						help="Maximum size of the request line and headers in bytes. Larger requests will be rejected with a 431 status. Leave empty to use the node limit."
+						type="text"
+						placeholder="Node limit"
+						value={balancer.max_header_bytes || ''}
+						onChange={(val): void => {
+							this.set('max_header_bytes', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						hidden={!stream}
+						label="Health Check Port"
+						help="Port used for TCP connect health checks of backend servers. Leave empty to check the backend port. UDP balancers without a health check port are not checked."
+						type="text"
+						placeholder="Backend port"
+						value={balancer.check_port || ''}
+						onChange={(val): void => {
+							this.set('check_port', parseInt(val, 10) || 0);
+						}}
+					/>
+					<label
+						className="bp5-label"
+						style={css.label}
+						hidden={stream}
+					>
+						Health Check Status Codes
+						<Help
+							title="Health Check Status Codes"
+							content="Status codes that will mark a backend as healthy. When no status codes are set any 2xx status code will be accepted."
+						/>
+						<div>
+							{checkStatusCodes}
+						</div>
+					</label>
+					<PageInputButton
+						disabled={this.state.disabled}
+						buttonClass="bp5-intent-success bp5-icon-add"
+						hidden={stream}
+						label="Add"
+						type="text"
+						placeholder="Add status code"
+						value={this.state.addCheckStatus}
+						onChange={(val): void => {
+							this.setState({
+								...this.state,
+								addCheckStatus: val,
+							});
+						}}
+						onSubmit={this.onAddCheckStatus}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						hidden={stream}
+						label="Health Check Body"
+						help="Optional regular expression that must match the health check response body for the backend to be healthy."
+						type="text"
+						placeholder="Enter regular expression"
+						value={balancer.check_body}
+						onChange={(val): void => {
+							this.set('check_body', val);
+						}}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						label="Health Check Interval"
+						help="Seconds between health checks of each backend."
+						type="text"
+						placeholder="Default"
+						value={balancer.check_interval || ''}
+						onChange={(val): void => {
+							this.set('check_interval', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						label="Health Check Timeout"
+						help="Seconds to wait for a health check response before the check fails."
+						type="text"
+						placeholder="Default"
+						value={balancer.check_timeout || ''}
+						onChange={(val): void => {
+							this.set('check_timeout', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						label="Healthy Threshold"
+						help="Number of consecutive successful health checks required to mark a backend online."
+						type="text"
+						placeholder="Default"
+						value={balancer.healthy_threshold || ''}
+						onChange={(val): void => {
+							this.set('healthy_threshold', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						label="Unhealthy Threshold"
+						help="Number of consecutive failed health checks required to mark a backend offline."
+						type="text"
+						placeholder="Default"
+						value={balancer.unhealthy_threshold || ''}
+						onChange={(val): void => {
+							this.set('unhealthy_threshold',
+								parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						label="Outlier Errors"
+						help="Number of consecutive proxy errors that will eject a backend. An ejected backend will not be marked online by health checks until the ejection time has passed. Leave empty to disable outlier ejection."
+						type="text"
+						placeholder="Disabled"
+						value={balancer.outlier_errors || ''}
+						onChange={(val): void => {
+							this.set('outlier_errors', parseInt(val, 10) || 0);
+						}}
+					/>
+					<PageInput
+						disabled={this.state.disabled}
+						hidden={!balancer.outlier_errors}
+						label="Outlier Ejection Time"
+						help="Seconds that an ejected backend will remain offline."
+						type="text"
+						placeholder="Default"
+						value={balancer.outlier_time || ''}
+						onChange={(val): void => {
+							this.set('outlier_time', parseInt(val, 10) || 0);
+						}}
+					/>
 				</div>
 			</div>
 			<PageSave
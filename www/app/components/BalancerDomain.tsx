@@ -1,6 +1,7 @@
 /// <reference path="../References.d.ts"/>
 import * as React from 'react';
 import * as BalancerTypes from '../types/BalancerTypes';
+import BalancerRule from './BalancerRule';
 
 interface Props {
 	domain: BalancerTypes.Domain;
@@ -21,6 +22,9 @@ const css = {
 	domainBox: {
 		flex: '1',
 	} as React.CSSProperties,
+	ruleAdd: {
+		margin: '5px 0 0 15px',
+	} as React.CSSProperties,
 };
 
 export default class BalancerDomain extends React.Component<Props, {}> {
@@ -30,48 +34,119 @@ export default class BalancerDomain extends React.Component<Props, {}> {
 		};
 	}
 
+	onAddRule = (): void => {
+		let state = this.clone();
+
+		state.rules = [
+			...(state.rules || []),
+			{
+				path: '/',
+				method: '',
+				header: '',
+				value: '',
+				group: '',
+			},
+		];
+
+		this.props.onChange(state);
+	}
+
+	onChangeRule(i: number, rule: BalancerTypes.Rule): void {
+		let state = this.clone();
+
+		let rules = [
+			...(state.rules || []),
+		];
+		rules[i] = rule;
+		state.rules = rules;
+
+		this.props.onChange(state);
+	}
+
+	onRemoveRule(i: number): void {
+		let state = this.clone();
+
+		let rules = [
+			...(state.rules || []),
+		];
+		rules.splice(i, 1);
+		state.rules = rules;
+
+		this.props.onChange(state);
+	}
+
 	render(): JSX.Element {
 		let domain = this.props.domain;
 
-		return <div className="bp5-control-group" style={css.group}>
-			<div style={css.domainBox}>
-				<input
-					className="bp5-input"
-					style={css.domain}
-					type="text"
-					autoCapitalize="off"
-					spellCheck={false}
-					placeholder="Domain"
-					value={domain.domain || ''}
-					onChange={(evt): void => {
-						let state = this.clone();
-						state.domain = evt.target.value;
-						this.props.onChange(state);
+		let rules: JSX.Element[] = [];
+		let domainRules = domain.rules || [];
+		for (let i = 0; i < domainRules.length; i++) {
+			let index = i;
+
+			rules.push(
+				<BalancerRule
+					key={index}
+					rule={domainRules[index]}
+					onChange={(state: BalancerTypes.Rule): void => {
+						this.onChangeRule(index, state);
 					}}
-				/>
-			</div>
-			<div style={css.domainBox}>
-				<input
-					className="bp5-input"
-					style={css.domain}
-					type="text"
-					autoCapitalize="off"
-					spellCheck={false}
-					placeholder="Host"
-					value={domain.host || ''}
-					onChange={(evt): void => {
-						let state = this.clone();
-						state.host = evt.target.value;
-						this.props.onChange(state);
+					onRemove={(): void => {
+						this.onRemoveRule(index);
+					}}
+				/>,
+			);
+		}
+
+		return <div>
+			<div className="bp5-control-group" style={css.group}>
+				<div style={css.domainBox}>
+					<input
+						className="bp5-input"
+						style={css.domain}
+						type="text"
+						autoCapitalize="off"
+						spellCheck={false}
+						placeholder="Domain"
+						value={domain.domain || ''}
+						onChange={(evt): void => {
+							let state = this.clone();
+							state.domain = evt.target.value;
+							this.props.onChange(state);
+						}}
+					/>
+				</div>
+				<div style={css.domainBox}>
+					<input
+						className="bp5-input"
+						style={css.domain}
+						type="text"
+						autoCapitalize="off"
+						spellCheck={false}
+						placeholder="Host"
+						value={domain.host || ''}
+						onChange={(evt): void => {
+							let state = this.clone();
+							state.host = evt.target.value;
+							this.props.onChange(state);
+						}}
+					/>
+				</div>
+				<button
+					className="bp5-button bp5-minimal bp5-intent-danger bp5-icon-remove"
+					onClick={(): void => {
+						this.props.onRemove();
 					}}
 				/>
 			</div>
+			{rules}
 			<button
-				className="bp5-button bp5-minimal bp5-intent-danger bp5-icon-remove"
-				onClick={(): void => {
-					this.props.onRemove();
-				}}
-			/>
+				className="bp5-button bp5-small bp5-minimal bp5-intent-success bp5-icon-add"
+				style={css.ruleAdd}
+				type="button"
+				onClick={this.onAddRule}
+			>
+				Add Rule
+			</button>
 		</div>;
 	}
 }
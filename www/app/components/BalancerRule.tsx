@@ -0,0 +1,134 @@
+/// <reference path="../References.d.ts"/>
+import * as React from 'react';
+import * as BalancerTypes from '../types/BalancerTypes';
+
+interface Props {
+	rule: BalancerTypes.Rule;
+	onChange: (state: BalancerTypes.Rule) => void;
+	onRemove: () => void;
+}
+
+const css = {
+	group: {
+		width: '100%',
+		maxWidth: '420px',
+		marginTop: '5px',
+		paddingLeft: '15px',
+	} as React.CSSProperties,
+	method: {
+		flex: '0 1 auto',
+	} as React.CSSProperties,
+	input: {
+		width: '100%',
+	} as React.CSSProperties,
+	inputBox: {
+		flex: '1',
+	} as React.CSSProperties,
+	target: {
+		flex: '0 1 auto',
+		width: '70px',
+		borderRadius: '0 3px 3px 0',
+	} as React.CSSProperties,
+};
+
+export default class BalancerRule extends React.Component<Props, {}> {
+	clone(): BalancerTypes.Rule {
+		return {
+			...this.props.rule,
+		};
+	}
+
+	render(): JSX.Element {
+		let rule = this.props.rule;
+
+		return <div className="bp5-control-group" style={css.group}>
+			<div style={css.inputBox}>
+				<input
+					className="bp5-input"
+					style={css.input}
+					type="text"
+					autoCapitalize="off"
+					spellCheck={false}
+					placeholder="Path prefix"
+					value={rule.path || ''}
+					onChange={(evt): void => {
+						let state = this.clone();
+						state.path = evt.target.value;
+						this.props.onChange(state);
+					}}
+				/>
+			</div>
+			<div className="bp5-select" style={css.method}>
+				<select
+					value={rule.method || ''}
+					onChange={(evt): void => {
+						let state = this.clone();
+						state.method = evt.target.value;
+						this.props.onChange(state);
+					}}
+				>
+					<option value="">ANY</option>
+					<option value="GET">GET</option>
+					<option value="HEAD">HEAD</option>
+					<option value="POST">POST</option>
+					<option value="PUT">PUT</option>
+					<option value="PATCH">PATCH</option>
+					<option value="DELETE">DELETE</option>
+					<option value="OPTIONS">OPTIONS</option>
+				</select>
+			</div>
+			<div style={css.inputBox}>
+				<input
+					className="bp5-input"
+					style={css.input}
+					type="text"
+					autoCapitalize="off"
+					spellCheck={false}
+					placeholder="Header"
+					value={rule.header || ''}
+					onChange={(evt): void => {
+						let state = this.clone();
+						state.header = evt.target.value;
+						this.props.onChange(state);
+					}}
+				/>
+			</div>
+			<div style={css.inputBox}>
+				<input
+					className="bp5-input"
+					style={css.input}
+					type="text"
+					autoCapitalize="off"
+					spellCheck={false}
+					placeholder="Value"
+					value={rule.value || ''}
+					onChange={(evt): void => {
+						let state = this.clone();
+						state.value = evt.target.value;
+						this.props.onChange(state);
+					}}
+				/>
+			</div>
+			<input
+				className="bp5-input"
+				style={css.target}
+				type="text"
+				autoCapitalize="off"
+				spellCheck={false}
+				placeholder="Group"
+				value={rule.group || ''}
+				onChange={(evt): void => {
+					let state = this.clone();
+					state.group = evt.target.value;
+					this.props.onChange(state);
+				}}
+			/>
+			<button
+				className="bp5-button bp5-minimal bp5-intent-danger bp5-icon-remove"
+				onClick={(): void => {
+					this.props.onRemove();
+				}}
+			/>
+		</div>;
+	}
+}
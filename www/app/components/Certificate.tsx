@@ -66,6 +66,9 @@ const css = {
 	inputGroup: {
 		width: '100%',
 	} as React.CSSProperties,
+	version: {
+		marginBottom: '10px',
+	} as React.CSSProperties,
 };
 
 export default class Certificate extends React.Component<Props, State> {
@@ -152,6 +155,27 @@ export default class Certificate extends React.Component<Props, State> {
 		});
 	}
 
+	onRollback = (hash: string): void => {
+		this.setState({
+			...this.state,
+			disabled: true,
+		});
+		CertificateActions.rollback(
+			this.props.certificate.id,
+			hash,
+		).then((): void => {
+			this.setState({
+				...this.state,
+				disabled: false,
+			});
+		}).catch((): void => {
+			this.setState({
+				...this.state,
+				disabled: false,
+			});
+		});
+	}
+
 	onAddDomain = (): void => {
 		let cert: CertificateTypes.Certificate;
 
@@ -245,6 +269,44 @@ export default class Certificate extends React.Component<Props, State> {
 			this.props.certificate;
 
 		let info: CertificateTypes.Info = this.props.certificate.info || {};
+		let lifecycle: CertificateTypes.Lifecycle =
+			this.props.certificate.lifecycle || {};
+
+		let history: JSX.Element[] = [];
+		for (let version of (this.props.certificate.history || [])) {
+			let versionInfo: CertificateTypes.Info = version.info || {};
+
+			history.push(
+				<div
+					key={versionInfo.hash}
+					className="layout horizontal"
+					style={css.version}
+				>
+					<div className="flex">
+						<div>Issuer: {versionInfo.issuer || 'Unknown'}</div>
+						<div>
+							Expires On: {MiscUtils.formatDate(
+								versionInfo.expires_on) || 'Unknown'}
+						</div>
+						<div>
+							Replaced On: {MiscUtils.formatDate(
+								version.replaced) || 'Unknown'}
+						</div>
+					</div>
+					<ConfirmButton
+						className="bp5-minimal bp5-intent-warning bp5-icon-undo"
+						progressClassName="bp5-intent-warning"
+						dialogClassName="bp5-intent-warning bp5-icon-undo"
+						dialogLabel="Rollback Certificate"
+						confirmMsg="Replace the current certificate with this version"
+						disabled={this.state.disabled || !versionInfo.hash}
+						onConfirm={(): void => {
+							this.onRollback(versionInfo.hash);
+						}}
+					/>
+				</div>,
+			);
+		}
 
 		let organizationsSelect: JSX.Element[] = [];
 		organizationsSelect.push(
@@ -431,6 +493,51 @@ export default class Certificate extends React.Component<Props, State> {
 							},
 						]}
 					/>
+					<PageInfo
+						hidden={cert.type !== 'lets_encrypt'}
+						fields={[
+							{
+								label: 'Last Renewal Attempt',
+								value: MiscUtils.formatDate(
+									lifecycle.last_attempt) || 'Never',
+							},
+							{
+								label: 'Last Renewed',
+								value: MiscUtils.formatDate(
+									lifecycle.last_renewed) || 'Never',
+							},
+							{
+								label: 'Next Renewal Attempt',
+								value: MiscUtils.formatDate(
+									lifecycle.next_attempt) || 'Unknown',
+							},
+							{
+								label: 'Renewal Failures',
+								valueClass: lifecycle.failures ?
+									'bp5-text-intent-danger' : '',
+								value: lifecycle.failures || 0,
+							},
+							{
+								label: 'Last Renewal Error',
+								valueClass: lifecycle.last_error ?
+									'bp5-text-intent-danger' : '',
+								value: lifecycle.last_error || 'None',
+							},
+						]}
+					/>
+					<label
+						style={css.itemsLabel}
+						hidden={!history.length}
+					>
+						Previous Versions
+						<Help
+							title="Previous Versions"
+							content="Previously issued certificates kept for rollback. Rolling back will replace the current certificate and move it to the previous versions."
+						/>
+					</label>
+					<div hidden={!history.length}>
+						{history}
+					</div>
 					<PageSelect
 						label="Type"
 						disabled={this.state.disabled}
@@ -481,6 +588,7 @@ export default class Certificate extends React.Component<Props, State> {
 						<option value="acme_aws">AWS</option>
 						<option value="acme_cloudflare">Cloudflare</option>
 						<option value="acme_oracle_cloud">Oracle Cloud</option>
+						<option value="acme_rfc2136">RFC 2136</option>
 					</PageSelect>
 					<PageSelect
 						disabled={this.state.disabled}
@@ -494,6 +602,75 @@ export default class Certificate extends React.Component<Props, State> {
 					>
 						{secretsSelect}
 					</PageSelect>
+					<PageInput
+						label="ACME Directory"
+						hidden={cert.type !== 'lets_encrypt'}
+						help="ACME directory URL of the certificate authority, leave blank to use LetsEncrypt. ZeroSSL uses https://acme.zerossl.com/v2/DV90 and Google Trust Services uses https://dv.acme-v02.api.pki.goog/directory. Renewals will use the same directory."
+						type="text"
+						placeholder="https://acme-v02.api.letsencrypt.org/directory"
+						value={cert.acme_directory}
+						onChange={(val): void => {
+							this.set('acme_directory', val);
+						}}
+					/>
+					<PageTextArea
+						label="ACME Directory Root Certificate"
+						hidden={cert.type !== 'lets_encrypt'}
+						help="Optional root certificate in PEM format to trust when connecting to the ACME directory. Required for internal certificate authorities such as step-ca or a Pebble test server."
+						placeholder="ACME directory root certificate"
+						rows={3}
+						value={cert.acme_root_ca}
+						onChange={(val: string): void => {
+							this.set('acme_root_ca', val);
+						}}
+					/>
+					<PageInput
+						label="ACME External Account Key ID"
+						hidden={cert.type !== 'lets_encrypt'}
+						help="External account binding key ID provided by the certificate authority. Required by ZeroSSL and Google Trust Services."
+						type="text"
+						placeholder="EAB key ID"
+						value={cert.acme_eab_key_id}
+						onChange={(val): void => {
+							this.set('acme_eab_key_id', val);
+						}}
+					/>
+					<PageInput
+						label="ACME External Account HMAC Key"
+						hidden={cert.type !== 'lets_encrypt'}
+						help="External account binding HMAC key provided by the certificate authority in base64url format."
+						type="text"
+						placeholder="EAB HMAC key"
+						value={cert.acme_eab_hmac}
+						onChange={(val): void => {
+							this.set('acme_eab_hmac', val);
+						}}
+					/>
+					<PageInput
+						label="ACME Preferred Chain"
+						hidden={cert.type !== 'lets_encrypt'}
+						help="Optional common name of the root certificate issuer to select an alternate certificate chain offered by the certificate authority. The default chain will be used if no chain matches."
+						type="text"
+						placeholder="ISRG Root X1"
+						value={cert.acme_preferred_chain}
+						onChange={(val): void => {
+							this.set('acme_preferred_chain', val);
+						}}
+					/>
+					<PageSelect
+						label="ACME Key Type"
+						disabled={this.state.disabled}
+						hidden={cert.type !== 'lets_encrypt'}
+						help="Private key type for the certificate, default will use the key algorithm from the system settings."
+						value={cert.acme_key_type}
+						onChange={(val): void => {
+							this.set('acme_key_type', val);
+						}}
+					>
+						<option value="">Default</option>
+						<option value="rsa">RSA 4096</option>
+						<option value="ec">EC P-384</option>
+					</PageSelect>
 				</div>
 			</div>
 			<PageSave
@@ -12,6 +12,7 @@ import PageSelect from "./PageSelect";
 import PageTextArea from "./PageTextArea";
 import DomainRecord from "./DomainRecord";
 import * as Constants from "../Constants";
+import * as MiscUtils from '../utils/MiscUtils';
 import * as SecretTypes from "../types/SecretTypes";
 import Help from "./Help";
 
@@ -288,6 +289,12 @@ export default class DomainDetailed extends React.Component<Props, State> {
 		let domain: DomainTypes.Domain = this.state.domain ||
 			this.props.domain;
 
+		let drift: string[] = [];
+		for (let drft of (this.props.domain.drift || [])) {
+			drift.push((drft.sub_domain ? drft.sub_domain + ' ' : '@ ') +
+				drft.type + ' ' + drft.value);
+		}
+
 		let hasOrganizations = false
 		let organizationsSelect: JSX.Element[] = [];
 		if (this.props.organizations.length) {
@@ -349,6 +356,7 @@ export default class DomainDetailed extends React.Component<Props, State> {
 				<DomainRecord
 					key={index}
 					record={domain.records[index]}
+					builtin={domain.type === "builtin"}
 					onChange={(state: DomainTypes.Record): void => {
 						this.onChangeRecord(index, state);
 					}}
@@ -463,6 +471,15 @@ export default class DomainDetailed extends React.Component<Props, State> {
 								label: 'ID',
 								value: this.props.domain.id || 'Unknown',
 							},
+							{
+								label: 'Unknown Provider Records',
+								value: drift.length ? drift : 'None',
+							},
+							{
+								label: 'Provider Records Checked',
+								value: MiscUtils.formatDate(
+									this.props.domain.drift_checked) || 'Never',
+							},
 						]}
 					/>
 					<PageSelect
@@ -488,8 +505,11 @@ export default class DomainDetailed extends React.Component<Props, State> {
 						<option value="aws">AWS</option>
 						<option value="cloudflare">Cloudflare</option>
 						<option value="oracle_cloud">Oracle Cloud</option>
+						<option value="rfc2136">RFC 2136</option>
+						<option value="builtin">Built-in DNS Server</option>
 					</PageSelect>
 					<PageSelect
+						hidden={domain.type === "builtin"}
 						disabled={this.state.disabled}
 						label="Provider API Secret"
 						help="Secret containing API keys to use for provider."
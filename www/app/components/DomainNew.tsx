@@ -266,8 +266,11 @@ export default class DomainNew extends React.Component<Props, State> {
 							<option value="aws">AWS</option>
 							<option value="cloudflare">Cloudflare</option>
 							<option value="oracle_cloud">Oracle Cloud</option>
+							<option value="rfc2136">RFC 2136</option>
+							<option value="builtin">Built-in DNS Server</option>
 						</PageSelect>
 						<PageSelect
+							hidden={domain.type === "builtin"}
 							disabled={this.state.disabled}
 							label="Provider API Secret"
 							help="Secret containing API keys to use for provider."
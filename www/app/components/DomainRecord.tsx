@@ -4,6 +4,7 @@ import * as DomainTypes from '../types/DomainTypes';
 
 interface Props {
 	record: DomainTypes.Record;
+	builtin?: boolean;
 	onChange: (record: DomainTypes.Record) => void;
 	onRemove: () => void;
 }
@@ -11,7 +12,7 @@ interface Props {
 const css = {
 	group: {
 		width: '100%',
-		maxWidth: '310px',
+		maxWidth: '420px',
 		marginTop: '5px',
 	} as React.CSSProperties,
 	type: {
@@ -24,6 +25,9 @@ const css = {
 	domainBox: {
 		flex: '1',
 	} as React.CSSProperties,
+	ttl: {
+		width: '70px',
+	} as React.CSSProperties,
 };
 
 export default class DomainRecord extends React.Component<Props, {}> {
@@ -36,6 +40,43 @@ export default class DomainRecord extends React.Component<Props, {}> {
 	render(): JSX.Element {
 		let record = this.props.record;
 
+		let typesSelect: JSX.Element[] = [
+			<option key="A" value="A">A</option>,
+			<option key="AAAA" value="AAAA">AAAA</option>,
+			<option key="CNAME" value="CNAME">CNAME</option>,
+			<option key="MX" value="MX">MX</option>,
+			<option key="SRV" value="SRV">SRV</option>,
+			<option key="CAA" value="CAA">CAA</option>,
+			<option key="TXT" value="TXT">TXT</option>,
+		];
+		if (this.props.builtin) {
+			typesSelect.push(
+				<option key="PTR" value="PTR">PTR</option>,
+			);
+		}
+
+		let valuePlaceholder: string;
+		switch (record.type) {
+			case 'CNAME':
+			case 'PTR':
+				valuePlaceholder = 'Target';
+				break;
+			case 'MX':
+				valuePlaceholder = 'Priority Target';
+				break;
+			case 'SRV':
+				valuePlaceholder = 'Priority Weight Port Target';
+				break;
+			case 'CAA':
+				valuePlaceholder = 'Flags Tag "Value"';
+				break;
+			case 'TXT':
+				valuePlaceholder = 'Text';
+				break;
+			default:
+				valuePlaceholder = 'IP Address';
+		}
+
 		return <div className="bp5-control-group" style={css.group}>
 			<div className="bp5-select" style={css.type}>
 				<select
@@ -49,8 +90,7 @@ export default class DomainRecord extends React.Component<Props, {}> {
 						this.props.onChange(state);
 					}}
 				>
-					<option value="A">A</option>
-					<option value="AAAA">AAAA</option>
+					{typesSelect}
 				</select>
 			</div>
 			<div style={css.domainBox}>
@@ -79,7 +119,7 @@ export default class DomainRecord extends React.Component<Props, {}> {
 					type="text"
 					autoCapitalize="off"
 					spellCheck={false}
-					placeholder="IP Address"
+					placeholder={valuePlaceholder}
 					value={record.value || ''}
 					onChange={(evt): void => {
 						let state = this.clone();
@@ -91,6 +131,23 @@ export default class DomainRecord extends React.Component<Props, {}> {
 					}}
 				/>
 			</div>
+			<input
+				className="bp5-input"
+				style={css.ttl}
+				type="text"
+				autoCapitalize="off"
+				spellCheck={false}
+				placeholder="TTL"
+				value={record.ttl || ''}
+				onChange={(evt): void => {
+					let state = this.clone();
+					state.ttl = parseInt(evt.target.value, 10) || 0;
+					if (!state.operation) {
+						state.operation = "update"
+					}
+					this.props.onChange(state);
+				}}
+			/>
 			<button
 				className="bp5-button bp5-minimal bp5-intent-danger bp5-icon-remove"
 				onClick={(): void => {
@@ -3,11 +3,13 @@ import * as React from 'react';
 import * as MiscUtils from '../utils/MiscUtils';
 import * as FirewallTypes from '../types/FirewallTypes';
 import * as OrganizationTypes from "../types/OrganizationTypes";
+import * as AddrGroupTypes from "../types/AddrGroupTypes";
 import OrganizationsStore from '../stores/OrganizationsStore';
 import FirewallDetailed from './FirewallDetailed';
 
 interface Props {
 	organizations: OrganizationTypes.OrganizationsRo;
+	addrGroups: AddrGroupTypes.AddrGroupsRo;
 	firewall: FirewallTypes.FirewallRo;
 	selected: boolean;
 	onSelect: (shift: boolean) => void;
@@ -87,6 +89,7 @@ export default class Firewall extends React.Component<Props, {}> {
 			>
 				<FirewallDetailed
 					organizations={this.props.organizations}
+					addrGroups={this.props.addrGroups}
 					firewall={this.props.firewall}
 					selected={this.props.selected}
 					onSelect={this.props.onSelect}
@@ -3,7 +3,9 @@ import * as React from 'react';
 import * as Constants from '../Constants';
 import * as FirewallTypes from '../types/FirewallTypes';
 import * as FirewallActions from '../actions/FirewallActions';
+import * as MiscUtils from '../utils/MiscUtils';
 import * as OrganizationTypes from "../types/OrganizationTypes";
+import * as AddrGroupTypes from "../types/AddrGroupTypes";
 import FirewallRule from './FirewallRule';
 import PageInput from './PageInput';
 import PageSelect from './PageSelect';
@@ -14,9 +16,11 @@ import ConfirmButton from './ConfirmButton';
 import Overview from './Overview';
 import Help from './Help';
 import PageTextArea from "./PageTextArea";
+import PageSwitch from "./PageSwitch";
 
 interface Props {
 	organizations: OrganizationTypes.OrganizationsRo;
+	addrGroups: AddrGroupTypes.AddrGroupsRo;
 	firewall: FirewallTypes.FirewallRo;
 	selected: boolean;
 	onSelect: (shift: boolean) => void;
@@ -312,6 +316,98 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 		});
 	}
 
+	onAddEgress = (i: number): void => {
+		let firewall: FirewallTypes.Firewall;
+
+		if (this.state.changed) {
+			firewall = {
+				...this.state.firewall,
+			};
+		} else {
+			firewall = {
+				...this.props.firewall,
+			};
+		}
+
+		let egress = [
+			...(firewall.egress || []),
+		];
+
+		egress.splice(i + 1, 0, {
+			protocol: 'all',
+			source_ips: [
+				'0.0.0.0/0',
+				'::/0',
+			],
+		} as FirewallTypes.Rule);
+		firewall.egress = egress;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			firewall: firewall,
+		});
+	}
+
+	onChangeEgress(i: number, rule: FirewallTypes.Rule): void {
+		let firewall: FirewallTypes.Firewall;
+
+		if (this.state.changed) {
+			firewall = {
+				...this.state.firewall,
+			};
+		} else {
+			firewall = {
+				...this.props.firewall,
+			};
+		}
+
+		let egress = [
+			...(firewall.egress || []),
+		];
+
+		egress[i] = rule;
+
+		firewall.egress = egress;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			firewall: firewall,
+		});
+	}
+
+	onRemoveEgress(i: number): void {
+		let firewall: FirewallTypes.Firewall;
+
+		if (this.state.changed) {
+			firewall = {
+				...this.state.firewall,
+			};
+		} else {
+			firewall = {
+				...this.props.firewall,
+			};
+		}
+
+		let egress = [
+			...(firewall.egress || []),
+		];
+
+		egress.splice(i, 1);
+
+		firewall.egress = egress;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			message: '',
+			firewall: firewall,
+		});
+	}
+
 	onSave = (): void => {
 		this.setState({
 			...this.state,
@@ -370,6 +466,98 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 		});
 	}
 
+	ruleKey(rule: FirewallTypes.Rule): string {
+		return rule.counter_key || '';
+	}
+
+	ruleLabel(rule: FirewallTypes.Rule): string {
+		if (!rule.port) {
+			return rule.protocol;
+		}
+		return rule.protocol + ' ' + rule.port;
+	}
+
+	counterFields(): any[] {
+		let ingress: {[key: string]: FirewallTypes.Counter} = {};
+		let egress: {[key: string]: FirewallTypes.Counter} = {};
+		let counters = this.props.firewall.counters || {};
+
+		let add = (totals: {[key: string]: FirewallTypes.Counter},
+				values: {[key: string]: FirewallTypes.Counter}): void => {
+			for (let key in (values || {})) {
+				if (!values.hasOwnProperty(key)) {
+					continue;
+				}
+				let total = totals[key] || {
+					packets: 0,
+					bytes: 0,
+				};
+				total.packets += values[key].packets || 0;
+				total.bytes += values[key].bytes || 0;
+				totals[key] = total;
+			}
+		};
+
+		for (let nodeId in counters) {
+			if (!counters.hasOwnProperty(nodeId)) {
+				continue;
+			}
+			add(ingress, counters[nodeId].ingress);
+			add(egress, counters[nodeId].egress);
+		}
+
+		let format = (counter: FirewallTypes.Counter): string => {
+			if (!counter) {
+				return '0 packets';
+			}
+			return counter.packets + ' packets (' +
+				MiscUtils.formatBytes(counter.bytes) + ')';
+		};
+
+		let dropLabel = this.props.firewall.log_only ?
+			'Logged' : 'Dropped';
+		let fields: any[] = [];
+		let ingressSet: {[key: string]: boolean} = {};
+		for (let rule of (this.props.firewall.ingress || [])) {
+			let key = this.ruleKey(rule);
+			if (ingressSet[key]) {
+				continue;
+			}
+			ingressSet[key] = true;
+
+			fields.push({
+				label: 'Ingress ' + this.ruleLabel(rule),
+				value: format(ingress[key]),
+			});
+		}
+		fields.push({
+			label: 'Ingress ' + dropLabel,
+			value: format(ingress['drop']),
+		});
+
+		if ((this.props.firewall.egress || []).length) {
+			let egressSet: {[key: string]: boolean} = {};
+			for (let rule of this.props.firewall.egress) {
+				let key = this.ruleKey(rule);
+				if (egressSet[key]) {
+					continue;
+				}
+				egressSet[key] = true;
+
+				fields.push({
+					label: 'Egress ' + this.ruleLabel(rule),
+					value: format(egress[key]),
+				});
+			}
+			fields.push({
+				label: 'Egress ' + dropLabel,
+				value: format(egress['drop']),
+			});
+		}
+
+		return fields;
+	}
+
 	render(): JSX.Element {
 		let firewall: FirewallTypes.Firewall = this.state.firewall ||
 			this.props.firewall;
@@ -408,6 +596,15 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 			);
 		}
 
+		let nodeFirewall = MiscUtils.objectIdNil(firewall.organization);
+		let addrGroups: AddrGroupTypes.AddrGroupRo[] = [];
+		for (let addrGroup of (this.props.addrGroups || [])) {
+			if (MiscUtils.objectIdNil(addrGroup.organization) ? nodeFirewall :
+					addrGroup.organization === firewall.organization) {
+				addrGroups.push(addrGroup);
+			}
+		}
+
 		let rules: JSX.Element[] = [];
 		for (let i = 0; i < firewall.ingress.length; i++) {
 			let index = i;
@@ -415,6 +612,8 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 			rules.push(
 				<FirewallRule
 					key={index}
+					node={nodeFirewall}
+					addrGroups={addrGroups}
 					rule={firewall.ingress[index]}
 					onChange={(state: FirewallTypes.Rule): void => {
 						this.onChangeIngress(index, state);
@@ -429,6 +628,31 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 			);
 		}
 
+		let egressRules: JSX.Element[] = [];
+		let egress = firewall.egress || [];
+		for (let i = 0; i < egress.length; i++) {
+			let index = i;
+
+			egressRules.push(
+				<FirewallRule
+					key={index}
+					egress={true}
+					node={nodeFirewall}
+					addrGroups={addrGroups}
+					rule={egress[index]}
+					onChange={(state: FirewallTypes.Rule): void => {
+						this.onChangeEgress(index, state);
+					}}
+					onAdd={(): void => {
+						this.onAddEgress(index);
+					}}
+					onRemove={(): void => {
+						this.onRemoveEgress(index);
+					}}
+				/>,
+			);
+		}
+
 		return <td
 			className="bp5-cell"
 			colSpan={5}
@@ -512,6 +736,25 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 					<div style={css.rules}>
 						{rules}
 					</div>
+					<label style={css.itemsLabel}>
+						Egress Rules
+						<Help
+							title="Egress Rules"
+							content="Firewall rules for traffic sent from instances, the address ranges are matched against the destination. Return traffic for allowed connections is always permitted. When no egress rules are configured all outbound traffic is allowed."
+						/>
+					</label>
+					<div style={css.rules}>
+						{egressRules}
+						<button
+							className="bp5-button bp5-intent-success bp5-icon-add"
+							style={css.button}
+							hidden={egressRules.length !== 0}
+							disabled={this.state.disabled}
+							onClick={(): void => {
+								this.onAddEgress(-1);
+							}}
+						>Add Egress Rule</button>
+					</div>
 				</div>
 				<div style={css.group}>
 					<PageInfo
@@ -520,8 +763,18 @@ export default class FirewallDetailed extends React.Component<Props, State> {
 								label: 'ID',
 								value: this.props.firewall.id || 'Unknown',
 							},
+							...this.counterFields(),
 						]}
 					/>
+					<PageSwitch
+						disabled={this.state.disabled}
+						label="Log Only"
+						help="Log packets which match no rule to the system logs without dropping them. When a firewall shared with other firewalls is log only packets are logged and still dropped unless all of the firewalls are log only."
+						checked={firewall.log_only}
+						onToggle={(): void => {
+							this.set('log_only', !firewall.log_only);
+						}}
+					/>
 					<PageSelect
 						disabled={this.state.disabled}
 						hidden={Constants.user}
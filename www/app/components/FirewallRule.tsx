@@ -1,14 +1,22 @@
 /// <reference path="../References.d.ts"/>
 import * as React from 'react';
 import * as FirewallTypes from '../types/FirewallTypes';
+import * as AddrGroupTypes from '../types/AddrGroupTypes';
 
 interface Props {
 	rule: FirewallTypes.Rule;
+	egress?: boolean;
+	node?: boolean;
+	addrGroups?: AddrGroupTypes.AddrGroupRo[];
 	onChange: (state: FirewallTypes.Rule) => void;
 	onAdd: () => void;
 	onRemove: () => void;
 }
 
+interface State {
+	addRole: string;
+}
+
 const css = {
 	group: {
 		width: '100%',
@@ -34,9 +42,26 @@ const css = {
 		width: '52px',
 		borderRadius: '0 3px 3px 0',
 	} as React.CSSProperties,
+	sources: {
+		width: '100%',
+		maxWidth: '310px',
+	} as React.CSSProperties,
+	source: {
+		margin: '5px 5px 0 0',
+	} as React.CSSProperties,
+	groupSelect: {
+		width: '100%',
+	} as React.CSSProperties,
 };
 
-export default class FirewallRule extends React.Component<Props, {}> {
+export default class FirewallRule extends React.Component<Props, State> {
+	constructor(props: any, context: any) {
+		super(props, context);
+		this.state = {
+			addRole: '',
+		};
+	}
+
 	clone(): FirewallTypes.Rule {
 		return {
 			...this.props.rule,
@@ -82,8 +107,89 @@ export default class FirewallRule extends React.Component<Props, {}> {
 		this.props.onChange(state);
 	}
 
+	onAddSourceRole = (): void => {
+		let role = (this.state.addRole || '').trim();
+		if (!role) {
+			return;
+		}
+
+		let state = this.clone();
+
+		let sourceRoles = [
+			...(state.source_roles || []),
+		];
+
+		if (sourceRoles.indexOf(role) === -1) {
+			sourceRoles.push(role);
+		}
+		state.source_roles = sourceRoles;
+
+		this.setState({
+			...this.state,
+			addRole: '',
+		});
+		this.props.onChange(state);
+	}
+
+	onRemoveSourceRole = (role: string): void => {
+		let state = this.clone();
+
+		let sourceRoles = [
+			...(state.source_roles || []),
+		];
+
+		let i = sourceRoles.indexOf(role);
+		if (i === -1) {
+			return;
+		}
+
+		sourceRoles.splice(i, 1);
+		state.source_roles = sourceRoles;
+
+		this.props.onChange(state);
+	}
+
+	onAddSourceGroup = (groupId: string): void => {
+		if (!groupId) {
+			return;
+		}
+
+		let state = this.clone();
+
+		let sourceGroups = [
+			...(state.source_groups || []),
+		];
+
+		if (sourceGroups.indexOf(groupId) === -1) {
+			sourceGroups.push(groupId);
+		}
+		state.source_groups = sourceGroups;
+
+		this.props.onChange(state);
+	}
+
+	onRemoveSourceGroup = (groupId: string): void => {
+		let state = this.clone();
+
+		let sourceGroups = [
+			...(state.source_groups || []),
+		];
+
+		let i = sourceGroups.indexOf(groupId);
+		if (i === -1) {
+			return;
+		}
+
+		sourceGroups.splice(i, 1);
+		state.source_groups = sourceGroups;
+
+		this.props.onChange(state);
+	}
+
 	render(): JSX.Element {
 		let rule = this.props.rule;
+		let hideSources = rule.protocol === "multicast" ||
+			rule.protocol === "broadcast";
 
 		let port = rule.port;
 		let placeholder = '';
@@ -109,8 +215,7 @@ export default class FirewallRule extends React.Component<Props, {}> {
 				<div
 					className="bp5-control-group"
 					style={css.sourceGroup}
-					hidden={rule.protocol === "multicast" ||
-						rule.protocol === "broadcast"}
+					hidden={hideSources}
 					key={i}
 				>
 					<input
@@ -119,7 +224,8 @@ export default class FirewallRule extends React.Component<Props, {}> {
 						type="text"
 						autoCapitalize="off"
 						spellCheck={false}
-						placeholder="Source IP range"
+						placeholder={this.props.egress ?
+							'Destination IP range' : 'Source IP range'}
 						value={sourceIp}
 						onChange={(evt): void => {
 							this.onChangeSourceIp(i, evt.target.value);
@@ -141,6 +247,56 @@ export default class FirewallRule extends React.Component<Props, {}> {
 			);
 		});
 
+		let groupNames: {[key: string]: string} = {};
+		let groupsSelect: JSX.Element[] = [];
+		for (let addrGroup of (this.props.addrGroups || [])) {
+			groupNames[addrGroup.id] = addrGroup.name;
+			if ((rule.source_groups || []).indexOf(addrGroup.id) === -1) {
+				groupsSelect.push(
+					<option
+						key={addrGroup.id}
+						value={addrGroup.id}
+					>{addrGroup.name}</option>,
+				);
+			}
+		}
+
+		let sourceRefs: JSX.Element[] = [];
+		for (let role of (rule.source_roles || [])) {
+			sourceRefs.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-primary"
+					style={css.source}
+					key={'role-' + role}
+				>
+					{role}
+					<button
+						className="bp5-tag-remove"
+						onMouseUp={(): void => {
+							this.onRemoveSourceRole(role);
+						}}
+					/>
+				</div>,
+			);
+		}
+		for (let groupId of (rule.source_groups || [])) {
+			sourceRefs.push(
+				<div
+					className="bp5-tag bp5-tag-removable bp5-intent-success"
+					style={css.source}
+					key={'group-' + groupId}
+				>
+					{groupNames[groupId] || groupId}
+					<button
+						className="bp5-tag-remove"
+						onMouseUp={(): void => {
+							this.onRemoveSourceGroup(groupId);
+						}}
+					/>
+				</div>,
+			);
+		}
+
 		return <div>
 			<div className="bp5-control-group" style={css.group}>
 				<div className="bp5-select" style={css.protocol}>
@@ -161,8 +317,14 @@ export default class FirewallRule extends React.Component<Props, {}> {
 						<option value="icmp">ICMP</option>
 						<option value="tcp">TCP</option>
 						<option value="udp">UDP</option>
-						<option value="multicast">Multicast</option>
-						<option value="broadcast">Broadcast</option>
+						<option
+							value="multicast"
+							hidden={this.props.egress}
+						>Multicast</option>
+						<option
+							value="broadcast"
+							hidden={this.props.egress}
+						>Broadcast</option>
 					</select>
 				</div>
 				<div style={css.portBox}>
@@ -196,6 +358,58 @@ export default class FirewallRule extends React.Component<Props, {}> {
 				/>
 			</div>
 			{sourceIpsDoms}
+			<div style={css.sources} hidden={hideSources}>
+				{sourceRefs}
+			</div>
+			<div
+				className="bp5-control-group"
+				style={css.sourceGroup}
+				hidden={hideSources || this.props.node}
+			>
+				<input
+					className="bp5-input"
+					style={css.port}
+					type="text"
+					autoCapitalize="off"
+					spellCheck={false}
+					placeholder={this.props.egress ?
+						'Destination network role' : 'Source network role'}
+					value={this.state.addRole}
+					onChange={(evt): void => {
+						this.setState({
+							...this.state,
+							addRole: evt.target.value,
+						});
+					}}
+					onKeyDown={(evt): void => {
+						if (evt.key === 'Enter') {
+							this.onAddSourceRole();
+						}
+					}}
+				/>
+				<button
+					className="bp5-button bp5-minimal bp5-intent-success bp5-icon-add"
+					onClick={this.onAddSourceRole}
+				/>
+			</div>
+			<div
+				className="bp5-select"
+				style={css.sourceGroup}
+				hidden={hideSources || !groupsSelect.length}
+			>
+				<select
+					style={css.groupSelect}
+					value=""
+					onChange={(evt): void => {
+						this.onAddSourceGroup(evt.target.value);
+					}}
+				>
+					<option value="">{this.props.egress ?
+						'Add destination address group' :
+						'Add source address group'}</option>
+					{groupsSelect}
+				</select>
+			</div>
 		</div>;
 	}
 }
@@ -2,10 +2,13 @@
 import * as React from 'react';
 import * as FirewallTypes from '../types/FirewallTypes';
 import * as OrganizationTypes from '../types/OrganizationTypes';
+import * as AddrGroupTypes from '../types/AddrGroupTypes';
 import FirewallsStore from '../stores/FirewallsStore';
 import OrganizationsStore from '../stores/OrganizationsStore';
+import AddrGroupsStore from '../stores/AddrGroupsStore';
 import * as FirewallActions from '../actions/FirewallActions';
 import * as OrganizationActions from '../actions/OrganizationActions';
+import * as AddrGroupActions from '../actions/AddrGroupActions';
 import Firewall from './Firewall';
 import FirewallsFilter from './FirewallsFilter';
 import FirewallsPage from './FirewallsPage';
@@ -26,6 +29,7 @@ interface State {
 	firewalls: FirewallTypes.FirewallsRo;
 	filter: FirewallTypes.Filter;
 	organizations: OrganizationTypes.OrganizationsRo;
+	addrGroups: AddrGroupTypes.AddrGroupsRo;
 	selected: Selected;
 	opened: Opened;
 	newOpened: boolean;
@@ -69,6 +73,7 @@ export default class Firewalls extends React.Component<{}, State> {
 			firewalls: FirewallsStore.firewalls,
 			filter: FirewallsStore.filter,
 			organizations: OrganizationsStore.organizations,
+			addrGroups: AddrGroupsStore.addrGroups,
 			selected: {},
 			opened: {},
 			newOpened: false,
@@ -88,13 +93,16 @@ export default class Firewalls extends React.Component<{}, State> {
 	componentDidMount(): void {
 		FirewallsStore.addChangeListener(this.onChange);
 		OrganizationsStore.addChangeListener(this.onChange);
+		AddrGroupsStore.addChangeListener(this.onChange);
 		FirewallActions.sync();
 		OrganizationActions.sync();
+		AddrGroupActions.sync();
 	}
 
 	componentWillUnmount(): void {
 		FirewallsStore.removeChangeListener(this.onChange);
 		OrganizationsStore.removeChangeListener(this.onChange);
+		AddrGroupsStore.removeChangeListener(this.onChange);
 	}
 
 	onChange = (): void => {
@@ -118,6 +126,7 @@ export default class Firewalls extends React.Component<{}, State> {
 			firewalls: firewalls,
 			filter: FirewallsStore.filter,
 			organizations: OrganizationsStore.organizations,
+			addrGroups: AddrGroupsStore.addrGroups,
 			selected: selected,
 			opened: opened,
 		});
@@ -152,6 +161,7 @@ export default class Firewalls extends React.Component<{}, State> {
 				key={firewall.id}
 				firewall={firewall}
 				organizations={this.state.organizations}
+				addrGroups={this.state.addrGroups}
 				selected={!!this.state.selected[firewall.id]}
 				open={!!this.state.opened[firewall.id]}
 				onSelect={(shift: boolean): void => {
@@ -29,6 +29,8 @@ import Disks from './Disks';
 import Instances from './Instances';
 import Pods from './Pods';
 import Firewalls from './Firewalls';
+import AddrGroups from './AddrGroups';
+import Peerings from './Peerings';
 import Authorities from './Authorities';
 import Logs from './Logs';
 import Settings from './Settings';
@@ -61,6 +63,8 @@ import * as DiskActions from '../actions/DiskActions';
 import * as InstanceActions from '../actions/InstanceActions';
 import * as PodActions from '../actions/PodActions';
 import * as FirewallActions from '../actions/FirewallActions';
+import * as AddrGroupActions from '../actions/AddrGroupActions';
+import * as PeeringActions from '../actions/PeeringActions';
 import * as AuthorityActions from '../actions/AuthorityActions';
 import * as LogActions from '../actions/LogActions';
 import * as SettingsActions from '../actions/SettingsActions';
@@ -266,6 +270,13 @@ export default class Main extends React.Component<{}, State> {
 					>
 						VPCs
 					</RouterLink>
+					<RouterLink
+						className="bp5-button bp5-minimal bp5-icon-link"
+						style={css.link}
+						to="/peerings"
+					>
+						Peerings
+					</RouterLink>
 					<RouterLink
 						className="bp5-button bp5-minimal bp5-icon-map-marker"
 						style={css.link}
@@ -331,6 +342,13 @@ export default class Main extends React.Component<{}, State> {
 					>
 						Firewalls
 					</RouterLink>
+					<RouterLink
+						className="bp5-button bp5-minimal bp5-icon-ip-address"
+						style={css.link}
+						to="/addrgroups"
+					>
+						Address Groups
+					</RouterLink>
 					<RouterLink
 						className="bp5-button bp5-minimal bp5-icon-office"
 						style={css.link}
@@ -715,6 +733,30 @@ export default class Main extends React.Component<{}, State> {
 										disabled: false,
 									});
 								});
+							} else if (pathname === '/peerings') {
+								PeeringActions.sync().then((): void => {
+									this.setState({
+										...this.state,
+										disabled: false,
+									});
+								}).catch((): void => {
+									this.setState({
+										...this.state,
+										disabled: false,
+									});
+								});
+							} else if (pathname === '/addrgroups') {
+								AddrGroupActions.sync().then((): void => {
+									this.setState({
+										...this.state,
+										disabled: false,
+									});
+								}).catch((): void => {
+									this.setState({
+										...this.state,
+										disabled: false,
+									});
+								});
 							} else if (pathname === '/authorities') {
 								AuthorityActions.sync().then((): void => {
 									this.setState({
@@ -873,6 +915,12 @@ export default class Main extends React.Component<{}, State> {
 				<RouterRoute path="/firewalls" render={() => (
 					<Firewalls/>
 				)}/>
+				<RouterRoute path="/peerings" render={() => (
+					<Peerings/>
+				)}/>
+				<RouterRoute path="/addrgroups" render={() => (
+					<AddrGroups/>
+				)}/>
 				<RouterRoute path="/authorities" render={() => (
 					<Authorities/>
 				)}/>
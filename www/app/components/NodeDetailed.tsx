@@ -1128,6 +1128,52 @@ export default class NodeDetailed extends React.Component<Props, State> {
 			});
 		}
 
+		let evacuation = this.props.node.evacuation;
+		let evacuationFields: PageInfos.Field[] = [];
+		let evacuationBars: PageInfos.Bar[] = [];
+		if (evacuation) {
+			let total = evacuation.total || 0;
+			let evacuated = total - (evacuation.remaining || 0);
+			let stateClass = '';
+			if (evacuation.state === 'complete') {
+				stateClass = 'bp5-text-intent-success';
+			} else if (evacuation.state === 'failed') {
+				stateClass = 'bp5-text-intent-danger';
+			}
+
+			evacuationFields = [
+				{
+					valueClass: stateClass,
+					label: 'Evacuation State',
+					value: evacuation.state || 'Pending',
+				},
+				{
+					label: 'Evacuation Started',
+					value: MiscUtils.formatDate(evacuation.start) || 'Unknown',
+				},
+				{
+					label: 'Instances Evacuated',
+					value: evacuated + '/' + total,
+				},
+				{
+					label: 'Instances Migrating',
+					value: (evacuation.migrating || 0).toString(),
+				},
+				{
+					valueClass: evacuation.failed ? 'bp5-text-intent-danger' : '',
+					label: 'Instances Failed',
+					value: (evacuation.failed || 0).toString(),
+				},
+			];
+			evacuationBars = [
+				{
+					progressClass: 'bp5-no-stripes bp5-intent-success',
+					label: 'Evacuation',
+					value: total ? Math.round(evacuated / total * 100) : 100,
+				},
+			];
+		}
+
 		let externalIfaces: JSX.Element[] = [];
 		for (let iface of (node.external_interfaces || [])) {
 			externalIfaces.push(
@@ -1544,6 +1590,27 @@ export default class NodeDetailed extends React.Component<Props, State> {
 							this.toggleType('hypervisor');
 						}}
 					/>
+					<PageSwitch
+						disabled={this.state.disabled}
+						hidden={types.indexOf('hypervisor') === -1}
+						label="Maintenance"
+						help="Cordon node for maintenance, new instances will not be placed on this node."
+						checked={node.maintenance}
+						onToggle={(): void => {
+							this.set('maintenance', !node.maintenance);
+						}}
+					/>
+					<PageSwitch
+						disabled={this.state.disabled}
+						hidden={types.indexOf('hypervisor') === -1 ||
+							!node.maintenance}
+						label="Evacuate Instances"
+						help="Move instances to other nodes in the zone that match the instance shape roles. Running instances will be live migrated when supported, other instances will be stopped and the disks copied to the new node."
+						checked={node.evacuate}
+						onToggle={(): void => {
+							this.set('evacuate', !node.evacuate);
+						}}
+					/>
 					<PageInput
 						disabled={this.state.disabled}
 						hidden={types.indexOf('balancer') === -1 && (
@@ -2086,6 +2153,11 @@ export default class NodeDetailed extends React.Component<Props, State> {
 						]}
 						bars={resourceBars}
 					/>
+					<PageInfo
+						hidden={!evacuation || !node.evacuate}
+						fields={evacuationFields}
+						bars={evacuationBars}
+					/>
 					<PageSelect
 						hidden={types.indexOf('hypervisor') === -1}
 						disabled={this.state.disabled}
@@ -0,0 +1,314 @@
+/// <reference path="../References.d.ts"/>
+import * as React from 'react';
+import * as PeeringTypes from '../types/PeeringTypes';
+import * as VpcTypes from '../types/VpcTypes';
+import * as OrganizationTypes from '../types/OrganizationTypes';
+import * as PeeringActions from '../actions/PeeringActions';
+import OrganizationsStore from '../stores/OrganizationsStore';
+import PageInput from './PageInput';
+import PageSelect from './PageSelect';
+import PageSwitch from './PageSwitch';
+import PageInfo from './PageInfo';
+import PageTextArea from './PageTextArea';
+import PageSave from './PageSave';
+import ConfirmButton from './ConfirmButton';
+import * as Constants from "../Constants";
+
+interface Props {
+	peering: PeeringTypes.PeeringRo;
+	vpcs: VpcTypes.VpcsRo;
+	organizations: OrganizationTypes.OrganizationsRo;
+}
+
+interface State {
+	disabled: boolean;
+	changed: boolean;
+	message: string;
+	peering: PeeringTypes.Peering;
+}
+
+const css = {
+	card: {
+		position: 'relative',
+		padding: '10px 10px 0 10px',
+		marginBottom: '5px',
+	} as React.CSSProperties,
+	remove: {
+		position: 'absolute',
+		top: '5px',
+		right: '5px',
+	} as React.CSSProperties,
+	group: {
+		flex: 1,
+		minWidth: '280px',
+		margin: '0 10px',
+	} as React.CSSProperties,
+	save: {
+		paddingBottom: '10px',
+	} as React.CSSProperties,
+};
+
+export default class Peering extends React.Component<Props, State> {
+	constructor(props: any, context: any) {
+		super(props, context);
+		this.state = {
+			disabled: false,
+			changed: false,
+			message: '',
+			peering: null,
+		};
+	}
+
+	set(name: string, val: any): void {
+		let peering: any;
+
+		if (this.state.changed) {
+			peering = {
+				...this.state.peering,
+			};
+		} else {
+			peering = {
+				...this.props.peering,
+			};
+		}
+
+		peering[name] = val;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			peering: peering,
+		});
+	}
+
+	vpcName(vpcId: string): string {
+		for (let vpc of this.props.vpcs) {
+			if (vpc.id === vpcId) {
+				return vpc.name;
+			}
+		}
+		return vpcId || 'None';
+	}
+
+	organizationName(orgId: string): string {
+		for (let org of this.props.organizations) {
+			if (org.id === orgId) {
+				return org.name;
+			}
+		}
+		return orgId || 'None';
+	}
+
+	onSave = (): void => {
+		this.setState({
+			...this.state,
+			disabled: true,
+		});
+		PeeringActions.commit(this.state.peering).then((): void => {
+			this.setState({
+				...this.state,
+				message: 'Your changes have been saved',
+				changed: false,
+				disabled: false,
+			});
+
+			setTimeout((): void => {
+				if (!this.state.changed) {
+					this.setState({
+						...this.state,
+						message: '',
+						changed: false,
+						peering: null,
+					});
+				}
+			}, 3000);
+		}).catch((): void => {
+			this.setState({
+				...this.state,
+				message: '',
+				disabled: false,
+			});
+		});
+	}
+
+	onDelete = (): void => {
+		this.setState({
+			...this.state,
+			disabled: true,
+		});
+		PeeringActions.remove(this.props.peering.id).then((): void => {
+			this.setState({
+				...this.state,
+				disabled: false,
+			});
+		}).catch((): void => {
+			this.setState({
+				...this.state,
+				disabled: false,
+			});
+		});
+	}
+
+	render(): JSX.Element {
+		let peering: PeeringTypes.Peering = this.state.peering ||
+			this.props.peering;
+
+		let owner = !Constants.user ||
+			peering.organization === OrganizationsStore.current;
+		let peerOwner = !Constants.user ||
+			peering.peer_organization === OrganizationsStore.current;
+
+		let vpcsSelect: JSX.Element[] = [];
+		for (let vpc of this.props.vpcs) {
+			vpcsSelect.push(
+				<option
+					key={vpc.id}
+					value={vpc.id}
+				>{vpc.name}</option>,
+			);
+		}
+
+		let status: string;
+		if (this.props.peering.active) {
+			status = 'Active';
+		} else if (!this.props.peering.accepted) {
+			status = 'Pending acceptance from VPC organization';
+		} else {
+			status = 'Pending acceptance from peer VPC organization';
+		}
+
+		return <div
+			className="bp5-card"
+			style={css.card}
+		>
+			<div className="layout horizontal wrap">
+				<div style={css.group}>
+					<div style={css.remove}>
+						<ConfirmButton
+							safe={true}
+							className="bp5-minimal bp5-intent-danger bp5-icon-trash"
+							progressClassName="bp5-intent-danger"
+							dialogClassName="bp5-intent-danger bp5-icon-delete"
+							dialogLabel="Delete Peering"
+							confirmMsg="Permanently delete this peering"
+							confirmInput={true}
+							items={[peering.name]}
+							disabled={this.state.disabled}
+							onConfirm={this.onDelete}
+						/>
+					</div>
+					<PageInput
+						label="Name"
+						help="Name of peering"
+						type="text"
+						placeholder="Name"
+						value={peering.name}
+						onChange={(val): void => {
+							this.set('name', val);
+						}}
+					/>
+					<PageTextArea
+						label="Comment"
+						help="Peering comment."
+						placeholder="Peering comment"
+						rows={3}
+						value={peering.comment}
+						onChange={(val: string): void => {
+							this.set('comment', val);
+						}}
+					/>
+					<PageSelect
+						disabled={this.state.disabled}
+						hidden={Constants.user}
+						label="VPC"
+						help="VPC to peer."
+						value={peering.vpc}
+						onChange={(val): void => {
+							this.set('vpc', val);
+						}}
+					>
+						{vpcsSelect}
+					</PageSelect>
+					<PageSelect
+						disabled={this.state.disabled}
+						hidden={Constants.user}
+						label="Peer VPC"
+						help="VPC to peer with, the peer VPC must be in the same datacenter and the VPC networks cannot overlap."
+						value={peering.peer_vpc}
+						onChange={(val): void => {
+							this.set('peer_vpc', val);
+						}}
+					>
+						{vpcsSelect}
+					</PageSelect>
+					<PageSwitch
+						disabled={this.state.disabled || !owner}
+						label="Accepted"
+						help="Peering accepted by the organization of the VPC. Traffic is only routed once the peering is accepted by the organizations of both VPCs."
+						checked={peering.accepted}
+						onToggle={(): void => {
+							this.set('accepted', !peering.accepted);
+						}}
+					/>
+					<PageSwitch
+						disabled={this.state.disabled || !peerOwner}
+						label="Peer Accepted"
+						help="Peering accepted by the organization of the peer VPC. Traffic is only routed once the peering is accepted by the organizations of both VPCs."
+						checked={peering.peer_accepted}
+						onToggle={(): void => {
+							this.set('peer_accepted', !peering.peer_accepted);
+						}}
+					/>
+				</div>
+				<div style={css.group}>
+					<PageInfo
+						fields={[
+							{
+								label: 'ID',
+								value: this.props.peering.id || 'None',
+							},
+							{
+								label: 'Status',
+								value: status,
+							},
+							{
+								label: 'VPC',
+								value: this.vpcName(this.props.peering.vpc),
+							},
+							{
+								label: 'VPC Organization',
+								value: this.organizationName(
+									this.props.peering.organization),
+							},
+							{
+								label: 'Peer VPC',
+								value: this.vpcName(this.props.peering.peer_vpc),
+							},
+							{
+								label: 'Peer VPC Organization',
+								value: this.organizationName(
+									this.props.peering.peer_organization),
+							},
+						]}
+					/>
+				</div>
+			</div>
+			<PageSave
+				style={css.save}
+				hidden={!this.state.peering}
+				message={this.state.message}
+				changed={this.state.changed}
+				disabled={this.state.disabled}
+				light={true}
+				onCancel={(): void => {
+					this.setState({
+						...this.state,
+						changed: false,
+						peering: null,
+					});
+				}}
+				onSave={this.onSave}
+			/>
+		</div>;
+	}
+}
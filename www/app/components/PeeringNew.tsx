@@ -0,0 +1,204 @@
+/// <reference path="../References.d.ts"/>
+import * as React from 'react';
+import * as PeeringTypes from '../types/PeeringTypes';
+import * as VpcTypes from '../types/VpcTypes';
+import * as PeeringActions from '../actions/PeeringActions';
+import PageInput from './PageInput';
+import PageSelect from './PageSelect';
+import PageTextArea from './PageTextArea';
+import PageCreate from './PageCreate';
+import * as Constants from "../Constants";
+
+interface Props {
+	vpcs: VpcTypes.VpcsRo;
+	onClose: () => void;
+}
+
+interface State {
+	closed: boolean;
+	disabled: boolean;
+	changed: boolean;
+	message: string;
+	peering: PeeringTypes.Peering;
+}
+
+const css = {
+	card: {
+		position: 'relative',
+		padding: '10px 10px 0 10px',
+		marginBottom: '5px',
+	} as React.CSSProperties,
+	group: {
+		flex: 1,
+		minWidth: '280px',
+		margin: '0 10px',
+	} as React.CSSProperties,
+	save: {
+		paddingBottom: '10px',
+	} as React.CSSProperties,
+};
+
+export default class PeeringNew extends React.Component<Props, State> {
+	constructor(props: any, context: any) {
+		super(props, context);
+		this.state = {
+			closed: false,
+			disabled: false,
+			changed: false,
+			message: '',
+			peering: {},
+		};
+	}
+
+	set(name: string, val: any): void {
+		let peering: any = {
+			...this.state.peering,
+		};
+
+		peering[name] = val;
+
+		this.setState({
+			...this.state,
+			changed: true,
+			peering: peering,
+		});
+	}
+
+	onCreate = (): void => {
+		this.setState({
+			...this.state,
+			disabled: true,
+		});
+
+		let peering: PeeringTypes.Peering = {
+			...this.state.peering,
+		};
+
+		if (this.props.vpcs.length && !peering.vpc) {
+			peering.vpc = this.props.vpcs[0].id;
+		}
+
+		PeeringActions.create(peering).then((): void => {
+			this.setState({
+				...this.state,
+				message: 'Peering created successfully',
+				changed: false,
+			});
+
+			setTimeout((): void => {
+				this.setState({
+					...this.state,
+					disabled: false,
+					changed: true,
+				});
+			}, 2000);
+		}).catch((): void => {
+			this.setState({
+				...this.state,
+				message: '',
+				disabled: false,
+			});
+		});
+	}
+
+	render(): JSX.Element {
+		let peering = this.state.peering;
+
+		let vpcsSelect: JSX.Element[] = [];
+		for (let vpc of this.props.vpcs) {
+			vpcsSelect.push(
+				<option
+					key={vpc.id}
+					value={vpc.id}
+				>{vpc.name}</option>,
+			);
+		}
+
+		let peerVpcsSelect: JSX.Element[] = [
+			<option key="null" value="">Select Peer VPC</option>,
+			...vpcsSelect,
+		];
+
+		if (!vpcsSelect.length) {
+			vpcsSelect.push(<option key="null" value="">No VPCs</option>);
+		}
+
+		return <div
+			className="bp5-card"
+			style={css.card}
+		>
+			<div className="layout horizontal wrap">
+				<div style={css.group}>
+					<PageInput
+						label="Name"
+						help="Name of peering."
+						type="text"
+						placeholder="Enter name"
+						value={peering.name}
+						onChange={(val): void => {
+							this.set('name', val);
+						}}
+					/>
+					<PageTextArea
+						label="Comment"
+						help="Peering comment."
+						placeholder="Peering comment"
+						rows={3}
+						value={peering.comment}
+						onChange={(val: string): void => {
+							this.set('comment', val);
+						}}
+					/>
+				</div>
+				<div style={css.group}>
+					<PageSelect
+						disabled={this.state.disabled}
+						label="VPC"
+						help="VPC to peer."
+						value={peering.vpc}
+						onChange={(val): void => {
+							this.set('vpc', val);
+						}}
+					>
+						{vpcsSelect}
+					</PageSelect>
+					<PageSelect
+						disabled={this.state.disabled}
+						hidden={Constants.user}
+						label="Peer VPC"
+						help="VPC to peer with, the peer VPC must be in the same datacenter and the VPC networks cannot overlap."
+						value={peering.peer_vpc}
+						onChange={(val): void => {
+							this.set('peer_vpc', val);
+						}}
+					>
+						{peerVpcsSelect}
+					</PageSelect>
+					<PageInput
+						disabled={this.state.disabled}
+						hidden={!Constants.user}
+						label="Peer VPC ID"
+						help="ID of VPC to peer with, the peer VPC must be in the same datacenter and the VPC networks cannot overlap. Peering with a VPC in another organization must be accepted by that organization."
+						type="text"
+						placeholder="Enter peer VPC ID"
+						value={peering.peer_vpc}
+						onChange={(val): void => {
+							this.set('peer_vpc', val);
+						}}
+					/>
+				</div>
+			</div>
+			<PageCreate
+				style={css.save}
+				hidden={!this.state.peering}
+				message={this.state.message}
+				changed={this.state.changed}
+				disabled={this.state.disabled}
+				closed={this.state.closed}
+				light={true}
+				onCancel={this.props.onClose}
+				onCreate={this.onCreate}
+			/>
+		</div>;
+	}
+}
@@ -0,0 +1,136 @@
+/// <reference path="../References.d.ts"/>
+import * as React from 'react';
+import * as PeeringTypes from '../types/PeeringTypes';
+import * as VpcTypes from '../types/VpcTypes';
+import * as OrganizationTypes from '../types/OrganizationTypes';
+import PeeringsStore from '../stores/PeeringsStore';
+import VpcsNameStore from '../stores/VpcsNameStore';
+import OrganizationsStore from '../stores/OrganizationsStore';
+import * as PeeringActions from '../actions/PeeringActions';
+import * as VpcActions from '../actions/VpcActions';
+import * as OrganizationActions from '../actions/OrganizationActions';
+import NonState from './NonState';
+import Peering from './Peering';
+import PeeringNew from './PeeringNew';
+import Page from './Page';
+import PageHeader from './PageHeader';
+
+interface State {
+	peerings: PeeringTypes.PeeringsRo;
+	vpcs: VpcTypes.VpcsRo;
+	organizations: OrganizationTypes.OrganizationsRo;
+	newOpened: boolean;
+	disabled: boolean;
+}
+
+const css = {
+	header: {
+		marginTop: '-19px',
+	} as React.CSSProperties,
+	heading: {
+		margin: '19px 0 0 0',
+	} as React.CSSProperties,
+	button: {
+		margin: '8px 0 0 8px',
+	} as React.CSSProperties,
+	buttons: {
+		marginTop: '8px',
+	} as React.CSSProperties,
+};
+
+export default class Peerings extends React.Component<{}, State> {
+	constructor(props: any, context: any) {
+		super(props, context);
+		this.state = {
+			peerings: PeeringsStore.peerings,
+			vpcs: VpcsNameStore.vpcs,
+			organizations: OrganizationsStore.organizations,
+			newOpened: false,
+			disabled: false,
+		};
+	}
+
+	componentDidMount(): void {
+		PeeringsStore.addChangeListener(this.onChange);
+		VpcsNameStore.addChangeListener(this.onChange);
+		OrganizationsStore.addChangeListener(this.onChange);
+		PeeringActions.sync();
+		VpcActions.syncNames();
+		OrganizationActions.sync();
+	}
+
+	componentWillUnmount(): void {
+		PeeringsStore.removeChangeListener(this.onChange);
+		VpcsNameStore.removeChangeListener(this.onChange);
+		OrganizationsStore.removeChangeListener(this.onChange);
+	}
+
+	onChange = (): void => {
+		this.setState({
+			...this.state,
+			peerings: PeeringsStore.peerings,
+			vpcs: VpcsNameStore.vpcs,
+			organizations: OrganizationsStore.organizations,
+		});
+	}
+
+	render(): JSX.Element {
+		let peeringsDom: JSX.Element[] = [];
+
+		this.state.peerings.forEach((
+				peering: PeeringTypes.PeeringRo): void => {
+			peeringsDom.push(<Peering
+				key={peering.id}
+				peering={peering}
+				vpcs={this.state.vpcs}
+				organizations={this.state.organizations}
+			/>);
+		});
+
+		let newPeeringDom: JSX.Element;
+		if (this.state.newOpened) {
+			newPeeringDom = <PeeringNew
+				vpcs={this.state.vpcs}
+				onClose={(): void => {
+					this.setState({
+						...this.state,
+						newOpened: false,
+					});
+				}}
+			/>;
+		}
+
+		return <Page>
+			<PageHeader>
+				<div className="layout horizontal wrap" style={css.header}>
+					<h2 style={css.heading}>VPC Peerings</h2>
+					<div className="flex"/>
+					<div style={css.buttons}>
+						<button
+							className="bp5-button bp5-intent-success bp5-icon-add"
+							style={css.button}
+							disabled={this.state.disabled || this.state.newOpened}
+							type="button"
+							onClick={(): void => {
+								this.setState({
+									...this.state,
+									newOpened: true,
+								});
+							}}
+						>New</button>
+					</div>
+				</div>
+			</PageHeader>
+			<div>
+				{newPeeringDom}
+				{peeringsDom}
+			</div>
+			<NonState
+				hidden={!!peeringsDom.length || this.state.newOpened}
+				iconClass="bp5-icon-link"
+				title="No peerings"
+				description="Add a new peering to connect VPCs."
+			/>
+		</Page>;
+	}
+}
@@ -224,6 +224,20 @@ export default class Secret extends React.Component<Props, State> {
 				publicKeyHelp = "Public key for Oracle Cloud API authentication.";
 				publicKeyPlaceholder = "Oracle Cloud Public Key";
 				break;
+			case "rfc2136":
+				keyLabel = "TSIG Key Name";
+				keyHelp = "Name of TSIG key used to sign DNS updates. Prefix the name with the algorithm such as hmac-sha512:name to use an algorithm other than hmac-sha256.";
+				keyPlaceholder = "Key name";
+				valLabel = "TSIG Secret";
+				valHelp = "Base64 encoded TSIG key secret.";
+				valPlaceholder = "Secret";
+				regionLabel = "DNS Server";
+				regionHelp = "Address of authoritative DNS server accepting dynamic updates, port defaults to 53.";
+				regionPlaceholder = "host:port";
+				publicKeyLabel = "";
+				publicKeyHelp = "";
+				publicKeyPlaceholder = "";
+				break;
 		}
 
 		return <div
@@ -321,6 +335,7 @@ export default class Secret extends React.Component<Props, State> {
 						<option value="aws">AWS</option>
 						<option value="cloudflare">Cloudflare</option>
 						<option value="oracle_cloud">Oracle Cloud</option>
+						<option value="rfc2136">RFC 2136</option>
 					</PageSelect>
 					<PageSelect
 						disabled={this.state.disabled}
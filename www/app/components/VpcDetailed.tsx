@@ -10,6 +10,7 @@ import VpcRoute from './VpcRoute';
 import VpcMap from './VpcMap';
 import VpcSubnet from './VpcSubnet';
 import PageInput from './PageInput';
+import PageSwitch from './PageSwitch';
 import PageInfo from './PageInfo';
 import PageSave from './PageSave';
 import ConfirmButton from './ConfirmButton';
@@ -680,6 +681,15 @@ export default class VpcDetailed extends React.Component<Props, State> {
 							this.set('network', val);
 						}}
 					/>
+					<PageSwitch
+						disabled={this.state.disabled}
+						label="DNS Server"
+						help="Run a DNS server on the VPC gateway and configure instances to use it. Instances in the VPC and peered VPCs can be resolved as <instance>.<vpc>.internal and records of built-in organization domains are served. Other names are forwarded to the hypervisor DNS servers. Instances must be restarted to apply the DNS configuration."
+						checked={!!vpc.dns_server}
+						onToggle={(): void => {
+							this.set('dns_server', !vpc.dns_server);
+						}}
+					/>
 					<label style={css.itemsLabel}>
 						Subnets
 						<Help
@@ -0,0 +1,77 @@
+/// <reference path="../References.d.ts"/>
+import Dispatcher from '../dispatcher/Dispatcher';
+import EventEmitter from '../EventEmitter';
+import * as AddrGroupTypes from '../types/AddrGroupTypes';
+import * as GlobalTypes from '../types/GlobalTypes';
+
+class AddrGroupsStore extends EventEmitter {
+	_addrGroups: AddrGroupTypes.AddrGroupsRo = Object.freeze([]);
+	_map: {[key: string]: number} = {};
+	_token = Dispatcher.register((this._callback).bind(this));
+
+	_reset(): void {
+		this._addrGroups = Object.freeze([]);
+		this._map = {};
+		this.emitChange();
+	}
+
+	get addrGroups(): AddrGroupTypes.AddrGroupsRo {
+		return this._addrGroups;
+	}
+
+	get addrGroupsM(): AddrGroupTypes.AddrGroups {
+		let addrGroups: AddrGroupTypes.AddrGroups = [];
+		this._addrGroups.forEach((
+				addrGroup: AddrGroupTypes.AddrGroupRo): void => {
+			addrGroups.push({
+				...addrGroup,
+			});
+		});
+		return addrGroups;
+	}
+
+	addrGroup(id: string): AddrGroupTypes.AddrGroupRo {
+		let i = this._map[id];
+		if (i === undefined) {
+			return null;
+		}
+		return this._addrGroups[i];
+	}
+
+	emitChange(): void {
+		this.emitDefer(GlobalTypes.CHANGE);
+	}
+
+	addChangeListener(callback: () => void): void {
+		this.on(GlobalTypes.CHANGE, callback);
+	}
+
+	removeChangeListener(callback: () => void): void {
+		this.removeListener(GlobalTypes.CHANGE, callback);
+	}
+
+	_sync(addrGroups: AddrGroupTypes.AddrGroup[]): void {
+		this._map = {};
+		for (let i = 0; i < addrGroups.length; i++) {
+			addrGroups[i] = Object.freeze(addrGroups[i]);
+			this._map[addrGroups[i].id] = i;
+		}
+
+		this._addrGroups = Object.freeze(addrGroups);
+		this.emitChange();
+	}
+
+	_callback(action: AddrGroupTypes.AddrGroupDispatch): void {
+		switch (action.type) {
+			case GlobalTypes.RESET:
+				this._reset();
+				break;
+
+			case AddrGroupTypes.SYNC:
+				this._sync(action.data.addrGroups);
+				break;
+		}
+	}
+}
+
+export default new AddrGroupsStore();
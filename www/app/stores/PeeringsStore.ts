@@ -0,0 +1,77 @@
+/// <reference path="../References.d.ts"/>
+import Dispatcher from '../dispatcher/Dispatcher';
+import EventEmitter from '../EventEmitter';
+import * as PeeringTypes from '../types/PeeringTypes';
+import * as GlobalTypes from '../types/GlobalTypes';
+
+class PeeringsStore extends EventEmitter {
+	_peerings: PeeringTypes.PeeringsRo = Object.freeze([]);
+	_map: {[key: string]: number} = {};
+	_token = Dispatcher.register((this._callback).bind(this));
+
+	_reset(): void {
+		this._peerings = Object.freeze([]);
+		this._map = {};
+		this.emitChange();
+	}
+
+	get peerings(): PeeringTypes.PeeringsRo {
+		return this._peerings;
+	}
+
+	get peeringsM(): PeeringTypes.Peerings {
+		let peerings: PeeringTypes.Peerings = [];
+		this._peerings.forEach((
+				peering: PeeringTypes.PeeringRo): void => {
+			peerings.push({
+				...peering,
+			});
+		});
+		return peerings;
+	}
+
+	peering(id: string): PeeringTypes.PeeringRo {
+		let i = this._map[id];
+		if (i === undefined) {
+			return null;
+		}
+		return this._peerings[i];
+	}
+
+	emitChange(): void {
+		this.emitDefer(GlobalTypes.CHANGE);
+	}
+
+	addChangeListener(callback: () => void): void {
+		this.on(GlobalTypes.CHANGE, callback);
+	}
+
+	removeChangeListener(callback: () => void): void {
+		this.removeListener(GlobalTypes.CHANGE, callback);
+	}
+
+	_sync(peerings: PeeringTypes.Peering[]): void {
+		this._map = {};
+		for (let i = 0; i < peerings.length; i++) {
+			peerings[i] = Object.freeze(peerings[i]);
+			this._map[peerings[i].id] = i;
+		}
+
+		this._peerings = Object.freeze(peerings);
+		this.emitChange();
+	}
+
+	_callback(action: PeeringTypes.PeeringDispatch): void {
+		switch (action.type) {
+			case GlobalTypes.RESET:
+				this._reset();
+				break;
+
+			case PeeringTypes.SYNC:
+				this._sync(action.data.peerings);
+				break;
+		}
+	}
+}
+
+export default new PeeringsStore();
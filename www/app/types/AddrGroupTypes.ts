@@ -0,0 +1,25 @@
+/// <reference path="../References.d.ts"/>
+export const SYNC = 'addrgroup.sync';
+export const CHANGE = 'addrgroup.change';
+
+export interface AddrGroup {
+	id?: string;
+	name?: string;
+	comment?: string;
+	organization?: string;
+	addresses?: string[];
+}
+
+export type AddrGroups = AddrGroup[];
+
+export type AddrGroupRo = Readonly<AddrGroup>;
+export type AddrGroupsRo = ReadonlyArray<AddrGroupRo>;
+
+export interface AddrGroupDispatch {
+	type: string;
+	data?: {
+		id?: string;
+		addrGroup?: AddrGroup;
+		addrGroups?: AddrGroups;
+	};
+}
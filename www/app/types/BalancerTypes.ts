@@ -4,15 +4,42 @@ export const TRAVERSE = 'balancer.traverse';
 export const FILTER = 'balancer.filter';
 export const CHANGE = 'balancer.change';
 
+export interface Rule {
+	path?: string;
+	method?: string;
+	header?: string;
+	value?: string;
+	group?: string;
+}
+
 export interface Domain {
 	domain?: string;
 	host?: string;
+	rules?: Rule[];
 }
 
 export interface Backend {
 	protocol?: string;
 	hostname?: string;
 	port?: number;
+	group?: string;
+	weight?: number;
+	drain?: boolean;
+	drain_start?: string;
+}
+
+// Upper bounds in milliseconds of the backend latency histogram, must
+// match balancer.LatencyBuckets.
+export const LatencyBuckets = [5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000];
+
+export interface BackendState {
+	key?: string;
+	connections?: number;
+	connections_total?: number;
+	errors?: number;
+	requests?: number;
+	latency?: number[];
+	statuses?: {[key: string]: number};
 }
 
 export interface State {
@@ -25,6 +52,9 @@ export interface State {
 	unknown_mid?: string[];
 	unknown_low?: string[];
 	offline?: string[];
+	draining?: string[];
+	rejected?: number;
+	backends?: BackendState[];
 }
 
 export interface Balancer {
@@ -40,6 +70,26 @@ export interface Balancer {
 	domains?: Domain[];
 	backends?: Backend[];
 	check_path?: string;
+	check_interval?: number;
+	check_timeout?: number;
+	check_status_codes?: number[];
+	check_body?: string;
+	healthy_threshold?: number;
+	unhealthy_threshold?: number;
+	outlier_errors?: number;
+	outlier_time?: number;
+	check_port?: number;
+	listen_ports?: number[];
+	proxy_protocol?: string;
+	affinity?: string;
+	drain_timeout?: number;
+	allow_sources?: string[];
+	deny_sources?: string[];
+	rate_limit?: number;
+	rate_burst?: number;
+	max_body_bytes?: number;
+	max_header_bytes?: number;
+	access_log?: string;
 	states?: {[key: string]: State};
 }
 
@@ -3,6 +3,7 @@ export const SYNC = 'certificate.sync';
 export const CHANGE = 'certificate.change';
 
 export interface Info {
+	hash?: string;
 	signature_alg?: string;
 	public_key_alg?: string;
 	issuer?: string;
@@ -11,6 +12,20 @@ export interface Info {
 	dns_names?: string[];
 }
 
+export interface Lifecycle {
+	last_attempt?: string;
+	last_renewed?: string;
+	last_error?: string;
+	failures?: number;
+	next_attempt?: string;
+}
+
+export interface Version {
+	certificate?: string;
+	info?: Info;
+	replaced?: string;
+}
+
 export interface Certificate {
 	id?: string;
 	name?: string;
@@ -21,6 +36,17 @@ export interface Certificate {
 	certificate?: string;
 	info?: Info;
 	acme_domains?: string[];
+	acme_type?: string;
+	acme_auth?: string;
+	acme_secret?: string;
+	acme_directory?: string;
+	acme_root_ca?: string;
+	acme_eab_key_id?: string;
+	acme_eab_hmac?: string;
+	acme_preferred_chain?: string;
+	acme_key_type?: string;
+	lifecycle?: Lifecycle;
+	history?: Version[];
 }
 
 export type Certificates = Certificate[];
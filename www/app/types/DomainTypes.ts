@@ -13,9 +13,17 @@ export interface Domain {
 	type?: string;
 	secret?: string;
 	root_domain?: string;
+	drift?: Drift[];
+	drift_checked?: string;
 	records?: Record[];
 }
 
+export interface Drift {
+	sub_domain?: string;
+	type?: string;
+	value?: string;
+}
+
 export interface Record {
 	id?: string;
 	domain?: string;
@@ -23,6 +31,7 @@ export interface Record {
 	sub_domain?: string;
 	type?: string;
 	value?: string;
+	ttl?: number;
 	update?: boolean;
 	delete?: boolean;
 }
@@ -8,6 +8,20 @@ export interface Rule {
 	protocol: string;
 	port?: string;
 	source_ips?: string[];
+	source_roles?: string[];
+	source_groups?: string[];
+	counter_key?: string;
+}
+
+export interface Counter {
+	packets: number;
+	bytes: number;
+}
+
+export interface Counters {
+	timestamp?: string;
+	ingress?: {[key: string]: Counter};
+	egress?: {[key: string]: Counter};
 }
 
 export interface Firewall {
@@ -16,7 +30,10 @@ export interface Firewall {
 	comment?: string;
 	organization?: string;
 	network_roles?: string[];
+	log_only?: boolean;
 	ingress?: Rule[];
+	egress?: Rule[];
+	counters?: {[key: string]: Counters};
 }
 
 export interface Filter {
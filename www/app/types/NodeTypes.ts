@@ -75,6 +75,19 @@ export interface Node {
 	oracle_user?: string;
 	oracle_public_key?: string;
 	oracle_host_route?: boolean;
+	maintenance?: boolean;
+	evacuate?: boolean;
+	evacuation?: Evacuation;
+}
+
+export interface Evacuation {
+	state?: string;
+	start?: string;
+	timestamp?: string;
+	total?: number;
+	remaining?: number;
+	migrating?: number;
+	failed?: number;
 }
 
 export interface Vpc {
@@ -0,0 +1,31 @@
+/// <reference path="../References.d.ts"/>
+export const SYNC = 'peering.sync';
+export const CHANGE = 'peering.change';
+
+export interface Peering {
+	id?: string;
+	name?: string;
+	comment?: string;
+	datacenter?: string;
+	organization?: string;
+	vpc?: string;
+	accepted?: boolean;
+	peer_organization?: string;
+	peer_vpc?: string;
+	peer_accepted?: boolean;
+	active?: boolean;
+}
+
+export type Peerings = Peering[];
+
+export type PeeringRo = Readonly<Peering>;
+export type PeeringsRo = ReadonlyArray<PeeringRo>;
+
+export interface PeeringDispatch {
+	type: string;
+	data?: {
+		id?: string;
+		peering?: Peering;
+		peerings?: Peerings;
+	};
+}
@@ -25,6 +25,21 @@ export function formatAmount(amount: number): string {
 	return '$' + (amount / 100).toFixed(2);
 }
 
+export function formatBytes(bytes: number): string {
+	if (!bytes) {
+		return '0 B';
+	}
+
+	let units = ['B', 'KB', 'MB', 'GB', 'TB'];
+	let i = 0;
+	while (bytes >= 1024 && i < units.length - 1) {
+		bytes /= 1024;
+		i += 1;
+	}
+
+	return (i === 0 ? bytes : bytes.toFixed(1)) + ' ' + units[i];
+}
+
 export function formatDate(dateStr: string): string {
 	if (!dateStr || dateStr === '0001-01-01T00:00:00Z') {
 		return '';